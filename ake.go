@@ -225,7 +225,7 @@ func (c *Conversation) processDHKey(msg []byte) (isSame bool, err error) {
 	}
 
 	if !isGroupElement(dhKeyMsg.gy) {
-		return false, newOtrError("DH value out of range")
+		return false, newOtrErrorOfKind(ErrMalformedMessage, "DH value out of range").aboutMessage(msgTypeDHKey)
 	}
 
 	//If receive same public key twice, just retransmit the previous Reveal Signature
@@ -266,7 +266,7 @@ func (c *Conversation) processRevealSig(msg []byte) (err error) {
 
 	c.calcAKEKeys(c.calcDHSharedSecret())
 	if err = c.processEncryptedSig(encryptedSig, theirMAC, &c.ake.revealKey); err != nil {
-		return newOtrError("in reveal signature message: " + err.Error())
+		return inMessage(msgTypeRevealSig, "in reveal signature message: ", err)
 	}
 
 	return nil
@@ -285,7 +285,7 @@ func (c *Conversation) processSig(msg []byte) (err error) {
 	encryptedSig := sigMsg.encryptedSig
 
 	if err := c.processEncryptedSig(encryptedSig, theirMAC, &c.ake.sigKey); err != nil {
-		return newOtrError("in signature message: " + err.Error())
+		return inMessage(msgTypeSig, "in signature message: ", err)
 	}
 
 	return nil
@@ -294,7 +294,7 @@ func (c *Conversation) processSig(msg []byte) (err error) {
 func (c *Conversation) checkedSignatureVerification(mb, sig []byte) error {
 	rest, ok := c.theirKey.Verify(mb, sig)
 	if !ok {
-		return newOtrError("bad signature in encrypted signature")
+		return newOtrErrorOfKind(ErrBadSignature, "bad signature in encrypted signature")
 	}
 
 	if len(rest) > 0 {
@@ -310,7 +310,7 @@ func verifyEncryptedSignatureMAC(encryptedSig []byte, theirMAC []byte, keys *ake
 	myMAC := sumHMAC(keys.m2, tomac, v)[:v.truncateLength()]
 
 	if len(myMAC) != len(theirMAC) || subtle.ConstantTimeCompare(myMAC, theirMAC) == 0 {
-		return newOtrError("bad signature MAC in encrypted signature")
+		return newOtrErrorOfKind(ErrMACFailure, "bad signature MAC in encrypted signature")
 	}
 
 	return nil
@@ -362,11 +362,11 @@ func (c *Conversation) processEncryptedSig(encryptedSig []byte, theirMAC []byte,
 func extractGx(decryptedGx []byte) (*big.Int, error) {
 	newData, gx, ok := extractMPI(decryptedGx)
 	if !ok || len(newData) > 0 {
-		return gx, newOtrError("gx corrupt after decryption")
+		return gx, newOtrErrorOfKind(ErrMalformedMessage, "gx corrupt after decryption").aboutMessage(msgTypeRevealSig)
 	}
 
 	if !isGroupElement(gx) {
-		return gx, newOtrError("DH value out of range")
+		return gx, newOtrErrorOfKind(ErrMalformedMessage, "DH value out of range").aboutMessage(msgTypeRevealSig)
 	}
 
 	return gx, nil
@@ -382,7 +382,7 @@ func checkDecryptedGx(decryptedGx, hashedGx []byte, v otrVersion) error {
 	digest := v.hash2(decryptedGx)
 
 	if subtle.ConstantTimeCompare(digest[:], hashedGx[:]) == 0 {
-		return newOtrError("bad commit MAC in reveal signature message")
+		return newOtrErrorOfKind(ErrMACFailure, "bad commit MAC in reveal signature message").aboutMessage(msgTypeRevealSig)
 	}
 
 	return nil
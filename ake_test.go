@@ -223,18 +223,18 @@ func Test_processSig(t *testing.T) {
 func Test_processSig_returnsErrorIfTheSignatureDataIsInvalid(t *testing.T) {
 	c := newConversation(otrV2{}, fixtureRand())
 	err := c.processSig([]byte{0x01, 0x01, 0x00})
-	assertDeepEquals(t, err, newOtrError("corrupt signature message"))
+	assertDeepEquals(t, err, newOtrErrorOfKind(ErrMalformedMessage, "corrupt signature message").aboutMessage(msgTypeSig))
 }
 func Test_processRevealSig_returnsErrorIfTheRDataIsInvalid(t *testing.T) {
 	c := newConversation(otrV2{}, fixtureRand())
 	err := c.processRevealSig([]byte{0x01, 0x01, 0x00})
-	assertDeepEquals(t, err, newOtrError("corrupt reveal signature message"))
+	assertDeepEquals(t, err, newOtrErrorOfKind(ErrMalformedMessage, "corrupt reveal signature message").aboutMessage(msgTypeRevealSig))
 }
 
 func Test_processRevealSig_returnsErrorIfTheSignatureDataIsInvalid(t *testing.T) {
 	c := newConversation(otrV2{}, fixtureRand())
 	err := c.processRevealSig([]byte{0x00, 0x00, 0x00, 0x01, 0x01, 0x00, 0x00, 0x00, 0x02, 0x01})
-	assertDeepEquals(t, err, newOtrError("corrupt reveal signature message"))
+	assertDeepEquals(t, err, newOtrErrorOfKind(ErrMalformedMessage, "corrupt reveal signature message").aboutMessage(msgTypeRevealSig))
 }
 
 func Test_sigMessage(t *testing.T) {
@@ -329,7 +329,7 @@ func Test_extractGxWithCorruptError(t *testing.T) {
 
 func Test_extractGx_returnsErrorWhenThereIsNotEnoughLengthForTheMPI(t *testing.T) {
 	_, err := extractGx([]byte{0x00, 0x00, 0x00, 0x02, 0x01})
-	assertDeepEquals(t, err, newOtrError("gx corrupt after decryption"))
+	assertDeepEquals(t, err, newOtrErrorOfKind(ErrMalformedMessage, "gx corrupt after decryption").aboutMessage(msgTypeRevealSig))
 }
 
 func Test_extractGxWithRangeError(t *testing.T) {
@@ -419,13 +419,13 @@ func Test_generateSigKeyEncryptedSignature(t *testing.T) {
 func Test_processDHCommit_returnsErrorIfTheEncryptedGXPartIsNotCorrect(t *testing.T) {
 	c := newConversation(otrV2{}, fixtureRand())
 	err := c.processDHCommit([]byte{0x00, 0x00, 0x00, 0x02, 0x01})
-	assertDeepEquals(t, err, newOtrError("corrupt DH commit message"))
+	assertDeepEquals(t, err, newOtrErrorOfKind(ErrMalformedMessage, "corrupt DH commit message").aboutMessage(msgTypeDHCommit))
 }
 
 func Test_processDHCommit_returnsErrorIfTheHashedGXPartIsNotCorrect(t *testing.T) {
 	c := newConversation(otrV2{}, fixtureRand())
 	err := c.processDHCommit([]byte{0x00, 0x00, 0x00, 0x01, 0x01, 0x00, 0x00, 0x00, 0x02, 0x01})
-	assertDeepEquals(t, err, newOtrError("corrupt DH commit message"))
+	assertDeepEquals(t, err, newOtrErrorOfKind(ErrMalformedMessage, "corrupt DH commit message").aboutMessage(msgTypeDHCommit))
 }
 
 func Test_calcXBb_returnsErrorIfTheSigningDoesntWork(t *testing.T) {
@@ -496,11 +496,11 @@ func Test_sigMessage_returnsErrorFromgenerateEncryptedSignature(t *testing.T) {
 func Test_processDHKey_returnsErrorIfTheMessageHasAnIncorrectGyParameter(t *testing.T) {
 	c := newConversation(otrV2{}, fixedRand([]string{}))
 	_, err := c.processDHKey([]byte{0x00, 0x00, 0x00, 0x02, 0x01})
-	assertDeepEquals(t, err, newOtrError("corrupt DH key message"))
+	assertDeepEquals(t, err, newOtrErrorOfKind(ErrMalformedMessage, "corrupt DH key message").aboutMessage(msgTypeDHKey))
 }
 
 func Test_processDHKey_returnsErrorIfGyIsNotAValidDHParameter(t *testing.T) {
 	c := newConversation(otrV2{}, fixedRand([]string{}))
 	_, err := c.processDHKey([]byte{0x00, 0x00, 0x00, 0x01, 0x01})
-	assertDeepEquals(t, err, newOtrError("DH value out of range"))
+	assertDeepEquals(t, err, newOtrErrorOfKind(ErrMalformedMessage, "DH value out of range").aboutMessage(msgTypeDHKey))
 }
@@ -12,6 +12,11 @@ func (c *Conversation) generateNewDHKeyPair() error {
 }
 
 func (c *Conversation) akeHasFinished() error {
+	if err := c.checkTheirKey(); err != nil {
+		c.ake.wipe(true)
+		return err
+	}
+
 	c.keys.wipe()
 	c.keys = c.ake.keys
 	c.ake.wipe(false)
@@ -26,6 +31,10 @@ func (c *Conversation) akeHasFinished() error {
 		c.messageEvent(MessageEventMessageReflected)
 	}
 
+	if c.theirKeyHeld {
+		c.messageEvent(MessageEventTheirKeyHeld)
+	}
+
 	return c.generateNewDHKeyPair()
 }
 
@@ -47,7 +56,7 @@ func (c *Conversation) processAKE(msgType byte, msg []byte) (toSend []messageWit
 		c.ake.state, toSendSingle, err = c.ake.state.receiveSigMessage(c, msg)
 		toSendExtra, _ = c.maybeRetransmit()
 	default:
-		err = newOtrErrorf("unknown message type 0x%X", msgType)
+		err = newOtrErrorOfKindf(ErrMalformedMessage, "unknown message type 0x%X", msgType).aboutMessage(msgType)
 	}
 
 	c.ake.lastStateChange = time.Now()
@@ -270,7 +270,7 @@ func Test_authStateAwaitingRevealSig_receiveRevealSigMessage_returnsErrorIfProce
 	c := newConversation(otrV2{}, fixtureRand())
 	c.Policies.add(allowV2)
 	_, _, err := authStateAwaitingRevealSig{}.receiveRevealSigMessage(c, []byte{0x00, 0x00})
-	assertDeepEquals(t, err, newOtrError("corrupt reveal signature message"))
+	assertDeepEquals(t, err, newOtrErrorOfKind(ErrMalformedMessage, "corrupt reveal signature message").aboutMessage(msgTypeRevealSig))
 }
 
 func Test_receiveRevealSig_IgnoreMessageIfNotInStateAwaitingRevealSig(t *testing.T) {
@@ -336,8 +336,8 @@ func Test_receiveDecoded_receiveRevealSigMessageAndSetMessageStateToEncrypted(t
 
 func Test_receiveDecoded_receiveRevealSigMessageWillResendPotentialLastMessage(t *testing.T) {
 	c := aliceContextAtAwaitingRevealSig()
-	c.resend.later(MessagePlaintext("what do you think turn 2"))
-	c.resend.later(MessagePlaintext("I mean, about that thing"))
+	c.resend.later(MessagePlaintext("what do you think turn 2"), SendOptions{})
+	c.resend.later(MessagePlaintext("I mean, about that thing"), SendOptions{})
 	c.resend.mayRetransmit = retransmitWithPrefix
 	c.updateLastSent()
 	msg := fixtureRevealSigMsg(otrV2{})
@@ -403,8 +403,8 @@ func Test_receiveDecoded_receiveSigMessageAndSetMessageStateToEncrypted(t *testi
 
 func Test_receiveDecoded_receiveSigMessageWillResendTheLastPotentialMessage(t *testing.T) {
 	c := bobContextAtAwaitingSig()
-	c.resend.later(MessagePlaintext("what do you think"))
-	c.resend.later(MessagePlaintext("you think, dont you?"))
+	c.resend.later(MessagePlaintext("what do you think"), SendOptions{})
+	c.resend.later(MessagePlaintext("you think, dont you?"), SendOptions{})
 	c.resend.mayRetransmit = retransmitWithPrefix
 	c.updateLastSent()
 
@@ -454,7 +454,7 @@ func Test_authStateAwaitingDHKey_receiveDHKeyMessage_returnsErrorIfprocessDHKeyR
 
 	_, _, err := authStateAwaitingDHKey{}.receiveDHKeyMessage(c, []byte{0x00, 0x02})
 
-	assertDeepEquals(t, err, newOtrError("corrupt DH key message"))
+	assertDeepEquals(t, err, newOtrErrorOfKind(ErrMalformedMessage, "corrupt DH key message").aboutMessage(msgTypeDHKey))
 }
 
 func Test_authStateAwaitingDHKey_receiveDHKeyMessage_returnsErrorIfrevealSigMessageReturnsError(t *testing.T) {
@@ -483,14 +483,14 @@ func Test_authStateAwaitingSig_receiveDHKeyMessage_returnsErrorIfprocessDHKeyRet
 
 	_, _, err := authStateAwaitingSig{}.receiveDHKeyMessage(c, []byte{0x01, 0x02})
 
-	assertEquals(t, err, newOtrError("corrupt DH key message"))
+	assertEquals(t, err, newOtrErrorOfKind(ErrMalformedMessage, "corrupt DH key message").aboutMessage(msgTypeDHKey))
 }
 
 func Test_authStateAwaitingSig_receiveSigMessage_returnsErrorIfProcessSigFails(t *testing.T) {
 	c := newConversation(otrV2{}, fixtureRand())
 	c.Policies.add(allowV2)
 	_, _, err := authStateAwaitingSig{}.receiveSigMessage(c, []byte{0x00, 0x00})
-	assertEquals(t, err, newOtrError("corrupt signature message"))
+	assertEquals(t, err, newOtrErrorOfKind(ErrMalformedMessage, "corrupt signature message").aboutMessage(msgTypeSig))
 }
 
 func Test_authStateAwaitingRevealSig_receiveDHCommitMessage_returnsErrorIfProcessDHCommitOrGenerateCommitInstanceTagsFailsFails(t *testing.T) {
@@ -502,7 +502,7 @@ func Test_authStateAwaitingRevealSig_receiveDHCommitMessage_returnsErrorIfProces
 	c.ake.theirPublicValue = ourDHCommitAKE.ake.ourPublicValue
 
 	_, _, err := authStateAwaitingRevealSig{}.receiveDHCommitMessage(c, []byte{0x00, 0x00})
-	assertEquals(t, err, newOtrError("corrupt DH commit message"))
+	assertEquals(t, err, newOtrErrorOfKind(ErrMalformedMessage, "corrupt DH commit message").aboutMessage(msgTypeDHCommit))
 }
 
 func Test_authStateNone_receiveDHCommitMessage_returnsErrorIfgenerateCommitMsgInstanceTagsFails(t *testing.T) {
@@ -514,7 +514,7 @@ func Test_authStateNone_receiveDHCommitMessage_returnsErrorIfgenerateCommitMsgIn
 	c.ake.theirPublicValue = ourDHCommitAKE.ake.ourPublicValue
 
 	_, _, err := authStateNone{}.receiveDHCommitMessage(c, []byte{0x00, 0x00})
-	assertEquals(t, err, newOtrError("corrupt DH commit message"))
+	assertEquals(t, err, newOtrErrorOfKind(ErrMalformedMessage, "corrupt DH commit message").aboutMessage(msgTypeDHCommit))
 }
 
 func Test_authStateNone_receiveDHCommitMessage_returnsErrorIfdhKeyMessageFails(t *testing.T) {
@@ -538,7 +538,7 @@ func Test_authStateNone_receiveDHCommitMessage_returnsErrorIfProcessDHCommitFail
 	c.ake.theirPublicValue = ourDHCommitAKE.ake.ourPublicValue
 
 	_, _, err := authStateNone{}.receiveDHCommitMessage(c, []byte{0x00, 0x00})
-	assertEquals(t, err, newOtrError("corrupt DH commit message"))
+	assertEquals(t, err, newOtrErrorOfKind(ErrMalformedMessage, "corrupt DH commit message").aboutMessage(msgTypeDHCommit))
 }
 
 func Test_authStateAwaitingDHKey_receiveDHCommitMessage_failsIfMsgDoesntHaveHeader(t *testing.T) {
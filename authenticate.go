@@ -1,30 +1,82 @@
 package otr3
 
+import "github.com/twstrike/otr3/smp"
+
 // StartAuthenticate should be called when the user wants to initiate authentication with a peer.
 // The authentication uses an optional question message and a shared secret. The authentication will proceed
 // until the event handler reports that SMP is complete, that a secret is needed or that SMP has failed.
 func (c *Conversation) StartAuthenticate(question string, mutualSecret []byte) ([]ValidMessage, error) {
-	c.smp.ensureSMP()
-
-	tlvs, err := c.smp.state.startAuthenticate(c, question, mutualSecret)
+	if !c.IsEncrypted() {
+		return nil, errCantAuthenticateWithoutEncryption
+	}
 
+	smpMessages, err := c.ensureSMP().Start(question, c.generateSMPSecret(true, mutualSecret))
 	if err != nil {
-		return nil, err
+		// Starting can only fail when there isn't enough randomness
+		return nil, errShortRandomRead
 	}
 
-	msgs, _, err := c.createSerializedDataMessage(nil, messageFlagIgnoreUnreadable, tlvs)
-	return msgs, err
+	return c.createSMPDataMessage(smpMessages...)
 }
 
 // ProvideAuthenticationSecret should be called when the peer has started an authentication request, and the UI has been notified that a secret is needed
 // It is only valid to call this function if the current SMP state is waiting for a secret to be provided. The return is the potential messages to send.
 func (c *Conversation) ProvideAuthenticationSecret(mutualSecret []byte) ([]ValidMessage, error) {
-	t, err := c.continueSMP(mutualSecret)
+	s := c.ensureSMP()
+	if s.State() != smp.StateWaitingForSecret {
+		s.Abort()
+		return nil, errNotWaitingForSMPSecret
+	}
+
+	if !c.IsEncrypted() {
+		s.Abort()
+		return nil, errCantAuthenticateWithoutEncryption
+	}
+
+	m, err := s.ProvideSecret(c.generateSMPSecret(false, mutualSecret))
 	if err != nil {
-		return nil, err
+		return nil, smpError(err)
+	}
+
+	return c.createSMPDataMessage(m)
+}
+
+// AbortAuthenticate should be called when the user wants to stop an authentication in progress, for example
+// because the peer never answered. The SMP state is reset, and the return is the messages that tell the peer about it.
+func (c *Conversation) AbortAuthenticate() ([]ValidMessage, error) {
+	if !c.IsEncrypted() {
+		return nil, errCantAuthenticateWithoutEncryption
+	}
+
+	return c.createSMPDataMessage(c.ensureSMP().Abort())
+}
+
+// CheckSMPTimeout aborts the authentication in progress if the peer hasn't answered within the timeout
+// set with SetSMPTimeout, and notifies the SMP event handler with SMPEventAbort. Nothing happens in the
+// background, so it should be called regularly, for example from a timer. The return is the potential messages to send.
+func (c *Conversation) CheckSMPTimeout() ([]ValidMessage, error) {
+	if c.smp == nil {
+		return nil, nil
+	}
+
+	m := c.ensureSMP().CheckTimeout()
+	if m == nil || !c.IsEncrypted() {
+		return nil, nil
+	}
+
+	return c.createSMPDataMessage(m)
+}
+
+func (c *Conversation) createSMPDataMessage(smpMessages ...smp.Message) ([]ValidMessage, error) {
+	tlvs := make([]tlv, 0, len(smpMessages)+1)
+	for _, m := range smpMessages {
+		if c.smpNormalization != smp.NormalizeNone && announcesSMPSecretNormalization(m.Type()) {
+			tlvs = append(tlvs, smpSecretNormalizationTLV(c.smpNormalization))
+		}
+		tlvs = append(tlvs, smpMessageTLV(m))
 	}
 
-	msgs, _, err := c.createSerializedDataMessage(nil, messageFlagIgnoreUnreadable, []tlv{*t})
+	msgs, _, err := c.createSerializedDataMessage(nil, messageFlagIgnoreUnreadable, tlvs)
 	return msgs, err
 }
 
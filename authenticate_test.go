@@ -1,6 +1,11 @@
 package otr3
 
-import "testing"
+import (
+	"testing"
+	"time"
+
+	"github.com/twstrike/otr3/smp"
+)
 
 func Test_StartAuthenticate_failsIfWeAreNotCurrentlyEncrypted(t *testing.T) {
 	c := newConversation(otrV3{}, fixtureRand())
@@ -29,9 +34,7 @@ func Test_StartAuthenticate_generatesAnSMPSecretFromTheSharedSecret(t *testing.T
 	c.ourCurrentKey = alicePrivateKey
 	c.theirKey = bobPrivateKey.PublicKey()
 
-	_, e := c.StartAuthenticate("", []byte("hello world"))
-	assertEquals(t, e, nil)
-	assertDeepEquals(t, c.smp.secret, bnFromHex("3D7264BD983B8CA53CB365444844816F7D2453580B552EEE45CD09CA13614A5"))
+	assertDeepEquals(t, c.generateSMPSecret(true, []byte("hello world")), bnFromHex("3D7264BD983B8CA53CB365444844816F7D2453580B552EEE45CD09CA13614A5"))
 }
 
 func Test_StartAuthenticate_generatesAndReturnsTheFirstSMPMessageToSend(t *testing.T) {
@@ -43,42 +46,48 @@ func Test_StartAuthenticate_generatesAndReturnsTheFirstSMPMessageToSend(t *testi
 
 	msg, e := c.StartAuthenticate("", []byte("hello world"))
 	assertEquals(t, e, nil)
-	assertEquals(t, c.smp.state, smpStateExpect2{})
+	assertEquals(t, c.smp.State(), smp.StateExpect2)
 
 	dec, _ := c.decode(encodedMessage(msg[0]))
 	_, messageBody, _ := c.parseMessageHeader(dec)
-	assertDeepEquals(t, len(messageBody), 1361)
+	assertDeepEquals(t, len(messageBody), 1265)
 }
 
-func Test_StartAuthenticate_generatesAndSetsTheFirstMessageOnTheConversation(t *testing.T) {
-	c := bobContextAfterAKE()
-	c.msgState = encrypted
-	c.ssid = [8]byte{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08}
-	c.ourCurrentKey = bobPrivateKey
-	c.theirKey = alicePrivateKey.PublicKey()
-	c.smp.s1 = nil
+func Test_StartAuthenticate_sendsAnSMP1MessageWithoutAQuestion(t *testing.T) {
+	alice, bob := smpConversationsAfterAKE(t)
 
-	c.StartAuthenticate("", []byte("hello world"))
+	msgs, _ := alice.StartAuthenticate("", []byte("hello world"))
 
-	assertNotNil(t, c.smp.s1)
-	assertEquals(t, c.smp.s1.msg.hasQuestion, false)
-	assertEquals(t, c.smp.s1.msg.tlv().tlvType, tlvTypeSMP1)
+	bob.expectSMPEvent(t, func() {
+		bob.Receive(msgs[0])
+	}, SMPEventAskForSecret, 25, "")
+	_, ok := bob.SMPQuestion()
+	assertEquals(t, ok, false)
 }
 
-func Test_StartAuthenticate_generatesAn1QMessageIfAQuestionIsGiven(t *testing.T) {
-	c := bobContextAfterAKE()
-	c.msgState = encrypted
-	c.ssid = [8]byte{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08}
-	c.ourCurrentKey = bobPrivateKey
-	c.theirKey = alicePrivateKey.PublicKey()
-	c.smp.s1 = nil
+func Test_StartAuthenticate_sendsAnSMP1QMessageIfAQuestionIsGiven(t *testing.T) {
+	alice, bob := smpConversationsAfterAKE(t)
+
+	msgs, _ := alice.StartAuthenticate("Where did we meet?", []byte("hello world"))
+
+	bob.expectSMPEvent(t, func() {
+		bob.Receive(msgs[0])
+	}, SMPEventAskForAnswer, 25, "Where did we meet?")
+	q, _ := bob.SMPQuestion()
+	assertEquals(t, q, "Where did we meet?")
+}
+
+func Test_StartAuthenticate_usesTheSharedSecret(t *testing.T) {
+	alice, bob := smpConversationsAfterAKE(t)
 
-	c.StartAuthenticate("Where did we meet?", []byte("hello world"))
+	aliceMessages, _ := alice.StartAuthenticate("", []byte("hello world"))
+	bob.Receive(aliceMessages[0])
+	bobMessages, _ := bob.ProvideAuthenticationSecret([]byte("goodbye world"))
+	_, aliceMessages, _ = alice.Receive(bobMessages[0])
 
-	assertNotNil(t, c.smp.s1)
-	assertEquals(t, c.smp.s1.msg.hasQuestion, true)
-	assertEquals(t, c.smp.s1.msg.question, "Where did we meet?")
-	assertEquals(t, c.smp.s1.msg.tlv().tlvType, tlvTypeSMP1WithQuestion)
+	bob.expectSMPEvent(t, func() {
+		bob.Receive(aliceMessages[0])
+	}, SMPEventFailure, 100, "")
 }
 
 func Test_StartAuthenticate_generatesAnAbortMessageTLVIfWeAreInAnSMPStateAlready(t *testing.T) {
@@ -87,14 +96,13 @@ func Test_StartAuthenticate_generatesAnAbortMessageTLVIfWeAreInAnSMPStateAlready
 	c.ssid = [8]byte{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08}
 	c.ourCurrentKey = bobPrivateKey
 	c.theirKey = alicePrivateKey.PublicKey()
-	c.smp.s1 = nil
-	c.smp.state = smpStateExpect3{}
+	c.StartAuthenticate("", []byte("hello world"))
 
 	msg, e := c.StartAuthenticate("", []byte("hello world"))
 	assertEquals(t, e, nil)
 	dec, _ := c.decode(encodedMessage(msg[0]))
 	_, messageBody, _ := c.parseMessageHeader(dec)
-	assertDeepEquals(t, len(messageBody), 1369)
+	assertDeepEquals(t, len(messageBody), 1265)
 }
 
 func Test_ProvideAuthenticationSecret_failsIfWeAreNotCurrentlyEncrypted(t *testing.T) {
@@ -103,7 +111,7 @@ func Test_ProvideAuthenticationSecret_failsIfWeAreNotCurrentlyEncrypted(t *testi
 	c.ssid = [8]byte{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08}
 	c.ourCurrentKey = bobPrivateKey
 	c.theirKey = alicePrivateKey.PublicKey()
-	c.smp.state = smpStateWaitingForSecret{msg: fixtureMessage1()}
+	c.waitForSMPSecret()
 
 	_, e := c.ProvideAuthenticationSecret([]byte("hello world"))
 	assertEquals(t, e, errCantAuthenticateWithoutEncryption)
@@ -115,11 +123,8 @@ func Test_ProvideAuthenticationSecret_generatesAnSMPSecretFromTheSharedSecret(t
 	c.ssid = [8]byte{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08}
 	c.ourCurrentKey = bobPrivateKey
 	c.theirKey = alicePrivateKey.PublicKey()
-	c.smp.state = smpStateWaitingForSecret{msg: fixtureMessage1()}
 
-	_, e := c.ProvideAuthenticationSecret([]byte("hello world"))
-	assertEquals(t, e, nil)
-	assertDeepEquals(t, c.smp.secret, bnFromHex("3D7264BD983B8CA53CB365444844816F7D2453580B552EEE45CD09CA13614A5"))
+	assertDeepEquals(t, c.generateSMPSecret(false, []byte("hello world")), bnFromHex("3D7264BD983B8CA53CB365444844816F7D2453580B552EEE45CD09CA13614A5"))
 }
 
 func Test_ProvideAuthenticationSecret_failsAndAbortsIfWeAreNotWaitingForASecret(t *testing.T) {
@@ -128,10 +133,11 @@ func Test_ProvideAuthenticationSecret_failsAndAbortsIfWeAreNotWaitingForASecret(
 	c.ssid = [8]byte{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08}
 	c.ourCurrentKey = bobPrivateKey
 	c.theirKey = alicePrivateKey.PublicKey()
-	c.smp.state = smpStateExpect3{}
+	c.StartAuthenticate("", []byte("hello world"))
 
 	_, e := c.ProvideAuthenticationSecret([]byte("hello world"))
 	assertEquals(t, e, errNotWaitingForSMPSecret)
+	assertEquals(t, c.smp.State(), smp.StateExpect1)
 }
 
 func Test_ProvideAuthenticationSecret_continuesWithMessageProcessingIfInTheRightState(t *testing.T) {
@@ -140,13 +146,13 @@ func Test_ProvideAuthenticationSecret_continuesWithMessageProcessingIfInTheRight
 	c.ssid = [8]byte{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08}
 	c.ourCurrentKey = bobPrivateKey
 	c.theirKey = alicePrivateKey.PublicKey()
-	c.smp.state = smpStateWaitingForSecret{msg: fixtureMessage1()}
+	c.waitForSMPSecret()
 
 	msg, e := c.ProvideAuthenticationSecret([]byte("hello world"))
 	assertNil(t, e)
 	dec, _ := c.decode(encodedMessage(msg[0]))
 	_, messageBody, _ := c.parseMessageHeader(dec)
-	assertDeepEquals(t, len(messageBody), 2181)
+	assertDeepEquals(t, len(messageBody), 2033)
 }
 
 func Test_ProvideAuthenticationSecret_setsTheNextMessageState(t *testing.T) {
@@ -155,12 +161,12 @@ func Test_ProvideAuthenticationSecret_setsTheNextMessageState(t *testing.T) {
 	c.ssid = [8]byte{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08}
 	c.ourCurrentKey = bobPrivateKey
 	c.theirKey = alicePrivateKey.PublicKey()
-	c.smp.state = smpStateWaitingForSecret{msg: fixtureMessage1()}
+	c.waitForSMPSecret()
 
 	_, e := c.ProvideAuthenticationSecret([]byte("hello world"))
 	assertNil(t, e)
 
-	assertDeepEquals(t, c.smp.state, smpStateExpect3{})
+	assertEquals(t, c.smp.State(), smp.StateExpect3)
 }
 
 func Test_ProvideAuthenticationSecret_returnsFailureFromContinueSMP(t *testing.T) {
@@ -169,8 +175,168 @@ func Test_ProvideAuthenticationSecret_returnsFailureFromContinueSMP(t *testing.T
 	c.ssid = [8]byte{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08}
 	c.ourCurrentKey = bobPrivateKey
 	c.theirKey = alicePrivateKey.PublicKey()
-	c.smp.state = smpStateWaitingForSecret{msg: fixtureMessage1()}
+	c.waitForSMPSecret()
 
 	_, e := c.ProvideAuthenticationSecret([]byte("hello world"))
 	assertEquals(t, e, errCantAuthenticateWithoutEncryption)
 }
+
+func Test_AbortAuthenticate_failsIfWeAreNotCurrentlyEncrypted(t *testing.T) {
+	c := newConversation(otrV3{}, fixtureRand())
+	c.msgState = plainText
+
+	_, e := c.AbortAuthenticate()
+	assertEquals(t, e, errCantAuthenticateWithoutEncryption)
+}
+
+func Test_AbortAuthenticate_resetsTheSMPState(t *testing.T) {
+	alice, _ := smpConversationsAfterAKE(t)
+	alice.StartAuthenticate("", []byte("hello world"))
+
+	msgs, e := alice.AbortAuthenticate()
+	assertNil(t, e)
+	assertEquals(t, len(msgs), 1)
+	assertEquals(t, alice.smp.State(), smp.StateExpect1)
+}
+
+func Test_AbortAuthenticate_tellsThePeerAboutIt(t *testing.T) {
+	alice, bob := smpConversationsAfterAKE(t)
+	aliceMessages, _ := alice.StartAuthenticate("", []byte("hello world"))
+	bob.Receive(aliceMessages[0])
+
+	aliceMessages, _ = alice.AbortAuthenticate()
+
+	bob.expectSMPEvent(t, func() {
+		bob.Receive(aliceMessages[0])
+	}, SMPEventAbort, 0, "")
+	assertEquals(t, bob.smp.State(), smp.StateExpect1)
+}
+
+func Test_CheckSMPTimeout_abortsWhenThePeerDoesNotAnswerInTime(t *testing.T) {
+	alice, bob := smpConversationsAfterAKE(t)
+	alice.SetSMPTimeout(time.Nanosecond)
+	alice.StartAuthenticate("", []byte("hello world"))
+	time.Sleep(time.Millisecond)
+
+	var msgs []ValidMessage
+	alice.expectSMPEvent(t, func() {
+		msgs, _ = alice.CheckSMPTimeout()
+	}, SMPEventAbort, 0, "")
+	assertEquals(t, alice.smp.State(), smp.StateExpect1)
+
+	bob.expectSMPEvent(t, func() {
+		bob.Receive(msgs[0])
+	}, SMPEventAbort, 0, "")
+}
+
+func Test_CheckSMPTimeout_doesNothingWithoutATimeout(t *testing.T) {
+	alice, _ := smpConversationsAfterAKE(t)
+	alice.StartAuthenticate("", []byte("hello world"))
+
+	msgs, e := alice.CheckSMPTimeout()
+	assertNil(t, e)
+	assertNil(t, msgs)
+	assertEquals(t, alice.smp.State(), smp.StateExpect2)
+}
+
+func Test_CheckSMPTimeout_doesNothingIfSMPHasNotStarted(t *testing.T) {
+	c := &Conversation{}
+	c.SetSMPTimeout(time.Nanosecond)
+
+	msgs, e := c.CheckSMPTimeout()
+	assertNil(t, e)
+	assertNil(t, msgs)
+}
+
+func Test_generateSMPSecret_normalizesTheSecretIfAsked(t *testing.T) {
+	c := bobContextAfterAKE()
+	c.ssid = [8]byte{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08}
+	c.ourCurrentKey = bobPrivateKey
+	c.theirKey = alicePrivateKey.PublicKey()
+	c.SetSMPSecretNormalization(smp.NormalizeDefault | smp.NormalizeCase)
+
+	assertDeepEquals(t, c.generateSMPSecret(false, []byte(" Hello World\n")), bnFromHex("3D7264BD983B8CA53CB365444844816F7D2453580B552EEE45CD09CA13614A5"))
+}
+
+func Test_SMPSecretNormalization_reportsTheNormalizationInUse(t *testing.T) {
+	c := &Conversation{}
+	assertEquals(t, c.SMPSecretNormalization(), smp.NormalizeNone)
+
+	c.SetSMPSecretNormalization(smp.NormalizeDefault)
+	assertEquals(t, c.SMPSecretNormalization(), smp.NormalizeDefault)
+}
+
+func Test_StartAuthenticate_succeedsWithDifferentlyTypedSecretsWhenBothPeersNormalize(t *testing.T) {
+	alice, bob := smpConversationsAfterAKE(t)
+	alice.SetSMPSecretNormalization(smp.NormalizeDefault | smp.NormalizeCase)
+	bob.SetSMPSecretNormalization(smp.NormalizeDefault | smp.NormalizeCase)
+
+	aliceMessages, _ := alice.StartAuthenticate("", []byte("Paris"))
+	bob.Receive(aliceMessages[0])
+	bobMessages, _ := bob.ProvideAuthenticationSecret([]byte(" paris\n"))
+	_, aliceMessages, _ = alice.Receive(bobMessages[0])
+
+	bob.expectSMPEvent(t, func() {
+		bob.Receive(aliceMessages[0])
+	}, SMPEventSuccess, 100, "")
+}
+
+func (c *Conversation) recordSMPEvents() *[]SMPEvent {
+	var events []SMPEvent
+	c.smpEventHandler = dynamicSMPEventHandler{func(event SMPEvent, progressPercent int, question string) {
+		events = append(events, event)
+	}}
+	return &events
+}
+
+func Test_StartAuthenticate_announcesTheNormalizationToThePeer(t *testing.T) {
+	alice, bob := smpConversationsAfterAKE(t)
+	alice.SetSMPSecretNormalization(smp.NormalizeDefault | smp.NormalizeCase)
+	bob.SetSMPSecretNormalization(smp.NormalizeDefault | smp.NormalizeCase)
+	events := bob.recordSMPEvents()
+
+	aliceMessages, _ := alice.StartAuthenticate("", []byte("Paris"))
+	bob.Receive(aliceMessages[0])
+
+	assertDeepEquals(t, *events, []SMPEvent{SMPEventAskForSecret})
+	assertEquals(t, bob.TheirSMPSecretNormalization(), smp.NormalizeDefault|smp.NormalizeCase)
+}
+
+func Test_StartAuthenticate_signalsWhenThePeerNormalizesDifferently(t *testing.T) {
+	alice, bob := smpConversationsAfterAKE(t)
+	alice.SetSMPSecretNormalization(smp.NormalizeDefault)
+	events := bob.recordSMPEvents()
+
+	aliceMessages, _ := alice.StartAuthenticate("", []byte("Paris"))
+	bob.Receive(aliceMessages[0])
+
+	assertDeepEquals(t, *events, []SMPEvent{SMPEventNormalizationMismatch, SMPEventAskForSecret})
+	assertEquals(t, bob.TheirSMPSecretNormalization(), smp.NormalizeDefault)
+}
+
+func Test_StartAuthenticate_signalsWhenThePeerDoesntAnnounceANormalization(t *testing.T) {
+	alice, bob := smpConversationsAfterAKE(t)
+	bob.SetSMPSecretNormalization(smp.NormalizeDefault)
+	events := bob.recordSMPEvents()
+
+	aliceMessages, _ := alice.StartAuthenticate("", []byte("Paris"))
+	bob.Receive(aliceMessages[0])
+
+	assertDeepEquals(t, *events, []SMPEvent{SMPEventNormalizationMismatch, SMPEventAskForSecret})
+	assertEquals(t, bob.TheirSMPSecretNormalization(), smp.NormalizeNone)
+}
+
+func Test_ProvideAuthenticationSecret_announcesTheNormalizationToTheInitiator(t *testing.T) {
+	alice, bob := smpConversationsAfterAKE(t)
+	bob.SetSMPSecretNormalization(smp.NormalizeDefault)
+
+	aliceMessages, _ := alice.StartAuthenticate("", []byte("Paris"))
+	bob.Receive(aliceMessages[0])
+	bobMessages, _ := bob.ProvideAuthenticationSecret([]byte("Paris"))
+
+	events := alice.recordSMPEvents()
+	alice.Receive(bobMessages[0])
+
+	assertDeepEquals(t, *events, []SMPEvent{SMPEventNormalizationMismatch, SMPEventInProgress})
+	assertEquals(t, alice.TheirSMPSecretNormalization(), smp.NormalizeDefault)
+}
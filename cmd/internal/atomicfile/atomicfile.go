@@ -0,0 +1,39 @@
+// Package atomicfile writes the files of the commands so that a failure never leaves a truncated file behind.
+package atomicfile
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// Write writes a new version of the file at path, readable only by its owner.
+// The content is written to a temporary file in the same directory that is renamed over path
+// once complete, so the file has either its old or its new content.
+func Write(path string, write func(io.Writer) error) (err error) {
+	f, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			f.Close()
+			os.Remove(f.Name())
+		}
+	}()
+
+	if err = f.Chmod(0600); err != nil {
+		return err
+	}
+	if err = write(f); err != nil {
+		return err
+	}
+	if err = f.Sync(); err != nil {
+		return err
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
@@ -0,0 +1,66 @@
+package atomicfile
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "atomicfile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func Test_Write_writesTheFileReadableOnlyByItsOwner(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "file")
+
+	err := Write(path, func(w io.Writer) error {
+		_, err := io.WriteString(w, "new")
+		return err
+	})
+	assertNil(t, err)
+
+	data, _ := ioutil.ReadFile(path)
+	assertEquals(t, string(data), "new")
+	info, _ := os.Stat(path)
+	assertEquals(t, info.Mode().Perm(), os.FileMode(0600))
+}
+
+func Test_Write_keepsTheOldContentIfWritingFails(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "file")
+	ioutil.WriteFile(path, []byte("old"), 0600)
+	failure := errors.New("failure")
+
+	err := Write(path, func(w io.Writer) error {
+		io.WriteString(w, "ne")
+		return failure
+	})
+	assertEquals(t, err, failure)
+
+	data, _ := ioutil.ReadFile(path)
+	assertEquals(t, string(data), "old")
+	files, _ := ioutil.ReadDir(dir)
+	assertEquals(t, len(files), 1)
+}
+
+func assertEquals(t *testing.T, actual, expected interface{}) {
+	if actual != expected {
+		t.Errorf("Expected:\n%#v \nto equal:\n%#v\n", actual, expected)
+	}
+}
+
+func assertNil(t *testing.T, actual interface{}) {
+	if actual != nil {
+		t.Errorf("Expected:\n%#v \nto be nil\n", actual)
+	}
+}
@@ -0,0 +1,30 @@
+// Package keyfile loads the key of an account for the commands, from a file written by otr3.ExportKeysToFile.
+package keyfile
+
+import (
+	"fmt"
+
+	"github.com/twstrike/otr3"
+)
+
+// Load returns the key of the named account in the file, or of the only account if no name is given
+func Load(fname, name string) (otr3.PrivateKey, error) {
+	accounts, err := otr3.ImportKeysFromFile(fname)
+	if err != nil {
+		return nil, err
+	}
+
+	if name == "" {
+		if len(accounts) != 1 {
+			return nil, fmt.Errorf("%s has %d accounts, choose one with -account", fname, len(accounts))
+		}
+		return accounts[0].Key, nil
+	}
+
+	for _, a := range accounts {
+		if a.Name == name {
+			return a.Key, nil
+		}
+	}
+	return nil, fmt.Errorf("no account named %q in %s", name, fname)
+}
@@ -0,0 +1,56 @@
+package keyfile
+
+import (
+	"crypto/rand"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/twstrike/otr3"
+)
+
+func generateKey(t *testing.T) otr3.PrivateKey {
+	key := &otr3.DSAPrivateKey{}
+	if err := key.Generate(rand.Reader); err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func Test_Load_choosesTheAccountByName(t *testing.T) {
+	dir, err := ioutil.TempDir("", "keyfile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	fname := filepath.Join(dir, "keys.asc")
+	alice, bob := generateKey(t), generateKey(t)
+	otr3.ExportKeysToFile([]*otr3.Account{
+		{Name: "alice", Protocol: "chat", Key: alice},
+		{Name: "bob", Protocol: "chat", Key: bob},
+	}, fname)
+
+	key, err := Load(fname, "bob")
+	assertNil(t, err)
+	assertEquals(t, otr3.FormatFingerprint(key.PublicKey().Fingerprint()), otr3.FormatFingerprint(bob.PublicKey().Fingerprint()))
+
+	_, err = Load(fname, "")
+	assertEquals(t, err.Error(), fname+" has 2 accounts, choose one with -account")
+
+	_, err = Load(fname, "carol")
+	assertEquals(t, err.Error(), `no account named "carol" in `+fname)
+}
+
+func assertEquals(t *testing.T, actual, expected interface{}) {
+	if actual != expected {
+		t.Errorf("Expected:\n%#v \nto equal:\n%#v\n", actual, expected)
+	}
+}
+
+func assertNil(t *testing.T, actual interface{}) {
+	if actual != nil {
+		t.Errorf("Expected:\n%#v \nto be nil\n", actual)
+	}
+}
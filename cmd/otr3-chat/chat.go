@@ -0,0 +1,143 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/twstrike/otr3"
+)
+
+// chat connects the conversation with the peer on one side, and the terminal on the other
+type chat struct {
+	key  otr3.PrivateKey
+	conv *otr3.Conversation
+	conn io.Writer
+	peer io.Reader
+	out  io.Writer
+
+	// mu guards the conversation, and the writes to the peer and to the terminal
+	mu sync.Mutex
+	// askedForSecret is true when the peer has started SMP and is waiting for our secret
+	askedForSecret bool
+}
+
+func newChat(key otr3.PrivateKey, conn io.ReadWriter, out io.Writer) *chat {
+	c := &chat{
+		key:  key,
+		conv: &otr3.Conversation{Rand: rand.Reader},
+		conn: conn,
+		peer: conn,
+		out:  out,
+	}
+
+	c.conv.SetOurKeys([]otr3.PrivateKey{key})
+	c.conv.Policies.AllowV2()
+	c.conv.Policies.AllowV3()
+	c.conv.Policies.WhitespaceStartAKE()
+	c.conv.Policies.ErrorStartAKE()
+
+	c.conv.SetSecurityEventHandler(c)
+	c.conv.SetSMPEventHandler(c)
+	c.conv.SetMessageEventHandler(c)
+	return c
+}
+
+// run chats until the terminal input ends, the user quits or the peer disconnects
+func (c *chat) run(input io.Reader) error {
+	done := make(chan error, 2)
+	go func() {
+		done <- c.receiveAll()
+	}()
+	go func() {
+		done <- c.sendAll(input)
+	}()
+	return <-done
+}
+
+func (c *chat) receiveAll() error {
+	r := bufio.NewReader(c.peer)
+	for {
+		line, err := r.ReadBytes('\n')
+		if line = bytes.TrimRight(line, "\r\n"); len(line) > 0 {
+			c.receive(otr3.ValidMessage(line))
+		}
+
+		if err == io.EOF {
+			c.mu.Lock()
+			c.printf("the peer has disconnected")
+			c.mu.Unlock()
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (c *chat) receive(m otr3.ValidMessage) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	info, toSend, err := c.conv.ReceiveWithInfo(m)
+	if err != nil {
+		c.printf("[error] %v", err)
+	}
+
+	if len(info.Plaintext) > 0 {
+		if info.Encrypted {
+			fmt.Fprintf(c.out, "<peer> %s\n", info.Plaintext)
+		} else {
+			fmt.Fprintf(c.out, "<peer> %s [unencrypted]\n", info.Plaintext)
+		}
+	}
+
+	c.send(toSend)
+}
+
+func (c *chat) sendAll(input io.Reader) error {
+	lines := bufio.NewScanner(input)
+	for lines.Scan() {
+		line := lines.Text()
+		if line == "/quit" {
+			return nil
+		}
+
+		c.mu.Lock()
+		if strings.HasPrefix(line, "/") {
+			c.command(line)
+		} else if line != "" {
+			c.sendMessage(line)
+		}
+		c.mu.Unlock()
+	}
+	return lines.Err()
+}
+
+func (c *chat) sendMessage(line string) {
+	toSend, err := c.conv.Send(otr3.ValidMessage(line))
+	if err != nil {
+		c.printf("[error] %v", err)
+		return
+	}
+	c.send(toSend)
+}
+
+// send must be called with the lock held
+func (c *chat) send(msgs []otr3.ValidMessage) {
+	for _, m := range msgs {
+		if _, err := fmt.Fprintf(c.conn, "%s\n", m); err != nil {
+			c.printf("[error] %v", err)
+			return
+		}
+	}
+}
+
+// printf must be called with the lock held
+func (c *chat) printf(format string, args ...interface{}) {
+	fmt.Fprintf(c.out, format+"\n", args...)
+}
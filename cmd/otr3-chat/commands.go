@@ -0,0 +1,76 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/twstrike/otr3"
+)
+
+// command runs a slash command typed by the user. It must be called with the lock held
+func (c *chat) command(line string) {
+	args := strings.Fields(line)
+
+	switch {
+	case line == "/otr start":
+		c.send([]otr3.ValidMessage{c.conv.QueryMessage()})
+	case line == "/otr end":
+		toSend, err := c.conv.End()
+		if err != nil {
+			c.printf("[error] %v", err)
+			return
+		}
+		c.send(toSend)
+	case args[0] == "/smp":
+		c.smpCommand(args[1:])
+	case line == "/fingerprint":
+		c.fingerprintCommand()
+	case line == "/ssid":
+		c.ssidCommand()
+	default:
+		c.printf("[error] unknown command %s - the commands are /otr start, /otr end, /smp [QUESTION] SECRET, /fingerprint, /ssid and /quit", args[0])
+	}
+}
+
+func (c *chat) smpCommand(args []string) {
+	if len(args) == 0 {
+		c.printf("[error] usage: /smp [QUESTION] SECRET")
+		return
+	}
+
+	var toSend []otr3.ValidMessage
+	var err error
+	if c.askedForSecret {
+		// The answer to the question of the peer can have several words
+		toSend, err = c.conv.ProvideAuthenticationSecret([]byte(strings.Join(args, " ")))
+		c.askedForSecret = false
+	} else {
+		toSend, err = c.conv.StartAuthenticate(strings.Join(args[:len(args)-1], " "), []byte(args[len(args)-1]))
+	}
+
+	if err != nil {
+		c.printf("[error] %v", err)
+		return
+	}
+	c.send(toSend)
+}
+
+func (c *chat) fingerprintCommand() {
+	c.printf("[otr] our fingerprint: %s", otr3.FormatFingerprint(c.key.PublicKey().Fingerprint()))
+
+	if theirs := c.conv.GetTheirKey(); theirs != nil {
+		c.printf("[otr] peer fingerprint: %s", otr3.FormatFingerprint(theirs.Fingerprint()))
+	} else {
+		c.printf("[otr] the peer fingerprint isn't known until a private conversation is started")
+	}
+}
+
+func (c *chat) ssidCommand() {
+	if !c.conv.IsEncrypted() {
+		c.printf("[otr] not in a private conversation")
+		return
+	}
+
+	parts, highlight := c.conv.SecureSessionID()
+	parts[highlight] = "[" + parts[highlight] + "]"
+	c.printf("[otr] secure session id: %s", strings.Join(parts, " "))
+}
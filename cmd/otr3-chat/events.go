@@ -0,0 +1,50 @@
+package main
+
+import (
+	"github.com/twstrike/otr3"
+)
+
+// The event handlers are called by the conversation while the lock is held
+
+func (c *chat) HandleSecurityEvent(event otr3.SecurityEvent) {
+	switch event {
+	case otr3.GoneSecure:
+		c.printf("[otr] private conversation started, the peer fingerprint is %s", otr3.FormatFingerprint(c.conv.GetTheirKey().Fingerprint()))
+	case otr3.StillSecure:
+		c.printf("[otr] private conversation refreshed")
+	case otr3.GoneInsecure:
+		c.printf("[otr] private conversation ended")
+	}
+}
+
+func (c *chat) HandleSMPEvent(event otr3.SMPEvent, progressPercent int, question string) {
+	switch event {
+	case otr3.SMPEventAskForAnswer:
+		c.askedForSecret = true
+		c.printf("[smp] the peer asks: %s - answer with /smp ANSWER", question)
+	case otr3.SMPEventAskForSecret:
+		c.askedForSecret = true
+		c.printf("[smp] the peer wants to authenticate - give the shared secret with /smp SECRET")
+	case otr3.SMPEventSuccess:
+		c.printf("[smp] the peer is authenticated")
+	case otr3.SMPEventFailure:
+		c.printf("[smp] authentication failed")
+	case otr3.SMPEventAbort, otr3.SMPEventCheated, otr3.SMPEventError:
+		c.askedForSecret = false
+		c.printf("[smp] authentication aborted")
+	}
+}
+
+func (c *chat) HandleMessageEvent(event otr3.MessageEvent, message []byte, err error, trace ...interface{}) {
+	switch event {
+	case otr3.MessageEventConnectionEnded:
+		c.printf("[otr] the message wasn't sent because the peer has ended the private conversation - use /otr end or /otr start")
+	case otr3.MessageEventReceivedMessageGeneralError:
+		c.printf("[otr] the peer reports an error: %s", message)
+	case otr3.MessageEventReceivedMessageUnreadable, otr3.MessageEventReceivedMessageMalformed,
+		otr3.MessageEventReceivedMessageNotInPrivate:
+		c.printf("[otr] a message from the peer couldn't be read")
+	case otr3.MessageEventSetupError, otr3.MessageEventEncryptionError:
+		c.printf("[otr] %v", err)
+	}
+}
@@ -0,0 +1,94 @@
+// Command otr3-chat is a two-party terminal chat protected by OTR.
+//
+// One side listens and the other connects, over TCP or a Unix socket. Lines typed are sent to the peer,
+// and lines starting with a slash are commands:
+//
+//	/otr start               start a private conversation
+//	/otr end                 end the private conversation
+//	/smp [QUESTION] SECRET   authenticate the peer with a secret you share, optionally asking a question.
+//	                         When the peer has asked to authenticate, /smp ANSWER gives the answer, which can have several words
+//	/fingerprint             show our fingerprint and the fingerprint of the peer
+//	/ssid                    show the secure session ID, to compare with the peer over another channel
+//	/quit                    leave the chat
+//
+// Usage:
+//
+//	otr3-chat -keys FILE [-account NAME] [-network tcp|unix] -listen ADDRESS
+//	otr3-chat -keys FILE [-account NAME] [-network tcp|unix] -connect ADDRESS
+//
+// The key file is in the libotr format, and can be created with otr3-keytool. If it holds more than one
+// account, the one to use is chosen with -account.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+
+	"github.com/twstrike/otr3/cmd/internal/keyfile"
+)
+
+const toolName = "otr3-chat"
+
+const usage = "usage: otr3-chat -keys FILE [-account NAME] [-network tcp|unix] (-listen ADDRESS | -connect ADDRESS)"
+
+var errUsage = errors.New("invalid usage")
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet(toolName, flag.ContinueOnError)
+	fs.SetOutput(ioutil.Discard)
+	keys := fs.String("keys", "", "")
+	account := fs.String("account", "", "")
+	network := fs.String("network", "tcp", "")
+	listen := fs.String("listen", "", "")
+	connect := fs.String("connect", "", "")
+
+	if err := fs.Parse(args); err != nil || fs.NArg() != 0 || *keys == "" || (*listen == "") == (*connect == "") {
+		fmt.Fprintln(stderr, usage)
+		return 2
+	}
+
+	key, err := keyfile.Load(*keys, *account)
+	if err != nil {
+		fmt.Fprintf(stderr, "%s: %v\n", toolName, err)
+		return 1
+	}
+
+	var conn net.Conn
+	if *listen != "" {
+		fmt.Fprintf(stdout, "waiting for the peer on %s\n", *listen)
+		conn, err = accept(*network, *listen)
+	} else {
+		conn, err = net.Dial(*network, *connect)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "%s: %v\n", toolName, err)
+		return 1
+	}
+	defer conn.Close()
+
+	fmt.Fprintf(stdout, "connected to %s\n", conn.RemoteAddr())
+	if err := newChat(key, conn, stdout).run(stdin); err != nil {
+		fmt.Fprintf(stderr, "%s: %v\n", toolName, err)
+		return 1
+	}
+	return 0
+}
+
+func accept(network, address string) (net.Conn, error) {
+	l, err := net.Listen(network, address)
+	if err != nil {
+		return nil, err
+	}
+	defer l.Close()
+
+	return l.Accept()
+}
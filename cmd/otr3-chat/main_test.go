@@ -0,0 +1,197 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/twstrike/otr3"
+)
+
+// terminal is the input and output of a chat in a test
+type terminal struct {
+	input *io.PipeWriter
+	mu    sync.Mutex
+	out   bytes.Buffer
+}
+
+func (t *terminal) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.out.Write(p)
+}
+
+func (t *terminal) output() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.out.String()
+}
+
+func (t *terminal) typeLine(line string) {
+	io.WriteString(t.input, line+"\n")
+}
+
+func (t *terminal) waitFor(tt *testing.T, s string) {
+	for deadline := time.Now().Add(10 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if strings.Contains(t.output(), s) {
+			return
+		}
+	}
+	tt.Fatalf("Expected output to contain %q, but it was:\n%s", s, t.output())
+}
+
+func generateKey(t *testing.T) otr3.PrivateKey {
+	key := &otr3.DSAPrivateKey{}
+	if err := key.Generate(rand.Reader); err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func startChat(t *testing.T, key otr3.PrivateKey, conn net.Conn) *terminal {
+	r, w := io.Pipe()
+	term := &terminal{input: w}
+	go newChat(key, conn, term).run(r)
+	return term
+}
+
+func startChats(t *testing.T) (alice, bob *terminal) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	dialed, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	accepted, err := l.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return startChat(t, generateKey(t), dialed), startChat(t, generateKey(t), accepted)
+}
+
+func Test_chat_startsAPrivateConversationAndSendsMessages(t *testing.T) {
+	alice, bob := startChats(t)
+
+	alice.typeLine("/otr start")
+	alice.waitFor(t, "[otr] private conversation started")
+	bob.waitFor(t, "[otr] private conversation started")
+
+	alice.typeLine("hello bob")
+	bob.waitFor(t, "<peer> hello bob\n")
+
+	bob.typeLine("/otr end")
+	bob.waitFor(t, "[otr] private conversation ended")
+	alice.waitFor(t, "[otr] private conversation ended")
+}
+
+func Test_chat_tellsWhenThePeerDisconnects(t *testing.T) {
+	ours, theirs := net.Pipe()
+	term := startChat(t, generateKey(t), ours)
+
+	theirs.Close()
+
+	term.waitFor(t, "the peer has disconnected")
+}
+
+func Test_chat_marksUnencryptedMessages(t *testing.T) {
+	alice, bob := startChats(t)
+
+	alice.typeLine("hello")
+
+	bob.waitFor(t, "<peer> hello [unencrypted]")
+}
+
+func Test_chat_authenticatesThePeerWithSMP(t *testing.T) {
+	alice, bob := startChats(t)
+	alice.typeLine("/otr start")
+	bob.waitFor(t, "[otr] private conversation started")
+
+	alice.typeLine("/smp where did we meet? paris")
+	bob.waitFor(t, "[smp] the peer asks: where did we meet?")
+	bob.typeLine("/smp paris")
+
+	alice.waitFor(t, "[smp] the peer is authenticated")
+	bob.waitFor(t, "[smp] the peer is authenticated")
+}
+
+func Test_chat_takesAllTheWordsOfAnAnswerAsTheSecret(t *testing.T) {
+	alice, bob := startChats(t)
+	alice.typeLine("/otr start")
+	bob.waitFor(t, "[otr] private conversation started")
+
+	alice.typeLine("/smp where did we meet? paris")
+	bob.waitFor(t, "[smp] the peer asks: where did we meet?")
+	bob.typeLine("/smp paris france")
+
+	bob.waitFor(t, "[smp] authentication failed")
+	alice.waitFor(t, "[smp] authentication aborted")
+}
+
+func Test_chat_showsTheSameSecureSessionIDOnBothSides(t *testing.T) {
+	alice, bob := startChats(t)
+	alice.typeLine("/otr start")
+	alice.waitFor(t, "[otr] private conversation started")
+	bob.waitFor(t, "[otr] private conversation started")
+
+	alice.typeLine("/ssid")
+	bob.typeLine("/ssid")
+	alice.waitFor(t, "[otr] secure session id: ")
+	bob.waitFor(t, "[otr] secure session id: ")
+
+	ssid := func(out string) string {
+		line := out[strings.Index(out, "secure session id: "):]
+		return strings.NewReplacer("[", "", "]", "").Replace(line[:strings.Index(line, "\n")])
+	}
+	assertEquals(t, ssid(alice.output()), ssid(bob.output()))
+}
+
+func Test_chat_showsTheFingerprints(t *testing.T) {
+	alice, bob := startChats(t)
+
+	alice.typeLine("/fingerprint")
+	alice.waitFor(t, "[otr] the peer fingerprint isn't known")
+
+	alice.typeLine("/otr start")
+	bob.waitFor(t, "[otr] private conversation started")
+	bob.typeLine("/fingerprint")
+	bob.waitFor(t, "[otr] peer fingerprint: ")
+}
+
+func Test_chat_reportsUnknownCommands(t *testing.T) {
+	alice, _ := startChats(t)
+
+	alice.typeLine("/frobnicate")
+
+	alice.waitFor(t, "[error] unknown command /frobnicate")
+}
+
+func Test_run_failsWithoutAnAddress(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+
+	code := run([]string{"-keys", "keys.asc"}, nil, &stdout, &stderr)
+
+	assertEquals(t, code, 2)
+	assertEquals(t, stderr.String(), usage+"\n")
+}
+
+func assertEquals(t *testing.T, actual, expected interface{}) {
+	if actual != expected {
+		t.Errorf("Expected:\n%#v \nto equal:\n%#v\n", actual, expected)
+	}
+}
+
+func assertNil(t *testing.T, actual interface{}) {
+	if actual != nil {
+		t.Errorf("Expected:\n%#v \nto be nil\n", actual)
+	}
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/twstrike/otr3"
+)
+
+func findAccount(acs []*otr3.Account, name, protocol string) int {
+	for i, a := range acs {
+		if a.Name == name && a.Protocol == protocol {
+			return i
+		}
+	}
+	return -1
+}
+
+func listCommand(args []string, stdout io.Writer) error {
+	fs := newFlagSet("list")
+	keys := fs.String("keys", "", "the key file to list")
+	format := fs.String("format", formatLibOTR, "the format of the key file")
+	fingerprint := fs.String("fingerprint", "", "only list the accounts with this fingerprint")
+	if err := parseFlags(fs, args, keys); err != nil {
+		return err
+	}
+
+	var fpr []byte
+	if *fingerprint != "" {
+		var err error
+		if fpr, err = otr3.ParseFingerprint(*fingerprint); err != nil {
+			return err
+		}
+	}
+
+	acs, err := readKeyFile(*keys, *format)
+	if err != nil {
+		return err
+	}
+
+	for _, a := range acs {
+		if fpr != nil && !a.Key.PublicKey().MatchesFingerprint(fpr) {
+			continue
+		}
+		fmt.Fprintf(stdout, "%s\t%s\t%s\n", a.Name, a.Protocol, otr3.FormatFingerprint(a.Key.PublicKey().Fingerprint()))
+	}
+	return nil
+}
+
+// readKeyFileIfExists is like readKeyFile, but a file that doesn't exist is treated as a file without accounts
+func readKeyFileIfExists(path, format string) ([]*otr3.Account, error) {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil, nil
+	}
+	return readKeyFile(path, format)
+}
+
+func generateCommand(args []string, stdout io.Writer) error {
+	fs := newFlagSet("generate")
+	keys := fs.String("keys", "", "the key file to add the new key to")
+	format := fs.String("format", formatLibOTR, "the format of the key file")
+	name := fs.String("account", "", "the name of the account")
+	protocol := fs.String("protocol", "", "the protocol of the account, for example prpl-jabber")
+	if err := parseFlags(fs, args, keys, name, protocol); err != nil {
+		return err
+	}
+
+	acs, err := readKeyFileIfExists(*keys, *format)
+	if err != nil {
+		return err
+	}
+
+	if findAccount(acs, *name, *protocol) != -1 {
+		return fmt.Errorf("account %s (%s) already has a key", *name, *protocol)
+	}
+
+	generated, err := otr3.GenerateMissingKeys(nil)
+	if err != nil {
+		return err
+	}
+
+	for _, k := range generated {
+		acs = append(acs, &otr3.Account{Name: *name, Protocol: *protocol, Key: k})
+		fmt.Fprintf(stdout, "%s\t%s\t%s\n", *name, *protocol, otr3.FormatFingerprint(k.PublicKey().Fingerprint()))
+	}
+
+	return writeKeyFile(*keys, *format, acs)
+}
+
+func deleteCommand(args []string, stdout io.Writer) error {
+	fs := newFlagSet("delete")
+	keys := fs.String("keys", "", "the key file to delete the key from")
+	format := fs.String("format", formatLibOTR, "the format of the key file")
+	name := fs.String("account", "", "the name of the account")
+	protocol := fs.String("protocol", "", "the protocol of the account")
+	if err := parseFlags(fs, args, keys, name, protocol); err != nil {
+		return err
+	}
+
+	acs, err := readKeyFile(*keys, *format)
+	if err != nil {
+		return err
+	}
+
+	ix := findAccount(acs, *name, *protocol)
+	if ix == -1 {
+		return fmt.Errorf("no key found for account %s (%s)", *name, *protocol)
+	}
+
+	return writeKeyFile(*keys, *format, append(acs[:ix], acs[ix+1:]...))
+}
+
+func mergeCommand(args []string, stdout io.Writer) error {
+	fs := newFlagSet("merge")
+	out := fs.String("out", "", "the key file to write the merged keys to")
+	format := fs.String("format", formatLibOTR, "the format of all the key files")
+	if err := parseFlags(fs, args, out); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return errUsage
+	}
+
+	var result []*otr3.Account
+	for _, in := range fs.Args() {
+		acs, err := readKeyFile(in, *format)
+		if err != nil {
+			return err
+		}
+		for _, a := range acs {
+			ix := findAccount(result, a.Name, a.Protocol)
+			if ix == -1 {
+				result = append(result, a)
+				continue
+			}
+			if !bytes.Equal(result[ix].Key.Serialize(), a.Key.Serialize()) {
+				return fmt.Errorf("%s: account %s (%s) has a different key than in an earlier file", in, a.Name, a.Protocol)
+			}
+		}
+	}
+
+	return writeKeyFile(*out, *format, result)
+}
+
+func convertCommand(args []string, stdout io.Writer) error {
+	fs := newFlagSet("convert")
+	in := fs.String("in", "", "the key file to convert")
+	out := fs.String("out", "", "the key file to write")
+	from := fs.String("from", formatLibOTR, "the format of the input file")
+	to := fs.String("to", formatLibOTR, "the format of the output file")
+	if err := parseFlags(fs, args, in, out); err != nil {
+		return err
+	}
+	if err := firstError(checkFormat(*from), checkFormat(*to)); err != nil {
+		return err
+	}
+
+	acs, err := readKeyFile(*in, *from)
+	if err != nil {
+		return err
+	}
+	return writeKeyFile(*out, *to, acs)
+}
+
+func firstError(es ...error) error {
+	for _, e := range es {
+		if e != nil {
+			return e
+		}
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/twstrike/otr3"
+	"github.com/twstrike/otr3/cmd/internal/atomicfile"
+)
+
+const (
+	formatLibOTR = "libotr"
+	formatJSON   = "json"
+)
+
+// jsonAccount is the JSON representation of an account. The key is the hex encoding of the OTR serialization of the private key
+type jsonAccount struct {
+	Name     string `json:"name"`
+	Protocol string `json:"protocol"`
+	Key      string `json:"key"`
+}
+
+type jsonKeyFile struct {
+	Accounts []jsonAccount `json:"accounts"`
+}
+
+func checkFormat(format string) error {
+	switch format {
+	case formatLibOTR, formatJSON:
+		return nil
+	}
+	return fmt.Errorf("unknown key file format %q", format)
+}
+
+func readKeyFile(path, format string) ([]*otr3.Account, error) {
+	switch format {
+	case formatLibOTR:
+		return otr3.ImportKeysFromFile(path)
+	case formatJSON:
+		return readJSONKeyFile(path)
+	}
+	return nil, checkFormat(format)
+}
+
+func writeKeyFile(path, format string, acs []*otr3.Account) error {
+	switch format {
+	case formatLibOTR:
+		return atomicfile.Write(path, func(w io.Writer) error {
+			return otr3.ExportKeys(acs, w)
+		})
+	case formatJSON:
+		return atomicfile.Write(path, func(w io.Writer) error {
+			return writeJSONKeys(w, acs)
+		})
+	}
+	return checkFormat(format)
+}
+
+func readJSONKeyFile(path string) ([]*otr3.Account, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var kf jsonKeyFile
+	if err := json.Unmarshal(data, &kf); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+
+	var acs []*otr3.Account
+	for _, ja := range kf.Accounts {
+		serialized, err := hex.DecodeString(ja.Key)
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid key for account %s (%s): %v", path, ja.Name, ja.Protocol, err)
+		}
+		_, ok, key := otr3.ParsePrivateKey(serialized)
+		if !ok {
+			return nil, fmt.Errorf("%s: invalid key for account %s (%s)", path, ja.Name, ja.Protocol)
+		}
+		acs = append(acs, &otr3.Account{Name: ja.Name, Protocol: ja.Protocol, Key: key})
+	}
+	return acs, nil
+}
+
+func writeJSONKeys(w io.Writer, acs []*otr3.Account) error {
+	kf := jsonKeyFile{Accounts: []jsonAccount{}}
+	for _, a := range acs {
+		kf.Accounts = append(kf.Accounts, jsonAccount{
+			Name:     a.Name,
+			Protocol: a.Protocol,
+			Key:      hex.EncodeToString(a.Key.Serialize()),
+		})
+	}
+
+	data, err := json.MarshalIndent(kf, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
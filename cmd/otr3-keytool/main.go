@@ -0,0 +1,97 @@
+// Command otr3-keytool manages OTR private key files.
+//
+// It can list the accounts and fingerprints in a key file, generate keys for new accounts,
+// delete the key of an account, merge several key files and convert between the libotr
+// key file format and a JSON representation.
+//
+// Usage:
+//
+//	otr3-keytool list -keys FILE [-fingerprint FINGERPRINT]
+//	otr3-keytool generate -keys FILE -account NAME -protocol PROTOCOL
+//	otr3-keytool delete -keys FILE -account NAME -protocol PROTOCOL
+//	otr3-keytool merge -out FILE INPUT...
+//	otr3-keytool convert -in FILE -out FILE [-from FORMAT] [-to FORMAT]
+//
+// Formats are "libotr" (the default) and "json".
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+)
+
+const toolName = "otr3-keytool"
+
+var errUsage = errors.New("invalid usage")
+
+type command struct {
+	name    string
+	usage   string
+	execute func(args []string, stdout io.Writer) error
+}
+
+var commands = []command{
+	{"list", "list -keys FILE [-fingerprint FINGERPRINT]", listCommand},
+	{"generate", "generate -keys FILE -account NAME -protocol PROTOCOL", generateCommand},
+	{"delete", "delete -keys FILE -account NAME -protocol PROTOCOL", deleteCommand},
+	{"merge", "merge -out FILE INPUT...", mergeCommand},
+	{"convert", "convert -in FILE -out FILE [-from libotr|json] [-to libotr|json]", convertCommand},
+}
+
+func printUsage(w io.Writer) {
+	fmt.Fprintf(w, "usage:\n")
+	for _, c := range commands {
+		fmt.Fprintf(w, "  %s %s\n", toolName, c.usage)
+	}
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		printUsage(stderr)
+		return 2
+	}
+
+	for _, c := range commands {
+		if c.name == args[0] {
+			if err := c.execute(args[1:], stdout); err != nil {
+				if err == errUsage {
+					fmt.Fprintf(stderr, "usage: %s %s\n", toolName, c.usage)
+					return 2
+				}
+				fmt.Fprintf(stderr, "%s: %v\n", toolName, err)
+				return 1
+			}
+			return 0
+		}
+	}
+
+	fmt.Fprintf(stderr, "%s: unknown command %q\n", toolName, args[0])
+	printUsage(stderr)
+	return 2
+}
+
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(ioutil.Discard)
+	return fs
+}
+
+func parseFlags(fs *flag.FlagSet, args []string, required ...*string) error {
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+	for _, r := range required {
+		if *r == "" {
+			return errUsage
+		}
+	}
+	return nil
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update the golden files")
+
+func assertGolden(t *testing.T, name string, actual []byte) {
+	path := filepath.Join("testdata", name+".golden")
+	if *update {
+		if err := ioutil.WriteFile(path, actual, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expected, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(actual, expected) {
+		t.Errorf("Output doesn't match %s:\n%s\nexpected:\n%s", path, actual, expected)
+	}
+}
+
+func runTool(t *testing.T, expectedCode int, args ...string) (string, string) {
+	var stdout, stderr bytes.Buffer
+	if code := run(args, &stdout, &stderr); code != expectedCode {
+		t.Fatalf("Expected exit code %d but got %d, stderr: %s", expectedCode, code, stderr.String())
+	}
+	return stdout.String(), stderr.String()
+}
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "otr3-keytool")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func copyFile(t *testing.T, from, to string) {
+	data, err := ioutil.ReadFile(from)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(to, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readFile(t *testing.T, path string) []byte {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func Test_list_printsAccountsAndFingerprints(t *testing.T) {
+	out, _ := runTool(t, 0, "list", "-keys", "testdata/keys.asc")
+	assertGolden(t, "list", []byte(out))
+}
+
+func Test_list_reportsWhyAFileIsInvalid(t *testing.T) {
+	_, errOut := runTool(t, 1, "list", "-keys", "testdata/invalid_keys.asc")
+	assertGolden(t, "list_invalid", []byte(errOut))
+}
+
+func Test_list_requiresAKeyFile(t *testing.T) {
+	_, errOut := runTool(t, 2, "list")
+	assertGolden(t, "list_usage", []byte(errOut))
+}
+
+func Test_delete_removesTheAccount(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	keys := filepath.Join(dir, "keys.asc")
+	copyFile(t, "testdata/keys.asc", keys)
+
+	runTool(t, 0, "delete", "-keys", keys, "-account", "alice", "-protocol", "prpl-irc")
+	assertGolden(t, "delete", readFile(t, keys))
+}
+
+func Test_delete_failsForAnUnknownAccount(t *testing.T) {
+	_, errOut := runTool(t, 1, "delete", "-keys", "testdata/keys.asc", "-account", "nobody", "-protocol", "prpl-irc")
+	assertGolden(t, "delete_unknown", []byte(errOut))
+}
+
+func Test_merge_combinesKeyFilesWithoutDuplicates(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	merged := filepath.Join(dir, "merged.asc")
+
+	runTool(t, 0, "merge", "-out", merged, "testdata/keys.asc", "testdata/other_keys.asc")
+	assertGolden(t, "merge", readFile(t, merged))
+}
+
+func Test_merge_refusesConflictingKeys(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	keys := filepath.Join(dir, "keys.json")
+	conflicting := filepath.Join(dir, "conflicting.json")
+	runTool(t, 0, "convert", "-in", "testdata/keys.asc", "-out", keys, "-to", "json")
+	runTool(t, 0, "convert", "-in", "testdata/other_keys.asc", "-out", conflicting, "-to", "json")
+	data := strings.Replace(string(readFile(t, conflicting)), `"bob@example.org"`, `"alice@example.com"`, 1)
+	ioutil.WriteFile(conflicting, []byte(data), 0600)
+
+	_, errOut := runTool(t, 1, "merge", "-format", "json", "-out", filepath.Join(dir, "out.json"), keys, conflicting)
+	assertGolden(t, "merge_conflict", []byte(strings.Replace(errOut, dir, "DIR", -1)))
+}
+
+func Test_convert_writesJSON(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	out := filepath.Join(dir, "keys.json")
+
+	runTool(t, 0, "convert", "-in", "testdata/keys.asc", "-out", out, "-to", "json")
+	assertGolden(t, "convert_json", readFile(t, out))
+}
+
+func Test_convert_roundTripsThroughJSON(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	json := filepath.Join(dir, "keys.json")
+	back := filepath.Join(dir, "keys.asc")
+
+	runTool(t, 0, "convert", "-in", "testdata/keys.asc", "-out", json, "-to", "json")
+	runTool(t, 0, "convert", "-in", json, "-from", "json", "-out", back)
+	if !bytes.Equal(readFile(t, back), readFile(t, "testdata/keys.asc")) {
+		t.Errorf("Expected the converted key file to be the same as the original")
+	}
+}
+
+func Test_convert_rejectsUnknownFormats(t *testing.T) {
+	_, errOut := runTool(t, 1, "convert", "-in", "testdata/keys.asc", "-out", "unused", "-to", "pem")
+	assertGolden(t, "convert_unknown_format", []byte(errOut))
+}
+
+func Test_generate_addsAKeyForANewAccount(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	keys := filepath.Join(dir, "keys.asc")
+	copyFile(t, "testdata/keys.asc", keys)
+
+	out, _ := runTool(t, 0, "generate", "-keys", keys, "-account", "carol@example.net", "-protocol", "prpl-jabber")
+	if !regexp.MustCompile(`^carol@example.net\tprpl-jabber\t([0-9A-F]{8} ){4}[0-9A-F]{8}\n$`).MatchString(out) {
+		t.Errorf("Unexpected output from generate: %q", out)
+	}
+
+	listed, _ := runTool(t, 0, "list", "-keys", keys)
+	expected, _ := runTool(t, 0, "list", "-keys", "testdata/keys.asc")
+	if listed != expected+out {
+		t.Errorf("Expected the new account to be added after the existing ones, got:\n%s", listed)
+	}
+}
+
+func Test_generate_refusesToReplaceAnExistingKey(t *testing.T) {
+	_, errOut := runTool(t, 1, "generate", "-keys", "testdata/keys.asc", "-account", "alice", "-protocol", "prpl-irc")
+	assertGolden(t, "generate_existing", []byte(errOut))
+}
+
+func Test_run_reportsUnknownCommands(t *testing.T) {
+	_, errOut := runTool(t, 2, "frobnicate")
+	assertGolden(t, "unknown_command", []byte(errOut))
+}
+
+func Test_list_canFilterByFingerprint(t *testing.T) {
+	out, _ := runTool(t, 0, "list", "-keys", "testdata/keys.asc", "-fingerprint", "65:00:50:95:3e:ea:aa:ee:de:82:c0:f9:f8:80:3c:cc:39:c8:97:ef")
+	assertGolden(t, "list_fingerprint", []byte(out))
+}
+
+func Test_list_rejectsAnInvalidFingerprint(t *testing.T) {
+	_, errOut := runTool(t, 1, "list", "-keys", "testdata/keys.asc", "-fingerprint", "65005095")
+	assertGolden(t, "list_invalid_fingerprint", []byte(errOut))
+}
+
+func Test_delete_writesTheKeyFileReadableOnlyByItsOwner(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	keys := filepath.Join(dir, "keys.asc")
+	copyFile(t, "testdata/keys.asc", keys)
+	if err := os.Chmod(keys, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	runTool(t, 0, "delete", "-keys", keys, "-account", "alice", "-protocol", "prpl-irc")
+
+	info, err := os.Stat(keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("Expected the key file to have mode 0600, got %v", info.Mode().Perm())
+	}
+
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("Expected no temporary files to be left behind, got %d entries", len(entries))
+	}
+}
+
+func Test_convert_writesJSONReadableOnlyByItsOwner(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	out := filepath.Join(dir, "keys.json")
+
+	runTool(t, 0, "convert", "-in", "testdata/keys.asc", "-out", out, "-to", "json")
+
+	info, err := os.Stat(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("Expected the key file to have mode 0600, got %v", info.Mode().Perm())
+	}
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+
+	"github.com/twstrike/otr3"
+)
+
+var (
+	errNoClient       = errors.New("no client is connected")
+	errNoFingerprint  = errors.New("the fingerprint of the peer isn't known yet")
+	errUnknownCommand = errors.New("unknown command")
+)
+
+// adminCommand runs a command with the lock held, and returns the lines of its answer
+type adminCommand struct {
+	args    int
+	usage   string
+	execute func(p *proxy, args []string) ([]string, error)
+}
+
+var adminCommands = map[string]adminCommand{
+	"status":      {0, "status", statusCommand},
+	"fingerprint": {0, "fingerprint", fingerprintCommand},
+	"start":       {1, "start PEER", sendingCommand(startConversation)},
+	"end":         {1, "end PEER", sendingCommand(endConversation)},
+	"trust":       {1, "trust PEER", trustCommand},
+	"distrust":    {1, "distrust PEER", distrustCommand},
+	"smp":         {2, "smp PEER SECRET [QUESTION]", sendingCommand(startSMP)},
+	"smp-answer":  {2, "smp-answer PEER SECRET", sendingCommand(answerSMP)},
+	"smp-abort":   {1, "smp-abort PEER", sendingCommand(abortSMP)},
+}
+
+// serveAdmin runs the commands from an admin connection. Each answer is zero or more lines, followed by
+// "ok" or by "error: " and the reason
+func (p *proxy) serveAdmin(conn net.Conn) {
+	defer conn.Close()
+
+	lines := bufio.NewScanner(conn)
+	for lines.Scan() {
+		answer, err := p.admin(lines.Text())
+		for _, l := range answer {
+			fmt.Fprintln(conn, l)
+		}
+		if err != nil {
+			fmt.Fprintf(conn, "error: %v\n", err)
+		} else {
+			fmt.Fprintln(conn, "ok")
+		}
+	}
+}
+
+func (p *proxy) admin(line string) ([]string, error) {
+	args := strings.Fields(line)
+	if len(args) == 0 {
+		return nil, errUnknownCommand
+	}
+
+	c, ok := adminCommands[args[0]]
+	if !ok {
+		return nil, errUnknownCommand
+	}
+	if len(args)-1 < c.args {
+		return nil, fmt.Errorf("usage: %s", c.usage)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return c.execute(p, args[1:])
+}
+
+func statusCommand(p *proxy, _ []string) ([]string, error) {
+	var names []string
+	for name := range p.peers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var status []string
+	for _, name := range names {
+		pe := p.peers[name]
+
+		state := "plaintext"
+		if pe.conv.IsEncrypted() {
+			state = "encrypted"
+		}
+
+		fpr, trust := pe.formattedFingerprint(), "untrusted"
+		if fpr == "" {
+			fpr = "-"
+		}
+		if p.trust.trusted(name, pe.fingerprint()) {
+			trust = "trusted"
+		}
+
+		line := fmt.Sprintf("%s\t%s\t%s\t%s", name, state, fpr, trust)
+		if pe.smp != "" {
+			line += "\tauthentication " + pe.smp
+		}
+		status = append(status, line)
+	}
+	return status, nil
+}
+
+func fingerprintCommand(p *proxy, _ []string) ([]string, error) {
+	return []string{otr3.FormatFingerprint(p.key.PublicKey().Fingerprint())}, nil
+}
+
+func trustCommand(p *proxy, args []string) ([]string, error) {
+	fpr := p.peer(args[0]).fingerprint()
+	if fpr == nil {
+		return nil, errNoFingerprint
+	}
+	return nil, p.trust.trust(args[0], fpr)
+}
+
+func distrustCommand(p *proxy, args []string) ([]string, error) {
+	return nil, p.trust.distrust(args[0])
+}
+
+// sendingCommand makes a command that sends the messages returned by f to the peer named by the first argument
+func sendingCommand(f func(c *otr3.Conversation, args []string) ([]otr3.ValidMessage, error)) func(*proxy, []string) ([]string, error) {
+	return func(p *proxy, args []string) ([]string, error) {
+		if p.server == nil {
+			return nil, errNoClient
+		}
+
+		pe := p.peer(args[0])
+		toSend, err := f(pe.conv, args[1:])
+		if err != nil {
+			return nil, err
+		}
+		p.send(pe, toSend)
+		return nil, nil
+	}
+}
+
+func startConversation(c *otr3.Conversation, _ []string) ([]otr3.ValidMessage, error) {
+	return []otr3.ValidMessage{c.QueryMessage()}, nil
+}
+
+func endConversation(c *otr3.Conversation, _ []string) ([]otr3.ValidMessage, error) {
+	return c.End()
+}
+
+func startSMP(c *otr3.Conversation, args []string) ([]otr3.ValidMessage, error) {
+	return c.StartAuthenticate(strings.Join(args[1:], " "), []byte(args[0]))
+}
+
+func answerSMP(c *otr3.Conversation, args []string) ([]otr3.ValidMessage, error) {
+	return c.ProvideAuthenticationSecret([]byte(args[0]))
+}
+
+func abortSMP(c *otr3.Conversation, _ []string) ([]otr3.ValidMessage, error) {
+	return c.AbortAuthenticate()
+}
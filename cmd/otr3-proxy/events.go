@@ -0,0 +1,56 @@
+package main
+
+import "github.com/twstrike/otr3"
+
+// peerEvents handles the events of the conversation with a peer. The events happen while the lock is held
+type peerEvents struct {
+	p  *proxy
+	pe *peer
+}
+
+func (e peerEvents) HandleSecurityEvent(event otr3.SecurityEvent) {
+	switch event {
+	case otr3.GoneSecure:
+		fpr := e.pe.formattedFingerprint()
+		if e.p.trust.trusted(e.pe.name, e.pe.fingerprint()) {
+			e.p.logf("private conversation with %s started, with the trusted fingerprint %s", e.pe.name, fpr)
+		} else {
+			e.p.logf("private conversation with %s started, with the UNTRUSTED fingerprint %s", e.pe.name, fpr)
+		}
+	case otr3.GoneInsecure:
+		e.p.logf("private conversation with %s ended", e.pe.name)
+	}
+}
+
+func (e peerEvents) HandleSMPEvent(event otr3.SMPEvent, progressPercent int, question string) {
+	switch event {
+	case otr3.SMPEventAskForAnswer:
+		e.pe.smp = "the peer asks: " + question
+	case otr3.SMPEventAskForSecret:
+		e.pe.smp = "the peer asks for the shared secret"
+	case otr3.SMPEventInProgress:
+		e.pe.smp = "in progress"
+	case otr3.SMPEventSuccess:
+		e.pe.smp = "succeeded"
+		if err := e.p.trust.trust(e.pe.name, e.pe.fingerprint()); err != nil {
+			e.p.logf("can't save the trust of %s: %v", e.pe.name, err)
+		}
+	case otr3.SMPEventFailure:
+		e.pe.smp = "failed"
+	case otr3.SMPEventAbort, otr3.SMPEventCheated, otr3.SMPEventError:
+		e.pe.smp = "aborted"
+	}
+	e.p.logf("authentication of %s: %s", e.pe.name, e.pe.smp)
+}
+
+func (e peerEvents) HandleMessageEvent(event otr3.MessageEvent, message []byte, err error, trace ...interface{}) {
+	switch event {
+	case otr3.MessageEventSetupError, otr3.MessageEventEncryptionError:
+		e.p.logf("conversation with %s: %v", e.pe.name, err)
+	case otr3.MessageEventReceivedMessageGeneralError:
+		e.p.logf("%s reports an error: %s", e.pe.name, message)
+	case otr3.MessageEventReceivedMessageUnreadable, otr3.MessageEventReceivedMessageMalformed,
+		otr3.MessageEventReceivedMessageNotInPrivate:
+		e.p.logf("a message from %s couldn't be read", e.pe.name)
+	}
+}
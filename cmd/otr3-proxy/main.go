@@ -0,0 +1,113 @@
+// Command otr3-proxy adds OTR to chat clients that don't support it.
+//
+// The proxy sits between a client and its server. The client connects to the proxy instead of the server, and the
+// proxy encrypts the chat messages the client sends and decrypts the ones it receives, running one OTR conversation
+// for every peer. Everything else is passed through unchanged.
+//
+// The chat protocol is line based: a chat message is a line of the form
+//
+//	MSG PEER TEXT
+//
+// sent by the client to send TEXT to PEER, and by the server to deliver TEXT from PEER. Other protocols, such as
+// XMPP, can be supported by implementing the protocol interface.
+//
+// The conversations are controlled through a local admin socket, which takes one command per line:
+//
+//	status                            list the peers, their fingerprints and whether they are trusted
+//	fingerprint                       show our fingerprint
+//	start PEER                        start a private conversation
+//	end PEER                          end the private conversation
+//	trust PEER                        trust the current fingerprint of the peer
+//	distrust PEER                     stop trusting the peer
+//	smp PEER SECRET [QUESTION]        authenticate the peer with a shared secret, trusting it if that succeeds
+//	smp-answer PEER SECRET            answer an authentication started by the peer
+//	smp-abort PEER                    abort the authentication
+//
+// Usage:
+//
+//	otr3-proxy -keys FILE [-account NAME] [-trust FILE] -listen ADDRESS -server ADDRESS -admin PATH
+//
+// The admin socket is a Unix socket. The trusted fingerprints are kept in the trust file, if one is given.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+
+	"github.com/twstrike/otr3/cmd/internal/keyfile"
+)
+
+const toolName = "otr3-proxy"
+
+const usage = "usage: otr3-proxy -keys FILE [-account NAME] [-trust FILE] -listen ADDRESS -server ADDRESS -admin PATH"
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stderr))
+}
+
+func run(args []string, stderr io.Writer) int {
+	fs := flag.NewFlagSet(toolName, flag.ContinueOnError)
+	fs.SetOutput(ioutil.Discard)
+	keys := fs.String("keys", "", "")
+	account := fs.String("account", "", "")
+	trustFile := fs.String("trust", "", "")
+	listen := fs.String("listen", "", "")
+	server := fs.String("server", "", "")
+	admin := fs.String("admin", "", "")
+
+	if err := fs.Parse(args); err != nil || fs.NArg() != 0 || *keys == "" || *listen == "" || *server == "" || *admin == "" {
+		fmt.Fprintln(stderr, usage)
+		return 2
+	}
+
+	if err := serve(*keys, *account, *trustFile, *listen, *server, *admin, stderr); err != nil {
+		fmt.Fprintf(stderr, "%s: %v\n", toolName, err)
+		return 1
+	}
+	return 0
+}
+
+func serve(keys, account, trustFile, listen, server, admin string, log io.Writer) error {
+	key, err := keyfile.Load(keys, account)
+	if err != nil {
+		return err
+	}
+
+	trust, err := loadTrust(trustFile)
+	if err != nil {
+		return err
+	}
+
+	clients, err := net.Listen("tcp", listen)
+	if err != nil {
+		return err
+	}
+	defer clients.Close()
+
+	admins, err := net.Listen("unix", admin)
+	if err != nil {
+		return err
+	}
+	defer admins.Close()
+
+	p := newProxy(key, lineProtocol{}, trust, log)
+	go acceptAll(admins, p.serveAdmin)
+
+	return acceptAll(clients, func(client net.Conn) {
+		p.serveClient(client, server)
+	})
+}
+
+func acceptAll(l net.Listener, serve func(net.Conn)) error {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return err
+		}
+		go serve(conn)
+	}
+}
@@ -0,0 +1,320 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/twstrike/otr3"
+)
+
+// chatServer is a server for the line protocol. A client names itself with "NICK NAME", and
+// "MSG PEER TEXT" lines are delivered to the peer as "MSG SENDER TEXT"
+type chatServer struct {
+	l       net.Listener
+	mu      sync.Mutex
+	clients map[string]net.Conn
+	seen    []string
+}
+
+func startChatServer(t *testing.T) *chatServer {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &chatServer{l: l, clients: make(map[string]net.Conn)}
+	go acceptAll(l, s.serve)
+	return s
+}
+
+func (s *chatServer) serve(conn net.Conn) {
+	var name string
+	lines := bufio.NewScanner(conn)
+	for lines.Scan() {
+		line := lines.Text()
+
+		s.mu.Lock()
+		s.seen = append(s.seen, line)
+		if strings.HasPrefix(line, "NICK ") {
+			name = line[len("NICK "):]
+			s.clients[name] = conn
+		} else if peer, text, ok := (lineProtocol{}).parse(line); ok && s.clients[peer] != nil {
+			s.clients[peer].Write([]byte("MSG " + name + " " + text + "\n"))
+		}
+		s.mu.Unlock()
+	}
+}
+
+func (s *chatServer) saw(prefix string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, l := range s.seen {
+		if strings.HasPrefix(l, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func eventually(t *testing.T, what string, f func() bool) {
+	for deadline := time.Now().Add(10 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if f() {
+			return
+		}
+	}
+	t.Fatalf("Timed out waiting until %s", what)
+}
+
+func generateKey(t *testing.T) otr3.PrivateKey {
+	key := &otr3.DSAPrivateKey{}
+	if err := key.Generate(rand.Reader); err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+type proxiedClient struct {
+	p     *proxy
+	conn  net.Conn
+	lines *bufio.Scanner
+}
+
+// connectThroughProxy starts a proxy for the named client, and connects the client to the server through it
+func connectThroughProxy(t *testing.T, s *chatServer, name string) *proxiedClient {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	trust, _ := loadTrust("")
+	p := newProxy(generateKey(t), lineProtocol{}, trust, ioutil.Discard)
+	go func() {
+		if client, err := l.Accept(); err == nil {
+			p.serveClient(client, s.l.Addr().String())
+		}
+	}()
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Write([]byte("NICK " + name + "\n"))
+	eventually(t, name+" is connected", func() bool { return s.saw("NICK " + name) })
+
+	return &proxiedClient{p, conn, bufio.NewScanner(conn)}
+}
+
+func (c *proxiedClient) status(t *testing.T) string {
+	lines, err := c.p.admin("status")
+	assertNil(t, err)
+	return strings.Join(lines, "\n")
+}
+
+func privateConversation(t *testing.T) (s *chatServer, alice, bob *proxiedClient) {
+	s = startChatServer(t)
+	alice = connectThroughProxy(t, s, "alice")
+	bob = connectThroughProxy(t, s, "bob")
+
+	_, err := alice.p.admin("start bob")
+	assertNil(t, err)
+
+	eventually(t, "the conversation is private", func() bool {
+		return strings.Contains(alice.status(t), "bob\tencrypted") && strings.Contains(bob.status(t), "alice\tencrypted")
+	})
+	return
+}
+
+func Test_proxy_encryptsTheMessagesOfTheClient(t *testing.T) {
+	s, alice, bob := privateConversation(t)
+
+	alice.conn.Write([]byte("MSG bob hello bob\n"))
+
+	bob.lines.Scan()
+	assertEquals(t, bob.lines.Text(), "MSG alice hello bob")
+	assertEquals(t, s.saw("MSG bob hello"), false)
+	assertEquals(t, s.saw("MSG bob ?OTR:"), true)
+}
+
+func Test_proxy_refusesASecondClient(t *testing.T) {
+	s := startChatServer(t)
+	alice := connectThroughProxy(t, s, "alice")
+
+	second, other := net.Pipe()
+	done := make(chan bool)
+	go func() {
+		alice.p.serveClient(other, s.l.Addr().String())
+		done <- true
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("Expected the second client to be disconnected")
+	}
+	_, err := second.Read(make([]byte, 1))
+	assertEquals(t, err != nil, true)
+
+	alice.p.mu.Lock()
+	connected := alice.p.client != nil && alice.p.server != nil
+	alice.p.mu.Unlock()
+	assertEquals(t, connected, true)
+}
+
+func Test_proxy_trustsThePeerAfterSMP(t *testing.T) {
+	_, alice, bob := privateConversation(t)
+	assertEquals(t, strings.Contains(alice.status(t), "untrusted"), true)
+
+	_, err := alice.p.admin("smp bob paris where did we meet?")
+	assertNil(t, err)
+	eventually(t, "bob is asked", func() bool {
+		return strings.Contains(bob.status(t), "authentication the peer asks: where did we meet?")
+	})
+
+	_, err = bob.p.admin("smp-answer alice paris")
+	assertNil(t, err)
+
+	eventually(t, "both are trusted", func() bool {
+		return strings.Contains(alice.status(t), "\ttrusted") && strings.Contains(bob.status(t), "\ttrusted")
+	})
+}
+
+func Test_proxy_trustAndDistrustTheCurrentFingerprint(t *testing.T) {
+	_, alice, _ := privateConversation(t)
+
+	_, err := alice.p.admin("trust bob")
+	assertNil(t, err)
+	assertEquals(t, strings.Contains(alice.status(t), "\ttrusted"), true)
+
+	_, err = alice.p.admin("distrust bob")
+	assertNil(t, err)
+	assertEquals(t, strings.Contains(alice.status(t), "\tuntrusted"), true)
+}
+
+func Test_admin_reportsBadCommands(t *testing.T) {
+	trust, _ := loadTrust("")
+	p := newProxy(generateKey(t), lineProtocol{}, trust, ioutil.Discard)
+
+	_, err := p.admin("frobnicate")
+	assertEquals(t, err, errUnknownCommand)
+
+	_, err = p.admin("smp bob")
+	assertEquals(t, err.Error(), "usage: smp PEER SECRET [QUESTION]")
+
+	_, err = p.admin("start bob")
+	assertEquals(t, err, errNoClient)
+
+	_, err = p.admin("trust bob")
+	assertEquals(t, err, errNoFingerprint)
+}
+
+func Test_serveAdmin_answersEachCommand(t *testing.T) {
+	trust, _ := loadTrust("")
+	key := generateKey(t)
+	p := newProxy(key, lineProtocol{}, trust, ioutil.Discard)
+	admin, conn := net.Pipe()
+	go p.serveAdmin(conn)
+
+	admin.Write([]byte("fingerprint\nfrobnicate\n"))
+	lines := bufio.NewScanner(admin)
+
+	lines.Scan()
+	assertEquals(t, lines.Text(), otr3.FormatFingerprint(key.PublicKey().Fingerprint()))
+	lines.Scan()
+	assertEquals(t, lines.Text(), "ok")
+	lines.Scan()
+	assertEquals(t, lines.Text(), "error: unknown command")
+	admin.Close()
+}
+
+func Test_trustStore_keepsTheFingerprintsInTheFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", toolName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "trust")
+
+	bobs := bytes.Repeat([]byte{0xAA}, 20)
+	alices := bytes.Repeat([]byte{0xBB}, 20)
+
+	trust, _ := loadTrust(path)
+	assertNil(t, trust.trust("bob", bobs))
+	assertNil(t, trust.trust("alice", alices))
+
+	data, _ := ioutil.ReadFile(path)
+	assertEquals(t, string(data), "alice\t"+otr3.FormatFingerprint(alices)+"\nbob\t"+otr3.FormatFingerprint(bobs)+"\n")
+
+	loaded, err := loadTrust(path)
+	assertNil(t, err)
+	assertEquals(t, loaded.trusted("bob", bobs), true)
+	assertEquals(t, loaded.trusted("bob", alices), false)
+	assertEquals(t, loaded.trusted("bob", nil), false)
+}
+
+func Test_trustStore_acceptsFingerprintsWrittenInOtherFormats(t *testing.T) {
+	dir, err := ioutil.TempDir("", toolName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "trust")
+	ioutil.WriteFile(path, []byte("bob\t"+strings.Repeat("aa:", 19)+"aa\n"), 0600)
+
+	loaded, err := loadTrust(path)
+	assertNil(t, err)
+	assertEquals(t, loaded.trusted("bob", bytes.Repeat([]byte{0xAA}, 20)), true)
+}
+
+func Test_trustStore_rejectsInvalidFingerprints(t *testing.T) {
+	dir, err := ioutil.TempDir("", toolName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "trust")
+	ioutil.WriteFile(path, []byte("bob\tAAAA\n"), 0600)
+
+	_, err = loadTrust(path)
+	if err == nil || !strings.HasPrefix(err.Error(), path+":1: ") {
+		t.Errorf("Expected an error about the first line of the trust file, got %v", err)
+	}
+}
+
+func Test_lineProtocol_parsesChatMessages(t *testing.T) {
+	peer, text, ok := lineProtocol{}.parse("MSG bob hello there")
+	assertEquals(t, ok, true)
+	assertEquals(t, peer, "bob")
+	assertEquals(t, text, "hello there")
+
+	_, _, ok = lineProtocol{}.parse("NICK bob")
+	assertEquals(t, ok, false)
+}
+
+func Test_run_failsWithoutTheRequiredFlags(t *testing.T) {
+	var stderr bytes.Buffer
+
+	assertEquals(t, run([]string{"-keys", "keys.asc"}, &stderr), 2)
+	assertEquals(t, stderr.String(), usage+"\n")
+}
+
+func assertEquals(t *testing.T, actual, expected interface{}) {
+	if actual != expected {
+		t.Errorf("Expected:\n%#v \nto equal:\n%#v\n", actual, expected)
+	}
+}
+
+func assertNil(t *testing.T, actual interface{}) {
+	if actual != nil {
+		t.Errorf("Expected:\n%#v \nto be nil\n", actual)
+	}
+}
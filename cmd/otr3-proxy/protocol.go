@@ -0,0 +1,33 @@
+package main
+
+import "strings"
+
+// protocol finds the chat messages among the lines a client and its server exchange.
+// The proxy encrypts and decrypts the text of chat messages, and passes all other lines through
+type protocol interface {
+	// parse returns the peer and the text of a chat message line
+	parse(line string) (peer, text string, ok bool)
+	// format returns the chat message line for the peer and text
+	format(peer, text string) string
+}
+
+// lineProtocol is the protocol where a chat message is a line of the form "MSG PEER TEXT"
+type lineProtocol struct{}
+
+const lineMessageCommand = "MSG "
+
+func (lineProtocol) parse(line string) (string, string, bool) {
+	if !strings.HasPrefix(line, lineMessageCommand) {
+		return "", "", false
+	}
+
+	parts := strings.SplitN(line[len(lineMessageCommand):], " ", 2)
+	if len(parts) != 2 || parts[0] == "" {
+		return "", "", false
+	}
+	return parts[0], parts[1], true
+}
+
+func (lineProtocol) format(peer, text string) string {
+	return lineMessageCommand + peer + " " + text
+}
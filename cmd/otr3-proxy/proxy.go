@@ -0,0 +1,198 @@
+package main
+
+import (
+	"bufio"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+
+	"github.com/twstrike/otr3"
+)
+
+// proxy runs the OTR conversations of one client. Only one client is served at a time
+type proxy struct {
+	key      otr3.PrivateKey
+	protocol protocol
+	log      io.Writer
+
+	// mu guards everything below, and the writes to the client and server
+	mu     sync.Mutex
+	trust  *trustStore
+	peers  map[string]*peer
+	client io.Writer
+	server io.Writer
+}
+
+// peer is someone the client chats with
+type peer struct {
+	name string
+	conv *otr3.Conversation
+	// smp describes the last authentication of the peer, for the status command
+	smp string
+}
+
+func newProxy(key otr3.PrivateKey, protocol protocol, trust *trustStore, log io.Writer) *proxy {
+	return &proxy{
+		key:      key,
+		protocol: protocol,
+		trust:    trust,
+		log:      log,
+		peers:    make(map[string]*peer),
+	}
+}
+
+// serveClient connects the client to the server, and relays the lines between them until one of them disconnects.
+// A client that connects while another one is being served is disconnected right away
+func (p *proxy) serveClient(client net.Conn, serverAddress string) {
+	defer client.Close()
+
+	p.mu.Lock()
+	busy := p.client != nil
+	if !busy {
+		p.client = client
+	}
+	p.mu.Unlock()
+
+	if busy {
+		p.logf("refusing the client %s: another client is connected", client.RemoteAddr())
+		return
+	}
+
+	defer func() {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		p.client, p.server = nil, nil
+	}()
+
+	server, err := net.Dial("tcp", serverAddress)
+	if err != nil {
+		p.logf("can't connect to the server: %v", err)
+		return
+	}
+	defer server.Close()
+
+	p.mu.Lock()
+	p.server = server
+	p.mu.Unlock()
+
+	done := make(chan bool, 2)
+	go func() {
+		p.relay(server, p.fromServer)
+		done <- true
+	}()
+	go func() {
+		p.relay(client, p.fromClient)
+		done <- true
+	}()
+	<-done
+}
+
+func (p *proxy) relay(r io.Reader, handle func(line string)) {
+	lines := bufio.NewReader(r)
+	for {
+		line, err := lines.ReadString('\n')
+		if line = strings.TrimRight(line, "\r\n"); line != "" {
+			p.mu.Lock()
+			handle(line)
+			p.mu.Unlock()
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (p *proxy) fromClient(line string) {
+	name, text, ok := p.protocol.parse(line)
+	if !ok {
+		p.writeLine(p.server, line)
+		return
+	}
+
+	pe := p.peer(name)
+	toSend, err := pe.conv.Send(otr3.ValidMessage(text))
+	if err != nil {
+		p.logf("can't send to %s: %v", name, err)
+	}
+	p.send(pe, toSend)
+}
+
+func (p *proxy) fromServer(line string) {
+	name, text, ok := p.protocol.parse(line)
+	if !ok {
+		p.writeLine(p.client, line)
+		return
+	}
+
+	pe := p.peer(name)
+	plain, toSend, err := pe.conv.Receive(otr3.ValidMessage(text))
+	if err != nil {
+		p.logf("can't receive from %s: %v", name, err)
+	}
+	p.send(pe, toSend)
+
+	if len(plain) > 0 {
+		for _, l := range strings.Split(string(plain), "\n") {
+			p.writeLine(p.client, p.protocol.format(name, strings.TrimRight(l, "\r")))
+		}
+	}
+}
+
+func (p *proxy) peer(name string) *peer {
+	pe, ok := p.peers[name]
+	if !ok {
+		pe = &peer{name: name, conv: &otr3.Conversation{Rand: rand.Reader}}
+		pe.conv.SetOurKeys([]otr3.PrivateKey{p.key})
+		pe.conv.Policies.AllowV2()
+		pe.conv.Policies.AllowV3()
+		pe.conv.Policies.SendWhitespaceTag()
+		pe.conv.Policies.WhitespaceStartAKE()
+		pe.conv.Policies.ErrorStartAKE()
+
+		events := peerEvents{p, pe}
+		pe.conv.SetSecurityEventHandler(events)
+		pe.conv.SetSMPEventHandler(events)
+		pe.conv.SetMessageEventHandler(events)
+
+		p.peers[name] = pe
+	}
+	return pe
+}
+
+func (p *proxy) send(pe *peer, toSend []otr3.ValidMessage) {
+	for _, m := range toSend {
+		p.writeLine(p.server, p.protocol.format(pe.name, string(m)))
+	}
+}
+
+func (p *proxy) writeLine(w io.Writer, line string) {
+	if w == nil {
+		return
+	}
+	if _, err := fmt.Fprintf(w, "%s\n", line); err != nil {
+		p.logf("%v", err)
+	}
+}
+
+func (p *proxy) logf(format string, args ...interface{}) {
+	fmt.Fprintf(p.log, "%s: %s\n", toolName, fmt.Sprintf(format, args...))
+}
+
+// fingerprint returns the fingerprint of the peer, or nil if it isn't known
+func (pe *peer) fingerprint() []byte {
+	if k := pe.conv.GetTheirKey(); k != nil {
+		return k.Fingerprint()
+	}
+	return nil
+}
+
+// formattedFingerprint returns the formatted fingerprint of the peer, or an empty string if it isn't known
+func (pe *peer) formattedFingerprint() string {
+	if fpr := pe.fingerprint(); fpr != nil {
+		return otr3.FormatFingerprint(fpr)
+	}
+	return ""
+}
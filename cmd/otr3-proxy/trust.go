@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/twstrike/otr3"
+	"github.com/twstrike/otr3/cmd/internal/atomicfile"
+)
+
+// trustStore keeps the fingerprints trusted for each peer, in a file if it has a path
+type trustStore struct {
+	path         string
+	fingerprints map[string][]byte
+}
+
+func loadTrust(path string) (*trustStore, error) {
+	t := &trustStore{path: path, fingerprints: make(map[string][]byte)}
+	if path == "" {
+		return t, nil
+	}
+
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return t, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	lines := bufio.NewScanner(f)
+	for n := 1; lines.Scan(); n++ {
+		parts := strings.SplitN(lines.Text(), "\t", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		fpr, err := otr3.ParseFingerprint(parts[1])
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", path, n, err)
+		}
+		t.fingerprints[parts[0]] = fpr
+	}
+	return t, lines.Err()
+}
+
+func (t *trustStore) trusted(peer string, fingerprint []byte) bool {
+	fpr, ok := t.fingerprints[peer]
+	return ok && fingerprint != nil && bytes.Equal(fpr, fingerprint)
+}
+
+func (t *trustStore) trust(peer string, fingerprint []byte) error {
+	t.fingerprints[peer] = fingerprint
+	return t.save()
+}
+
+func (t *trustStore) distrust(peer string) error {
+	delete(t.fingerprints, peer)
+	return t.save()
+}
+
+func (t *trustStore) save() error {
+	if t.path == "" {
+		return nil
+	}
+
+	var peers []string
+	for p := range t.fingerprints {
+		peers = append(peers, p)
+	}
+	sort.Strings(peers)
+
+	return atomicfile.Write(t.path, func(w io.Writer) error {
+		for _, p := range peers {
+			if _, err := fmt.Fprintf(w, "%s\t%s\n", p, otr3.FormatFingerprint(t.fingerprints[p])); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/twstrike/otr3"
+)
+
+// eventBufferSize is how many events are kept for a client of the event stream that doesn't keep up.
+// Newer events are dropped when the buffer is full
+const eventBufferSize = 256
+
+// event is an event of a conversation, as it is streamed to the clients
+type event struct {
+	Conversation string    `json:"conversation"`
+	Time         time.Time `json:"time"`
+	Kind         string    `json:"kind"`
+	Event        string    `json:"event"`
+	Progress     int       `json:"progress,omitempty"`
+	Question     string    `json:"question,omitempty"`
+	Message      string    `json:"message,omitempty"`
+	Error        string    `json:"error,omitempty"`
+}
+
+// eventFeed sends the events to the clients of the event stream
+type eventFeed struct {
+	mu          sync.Mutex
+	subscribers map[chan event]string
+}
+
+func newEventFeed() *eventFeed {
+	return &eventFeed{subscribers: make(map[chan event]string)}
+}
+
+// subscribe returns a channel with the events of the conversation with the id, or of all conversations if id is empty
+func (f *eventFeed) subscribe(id string) chan event {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	ch := make(chan event, eventBufferSize)
+	f.subscribers[ch] = id
+	return ch
+}
+
+func (f *eventFeed) unsubscribe(ch chan event) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	delete(f.subscribers, ch)
+}
+
+func (f *eventFeed) publish(e event) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	e.Time = time.Now()
+	for ch, id := range f.subscribers {
+		if id != "" && id != e.Conversation {
+			continue
+		}
+		select {
+		case ch <- e:
+		default:
+		}
+	}
+}
+
+func (f *eventFeed) serve(w http.ResponseWriter, r *http.Request) {
+	ch := f.subscribe(r.URL.Query().Get("id"))
+	defer f.unsubscribe(ch)
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+	flush(w)
+
+	enc := json.NewEncoder(w)
+	for {
+		select {
+		case e := <-ch:
+			if err := enc.Encode(e); err != nil {
+				return
+			}
+			flush(w)
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+func flush(w http.ResponseWriter) {
+	if f, ok := w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// conversationEvents publishes the events of a conversation
+type conversationEvents struct {
+	feed *eventFeed
+	id   string
+}
+
+func (c conversationEvents) HandleSMPEvent(e otr3.SMPEvent, progressPercent int, question string) {
+	c.feed.publish(event{Conversation: c.id, Kind: "smp", Event: e.String(), Progress: progressPercent, Question: question})
+}
+
+func (c conversationEvents) HandleMessageEvent(e otr3.MessageEvent, message []byte, err error, trace ...interface{}) {
+	ev := event{Conversation: c.id, Kind: "message", Event: e.String(), Message: string(message)}
+	if err != nil {
+		ev.Error = err.Error()
+	}
+	c.feed.publish(ev)
+}
+
+func (c conversationEvents) HandleSecurityEvent(e otr3.SecurityEvent) {
+	c.feed.publish(event{Conversation: c.id, Kind: "security", Event: e.String()})
+}
@@ -0,0 +1,131 @@
+// Command otr3-sidecar offers OTR conversations as a service, for programs that can't use the otr3 package directly.
+//
+// It serves a JSON-RPC 2.0 API over HTTP, on a local TCP address or a Unix socket. Requests are POSTed to /rpc,
+// and the methods are:
+//
+//	create       {"peer": NAME}                          -> {"id": ID}
+//	delete       {"id": ID}                              -> {}
+//	list         {}                                      -> {"conversations": [{"id": ID, "peer": NAME}]}
+//	query        {"id": ID}                              -> {"messages": [...]}
+//	send         {"id": ID, "message": TEXT}             -> {"messages": [...]}
+//	receive      {"id": ID, "message": MESSAGE}          -> {"plaintext": TEXT, "encrypted": BOOL, "messages": [...]}
+//	end          {"id": ID}                              -> {"messages": [...]}
+//	smp.start    {"id": ID, "secret": S, "question": Q}  -> {"messages": [...]}
+//	smp.answer   {"id": ID, "secret": S}                 -> {"messages": [...]}
+//	state        {"id": ID}                              -> {"encrypted": BOOL, "fingerprint": FPR, "ssid": SSID}
+//	fingerprint  {}                                      -> {"fingerprint": FPR}
+//
+// The messages returned have to be delivered to the peer by the caller, and the messages from the peer given to receive.
+// A method that fails can still have messages for the peer, like an OTR error message: they are in the data of the
+// JSON-RPC error, as {"messages": [...]}, and have to be delivered too.
+//
+// The events of the conversations - SMP, message and security events - are streamed from /events as one JSON object
+// per line. The stream can be limited to one conversation with /events?id=ID. A client that doesn't keep up with the
+// stream loses events rather than slowing the conversations down.
+//
+// With -state, the conversations are kept in a file and are still there after a restart. Their private conversations
+// are not: the session keys of OTR are never written anywhere, so a private conversation has to be started again.
+//
+// Usage:
+//
+//	otr3-sidecar -keys FILE [-account NAME] [-state FILE] -listen ADDRESS
+//
+// An address starting with "unix:" is the path of a Unix socket. TCP addresses have to be on the loopback interface,
+// like 127.0.0.1:8080 or localhost:8080: the API has no authentication, and gives whoever can reach it the plaintext
+// of the conversations and the use of the account key. For the same reason, requests have to name a loopback host
+// in their Host header - on a Unix socket too, where http://localhost/ does - and the requests to /rpc have to be
+// of type application/json, so a web page can't make the browser call the API.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/twstrike/otr3/cmd/internal/keyfile"
+)
+
+const toolName = "otr3-sidecar"
+
+const usage = "usage: otr3-sidecar -keys FILE [-account NAME] [-state FILE] -listen ADDRESS"
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stderr))
+}
+
+func run(args []string, stderr io.Writer) int {
+	fs := flag.NewFlagSet(toolName, flag.ContinueOnError)
+	fs.SetOutput(ioutil.Discard)
+	keys := fs.String("keys", "", "")
+	account := fs.String("account", "", "")
+	state := fs.String("state", "", "")
+	listen := fs.String("listen", "", "")
+
+	if err := fs.Parse(args); err != nil || fs.NArg() != 0 || *keys == "" || *listen == "" {
+		fmt.Fprintln(stderr, usage)
+		return 2
+	}
+
+	if err := serve(*keys, *account, *state, *listen); err != nil {
+		fmt.Fprintf(stderr, "%s: %v\n", toolName, err)
+		return 1
+	}
+	return 0
+}
+
+func serve(keys, account, state, address string) error {
+	key, err := keyfile.Load(keys, account)
+	if err != nil {
+		return err
+	}
+
+	s, err := newService(key, state)
+	if err != nil {
+		return err
+	}
+
+	l, err := listen(address)
+	if err != nil {
+		return err
+	}
+	defer l.Close()
+
+	return http.Serve(l, s.handler())
+}
+
+func listen(address string) (net.Listener, error) {
+	if strings.HasPrefix(address, "unix:") {
+		return net.Listen("unix", address[len("unix:"):])
+	}
+
+	if err := checkLoopback(address); err != nil {
+		return nil, err
+	}
+	return net.Listen("tcp", address)
+}
+
+// checkLoopback returns an error unless the TCP address can only be reached from this machine
+func checkLoopback(address string) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+
+	if isLoopbackHost(host) {
+		return nil
+	}
+	return fmt.Errorf("refusing to listen on %s: only loopback addresses and Unix sockets are allowed", address)
+}
+
+func isLoopbackHost(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
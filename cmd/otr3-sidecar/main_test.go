@@ -0,0 +1,328 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/twstrike/otr3"
+)
+
+type sidecar struct {
+	t      *testing.T
+	s      *service
+	server *httptest.Server
+}
+
+func startSidecar(t *testing.T, statePath string) *sidecar {
+	key := &otr3.DSAPrivateKey{}
+	if err := key.Generate(rand.Reader); err != nil {
+		t.Fatal(err)
+	}
+	return startSidecarWithKey(t, key, statePath)
+}
+
+func startSidecarWithKey(t *testing.T, key otr3.PrivateKey, statePath string) *sidecar {
+	s, err := newService(key, statePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &sidecar{t, s, httptest.NewServer(s.handler())}
+}
+
+// call calls the method, and decodes its result into result - or the data of the error, if the method failed.
+// It returns the JSON-RPC error, if any
+func (c *sidecar) call(method string, params interface{}, result interface{}) *rpcError {
+	req, _ := json.Marshal(map[string]interface{}{"jsonrpc": "2.0", "id": 1, "method": method, "params": params})
+	resp, err := http.Post(c.server.URL+"/rpc", "application/json", bytes.NewReader(req))
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	var r struct {
+		Result json.RawMessage `json:"result"`
+		Error  *struct {
+			rpcError
+			Data json.RawMessage `json:"data"`
+		} `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		c.t.Fatal(err)
+	}
+	if r.Error != nil {
+		if result != nil && r.Error.Data != nil {
+			json.Unmarshal(r.Error.Data, result)
+		}
+		return &r.Error.rpcError
+	}
+	if result != nil {
+		json.Unmarshal(r.Result, result)
+	}
+	return nil
+}
+
+func (c *sidecar) create(peer string) string {
+	var r struct{ ID string }
+	if err := c.call("create", params{Peer: peer}, &r); err != nil {
+		c.t.Fatal(err)
+	}
+	return r.ID
+}
+
+type receiveResult struct {
+	Plaintext string
+	Encrypted bool
+	Messages  []string
+}
+
+// deliver gives the messages to the conversation of the sidecar, and delivers the answers back and forth until
+// there are none. It returns the results of the receives on the side of the sidecar
+func deliver(from *sidecar, fromID string, to *sidecar, toID string, msgs []string) []receiveResult {
+	var results []receiveResult
+	for len(msgs) > 0 {
+		var answers []string
+		for _, m := range msgs {
+			var r receiveResult
+			if err := to.call("receive", params{ID: toID, Message: m}, &r); err != nil {
+				to.t.Fatal(err)
+			}
+			results = append(results, r)
+			answers = append(answers, r.Messages...)
+		}
+		msgs = nil
+		for _, m := range answers {
+			var r receiveResult
+			from.call("receive", params{ID: fromID, Message: m}, &r)
+			msgs = append(msgs, r.Messages...)
+		}
+	}
+	return results
+}
+
+func privateConversation(t *testing.T) (alice *sidecar, aliceID string, bob *sidecar, bobID string) {
+	alice, bob = startSidecar(t, ""), startSidecar(t, "")
+	aliceID, bobID = alice.create("bob"), bob.create("alice")
+
+	var query messagesResult
+	alice.call("query", params{ID: aliceID}, &query)
+	deliver(alice, aliceID, bob, bobID, query.Messages)
+	return
+}
+
+func Test_sidecar_runsAPrivateConversation(t *testing.T) {
+	alice, aliceID, bob, bobID := privateConversation(t)
+
+	var state struct {
+		Encrypted   bool
+		Fingerprint string
+	}
+	bob.call("state", params{ID: bobID}, &state)
+	assertEquals(t, state.Encrypted, true)
+
+	var fpr struct{ Fingerprint string }
+	alice.call("fingerprint", nil, &fpr)
+	assertEquals(t, state.Fingerprint, fpr.Fingerprint)
+
+	var sent messagesResult
+	alice.call("send", params{ID: aliceID, Message: "hello"}, &sent)
+	results := deliver(alice, aliceID, bob, bobID, sent.Messages)
+
+	assertEquals(t, results[0].Plaintext, "hello")
+	assertEquals(t, results[0].Encrypted, true)
+}
+
+func Test_sidecar_streamsTheEventsOfAConversation(t *testing.T) {
+	alice, aliceID, bob, bobID := privateConversation(t)
+
+	resp, err := http.Get(bob.server.URL + "/events?id=" + bobID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	events := bufio.NewScanner(resp.Body)
+
+	var started messagesResult
+	alice.call("smp.start", params{ID: aliceID, Secret: "paris", Question: "where did we meet?"}, &started)
+	deliver(alice, aliceID, bob, bobID, started.Messages)
+
+	var e event
+	for e.Kind != "smp" && events.Scan() {
+		assertNil(t, json.Unmarshal(events.Bytes(), &e))
+	}
+	assertEquals(t, e.Conversation, bobID)
+	assertEquals(t, e.Kind, "smp")
+	assertEquals(t, e.Event, "SMPEventAskForAnswer")
+	assertEquals(t, e.Question, "where did we meet?")
+	assertEquals(t, e.Time.IsZero(), false)
+}
+
+func Test_sidecar_reportsErrors(t *testing.T) {
+	alice := startSidecar(t, "")
+
+	assertEquals(t, alice.call("frobnicate", nil, nil).Code, rpcMethodNotFound)
+	assertEquals(t, alice.call("send", params{ID: "42", Message: "hello"}, nil).Code, rpcInvalidParams)
+	assertEquals(t, alice.call("create", params{}, nil).Code, rpcInvalidParams)
+
+	id := alice.create("bob")
+	assertEquals(t, alice.call("smp.answer", params{ID: id, Secret: "paris"}, nil).Code, rpcFailed)
+}
+
+func Test_sidecar_returnsTheMessagesOfAFailedReceive(t *testing.T) {
+	alice, aliceID, bob, bobID := privateConversation(t)
+	var sent messagesResult
+	alice.call("send", params{ID: aliceID, Message: "hello"}, &sent)
+
+	// Breaking the MAC makes the message unreadable for bob
+	data, _ := base64.StdEncoding.DecodeString(strings.TrimSuffix(strings.TrimPrefix(sent.Messages[0], "?OTR:"), "."))
+	data[len(data)-8] ^= 0xFF
+	broken := "?OTR:" + base64.StdEncoding.EncodeToString(data) + "."
+
+	var r receiveResult
+	err := bob.call("receive", params{ID: bobID, Message: broken}, &r)
+
+	assertEquals(t, err.Code, rpcFailed)
+	assertEquals(t, len(r.Messages), 1)
+	assertEquals(t, strings.HasPrefix(r.Messages[0], "?OTR Error:"), true)
+}
+
+func Test_sidecar_refusesRequestsThatArentJSON(t *testing.T) {
+	alice := startSidecar(t, "")
+
+	req := `{"jsonrpc": "2.0", "id": 1, "method": "create", "params": {"peer": "bob"}}`
+	resp, err := http.Post(alice.server.URL+"/rpc", "text/plain", strings.NewReader(req))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	assertEquals(t, resp.StatusCode, http.StatusUnsupportedMediaType)
+	assertEquals(t, len(alice.s.conversations), 0)
+}
+
+func Test_sidecar_refusesRequestsToAForeignHost(t *testing.T) {
+	alice := startSidecar(t, "")
+
+	req, _ := http.NewRequest("POST", alice.server.URL+"/rpc",
+		strings.NewReader(`{"jsonrpc": "2.0", "id": 1, "method": "create", "params": {"peer": "bob"}}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Host = "evil.example:8080"
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	assertEquals(t, resp.StatusCode, http.StatusForbidden)
+	assertEquals(t, len(alice.s.conversations), 0)
+}
+
+func Test_localOnly_acceptsOnlyLoopbackHosts(t *testing.T) {
+	h := localOnly(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	status := func(host string) int {
+		r := httptest.NewRequest("GET", "/events", nil)
+		r.Host = host
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	assertEquals(t, status("127.0.0.1:8080"), http.StatusOK)
+	assertEquals(t, status("[::1]:8080"), http.StatusOK)
+	assertEquals(t, status("localhost"), http.StatusOK)
+
+	assertEquals(t, status("example.com"), http.StatusForbidden)
+	assertEquals(t, status("192.168.1.10:8080"), http.StatusForbidden)
+	assertEquals(t, status(""), http.StatusForbidden)
+}
+
+func Test_sidecar_keepsTheConversationsInTheStateFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", toolName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "state.json")
+
+	first := startSidecar(t, path)
+	first.create("bob")
+	id := first.create("carol")
+	first.call("delete", params{ID: first.create("dave")}, nil)
+
+	restarted := startSidecarWithKey(t, first.s.key, path)
+
+	var list struct{ Conversations []savedConversation }
+	restarted.call("list", nil, &list)
+	assertEquals(t, len(list.Conversations), 2)
+	assertEquals(t, list.Conversations[1], savedConversation{ID: id, Peer: "carol"})
+	assertEquals(t, restarted.create("erin"), "4")
+}
+
+func Test_eventFeed_dropsEventsForSubscribersThatDontKeepUp(t *testing.T) {
+	f := newEventFeed()
+	ch := f.subscribe("")
+
+	for i := 0; i < eventBufferSize+10; i++ {
+		f.publish(event{Conversation: "1"})
+	}
+
+	assertEquals(t, len(ch), eventBufferSize)
+}
+
+func Test_eventFeed_onlySendsTheEventsOfTheConversationAskedFor(t *testing.T) {
+	f := newEventFeed()
+	ch := f.subscribe("2")
+
+	f.publish(event{Conversation: "1"})
+	f.publish(event{Conversation: "2"})
+
+	assertEquals(t, len(ch), 1)
+	assertEquals(t, (<-ch).Conversation, "2")
+}
+
+func Test_run_failsWithoutTheRequiredFlags(t *testing.T) {
+	var stderr bytes.Buffer
+
+	assertEquals(t, run([]string{"-listen", "127.0.0.1:0"}, &stderr), 2)
+	assertEquals(t, stderr.String(), usage+"\n")
+}
+
+func Test_checkLoopback_acceptsOnlyLoopbackAddresses(t *testing.T) {
+	assertNil(t, checkLoopback("127.0.0.1:8080"))
+	assertNil(t, checkLoopback("[::1]:8080"))
+	assertNil(t, checkLoopback("localhost:8080"))
+
+	assertEquals(t, checkLoopback(":8080") != nil, true)
+	assertEquals(t, checkLoopback("0.0.0.0:8080") != nil, true)
+	assertEquals(t, checkLoopback("[::]:8080") != nil, true)
+	assertEquals(t, checkLoopback("192.168.1.10:8080") != nil, true)
+	assertEquals(t, checkLoopback("example.com:8080") != nil, true)
+}
+
+func Test_listen_refusesNonLoopbackAddresses(t *testing.T) {
+	l, err := listen("0.0.0.0:0")
+
+	assertNil(t, l)
+	assertEquals(t, err.Error(), "refusing to listen on 0.0.0.0:0: only loopback addresses and Unix sockets are allowed")
+}
+
+func assertEquals(t *testing.T, actual, expected interface{}) {
+	if actual != expected {
+		t.Errorf("Expected:\n%#v \nto equal:\n%#v\n", actual, expected)
+	}
+}
+
+func assertNil(t *testing.T, actual interface{}) {
+	if actual != nil {
+		t.Errorf("Expected:\n%#v \nto be nil\n", actual)
+	}
+}
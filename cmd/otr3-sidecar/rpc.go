@@ -0,0 +1,104 @@
+package main
+
+import (
+	"encoding/json"
+	"mime"
+	"net/http"
+)
+
+// The error codes of JSON-RPC 2.0
+const (
+	rpcParseError     = -32700
+	rpcInvalidRequest = -32600
+	rpcMethodNotFound = -32601
+	rpcInvalidParams  = -32602
+	// rpcFailed is used when the method itself fails
+	rpcFailed = -32000
+)
+
+type rpcRequest struct {
+	Version string          `json:"jsonrpc"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params"`
+	ID      json.RawMessage `json:"id"`
+}
+
+type rpcResponse struct {
+	Version string          `json:"jsonrpc"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+	ID      json.RawMessage `json:"id"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	// Data is what the method gave back although it failed, like the messages that still have to be sent
+	Data interface{} `json:"data,omitempty"`
+}
+
+func (e *rpcError) Error() string {
+	return e.Message
+}
+
+func invalidParams(message string) *rpcError {
+	return &rpcError{Code: rpcInvalidParams, Message: message}
+}
+
+// rpcMethod runs a method with the lock of the service held. A result returned with an error is the data of the error
+type rpcMethod func(s *service, params json.RawMessage) (interface{}, error)
+
+func (s *service) serveRPC(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "JSON-RPC requests have to be POSTed", http.StatusMethodNotAllowed)
+		return
+	}
+	if t, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err != nil || t != "application/json" {
+		http.Error(w, "JSON-RPC requests have to be of type application/json", http.StatusUnsupportedMediaType)
+		return
+	}
+
+	var req rpcRequest
+	resp := rpcResponse{Version: "2.0", ID: json.RawMessage("null")}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		resp.Error = &rpcError{Code: rpcParseError, Message: err.Error()}
+	} else {
+		if req.ID != nil {
+			resp.ID = req.ID
+		}
+		resp.Result, resp.Error = s.call(req)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+func (s *service) call(req rpcRequest) (interface{}, *rpcError) {
+	if req.Version != "2.0" {
+		return nil, &rpcError{Code: rpcInvalidRequest, Message: "only JSON-RPC 2.0 is supported"}
+	}
+
+	method, ok := rpcMethods[req.Method]
+	if !ok {
+		return nil, &rpcError{Code: rpcMethodNotFound, Message: "unknown method " + req.Method}
+	}
+
+	if req.Params == nil {
+		req.Params = json.RawMessage("{}")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result, err := method(s, req.Params)
+	if err == nil {
+		return result, nil
+	}
+	e, ok := err.(*rpcError)
+	if !ok {
+		e = &rpcError{Code: rpcFailed, Message: err.Error()}
+	}
+	e.Data = result
+	return nil, e
+}
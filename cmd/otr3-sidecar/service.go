@@ -0,0 +1,271 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/twstrike/otr3"
+)
+
+// service keeps the conversations of the sidecar
+type service struct {
+	key    otr3.PrivateKey
+	events *eventFeed
+	store  *store
+
+	// mu guards the conversations, and is held while a method runs
+	mu            sync.Mutex
+	conversations map[string]*conversation
+	nextID        int
+}
+
+// conversation is a conversation with a peer, known to the clients of the sidecar by its id
+type conversation struct {
+	id   string
+	peer string
+	conv *otr3.Conversation
+}
+
+func newService(key otr3.PrivateKey, statePath string) (*service, error) {
+	s := &service{
+		key:           key,
+		events:        newEventFeed(),
+		store:         &store{path: statePath},
+		conversations: make(map[string]*conversation),
+		nextID:        1,
+	}
+
+	saved, err := s.store.load()
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range saved.Conversations {
+		s.add(c.ID, c.Peer)
+	}
+	if saved.NextID > s.nextID {
+		s.nextID = saved.NextID
+	}
+	return s, nil
+}
+
+func (s *service) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/rpc", s.serveRPC)
+	mux.HandleFunc("/events", s.events.serve)
+	return localOnly(mux)
+}
+
+// localOnly refuses the requests that don't name a loopback host. A web page that has its name resolve to the
+// loopback address can't get past it, since the browser still sends that name
+func localOnly(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, _, err := net.SplitHostPort(r.Host)
+		if err != nil {
+			host = r.Host
+		}
+		if !isLoopbackHost(strings.Trim(host, "[]")) {
+			http.Error(w, "only requests to a loopback host are served", http.StatusForbidden)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+func (s *service) add(id, peer string) *conversation {
+	c := &conversation{id: id, peer: peer, conv: &otr3.Conversation{Rand: rand.Reader}}
+	c.conv.SetOurKeys([]otr3.PrivateKey{s.key})
+	c.conv.Policies.AllowV2()
+	c.conv.Policies.AllowV3()
+	c.conv.Policies.WhitespaceStartAKE()
+	c.conv.Policies.ErrorStartAKE()
+
+	events := conversationEvents{s.events, id}
+	c.conv.SetSMPEventHandler(events)
+	c.conv.SetMessageEventHandler(events)
+	c.conv.SetSecurityEventHandler(events)
+
+	s.conversations[id] = c
+	return c
+}
+
+func (s *service) save() error {
+	var saved []savedConversation
+	for _, c := range s.conversations {
+		saved = append(saved, savedConversation{ID: c.id, Peer: c.peer})
+	}
+	sort.Sort(byID(saved))
+	return s.store.save(state{NextID: s.nextID, Conversations: saved})
+}
+
+var rpcMethods = map[string]rpcMethod{
+	"create":      createMethod,
+	"delete":      deleteMethod,
+	"list":        listMethod,
+	"query":       conversationMethod(queryMethod),
+	"send":        conversationMethod(sendMethod),
+	"receive":     conversationMethod(receiveMethod),
+	"end":         conversationMethod(endMethod),
+	"smp.start":   conversationMethod(smpStartMethod),
+	"smp.answer":  conversationMethod(smpAnswerMethod),
+	"state":       conversationMethod(stateMethod),
+	"fingerprint": fingerprintMethod,
+}
+
+// params are the parameters of all methods. Each method uses the ones it needs
+type params struct {
+	ID       string `json:"id"`
+	Peer     string `json:"peer"`
+	Message  string `json:"message"`
+	Secret   string `json:"secret"`
+	Question string `json:"question"`
+}
+
+type messagesResult struct {
+	Messages []string `json:"messages"`
+}
+
+// messages returns the messages to send as the result, also when there is an error: the conversation can have
+// messages for the peer even then, like an OTR error message
+func messages(toSend []otr3.ValidMessage, err error) (interface{}, error) {
+	result := messagesResult{Messages: []string{}}
+	for _, m := range toSend {
+		result.Messages = append(result.Messages, string(m))
+	}
+	return result, err
+}
+
+func parseParams(raw json.RawMessage) (params, error) {
+	var p params
+	if err := json.Unmarshal(raw, &p); err != nil {
+		return p, invalidParams(err.Error())
+	}
+	return p, nil
+}
+
+// conversationMethod makes a method that works on the conversation named by the id parameter
+func conversationMethod(f func(c *conversation, p params) (interface{}, error)) rpcMethod {
+	return func(s *service, raw json.RawMessage) (interface{}, error) {
+		p, err := parseParams(raw)
+		if err != nil {
+			return nil, err
+		}
+
+		c, ok := s.conversations[p.ID]
+		if !ok {
+			return nil, invalidParams(fmt.Sprintf("no conversation with id %q", p.ID))
+		}
+		return f(c, p)
+	}
+}
+
+func createMethod(s *service, raw json.RawMessage) (interface{}, error) {
+	p, err := parseParams(raw)
+	if err != nil {
+		return nil, err
+	}
+	if p.Peer == "" {
+		return nil, invalidParams("the peer is missing")
+	}
+
+	c := s.add(strconv.Itoa(s.nextID), p.Peer)
+	s.nextID++
+
+	if err := s.save(); err != nil {
+		return nil, err
+	}
+	return struct {
+		ID string `json:"id"`
+	}{c.id}, nil
+}
+
+func deleteMethod(s *service, raw json.RawMessage) (interface{}, error) {
+	p, err := parseParams(raw)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := s.conversations[p.ID]; !ok {
+		return nil, invalidParams(fmt.Sprintf("no conversation with id %q", p.ID))
+	}
+
+	delete(s.conversations, p.ID)
+	if err := s.save(); err != nil {
+		return nil, err
+	}
+	return struct{}{}, nil
+}
+
+func listMethod(s *service, _ json.RawMessage) (interface{}, error) {
+	list := []savedConversation{}
+	for _, c := range s.conversations {
+		list = append(list, savedConversation{ID: c.id, Peer: c.peer})
+	}
+	sort.Sort(byID(list))
+
+	return struct {
+		Conversations []savedConversation `json:"conversations"`
+	}{list}, nil
+}
+
+func queryMethod(c *conversation, _ params) (interface{}, error) {
+	return messages([]otr3.ValidMessage{c.conv.QueryMessage()}, nil)
+}
+
+func sendMethod(c *conversation, p params) (interface{}, error) {
+	return messages(c.conv.Send(otr3.ValidMessage(p.Message)))
+}
+
+func receiveMethod(c *conversation, p params) (interface{}, error) {
+	info, toSend, err := c.conv.ReceiveWithInfo(otr3.ValidMessage(p.Message))
+	if err != nil {
+		return messages(toSend, err)
+	}
+
+	m, _ := messages(toSend, nil)
+	return struct {
+		Plaintext string   `json:"plaintext"`
+		Encrypted bool     `json:"encrypted"`
+		Messages  []string `json:"messages"`
+	}{string(info.Plaintext), info.Encrypted, m.(messagesResult).Messages}, nil
+}
+
+func endMethod(c *conversation, _ params) (interface{}, error) {
+	return messages(c.conv.End())
+}
+
+func smpStartMethod(c *conversation, p params) (interface{}, error) {
+	return messages(c.conv.StartAuthenticate(p.Question, []byte(p.Secret)))
+}
+
+func smpAnswerMethod(c *conversation, p params) (interface{}, error) {
+	return messages(c.conv.ProvideAuthenticationSecret([]byte(p.Secret)))
+}
+
+func stateMethod(c *conversation, _ params) (interface{}, error) {
+	state := struct {
+		Encrypted   bool   `json:"encrypted"`
+		Fingerprint string `json:"fingerprint,omitempty"`
+		SSID        string `json:"ssid,omitempty"`
+	}{Encrypted: c.conv.IsEncrypted()}
+
+	if k := c.conv.GetTheirKey(); k != nil {
+		state.Fingerprint = otr3.FormatFingerprint(k.Fingerprint())
+	}
+	if state.Encrypted {
+		parts, _ := c.conv.SecureSessionID()
+		state.SSID = strings.Join(parts, " ")
+	}
+	return state, nil
+}
+
+func fingerprintMethod(s *service, _ json.RawMessage) (interface{}, error) {
+	return struct {
+		Fingerprint string `json:"fingerprint"`
+	}{otr3.FormatFingerprint(s.key.PublicKey().Fingerprint())}, nil
+}
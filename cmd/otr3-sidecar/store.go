@@ -0,0 +1,71 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+
+	"github.com/twstrike/otr3/cmd/internal/atomicfile"
+)
+
+// savedConversation is what is kept of a conversation between runs of the sidecar
+type savedConversation struct {
+	ID   string `json:"id"`
+	Peer string `json:"peer"`
+}
+
+// state is what the state file holds. NextID is kept so the ids of deleted conversations aren't used again
+type state struct {
+	NextID        int                 `json:"next_id"`
+	Conversations []savedConversation `json:"conversations"`
+}
+
+type byID []savedConversation
+
+func (s byID) Len() int      { return len(s) }
+func (s byID) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s byID) Less(i, j int) bool {
+	l, _ := strconv.Atoi(s[i].ID)
+	r, _ := strconv.Atoi(s[j].ID)
+	return l < r
+}
+
+// store keeps the conversations in a file, if it has a path
+type store struct {
+	path string
+}
+
+func (s *store) load() (state, error) {
+	var st state
+	if s.path == "" {
+		return st, nil
+	}
+
+	data, err := ioutil.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return st, nil
+	}
+	if err != nil {
+		return st, err
+	}
+
+	err = json.Unmarshal(data, &st)
+	return st, err
+}
+
+func (s *store) save(st state) error {
+	if s.path == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(st, "", "  ")
+	if err != nil {
+		return err
+	}
+	return atomicfile.Write(s.path, func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	})
+}
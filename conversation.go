@@ -3,6 +3,8 @@ package otr3
 import (
 	"io"
 	"time"
+
+	"github.com/twstrike/otr3/smp"
 )
 
 type msgState int
@@ -39,26 +41,47 @@ type Conversation struct {
 	theirKey      PublicKey
 
 	ake        *ake
-	smp        smp
+	smp        *smp.Protocol
 	keys       keyManagementContext
 	Policies   policies
 	heartbeat  heartbeatContext
 	resend     resendContext
 	injections injections
 
+	paddingPolicy PaddingPolicy
+
 	fragmentSize         uint16
-	fragmentationContext fragmentationContext
+	fragmentSizeMeasurer FragmentSizeMeasurer
+	fragmentSendMode     FragmentSendMode
+	messageInjector      MessageInjector
+	fragments            fragmentReassembly
+
+	smpTimeout       time.Duration
+	smpNormalization smp.Normalization
+	// theirSMPNormalization is the normalization the peer uses in the current authentication, and
+	// announcedSMPNormalization the one announced in the data message being received
+	theirSMPNormalization     smp.Normalization
+	announcedSMPNormalization *smp.Normalization
 
 	smpEventHandler      SMPEventHandler
 	errorMessageHandler  ErrorMessageHandler
 	messageEventHandler  MessageEventHandler
 	securityEventHandler SecurityEventHandler
 	receivedKeyHandler   ReceivedKeyHandler
+	tlvHandlers          map[uint16]TLVHandler
+	unknownTLVHandler    TLVHandler
+	queuedMessageHandler QueuedMessageHandler
+	events               eventStream
 
 	debug         bool
 	sentRevealSig bool
 
 	friendlyQueryMessage string
+	messageCatalog       *MessageCatalog
+	keyTrustHandler      KeyTrustHandler
+	theirKeyHeld         bool
+
+	receiveInfo *ReceivedMessageInfo
 }
 
 // NewConversationWithVersion creates a new conversation with the given version
@@ -114,7 +137,7 @@ func (c *Conversation) IsEncrypted() bool {
 func (c *Conversation) End() (toSend []ValidMessage, err error) {
 	previousMsgState := c.msgState
 	if c.msgState == encrypted {
-		c.smp.wipe()
+		c.wipeSMP()
 		// Error can only happen when Rand reader is broken
 		toSend, _, err = c.createSerializedDataMessage(nil, messageFlagIgnoreUnreadable, []tlv{tlv{tlvType: tlvTypeDisconnected}})
 	}
@@ -159,7 +182,21 @@ func (c *Conversation) SetSMPEventHandler(handler SMPEventHandler) {
 	c.smpEventHandler = handler
 }
 
-// SetErrorMessageHandler assigns handler for ErrorMessage
+// SetSMPTimeout sets how long to wait for the peer during an authentication before CheckSMPTimeout aborts it.
+// Zero, the default, means waiting forever
+func (c *Conversation) SetSMPTimeout(timeout time.Duration) {
+	c.smpTimeout = timeout
+}
+
+// SetSMPSecretNormalization sets how the secrets given to StartAuthenticate and ProvideAuthenticationSecret are normalized
+// before they are used. Both peers have to use the same normalization for authentication to succeed, so any other
+// normalization is announced to the peer when authenticating, and SMPEventNormalizationMismatch signals that the peer uses
+// a different one. The default is smp.NormalizeNone, which uses the secrets exactly as given, the same as libotr
+func (c *Conversation) SetSMPSecretNormalization(n smp.Normalization) {
+	c.smpNormalization = n
+}
+
+// SetErrorMessageHandler assigns handler for ErrorMessage. Without one, the error messages of the message catalog are sent
 func (c *Conversation) SetErrorMessageHandler(handler ErrorMessageHandler) {
 	c.errorMessageHandler = handler
 }
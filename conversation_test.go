@@ -4,6 +4,8 @@ import (
 	"crypto/rand"
 	"math/big"
 	"testing"
+
+	"github.com/twstrike/otr3/smp"
 )
 
 func Test_receive_OTRQueryMsgRepliesWithDHCommitMessage(t *testing.T) {
@@ -142,8 +144,8 @@ func Test_Send_returnsErrorIfFaislToGenerateDataMsg(t *testing.T) {
 	c.keys.theirKeyID = 0
 	s, err := c.Send(msg)
 
-	assertNil(t, s)
-	assertEquals(t, err, newOtrConflictError("invalid key id for remote peer"))
+	assertDeepEquals(t, s, []ValidMessage{ValidMessage("?OTR Error: Error occurred encrypting message.")})
+	assertEquals(t, err, errInvalidTheirKeyID)
 }
 
 func Test_send_appendWhitespaceTagsWhenAllowedbyThePolicy(t *testing.T) {
@@ -309,23 +311,14 @@ func Test_End_whenStateIsEncrypted(t *testing.T) {
 func Test_End_wipesSMPStateWhenGoingFromEncrypted(t *testing.T) {
 	c := bobContextAfterAKE()
 	c.msgState = encrypted
-	c.smp.state = smpStateExpect2{}
-	c.smp.secret = bnFromHex("ABCDE56321F9A9F8E364607C8C82DECD8E8E6209E2CB952C7E649620F5286FE3")
-	c.smp.s1 = fixtureSmp1()
-	c.smp.s2 = fixtureSmp2()
-	c.smp.s3 = fixtureSmp3()
-	q := "Hello"
-	c.smp.question = &q
+	c.waitForSMPSecret()
+	s := c.smp
 
 	_, e := c.End()
 
 	assertNil(t, e)
-	assertNil(t, c.smp.state)
-	assertNil(t, c.smp.question)
-	assertNil(t, c.smp.secret)
-	assertNil(t, c.smp.s1)
-	assertNil(t, c.smp.s2)
-	assertNil(t, c.smp.s3)
+	assertNil(t, c.smp)
+	assertEquals(t, s.State(), smp.StateExpect1)
 }
 
 func Test_End_whenStateIsEncrypted_willSignalSecurityEvent(t *testing.T) {
@@ -0,0 +1,141 @@
+package otr3
+
+// TLV is a type/length/value record sent inside an encrypted data message.
+// Types 0 to 8 are used by the OTR protocol itself and type 0xFF01 by this package, all other types can be used by the application
+type TLV struct {
+	Type  uint16
+	Value []byte
+}
+
+// TLVHandler is an interface that will be invoked when a TLV of a type registered by the application is received
+type TLVHandler interface {
+	// HandleTLV is called with the received TLV. A returned TLV will be sent back to the peer in an encrypted message.
+	// Returning an error stops the processing of the remaining TLVs in the message, and the message is treated as malformed
+	HandleTLV(t TLV) (reply *TLV, err error)
+}
+
+type dynamicTLVHandler struct {
+	eh func(t TLV) (*TLV, error)
+}
+
+func (d dynamicTLVHandler) HandleTLV(t TLV) (*TLV, error) {
+	return d.eh(t)
+}
+
+const maxTLVValueLength = 0xFFFF
+
+func isProtocolTLVType(tlvType uint16) bool {
+	return tlvType < uint16(len(tlvHandlers)) || tlvType == tlvTypeSMPSecretNormalization
+}
+
+func (t TLV) tlv() tlv {
+	return tlv{
+		tlvType:   t.Type,
+		tlvLength: uint16(len(t.Value)),
+		tlvValue:  t.Value,
+	}
+}
+
+func (c tlv) exported() TLV {
+	return TLV{
+		Type:  c.tlvType,
+		Value: makeCopy(c.tlvValue[:c.tlvLength]),
+	}
+}
+
+// RegisterTLVHandler sets the handler invoked when a TLV of the given type is received. A nil handler removes
+// the registration. Types used by the OTR protocol itself can't be registered, and neither can 0xFF01, which this
+// package uses to tell the peer how it normalizes SMP secrets
+func (c *Conversation) RegisterTLVHandler(tlvType uint16, handler TLVHandler) error {
+	if isProtocolTLVType(tlvType) {
+		return newOtrErrorf("TLV type %d is reserved by the protocol", tlvType)
+	}
+
+	if handler == nil {
+		delete(c.tlvHandlers, tlvType)
+		return nil
+	}
+
+	if c.tlvHandlers == nil {
+		c.tlvHandlers = make(map[uint16]TLVHandler)
+	}
+	c.tlvHandlers[tlvType] = handler
+	return nil
+}
+
+// SetUnknownTLVHandler sets the handler invoked when a TLV is received that neither the protocol nor a handler
+// registered with RegisterTLVHandler knows about. Without it, unknown TLVs are ignored
+func (c *Conversation) SetUnknownTLVHandler(handler TLVHandler) {
+	c.unknownTLVHandler = handler
+}
+
+// SendTLVs encrypts the message together with the given TLVs and returns zero or more messages to send to the peer.
+// The message can be empty, for sending the TLVs on their own. Only TLV types not used by the OTR protocol can be sent.
+// While the key of the peer is held, the message and TLVs are queued until ApproveTheirKey
+func (c *Conversation) SendTLVs(m ValidMessage, tlvs ...TLV) ([]ValidMessage, error) {
+	ts, err := tlvsFrom(tlvs)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.IsTheirKeyHeld() {
+		c.queueMessage(MessagePlaintext(makeCopy(m)), SendOptions{IgnoreUnreadable: len(m) == 0, TLVs: tlvs})
+		return nil, nil
+	}
+
+	flag := messageFlagNormal
+	if len(m) == 0 {
+		flag = messageFlagIgnoreUnreadable
+	}
+
+	toSend, _, err := c.createSerializedDataMessage(m, flag, ts)
+	return toSend, err
+}
+
+// tlvsFrom checks that the TLVs can be sent by the application, and converts them
+func tlvsFrom(tlvs []TLV) ([]tlv, error) {
+	ts := make([]tlv, 0, len(tlvs))
+	for _, t := range tlvs {
+		if isProtocolTLVType(t.Type) {
+			return nil, newOtrErrorf("TLV type %d is reserved by the protocol", t.Type)
+		}
+		if len(t.Value) > maxTLVValueLength {
+			return nil, newOtrError("TLV value is too long")
+		}
+		ts = append(ts, t.tlv())
+	}
+	return ts, nil
+}
+
+// customHandlerForTLV returns a handler for a TLV the protocol doesn't handle, calling the handlers set by the application
+func (c *Conversation) customHandlerForTLV(t tlv) (tlvHandler, bool) {
+	h, ok := c.tlvHandlers[t.tlvType]
+	if !ok {
+		c.withReceiveInfo(func(info *ReceivedMessageInfo) {
+			info.UnknownTLVs = append(info.UnknownTLVs, t.exported())
+		})
+
+		if c.unknownTLVHandler == nil {
+			return nil, false
+		}
+		h = c.unknownTLVHandler
+	}
+
+	return func(c *Conversation, t tlv, x dataMessageExtra) (*tlv, error) {
+		return c.processCustomTLV(h, t)
+	}, true
+}
+
+func (c *Conversation) processCustomTLV(h TLVHandler, t tlv) (toSend *tlv, err error) {
+	reply, err := h.HandleTLV(t.exported())
+	if err != nil || reply == nil {
+		return nil, err
+	}
+
+	if len(reply.Value) > maxTLVValueLength {
+		return nil, newOtrError("TLV value is too long")
+	}
+
+	r := reply.tlv()
+	return &r, nil
+}
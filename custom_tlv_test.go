@@ -0,0 +1,128 @@
+package otr3
+
+import (
+	"errors"
+	"testing"
+)
+
+func Test_RegisterTLVHandler_rejectsTypesUsedByTheProtocol(t *testing.T) {
+	c := &Conversation{}
+	err := c.RegisterTLVHandler(tlvTypeSMP1, dynamicTLVHandler{func(TLV) (*TLV, error) { return nil, nil }})
+
+	assertDeepEquals(t, err, newOtrError("TLV type 2 is reserved by the protocol"))
+	assertNil(t, c.tlvHandlers)
+}
+
+func Test_RegisterTLVHandler_rejectsTheTypeOfTheSMPSecretNormalization(t *testing.T) {
+	c := &Conversation{}
+	err := c.RegisterTLVHandler(0xFF01, dynamicTLVHandler{func(TLV) (*TLV, error) { return nil, nil }})
+
+	assertDeepEquals(t, err, newOtrError("TLV type 65281 is reserved by the protocol"))
+	assertNil(t, c.tlvHandlers)
+}
+
+func Test_RegisterTLVHandler_removesTheHandlerWhenGivenNil(t *testing.T) {
+	c := &Conversation{}
+	_ = c.RegisterTLVHandler(0x100, dynamicTLVHandler{func(TLV) (*TLV, error) { return nil, nil }})
+	_ = c.RegisterTLVHandler(0x100, nil)
+
+	assertEquals(t, len(c.tlvHandlers), 0)
+}
+
+func Test_SendTLVs_rejectsTypesUsedByTheProtocol(t *testing.T) {
+	alice, _ := smpConversationsAfterAKE(t)
+
+	toSend, err := alice.SendTLVs(nil, TLV{Type: tlvTypeDisconnected})
+
+	assertNil(t, toSend)
+	assertDeepEquals(t, err, newOtrError("TLV type 1 is reserved by the protocol"))
+}
+
+func Test_SendTLVs_failsWhenNotEncrypted(t *testing.T) {
+	c := &Conversation{}
+
+	_, err := c.SendTLVs(nil, TLV{Type: 0x100})
+
+	assertDeepEquals(t, err, newOtrErrorOfKind(ErrWrongState, "cannot send message in unencrypted state").asConflict().inState(plainText))
+}
+
+func Test_SendTLVs_sendsAMessageTogetherWithTLVsToTheRegisteredHandler(t *testing.T) {
+	alice, bob := smpConversationsAfterAKE(t)
+
+	var received []TLV
+	_ = bob.RegisterTLVHandler(0x100, dynamicTLVHandler{func(t TLV) (*TLV, error) {
+		received = append(received, t)
+		return nil, nil
+	}})
+
+	toSend, err := alice.SendTLVs(ValidMessage("hello"), TLV{0x100, []byte("one")}, TLV{0x100, []byte("two")})
+	assertNil(t, err)
+
+	info, _, err := bob.ReceiveWithInfo(toSend[0])
+
+	assertNil(t, err)
+	assertDeepEquals(t, info.Plaintext, MessagePlaintext("hello"))
+	assertDeepEquals(t, received, []TLV{{0x100, []byte("one")}, {0x100, []byte("two")}})
+	assertNil(t, info.UnknownTLVs)
+}
+
+func Test_SendTLVs_withoutAMessageIgnoresUnreadable(t *testing.T) {
+	alice, _ := smpConversationsAfterAKE(t)
+
+	toSend, err := alice.SendTLVs(nil, TLV{0x100, []byte("one")})
+	assertNil(t, err)
+
+	dec, _ := alice.decode(encodedMessage(toSend[0]))
+	assertEquals(t, extractDataMessageFlag(dec[otrv3HeaderLen:]), messageFlagIgnoreUnreadable)
+}
+
+func Test_receive_sendsTheReplyOfATLVHandlerBack(t *testing.T) {
+	alice, bob := smpConversationsAfterAKE(t)
+
+	_ = bob.RegisterTLVHandler(0x100, dynamicTLVHandler{func(t TLV) (*TLV, error) {
+		return &TLV{0x101, []byte("pong")}, nil
+	}})
+
+	var unknown []TLV
+	alice.SetUnknownTLVHandler(dynamicTLVHandler{func(t TLV) (*TLV, error) {
+		unknown = append(unknown, t)
+		return nil, nil
+	}})
+
+	toSend, _ := alice.SendTLVs(nil, TLV{0x100, []byte("ping")})
+	_, toSend, err := bob.Receive(toSend[0])
+	assertNil(t, err)
+	assertEquals(t, len(toSend), 1)
+
+	info, _, err := alice.ReceiveWithInfo(toSend[0])
+
+	assertNil(t, err)
+	assertDeepEquals(t, unknown, []TLV{{0x101, []byte("pong")}})
+	assertDeepEquals(t, info.UnknownTLVs, []TLV{{0x101, []byte("pong")}})
+}
+
+func Test_receive_ignoresUnknownTLVsWithoutAHandler(t *testing.T) {
+	alice, bob := smpConversationsAfterAKE(t)
+
+	toSend, _ := alice.SendTLVs(ValidMessage("hello"), TLV{0x100, []byte("ping")})
+	info, _, err := bob.ReceiveWithInfo(toSend[0])
+
+	assertNil(t, err)
+	assertDeepEquals(t, info.Plaintext, MessagePlaintext("hello"))
+	assertDeepEquals(t, info.UnknownTLVs, []TLV{{0x100, []byte("ping")}})
+}
+
+func Test_receive_treatsTheMessageAsMalformedIfATLVHandlerFails(t *testing.T) {
+	alice, bob := smpConversationsAfterAKE(t)
+
+	_ = bob.RegisterTLVHandler(0x100, dynamicTLVHandler{func(t TLV) (*TLV, error) {
+		return nil, errors.New("bad TLV")
+	}})
+
+	toSend, _ := alice.SendTLVs(ValidMessage("hello"), TLV{0x100, []byte("ping")})
+
+	bob.expectMessageEvent(t, func() {
+		_, _, err := bob.Receive(toSend[0])
+		assertDeepEquals(t, err, errors.New("bad TLV"))
+	}, MessageEventReceivedMessageMalformed, nil, nil)
+}
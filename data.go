@@ -1,7 +1,6 @@
 package otr3
 
 import (
-	"hash"
 	"math/big"
 	"strconv"
 )
@@ -29,19 +28,6 @@ func appendMPIs(l []byte, r ...*big.Int) []byte {
 	return l
 }
 
-func hashMPIs(h hash.Hash, magic byte, mpis ...*big.Int) []byte {
-	h.Reset()
-	h.Write([]byte{magic})
-	for _, mpi := range mpis {
-		h.Write(appendMPI(nil, mpi))
-	}
-	return h.Sum(nil)
-}
-
-func hashMPIsBN(h hash.Hash, magic byte, mpis ...*big.Int) *big.Int {
-	return new(big.Int).SetBytes(hashMPIs(h, magic, mpis...))
-}
-
 func extractWord(d []byte) ([]byte, uint32, bool) {
 	if len(d) < 4 {
 		return nil, 0, false
@@ -70,6 +56,10 @@ func extractMPIs(d []byte) ([]byte, []*big.Int, bool) {
 	if !ok {
 		return nil, nil, false
 	}
+	// Every MPI takes at least the four bytes of its length, so a larger count can't be valid
+	if int64(mpiCount) > int64(len(current)/4) {
+		return nil, nil, false
+	}
 	result := make([]*big.Int, int(mpiCount))
 	for i := 0; i < int(mpiCount); i++ {
 		current, result[i], ok = extractMPI(current)
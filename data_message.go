@@ -11,8 +11,13 @@ func (c *Conversation) genDataMsg(message []byte, tlvs ...tlv) (dataMsg, dataMes
 }
 
 func (c *Conversation) genDataMsgWithFlag(message []byte, flag byte, tlvs ...tlv) (dataMsg, dataMessageExtra, error) {
+	return c.genDataMsgWithOptions(message, flag, tlvs, SendOptions{})
+}
+
+// genDataMsgWithOptions generates a data message. The options decide the padding, and are kept in case the message has to be resent
+func (c *Conversation) genDataMsgWithOptions(message []byte, flag byte, tlvs []tlv, opts SendOptions) (dataMsg, dataMessageExtra, error) {
 	if c.msgState != encrypted {
-		return dataMsg{}, dataMessageExtra{}, newOtrConflictError("cannot send message in unencrypted state")
+		return dataMsg{}, dataMessageExtra{}, newOtrErrorOfKind(ErrWrongState, "cannot send message in unencrypted state").asConflict().inState(c.msgState)
 	}
 
 	keys, err := c.keys.calculateDHSessionKeys(c.keys.ourKeyID-1, c.keys.theirKeyID, c.version)
@@ -29,9 +34,12 @@ func (c *Conversation) genDataMsgWithFlag(message []byte, flag byte, tlvs ...tlv
 	binary.BigEndian.PutUint64(topHalfCtr[:], counter.ourCounter)
 	counter.ourCounter++
 
-	plain := plainDataMsg{
+	plain, err := plainDataMsg{
 		message: message,
 		tlvs:    tlvs,
+	}.pad(opts.padding(c), c.rand())
+	if err != nil {
+		return dataMsg{}, dataMessageExtra{}, err
 	}
 
 	encrypted := plain.encrypt(keys.sendingAESKey[:], topHalfCtr)
@@ -55,7 +63,7 @@ func (c *Conversation) genDataMsgWithFlag(message []byte, flag byte, tlvs ...tlv
 	dataMessage.sign(keys.sendingMACKey, header, c.version)
 
 	c.updateMayRetransmitTo(noRetransmit)
-	c.lastMessage(message)
+	c.lastMessage(message, opts)
 
 	x := dataMessageExtra{keys.extraKey[:]}
 
@@ -70,7 +78,11 @@ func extractDataMessageFlag(msg []byte) byte {
 }
 
 func (c *Conversation) createSerializedDataMessage(msg []byte, flag byte, tlvs []tlv) ([]ValidMessage, dataMessageExtra, error) {
-	dataMsg, x, err := c.genDataMsgWithFlag(msg, flag, tlvs...)
+	return c.createSerializedDataMessageWithOptions(msg, flag, tlvs, SendOptions{})
+}
+
+func (c *Conversation) createSerializedDataMessageWithOptions(msg []byte, flag byte, tlvs []tlv, opts SendOptions) ([]ValidMessage, dataMessageExtra, error) {
+	dataMsg, x, err := c.genDataMsgWithOptions(msg, flag, tlvs, opts)
 	if err != nil {
 		return nil, dataMessageExtra{}, err
 	}
@@ -85,7 +97,7 @@ func (c *Conversation) createSerializedDataMessage(msg []byte, flag byte, tlvs [
 }
 
 func (c *Conversation) fragEncode(msg messageWithHeader) []ValidMessage {
-	return c.fragment(c.encode(msg), c.fragmentSize)
+	return c.sendFragments(c.fragment(c.encode(msg), c.fragmentSize))
 }
 
 func (c *Conversation) encode(msg messageWithHeader) encodedMessage {
@@ -134,6 +146,13 @@ func (c *Conversation) processDataMessageWithRawErrors(header, msg []byte) (plai
 	//this can't return an error since receivingAESKey is a AES-128 key
 	p.decrypt(sessionKeys.receivingAESKey[:], dataMessage.topHalfCtr, dataMessage.encryptedMsg)
 
+	c.withReceiveInfo(func(info *ReceivedMessageInfo) {
+		info.Encrypted = true
+		info.Heartbeat = isHeartbeat(p.message, p.tlvs)
+		info.SenderKeyID = dataMessage.senderKeyID
+		info.RecipientKeyID = dataMessage.recipientKeyID
+	})
+
 	plain = makeCopy(p.message)
 	if len(plain) == 0 {
 		plain = nil
@@ -180,11 +199,15 @@ func decideFlagFrom(tlvs []tlv) byte {
 }
 
 func (c *Conversation) processSMPTLV(t tlv, x dataMessageExtra) (toSend *tlv, err error) {
-	c.smp.ensureSMP()
+	c.ensureSMP()
+
+	smpMessage, err := t.smpMessage()
+	if err != nil {
+		return nil, newOtrErrorOfKind(ErrMalformedMessage, "corrupt data message").aboutMessage(msgTypeData)
+	}
 
-	smpMessage, ok := t.smpMessage()
-	if !ok {
-		return nil, newOtrError("corrupt data message")
+	if announcesSMPSecretNormalization(t.tlvType) {
+		c.checkSMPSecretNormalization()
 	}
 
 	return c.receiveSMP(smpMessage)
@@ -193,12 +216,21 @@ func (c *Conversation) processSMPTLV(t tlv, x dataMessageExtra) (toSend *tlv, er
 func (c *Conversation) processTLVs(tlvs []tlv, x dataMessageExtra) ([]tlv, error) {
 	var retTLVs []tlv
 
+	c.announcedSMPNormalization = nil
+
 	for _, t := range tlvs {
 		mh, e := messageHandlerForTLV(t)
 		if e != nil {
-			continue
+			var ok bool
+			if mh, ok = c.customHandlerForTLV(t); !ok {
+				continue
+			}
 		}
 
+		c.withReceiveInfo(func(info *ReceivedMessageInfo) {
+			info.TLVTypes = append(info.TLVTypes, t.tlvType)
+		})
+
 		toSend, err := mh(c, t, x)
 		if err != nil {
 			//We assume this will only happen if the message was sent by a
@@ -9,7 +9,7 @@ import (
 func Test_processTLVs_ignoresInvalidTLVMessageTypes(t *testing.T) {
 	var nilT []tlv
 	tlvs := []tlv{
-		fixtureMessage1().tlv(),
+		smpMessageTLV(fixtureSMPMessage1()),
 		tlv{
 			tlvType:   9,
 			tlvLength: 1,
@@ -104,7 +104,7 @@ func Test_genDataMsg_setsLastMessageWhenNewMessageIsPlaintext(t *testing.T) {
 
 	assertDeepEquals(t, c.resend.pending(),
 		[]messageToResend{
-			messageToResend{MessagePlaintext(msg), nil},
+			messageToResend{MessagePlaintext(msg), SendOptions{}},
 		})
 }
 
@@ -139,7 +139,7 @@ func Test_genDataMsg_returnsErrorIfFailsToCalculateDHSessionKey(t *testing.T) {
 	c := newConversation(otrV3{}, rand.Reader)
 	c.msgState = encrypted
 	_, _, err := c.genDataMsg(nil)
-	assertEquals(t, err, newOtrConflictError("invalid key id for local peer"))
+	assertEquals(t, err, errInvalidOurKeyID)
 }
 
 func Test_genDataMsg_returnsErrorIfFailsToGenerateInstanceTag(t *testing.T) {
@@ -157,7 +157,6 @@ func Test_processDataMessage_deserializeAndDecryptDataMsg(t *testing.T) {
 	bob.msgState = encrypted
 	bob.Policies.add(allowV3)
 	bob.ourCurrentKey = bobPrivateKey
-	bob.smp.secret = bnFromHex("ABCDE56321F9A9F8E364607C8C82DECD8E8E6209E2CB952C7E649620F5286FE3")
 
 	plain := plainDataMsg{
 		message: []byte("hello"),
@@ -177,7 +176,6 @@ func Test_processDataMessage_willGenerateAHeartBeatEventForAnEmptyMessage(t *tes
 	bob := newConversation(otrV3{}, rand.Reader)
 	bob.Policies.add(allowV3)
 	bob.ourCurrentKey = bobPrivateKey
-	bob.smp.secret = bnFromHex("ABCDE56321F9A9F8E364607C8C82DECD8E8E6209E2CB952C7E649620F5286FE3")
 
 	plain := plainDataMsg{
 		message: []byte(""),
@@ -199,13 +197,11 @@ func Test_processDataMessage_processSMPMessage(t *testing.T) {
 	bob.Policies.add(allowV3)
 	bob.ourCurrentKey = bobPrivateKey
 
-	bob.smp.state = smpStateExpect2{}
-	bob.smp.s1 = fixtureSmp1()
-	bob.smp.secret = bnFromHex("ABCDE56321F9A9F8E364607C8C82DECD8E8E6209E2CB952C7E649620F5286FE3")
+	smpMessage2 := bob.startSMP()
 
 	plain := plainDataMsg{
 		tlvs: []tlv{
-			fixtureMessage2().tlv(),
+			smpMessageTLV(smpMessage2),
 		},
 	}
 
@@ -244,7 +240,7 @@ func Test_processDataMessage_returnsErrorIfDataMessageHasWrongCounter(t *testing
 	c.msgState = encrypted
 	_, _, err := c.receiveDecoded(msg)
 
-	assertDeepEquals(t, err, newOtrConflictError("counter regressed"))
+	assertDeepEquals(t, err, newOtrErrorOfKind(ErrCounterReplay, "counter regressed").asConflict().aboutMessage(msgTypeData))
 }
 
 func Test_processDataMessage_signalsThatMessageIsUnreadableForAGPGConflictError(t *testing.T) {
@@ -339,7 +335,7 @@ func Test_processDataMessage_shouldNotRotateKeysWhenDecryptFails(t *testing.T) {
 	bob.msgState = encrypted
 	_, _, err := bob.receiveDecoded(msg)
 
-	assertDeepEquals(t, err, newOtrConflictError("bad signature MAC in encrypted signature"))
+	assertDeepEquals(t, err, newOtrErrorOfKind(ErrMACFailure, "bad signature MAC in encrypted signature").asConflict().aboutMessage(msgTypeData))
 	assertDeepEquals(t, bobCurrentDHKeys, bob.keys.ourCurrentDHKeys)
 	assertDeepEquals(t, bobPreviousDHKeys, bob.keys.ourPreviousDHKeys)
 
@@ -418,21 +414,15 @@ func Test_processDataMessage_ignoresTLVsWhenFailsToRotateKeys(t *testing.T) {
 	bob.Policies.add(allowV3)
 	bob.ourCurrentKey = bobPrivateKey
 
-	// setup state for receiving a SMP message 2
-	bob.smp.state = smpStateExpect2{}
-	bob.smp.s1 = fixtureSmp1()
-	bob.smp.secret = bnFromHex("ABCDE56321F9A9F8E364607C8C82DECD8E8E6209E2CB952C7E649620F5286FE3")
-
 	var msg []byte
 	plain := plainDataMsg{}
-	plain.tlvs = append(plain.tlvs, fixtureMessage2().tlv())
+	plain.tlvs = append(plain.tlvs, smpMessageTLV(fixtureSMPMessage2()))
 	msg, bob.keys = fixtureDataMsg(plain)
 
 	bob.keys.theirKeyID = 1 //forces our key rotation
 	bobCurrentDHKeys := bob.keys.ourCurrentDHKeys
 
 	bob.msgState = encrypted
-	bob.smp.state = smpStateExpect1{}
 	bob.keys.ourKeyID = 1
 	_, toSend, err := bob.receiveDecoded(msg)
 
@@ -457,5 +447,5 @@ func Test_processDataMessage_returnErrorWhenOurKeyIDUnexpected(t *testing.T) {
 	bob.msgState = encrypted
 	_, _, err := bob.receiveDecoded(datamsg)
 
-	assertDeepEquals(t, err, newOtrConflictError("mismatched key id for local peer"))
+	assertDeepEquals(t, err, errMismatchedOurKeyID)
 }
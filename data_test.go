@@ -114,3 +114,9 @@ func Test_extractMPIs_returnsOKIfAnMPIIsReadCorrectly(t *testing.T) {
 	_, _, ok := extractMPIs(d)
 	assertDeepEquals(t, ok, true)
 }
+
+func Test_extractMPIs_returnsNotOKForACountLargerThanTheData(t *testing.T) {
+	d := []byte{0xFF, 0xFF, 0xFF, 0xFF, 0x00, 0x00, 0x00, 0x01, 0x01}
+	_, _, ok := extractMPIs(d)
+	assertDeepEquals(t, ok, false)
+}
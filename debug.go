@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"io"
 	"os"
+
+	"github.com/twstrike/otr3/smp"
 )
 
 const debugString = "?OTR!"
@@ -72,12 +74,13 @@ func (c *Conversation) dumpAKE(w *bufio.Writer) {
 func (c *Conversation) dumpSMP(w *bufio.Writer) {
 	w.WriteString("  SM state:\n")
 
-	if c.smp.state != nil {
-		w.WriteString(fmt.Sprintf("    Next expected: %d (%s)\n", c.smp.state.identity(), c.smp.state.identityString()))
+	if c.smp != nil {
+		state := c.smp.State()
+		w.WriteString(fmt.Sprintf("    Next expected: %d (%s)\n", state, smpStateIdentityStrings[state]))
 	}
 
 	receivedQ := 0
-	if c.smp.question != nil {
+	if _, ok := c.SMPQuestion(); ok {
 		receivedQ = 1
 	}
 	w.WriteString(fmt.Sprintf("    Received_Q: %d\n", receivedQ))
@@ -85,44 +88,13 @@ func (c *Conversation) dumpSMP(w *bufio.Writer) {
 	w.Flush()
 }
 
-func (smpStateExpect1) identity() int {
-	return 0
-}
-
-func (smpStateExpect2) identity() int {
-	return 2
-}
-
-func (smpStateExpect3) identity() int {
-	return 3
-}
-
-func (smpStateExpect4) identity() int {
-	return 4
-}
-
-func (smpStateWaitingForSecret) identity() int {
-	return 1
-}
-
-func (smpStateExpect1) identityString() string {
-	return "EXPECT1"
-}
-
-func (smpStateExpect2) identityString() string {
-	return "EXPECT2"
-}
-
-func (smpStateExpect3) identityString() string {
-	return "EXPECT3"
-}
-
-func (smpStateExpect4) identityString() string {
-	return "EXPECT4"
-}
-
-func (smpStateWaitingForSecret) identityString() string {
-	return "EXPECT1_WQ"
+// smpStateIdentityStrings are the names libotr uses for the SMP states
+var smpStateIdentityStrings = map[smp.State]string{
+	smp.StateExpect1:          "EXPECT1",
+	smp.StateWaitingForSecret: "EXPECT1_WQ",
+	smp.StateExpect2:          "EXPECT2",
+	smp.StateExpect3:          "EXPECT3",
+	smp.StateExpect4:          "EXPECT4",
 }
 
 func (authStateNone) identity() int {
@@ -5,29 +5,27 @@ import (
 	"bytes"
 	"crypto/rand"
 	"testing"
+
+	"github.com/twstrike/otr3/smp"
 )
 
-func Test_dumpSMP_dumpsTheCurrentSMPState(t *testing.T) {
+func Test_dumpSMP_dumpsTheCurrentSMPStateWithQuestion(t *testing.T) {
 	c := newConversation(otrV3{}, fixtureRand())
-	c.smp.state = smpStateExpect2{}
-	c.smp.s1 = fixtureSmp1()
-	c.smp.secret = bnFromHex("ABCDE56321F9A9F8E364607C8C82DECD8E8E6209E2CB952C7E649620F5286FE3")
-	q := "Blarg"
-	c.smp.question = &q
+	peer := &smp.Protocol{Rand: fixtureRand()}
+	msgs, _ := peer.Start("Blarg", fixtureSMPSecret())
+	c.receiveSMP(msgs[0])
 
 	bt := bytes.NewBuffer(make([]byte, 0, 200))
 	c.dumpSMP(bufio.NewWriter(bt))
 	assertDeepEquals(t, bt.String(), `  SM state:
-    Next expected: 2 (EXPECT2)
+    Next expected: 1 (EXPECT1_WQ)
     Received_Q: 1
 `)
 }
 
-func Test_dumpSMP_dumpsTheCurrentSMPStateWithQuestion(t *testing.T) {
+func Test_dumpSMP_dumpsTheCurrentSMPState(t *testing.T) {
 	c := newConversation(otrV3{}, fixtureRand())
-	c.smp.state = smpStateExpect2{}
-	c.smp.s1 = fixtureSmp1()
-	c.smp.secret = bnFromHex("ABCDE56321F9A9F8E364607C8C82DECD8E8E6209E2CB952C7E649620F5286FE3")
+	c.startSMP()
 
 	bt := bytes.NewBuffer(make([]byte, 0, 200))
 	c.dumpSMP(bufio.NewWriter(bt))
@@ -37,20 +35,30 @@ func Test_dumpSMP_dumpsTheCurrentSMPStateWithQuestion(t *testing.T) {
 `)
 }
 
+func Test_dumpSMP_doesntDumpTheNextExpectedStateIfSMPHasNotStarted(t *testing.T) {
+	c := &Conversation{}
+
+	bt := bytes.NewBuffer(make([]byte, 0, 200))
+	c.dumpSMP(bufio.NewWriter(bt))
+	assertDeepEquals(t, bt.String(), `  SM state:
+    Received_Q: 0
+`)
+}
+
 func Test_identity_isCorrectForAllSMPStates(t *testing.T) {
-	assertEquals(t, smpStateExpect1{}.identity(), 0)
-	assertEquals(t, smpStateWaitingForSecret{}.identity(), 1)
-	assertEquals(t, smpStateExpect2{}.identity(), 2)
-	assertEquals(t, smpStateExpect3{}.identity(), 3)
-	assertEquals(t, smpStateExpect4{}.identity(), 4)
+	assertEquals(t, int(smp.StateExpect1), 0)
+	assertEquals(t, int(smp.StateWaitingForSecret), 1)
+	assertEquals(t, int(smp.StateExpect2), 2)
+	assertEquals(t, int(smp.StateExpect3), 3)
+	assertEquals(t, int(smp.StateExpect4), 4)
 }
 
 func Test_identityString_isCorrectForAllSMPStates(t *testing.T) {
-	assertEquals(t, smpStateExpect1{}.identityString(), "EXPECT1")
-	assertEquals(t, smpStateWaitingForSecret{}.identityString(), "EXPECT1_WQ")
-	assertEquals(t, smpStateExpect2{}.identityString(), "EXPECT2")
-	assertEquals(t, smpStateExpect3{}.identityString(), "EXPECT3")
-	assertEquals(t, smpStateExpect4{}.identityString(), "EXPECT4")
+	assertEquals(t, smpStateIdentityStrings[smp.StateExpect1], "EXPECT1")
+	assertEquals(t, smpStateIdentityStrings[smp.StateWaitingForSecret], "EXPECT1_WQ")
+	assertEquals(t, smpStateIdentityStrings[smp.StateExpect2], "EXPECT2")
+	assertEquals(t, smpStateIdentityStrings[smp.StateExpect3], "EXPECT3")
+	assertEquals(t, smpStateIdentityStrings[smp.StateExpect4], "EXPECT4")
 }
 
 func Test_dumpAKE_dumpsTheCurrentAKEState(t *testing.T) {
@@ -203,7 +211,7 @@ func Test_SMP_CompleteDebug(t *testing.T) {
 
 	bobMessages, err = bob.StartAuthenticate("", []byte("secret"))
 	assertNil(t, err)
-	assertEquals(t, bob.smp.state, smpStateExpect2{})
+	assertEquals(t, bob.smp.State(), smp.StateExpect2)
 
 	bt.Reset()
 	bob.dumpSMP(bufio.NewWriter(bt))
@@ -215,9 +223,7 @@ func Test_SMP_CompleteDebug(t *testing.T) {
 	_, aliceMessages, err = alice.Receive(bobMessages[0])
 	assertNil(t, err)
 
-	// this is an internal state
-	_, ok := alice.smp.state.(smpStateWaitingForSecret)
-	assertEquals(t, ok, true)
+	assertEquals(t, alice.smp.State(), smp.StateWaitingForSecret)
 
 	bt.Reset()
 	alice.dumpSMP(bufio.NewWriter(bt))
@@ -232,7 +238,7 @@ func Test_SMP_CompleteDebug(t *testing.T) {
 
 	aliceMessages, err = alice.ProvideAuthenticationSecret([]byte("secret"))
 	assertNil(t, err)
-	assertEquals(t, alice.smp.state, smpStateExpect3{})
+	assertEquals(t, alice.smp.State(), smp.StateExpect3)
 
 	bt.Reset()
 	alice.dumpSMP(bufio.NewWriter(bt))
@@ -243,7 +249,7 @@ func Test_SMP_CompleteDebug(t *testing.T) {
 
 	_, bobMessages, err = bob.Receive(aliceMessages[0])
 	assertNil(t, err)
-	assertEquals(t, bob.smp.state, smpStateExpect4{})
+	assertEquals(t, bob.smp.State(), smp.StateExpect4)
 
 	bt.Reset()
 	bob.dumpSMP(bufio.NewWriter(bt))
@@ -254,7 +260,7 @@ func Test_SMP_CompleteDebug(t *testing.T) {
 
 	_, aliceMessages, err = alice.Receive(bobMessages[0])
 	assertNil(t, err)
-	assertEquals(t, alice.smp.state, smpStateExpect1{})
+	assertEquals(t, alice.smp.State(), smp.StateExpect1)
 
 	bt.Reset()
 	alice.dumpSMP(bufio.NewWriter(bt))
@@ -265,7 +271,7 @@ func Test_SMP_CompleteDebug(t *testing.T) {
 
 	_, bobMessages, err = bob.Receive(aliceMessages[0])
 	assertNil(t, err)
-	assertEquals(t, bob.smp.state, smpStateExpect1{})
+	assertEquals(t, bob.smp.State(), smp.StateExpect1)
 
 	bt.Reset()
 	alice.dumpSMP(bufio.NewWriter(bt))
@@ -276,7 +282,7 @@ func Test_SMP_CompleteDebug(t *testing.T) {
 
 	bobMessages, err = bob.StartAuthenticate("What is the secret?", []byte("secret"))
 	assertNil(t, err)
-	assertEquals(t, bob.smp.state, smpStateExpect2{})
+	assertEquals(t, bob.smp.State(), smp.StateExpect2)
 
 	bt.Reset()
 	bob.dumpSMP(bufio.NewWriter(bt))
@@ -288,9 +294,7 @@ func Test_SMP_CompleteDebug(t *testing.T) {
 	_, aliceMessages, err = alice.Receive(bobMessages[0])
 	assertNil(t, err)
 
-	// this is an internal state
-	_, ok = alice.smp.state.(smpStateWaitingForSecret)
-	assertEquals(t, ok, true)
+	assertEquals(t, alice.smp.State(), smp.StateWaitingForSecret)
 
 	bt.Reset()
 	alice.dumpSMP(bufio.NewWriter(bt))
@@ -305,7 +309,7 @@ func Test_SMP_CompleteDebug(t *testing.T) {
 
 	aliceMessages, err = alice.ProvideAuthenticationSecret([]byte("secret"))
 	assertNil(t, err)
-	assertEquals(t, alice.smp.state, smpStateExpect3{})
+	assertEquals(t, alice.smp.State(), smp.StateExpect3)
 
 	bt.Reset()
 	alice.dumpSMP(bufio.NewWriter(bt))
@@ -316,7 +320,7 @@ func Test_SMP_CompleteDebug(t *testing.T) {
 
 	_, bobMessages, err = bob.Receive(aliceMessages[0])
 	assertNil(t, err)
-	assertEquals(t, bob.smp.state, smpStateExpect4{})
+	assertEquals(t, bob.smp.State(), smp.StateExpect4)
 
 	bt.Reset()
 	bob.dumpSMP(bufio.NewWriter(bt))
@@ -327,7 +331,7 @@ func Test_SMP_CompleteDebug(t *testing.T) {
 
 	_, aliceMessages, err = alice.Receive(bobMessages[0])
 	assertNil(t, err)
-	assertEquals(t, alice.smp.state, smpStateExpect1{})
+	assertEquals(t, alice.smp.State(), smp.StateExpect1)
 
 	bt.Reset()
 	alice.dumpSMP(bufio.NewWriter(bt))
@@ -338,7 +342,7 @@ func Test_SMP_CompleteDebug(t *testing.T) {
 
 	_, bobMessages, err = bob.Receive(aliceMessages[0])
 	assertNil(t, err)
-	assertEquals(t, bob.smp.state, smpStateExpect1{})
+	assertEquals(t, bob.smp.State(), smp.StateExpect1)
 
 	bt.Reset()
 	alice.dumpSMP(bufio.NewWriter(bt))
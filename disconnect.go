@@ -8,7 +8,7 @@ func (c *Conversation) processDisconnectedTLV(t tlv, x dataMessageExtra) (toSend
 	defer c.signalSecurityEventIf(previousMsgState == encrypted, GoneInsecure)
 	c.lastMessageStateChange = time.Time{}
 	c.msgState = finished
-	c.smp.wipe()
+	c.wipeSMP()
 	c.ake = nil
 
 	c.keys = keyManagementContext{}
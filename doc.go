@@ -36,7 +36,76 @@
 //  toSend, err := c.Send(otr3.ValidMessage("hello"))
 //  plain, toSend, err := c.Receive(toSend[0])
 //
+//  // SendWithOptions can send TLVs and flags, and tags the message with an ID for the message events about it
+//  toSend, err := c.SendWithOptions(otr3.ValidMessage("hello"), otr3.SendOptions{ID: "42", IgnoreUnreadable: true})
+//
+//  // Messages sent before the conversation is encrypted are queued, and their status can be followed
+//  c.SetQueueLimits(20, 10*time.Minute)
+//  c.SetQueuedMessageHandler(handler)
+//  toSend, err := c.FlushQueue()
+//
+//  // Long messages can be fragmented, and all but one fragment injected outside the normal send path
+//  c.SetFragmentSizeForProtocol("prpl-irc")
+//  c.SetFragmentSendMode(otr3.FragmentSendAllButLast, injector)
+//
+//  // The fragment size can be counted the way the transport counts it, for example as whole IRC lines
+//  c.SetFragmentSize(otr3.MaxIRCLineLength)
+//  c.SetFragmentSizeMeasurer(otr3.MeasureIRCPrivmsg("alice!alice@example.com", "bob"))
+//
+//  // Fragments are reassembled separately for every instance of the peer, within limits on size and time
+//  c.SetFragmentReassemblyLimits(64*1024, time.Minute)
+//
+//  // ReceiveWithInfo also tells how the message arrived, for example if it was encrypted
+//  info, toSend, err := c.ReceiveWithInfo(toSend[0])
+//  if info.Encrypted { fmt.Println(string(info.Plaintext)) }
+//
+//  // Error messages and the query message text come from a message catalog, which can be changed for the locale
+//  c.SetMessageCatalog(otr3.MessageCatalogFor("pt_BR"))
+//  c.SetDefaultQueryMessage("alice@example.com")
+//
+//  // Error messages sent to the peer can start with a machine readable code, and received ones are recognized
+//  c.Policies.SendErrorCodes()
+//
+//  // Errors can be matched by their kind, and carry the message type and state they are about
+//  if errors.Is(err, otr3.ErrMACFailure) { ... }
+//  var oe otr3.OtrError
+//  if errors.As(err, &oe) { fmt.Println(oe.Kind, oe.MessageType, oe.State) }
+//
+//  // The key of the peer can be pinned: the AKE fails unless it is trusted, or sending waits until it is approved
+//  c.Policies.HoldUntrustedKeys()
+//  c.SetKeyTrustHandler(handler)
+//  toSend, err := c.ApproveTheirKey()
+//
+//  // All events can also be read from one channel, instead of or next to the handlers. The channel is never closed,
+//  // so the reader stops on its own signal
+//  c.SetEventBuffer(256, otr3.DropOldestEvent)
+//  events := c.Events()
+//  go func() {
+//    for {
+//      select {
+//      case e := <-events: fmt.Println(e.Kind, e.Time)
+//      case <-done: return
+//      }
+//    }
+//  }()
+//
+//  // Encrypted messages are padded to hide their length. The padding can be chosen
+//  c.SetPaddingPolicy(otr3.PowerOfTwoPadding{Min: 512})
+//
+//  // Applications can send their own TLVs over the encrypted channel, using types above 8 - except 0xFF01, which is
+//  // reserved for telling the peer how SMP secrets are normalized
+//  c.RegisterTLVHandler(0x100, handler)
+//  toSend, err := c.SendTLVs(otr3.ValidMessage("hello"), otr3.TLV{Type: 0x100, Value: []byte("data")})
+//
 //  // Use Authenticate to start a SMP process
 //  toSend, err := c.StartAuthenticate([]byte{"My pet's name?"},[]byte{"Gopher"})
 //  toSend, err := c.ProvideAuthenticationSecret([]byte{"Gopher"})
+//
+//  // Secrets are used exactly as typed, unless both peers ask for them to be normalized
+//  c.SetSMPSecretNormalization(smp.NormalizeDefault | smp.NormalizeCase)
+//
+//  // An authentication the peer never answers can be aborted explicitly, or after a timeout
+//  toSend, err := c.AbortAuthenticate()
+//  c.SetSMPTimeout(5 * time.Minute)
+//  toSend, err := c.CheckSMPTimeout()
 package otr3
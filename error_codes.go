@@ -1,6 +1,10 @@
 package otr3
 
-import "fmt"
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+)
 
 // ErrorCode represents an error that can happen during OTR processing
 type ErrorCode int
@@ -21,7 +25,8 @@ const (
 
 // ErrorMessageHandler generates error messages for error codes
 type ErrorMessageHandler interface {
-	// HandleErrorMessage should return a string according to the error event. This string will be concatenated to an OTR header to produce an OTR protocol error message
+	// HandleErrorMessage should return a string according to the error event. This string will be concatenated to an OTR header to produce an OTR protocol error message.
+	// If it returns nil, no error message is sent - not even the code of the error, with the SendErrorCodes policy
 	HandleErrorMessage(error ErrorCode) []byte
 }
 
@@ -34,10 +39,108 @@ func (d dynamicErrorMessageHandler) HandleErrorMessage(error ErrorCode) []byte {
 }
 
 func (c *Conversation) generatePotentialErrorMessage(ec ErrorCode) {
-	if c.errorMessageHandler != nil {
-		msg := c.errorMessageHandler.HandleErrorMessage(ec)
-		c.injectMessage(append(append(errorMarker, ' '), msg...))
+	c.publishEvent(Event{Kind: EventKindErrorMessage, ErrorCode: ec})
+
+	msg := c.getErrorMessageHandler().HandleErrorMessage(ec)
+	if msg == nil {
+		return
+	}
+
+	if c.Policies.has(sendErrorCodes) {
+		msg = withErrorCode(ec, msg)
+	}
+
+	c.injectMessage(append(append(errorMarker, ' '), msg...))
+}
+
+// errorCodeNumbers are the machine readable codes peers put in front of error messages, as ERROR_1 and so on
+var errorCodeNumbers = map[ErrorCode]int{
+	ErrorCodeMessageUnreadable:   1,
+	ErrorCodeMessageNotInPrivate: 2,
+	ErrorCodeEncryptionError:     3,
+	ErrorCodeMessageMalformed:    4,
+}
+
+const errorCodePrefix = "ERROR_"
+
+func withErrorCode(ec ErrorCode, msg []byte) []byte {
+	n, ok := errorCodeNumbers[ec]
+	if !ok {
+		return msg
+	}
+
+	coded := []byte(errorCodePrefix + strconv.Itoa(n))
+	if len(msg) == 0 {
+		return coded
+	}
+	return append(append(coded, ": "...), msg...)
+}
+
+// libotrErrorMessages are the beginnings of the English error messages libotr based clients send
+var libotrErrorMessages = []struct {
+	prefix string
+	code   ErrorCode
+}{
+	{"Error occurred encrypting message", ErrorCodeEncryptionError},
+	{"You sent encrypted data to ", ErrorCodeMessageNotInPrivate},
+	{"You transmitted an unreadable encrypted message", ErrorCodeMessageUnreadable},
+	{"You transmitted a malformed data message", ErrorCodeMessageMalformed},
+}
+
+// ReceivedErrorMessage is given as the error of MessageEventReceivedMessageGeneralError when the error message from the
+// peer could be recognized, either from its machine readable code or because it is one of the messages libotr sends
+type ReceivedErrorMessage struct {
+	Code ErrorCode
+	// Coded tells if the peer sent a machine readable code
+	Coded bool
+	// Text is the human readable part of the message, without the code
+	Text []byte
+}
+
+func (e ReceivedErrorMessage) Error() string {
+	return fmt.Sprintf("otr: peer sent error %s: %s", e.Code, e.Text)
+}
+
+// parseErrorMessage recognizes the content of an OTR error message, after the error marker. It returns nil if the
+// message isn't recognized
+func parseErrorMessage(msg []byte) *ReceivedErrorMessage {
+	msg = bytes.TrimSpace(msg)
+
+	if code, text, ok := parseErrorCode(msg); ok {
+		return &ReceivedErrorMessage{Code: code, Coded: true, Text: text}
+	}
+
+	for _, m := range libotrErrorMessages {
+		if bytes.HasPrefix(msg, []byte(m.prefix)) {
+			return &ReceivedErrorMessage{Code: m.code, Text: msg}
+		}
 	}
+
+	return nil
+}
+
+func parseErrorCode(msg []byte) (ErrorCode, []byte, bool) {
+	if !bytes.HasPrefix(msg, []byte(errorCodePrefix)) {
+		return 0, nil, false
+	}
+
+	rest := msg[len(errorCodePrefix):]
+	digits := 0
+	for digits < len(rest) && rest[digits] >= '0' && rest[digits] <= '9' {
+		digits++
+	}
+	if digits == 0 || (digits < len(rest) && rest[digits] != ':' && rest[digits] != ' ') {
+		return 0, nil, false
+	}
+
+	n, _ := strconv.Atoi(string(rest[:digits]))
+	for code, number := range errorCodeNumbers {
+		if number == n {
+			return code, bytes.TrimSpace(bytes.TrimPrefix(rest[digits:], []byte(":"))), true
+		}
+	}
+
+	return 0, nil, false
 }
 
 func (s ErrorCode) String() string {
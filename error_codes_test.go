@@ -51,3 +51,68 @@ func Test_debugErrorMessageHandler_writesTheErrorCodeToStderr(t *testing.T) {
 	})
 	assertEquals(t, ss, "[DEBUG] HandleErrorMessage(ErrorCodeMessageMalformed)\n")
 }
+
+func Test_parseErrorMessage_recognizesErrorCodes(t *testing.T) {
+	assertDeepEquals(t, parseErrorMessage([]byte(" ERROR_1: Unreadable message")), &ReceivedErrorMessage{Code: ErrorCodeMessageUnreadable, Coded: true, Text: []byte("Unreadable message")})
+	assertDeepEquals(t, parseErrorMessage([]byte("ERROR_2 Not in private state message")), &ReceivedErrorMessage{Code: ErrorCodeMessageNotInPrivate, Coded: true, Text: []byte("Not in private state message")})
+	assertDeepEquals(t, parseErrorMessage([]byte("ERROR_3")), &ReceivedErrorMessage{Code: ErrorCodeEncryptionError, Coded: true})
+	assertDeepEquals(t, parseErrorMessage([]byte("ERROR_4: Malformed message")), &ReceivedErrorMessage{Code: ErrorCodeMessageMalformed, Coded: true, Text: []byte("Malformed message")})
+}
+
+func Test_parseErrorMessage_doesntRecognizeUnknownOrBrokenCodes(t *testing.T) {
+	assertNil(t, parseErrorMessage([]byte("ERROR_5: Something else")))
+	assertNil(t, parseErrorMessage([]byte("ERROR_")))
+	assertNil(t, parseErrorMessage([]byte("ERROR_1x: hello")))
+	assertNil(t, parseErrorMessage([]byte("error msg")))
+}
+
+func Test_parseErrorMessage_recognizesTheMessagesLibotrSends(t *testing.T) {
+	assertEquals(t, parseErrorMessage([]byte(" Error occurred encrypting message.")).Code, ErrorCodeEncryptionError)
+	assertEquals(t, parseErrorMessage([]byte(" You sent encrypted data to bob@example.com, who wasn't expecting it.")).Code, ErrorCodeMessageNotInPrivate)
+	assertEquals(t, parseErrorMessage([]byte(" You transmitted an unreadable encrypted message.")).Code, ErrorCodeMessageUnreadable)
+	assertEquals(t, parseErrorMessage([]byte(" You transmitted a malformed data message.")).Code, ErrorCodeMessageMalformed)
+	assertEquals(t, parseErrorMessage([]byte(" You transmitted a malformed data message.")).Coded, false)
+}
+
+func Test_generatePotentialErrorMessage_sendsTheMessageOfTheCatalogWithoutAHandler(t *testing.T) {
+	c := &Conversation{}
+
+	c.generatePotentialErrorMessage(ErrorCodeMessageUnreadable)
+
+	assertDeepEquals(t, c.injections.messages, []ValidMessage{ValidMessage("?OTR Error: You transmitted an unreadable encrypted message.")})
+}
+
+func Test_generatePotentialErrorMessage_sendsNothingIfTheHandlerReturnsNil(t *testing.T) {
+	c := &Conversation{}
+	c.errorMessageHandler = DebugErrorMessageHandler{}
+
+	captureStderr(func() {
+		c.generatePotentialErrorMessage(ErrorCodeMessageUnreadable)
+	})
+
+	assertEquals(t, len(c.injections.messages), 0)
+}
+
+func Test_generatePotentialErrorMessage_prefixesTheCodeWhenThePolicyAsksForIt(t *testing.T) {
+	c := &Conversation{}
+	c.Policies.SendErrorCodes()
+	c.errorMessageHandler = dynamicErrorMessageHandler{func(error ErrorCode) []byte {
+		return []byte("You transmitted an unreadable encrypted message.")
+	}}
+
+	c.generatePotentialErrorMessage(ErrorCodeMessageUnreadable)
+
+	assertDeepEquals(t, c.injections.messages, []ValidMessage{ValidMessage("?OTR Error: ERROR_1: You transmitted an unreadable encrypted message.")})
+}
+
+func Test_generatePotentialErrorMessage_sendsNothingIfTheHandlerReturnsNilEvenWithErrorCodes(t *testing.T) {
+	c := &Conversation{}
+	c.Policies.SendErrorCodes()
+	c.errorMessageHandler = dynamicErrorMessageHandler{func(error ErrorCode) []byte {
+		return nil
+	}}
+
+	c.generatePotentialErrorMessage(ErrorCodeMessageMalformed)
+
+	assertNil(t, c.injections.messages)
+}
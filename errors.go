@@ -2,24 +2,75 @@ package otr3
 
 import "fmt"
 
-var errCantAuthenticateWithoutEncryption = newOtrError("can't authenticate a peer without a secure conversation established")
-var errCorruptEncryptedSignature = newOtrError("corrupt encrypted signature")
-var errEncryptedMessageWithNoSecureChannel = newOtrError("encrypted message received without encrypted session established")
-var errUnexpectedPlainMessage = newOtrError("plain message received when encryption was required")
-var errInvalidOTRMessage = newOtrError("invalid OTR message")
-var errInvalidVersion = newOtrError("no valid version agreement could be found") //libotr ignores this situation
-var errNotWaitingForSMPSecret = newOtrError("not expected SMP secret to be provided now")
+var errCantAuthenticateWithoutEncryption = newOtrErrorOfKind(ErrWrongState, "can't authenticate a peer without a secure conversation established")
+var errCorruptEncryptedSignature = newOtrErrorOfKind(ErrMalformedMessage, "corrupt encrypted signature")
+var errEncryptedMessageWithNoSecureChannel = newOtrErrorOfKind(ErrWrongState, "encrypted message received without encrypted session established")
+var errUnexpectedPlainMessage = newOtrErrorOfKind(ErrWrongState, "plain message received when encryption was required")
+var errInvalidOTRMessage = newOtrErrorOfKind(ErrMalformedMessage, "invalid OTR message")
+var errInvalidVersion = newOtrErrorOfKind(ErrVersionMismatch, "no valid version agreement could be found") //libotr ignores this situation
+var errNotWaitingForSMPSecret = newOtrErrorOfKind(ErrWrongState, "not expected SMP secret to be provided now")
 var errReceivedMessageForOtherInstance = newOtrError("received message for other OTR instance") //not exactly an error - we should ignore these messages by default
-var errShortRandomRead = newOtrError("short read from random source")
-var errUnexpectedMessage = newOtrError("unexpected SMP message")
-var errUnsupportedOTRVersion = newOtrError("unsupported OTR version")
-var errWrongProtocolVersion = newOtrError("wrong protocol version")
-var errMessageNotInPrivate = newOtrError("message not in private")
+var errShortRandomRead = newOtrErrorOfKind(ErrRandomFailure, "short read from random source")
+var errUnexpectedMessage = newOtrErrorOfKind(ErrWrongState, "unexpected SMP message")
+var errUnsupportedOTRVersion = newOtrErrorOfKind(ErrVersionMismatch, "unsupported OTR version")
+var errWrongProtocolVersion = newOtrErrorOfKind(ErrVersionMismatch, "wrong protocol version")
+var errMessageNotInPrivate = newOtrErrorOfKind(ErrWrongState, "message not in private")
+var errFragmentedMessageTooLarge = newOtrErrorOfKind(ErrFragment, "fragmented message is too large")
+var errFragmentedMessageTimedOut = newOtrErrorOfKind(ErrFragment, "fragmented message wasn't completed in time")
+var errTheirKeyHeld = newOtrErrorOfKind(ErrWrongState, "the key of the peer hasn't been approved").inState(encrypted)
+var errUntrustedKey = newOtrErrorOfKind(ErrUntrustedKey, "the key of the peer isn't trusted")
+var errTooManyFragmentedMessages = newOtrErrorOfKind(ErrFragment, "too many fragmented messages in progress")
+var errFragmentedMessageInterrupted = newOtrErrorOfKind(ErrFragment, "fragmented message was interrupted")
+var errInvalidSMPMessage = newOtrErrorOfKind(ErrMalformedMessage, "invalid SMP message")
+var errNoKeyForVersion = newOtrErrorOfKind(ErrVersionMismatch, "no possible key for current version")
+var errInvalidOurKeyID = newOtrErrorOfKind(ErrWrongState, "invalid key id for local peer").asConflict()
+var errMismatchedOurKeyID = newOtrErrorOfKind(ErrWrongState, "mismatched key id for local peer").asConflict()
+var errInvalidTheirKeyID = newOtrErrorOfKind(ErrWrongState, "invalid key id for remote peer").asConflict()
+var errMismatchedTheirKeyID = newOtrErrorOfKind(ErrWrongState, "mismatched key id for remote peer").asConflict()
+var errNoPreviousTheirKey = newOtrErrorOfKind(ErrWrongState, "no previous key for remote peer found").asConflict()
+
+// ErrorKind is the class of an error returned by this package. The kinds are also sentinel errors, so
+// errors.Is(err, otr3.ErrMACFailure) tells if err is a MAC failure, and errors.As gives the OtrError with the details
+type ErrorKind string
+
+func (k ErrorKind) Error() string {
+	return "otr: " + string(k)
+}
+
+var (
+	// ErrWrongState means the operation or message isn't possible in the current state of the conversation
+	ErrWrongState = ErrorKind("wrong state")
+	// ErrMalformedMessage means a message from the peer couldn't be parsed
+	ErrMalformedMessage = ErrorKind("malformed message")
+	// ErrMACFailure means a message from the peer failed its MAC check
+	ErrMACFailure = ErrorKind("MAC verification failed")
+	// ErrCounterReplay means a data message from the peer reused a counter, which is how replays show
+	ErrCounterReplay = ErrorKind("counter replayed")
+	// ErrVersionMismatch means no OTR version both peers and the policies allow could be agreed on
+	ErrVersionMismatch = ErrorKind("version mismatch")
+	// ErrBadSignature means the signature of the peer in the AKE couldn't be verified
+	ErrBadSignature = ErrorKind("bad signature")
+	// ErrRandomFailure means the random source didn't give enough randomness
+	ErrRandomFailure = ErrorKind("random source failed")
+	// ErrFragment means a fragmented message couldn't be reassembled
+	ErrFragment = ErrorKind("fragment error")
+	// ErrUntrustedKey means the AKE was aborted because the key of the peer isn't trusted
+	ErrUntrustedKey = ErrorKind("untrusted key")
+)
 
 // OtrError is an error in the OTR library
 type OtrError struct {
 	msg      string
 	conflict bool
+	cause    error
+
+	// Kind is the class of the error, or empty if it isn't one of the ErrorKinds
+	Kind ErrorKind
+	// MessageType is the type of the OTR message the error is about - 0x02 for DH commit, 0x03 for data, 0x0A for DH key,
+	// 0x11 for reveal signature and 0x12 for signature messages - or 0 if it isn't about one
+	MessageType byte
+	// State is the message state of the conversation - PLAINTEXT, ENCRYPTED or FINISHED - if it matters for the error
+	State string
 }
 
 func newOtrError(s string) error {
@@ -38,6 +89,55 @@ func (oe OtrError) Error() string {
 	return "otr: " + oe.msg
 }
 
+// Is makes errors.Is match an OtrError with its kind
+func (oe OtrError) Is(target error) bool {
+	k, ok := target.(ErrorKind)
+	return ok && oe.Kind != "" && oe.Kind == k
+}
+
+// Unwrap returns the error from another package this error was made from, if any
+func (oe OtrError) Unwrap() error {
+	return oe.cause
+}
+
+func newOtrErrorOfKind(k ErrorKind, s string) OtrError {
+	return OtrError{msg: s, Kind: k}
+}
+
+func newOtrErrorOfKindf(k ErrorKind, format string, a ...interface{}) OtrError {
+	return OtrError{msg: fmt.Sprintf(format, a...), Kind: k}
+}
+
+func (oe OtrError) asConflict() OtrError {
+	oe.conflict = true
+	return oe
+}
+
+func (oe OtrError) aboutMessage(msgType byte) OtrError {
+	oe.MessageType = msgType
+	return oe
+}
+
+func (oe OtrError) causedBy(err error) OtrError {
+	oe.cause = err
+	return oe
+}
+
+func (oe OtrError) inState(s msgState) OtrError {
+	oe.State = s.identityString()
+	return oe
+}
+
+// inMessage prefixes the error with where it happened, keeping its kind
+func inMessage(msgType byte, prefix string, err error) error {
+	oe, ok := err.(OtrError)
+	if !ok {
+		return newOtrError(prefix + err.Error())
+	}
+	oe.msg = prefix + err.Error()
+	return oe.aboutMessage(msgType)
+}
+
 func firstError(es ...error) error {
 	for _, e := range es {
 		if e != nil {
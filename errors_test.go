@@ -1,8 +1,100 @@
 package otr3
 
-import "testing"
+import (
+	"errors"
+	"testing"
+
+	"github.com/twstrike/otr3/smp"
+)
 
 func Test_OtrError_Error_returnsAValidErrorString(t *testing.T) {
 	e := newOtrError("hello world")
 	assertEquals(t, e.Error(), "otr: hello world")
 }
+
+func Test_OtrError_isMatchedByItsKind(t *testing.T) {
+	e := newOtrErrorOfKind(ErrMACFailure, "bad MAC")
+
+	assertEquals(t, errors.Is(e, ErrMACFailure), true)
+	assertEquals(t, errors.Is(e, ErrBadSignature), false)
+	assertEquals(t, e.Error(), "otr: bad MAC")
+}
+
+func Test_OtrError_withoutKindIsNotMatchedByAnyKind(t *testing.T) {
+	e := newOtrError("hello world")
+
+	assertEquals(t, errors.Is(e, ErrorKind("")), false)
+	assertEquals(t, errors.Is(e, ErrWrongState), false)
+}
+
+func Test_OtrError_isStillMatchedWhenWrapped(t *testing.T) {
+	e := newOtrErrorOfKind(ErrCounterReplay, "counter regressed").asConflict().aboutMessage(msgTypeData)
+	wrapped := errors.Join(errors.New("while receiving"), e)
+
+	var oe OtrError
+	assertEquals(t, errors.Is(wrapped, ErrCounterReplay), true)
+	assertEquals(t, errors.As(wrapped, &oe), true)
+	assertEquals(t, oe.Kind, ErrCounterReplay)
+	assertEquals(t, oe.MessageType, msgTypeData)
+	assertEquals(t, isConflict(oe), true)
+}
+
+func Test_inMessage_keepsTheKindOfTheError(t *testing.T) {
+	e := inMessage(msgTypeSig, "in signature message: ", newOtrErrorOfKind(ErrBadSignature, "bad signature"))
+
+	assertDeepEquals(t, e, newOtrErrorOfKind(ErrBadSignature, "in signature message: otr: bad signature").aboutMessage(msgTypeSig))
+}
+
+func Test_inMessage_wrapsOtherErrors(t *testing.T) {
+	e := inMessage(msgTypeSig, "in signature message: ", errors.New("hello"))
+
+	assertDeepEquals(t, e, newOtrError("in signature message: hello"))
+}
+
+func Test_Send_returnsAWrongStateErrorWithTheState(t *testing.T) {
+	c := &Conversation{}
+	c.Policies = policies(allowV3)
+	c.msgState = finished
+
+	_, err := c.Send(ValidMessage("hello"))
+
+	var oe OtrError
+	assertEquals(t, errors.Is(err, ErrWrongState), true)
+	assertEquals(t, errors.As(err, &oe), true)
+	assertEquals(t, oe.State, "FINISHED")
+}
+
+func Test_dhKey_deserialize_returnsAMalformedMessageErrorWithTheMessageType(t *testing.T) {
+	err := (&dhKey{}).deserialize([]byte{0x00, 0x00})
+
+	var oe OtrError
+	assertEquals(t, errors.Is(err, ErrMalformedMessage), true)
+	assertEquals(t, errors.As(err, &oe), true)
+	assertEquals(t, oe.MessageType, msgTypeDHKey)
+}
+
+func Test_sentinelErrors_haveKinds(t *testing.T) {
+	assertEquals(t, errors.Is(errShortRandomRead, ErrRandomFailure), true)
+	assertEquals(t, errors.Is(errUnsupportedOTRVersion, ErrVersionMismatch), true)
+	assertEquals(t, errors.Is(errInvalidOTRMessage, ErrMalformedMessage), true)
+	assertEquals(t, errors.Is(errFragmentedMessageTooLarge, ErrFragment), true)
+	assertEquals(t, errors.Is(errMessageNotInPrivate, ErrWrongState), true)
+	assertEquals(t, errors.Is(errInvalidOurKeyID, ErrWrongState), true)
+	assertEquals(t, errors.Is(errMismatchedTheirKeyID, ErrWrongState), true)
+	assertEquals(t, errors.Is(errNoPreviousTheirKey, ErrWrongState), true)
+	assertEquals(t, errors.Is(errNoKeyForVersion, ErrVersionMismatch), true)
+}
+
+func Test_smpError_givesTheErrorsOfTheSMPPackageAKind(t *testing.T) {
+	assertEquals(t, errors.Is(smpError(smp.ErrShortRandomRead), ErrRandomFailure), true)
+	assertEquals(t, errors.Is(smpError(smp.ErrInvalidMessage), ErrMalformedMessage), true)
+	assertEquals(t, errors.Is(smpError(smp.ErrNotWaitingForSecret), ErrWrongState), true)
+	assertEquals(t, errors.Is(smpError(errors.New("g2a is an invalid group element")), ErrMalformedMessage), true)
+}
+
+func Test_smpError_keepsTheErrorOfTheSMPPackage(t *testing.T) {
+	err := smpError(smp.ErrShortRandomRead)
+
+	assertEquals(t, errors.Is(err, smp.ErrShortRandomRead), true)
+	assertEquals(t, err.Error(), "otr: short read from random source")
+}
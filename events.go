@@ -0,0 +1,136 @@
+package otr3
+
+import (
+	"sync"
+	"time"
+)
+
+// EventKind tells which of the fields of an Event are set
+type EventKind int
+
+const (
+	// EventKindSMP is an SMP event, as given to the SMPEventHandler
+	EventKindSMP EventKind = iota
+	// EventKindMessage is a message event, as given to the MessageEventHandler
+	EventKindMessage
+	// EventKindSecurity is a security event, as given to the SecurityEventHandler
+	EventKindSecurity
+	// EventKindErrorMessage is signaled when an OTR error message is sent to the peer, with the code given to the ErrorMessageHandler
+	EventKindErrorMessage
+	// EventKindReceivedKey is signaled when the peer asks us to use the extra symmetric key, as given to the ReceivedKeyHandler
+	EventKindReceivedKey
+)
+
+// Event is an event of a conversation, as delivered by Events. Kind tells which of the other fields are set
+type Event struct {
+	Kind EventKind
+	Time time.Time
+
+	// Conversation is the conversation the event happened in. The instance tags are the ones in use when it happened
+	Conversation     *Conversation
+	OurInstanceTag   uint32
+	TheirInstanceTag uint32
+
+	// Dropped is the number of events dropped right before this one because the channel was full
+	Dropped int
+
+	// SMP, ProgressPercent and Question are set for EventKindSMP
+	SMP             SMPEvent
+	ProgressPercent int
+	Question        string
+
+	// Message, MessageData, Error and Trace are set for EventKindMessage
+	Message     MessageEvent
+	MessageData []byte
+	Error       error
+	Trace       []interface{}
+
+	// Security is set for EventKindSecurity
+	Security SecurityEvent
+
+	// ErrorCode is set for EventKindErrorMessage
+	ErrorCode ErrorCode
+
+	// SymmetricKey is set for EventKindReceivedKey
+	SymmetricKey ReceivedSymmetricKey
+}
+
+// EventDropPolicy decides which events are dropped when the channel returned by Events is full
+type EventDropPolicy int
+
+const (
+	// DropNewestEvent drops the event that doesn't fit in the channel. It is the default
+	DropNewestEvent EventDropPolicy = iota
+	// DropOldestEvent drops the oldest event in the channel to make room for the new one
+	DropOldestEvent
+)
+
+// DefaultEventBufferSize is the number of events the channel returned by Events holds if no other size has been set
+const DefaultEventBufferSize = 64
+
+// eventStream has its own lock, since Events can be called from another goroutine than the one using the conversation
+type eventStream struct {
+	mu      sync.Mutex
+	ch      chan Event
+	size    int
+	policy  EventDropPolicy
+	dropped int
+}
+
+// SetEventBuffer sets how many events the channel returned by Events holds, and what happens to new events when
+// it is full. It must be called before Events
+func (c *Conversation) SetEventBuffer(size int, policy EventDropPolicy) {
+	c.events.mu.Lock()
+	defer c.events.mu.Unlock()
+
+	c.events.size = size
+	c.events.policy = policy
+}
+
+// Events returns a channel with all the events of the conversation - the same events the handlers get, which are still
+// called. The events are sent without waiting, so events are dropped if the application doesn't read them fast enough.
+// Only the events that happen after the first call are sent. It can be called from any goroutine, and the channel is
+// never closed
+func (c *Conversation) Events() <-chan Event {
+	c.events.mu.Lock()
+	defer c.events.mu.Unlock()
+
+	if c.events.ch == nil {
+		size := c.events.size
+		if size <= 0 {
+			size = DefaultEventBufferSize
+		}
+		c.events.ch = make(chan Event, size)
+	}
+	return c.events.ch
+}
+
+func (c *Conversation) publishEvent(e Event) {
+	c.events.mu.Lock()
+	defer c.events.mu.Unlock()
+
+	if c.events.ch == nil {
+		return
+	}
+
+	e.Time = time.Now()
+	e.Conversation = c
+	e.OurInstanceTag = c.ourInstanceTag
+	e.TheirInstanceTag = c.theirInstanceTag
+
+	if c.events.policy == DropOldestEvent && len(c.events.ch) == cap(c.events.ch) {
+		select {
+		case <-c.events.ch:
+			c.events.dropped++
+		default:
+		}
+	}
+
+	e.Dropped = c.events.dropped
+	select {
+	case c.events.ch <- e:
+		c.events.dropped = 0
+	default:
+		c.events.dropped++
+	}
+}
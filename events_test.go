@@ -0,0 +1,131 @@
+package otr3
+
+import (
+	"errors"
+	"testing"
+)
+
+func Test_Events_returnsTheSameChannelEveryTime(t *testing.T) {
+	c := &Conversation{}
+	assertEquals(t, c.Events(), c.Events())
+	assertEquals(t, cap(c.events.ch), DefaultEventBufferSize)
+}
+
+func Test_publishEvent_doesNothingIfEventsHaveNotBeenAskedFor(t *testing.T) {
+	c := &Conversation{}
+	c.securityEvent(GoneSecure)
+	assertNil(t, c.events.ch)
+}
+
+func Test_Events_canBeCalledWhileTheConversationIsInUse(t *testing.T) {
+	c := &Conversation{}
+	ch := make(chan (<-chan Event))
+	go func() {
+		ch <- c.Events()
+	}()
+
+	for i := 0; i < 100; i++ {
+		c.securityEvent(GoneSecure)
+	}
+
+	assertEquals(t, <-ch, c.Events())
+}
+
+func Test_Events_deliversEventsOfAllKinds(t *testing.T) {
+	c := &Conversation{ourInstanceTag: 0x101, theirInstanceTag: 0x202}
+	events := c.Events()
+
+	anError := errors.New("hello")
+	c.smpEventWithQuestion(SMPEventAskForAnswer, 25, "what?")
+	c.messageEventWithError(MessageEventSetupError, anError)
+	c.messageEventWithMessage(MessageEventReceivedMessageUnrecognized, []byte("hi"))
+	c.securityEvent(GoneSecure)
+	c.generatePotentialErrorMessage(ErrorCodeMessageUnreadable)
+	c.receivedSymKey(1, []byte{0x01}, []byte{0x02})
+
+	e := <-events
+	assertEquals(t, e.Kind, EventKindSMP)
+	assertEquals(t, e.SMP, SMPEventAskForAnswer)
+	assertEquals(t, e.ProgressPercent, 25)
+	assertEquals(t, e.Question, "what?")
+	assertEquals(t, e.Conversation, c)
+	assertEquals(t, e.OurInstanceTag, uint32(0x101))
+	assertEquals(t, e.TheirInstanceTag, uint32(0x202))
+	assertEquals(t, e.Time.IsZero(), false)
+
+	e = <-events
+	assertEquals(t, e.Kind, EventKindMessage)
+	assertEquals(t, e.Message, MessageEventSetupError)
+	assertEquals(t, e.Error, anError)
+
+	e = <-events
+	assertEquals(t, e.Kind, EventKindMessage)
+	assertDeepEquals(t, e.MessageData, []byte("hi"))
+
+	e = <-events
+	assertEquals(t, e.Kind, EventKindSecurity)
+	assertEquals(t, e.Security, GoneSecure)
+
+	e = <-events
+	assertEquals(t, e.Kind, EventKindErrorMessage)
+	assertEquals(t, e.ErrorCode, ErrorCodeMessageUnreadable)
+
+	e = <-events
+	assertEquals(t, e.Kind, EventKindReceivedKey)
+	assertDeepEquals(t, e.SymmetricKey, ReceivedSymmetricKey{1, []byte{0x01}, []byte{0x02}})
+
+	assertEquals(t, len(events), 0)
+}
+
+func Test_Events_stillCallsTheHandlers(t *testing.T) {
+	c := &Conversation{}
+	var called bool
+	c.securityEventHandler = dynamicSecurityEventHandler{func(event SecurityEvent) {
+		called = true
+	}}
+	c.Events()
+	c.securityEvent(GoneSecure)
+
+	assertEquals(t, called, true)
+	assertEquals(t, len(c.events.ch), 1)
+}
+
+func Test_Events_dropsTheNewestEventsWhenFullByDefault(t *testing.T) {
+	c := &Conversation{}
+	c.SetEventBuffer(2, DropNewestEvent)
+	events := c.Events()
+
+	c.securityEvent(GoneSecure)
+	c.securityEvent(StillSecure)
+	c.securityEvent(GoneInsecure)
+	c.securityEvent(GoneInsecure)
+
+	assertEquals(t, (<-events).Security, GoneSecure)
+	assertEquals(t, (<-events).Security, StillSecure)
+
+	c.securityEvent(GoneSecure)
+	e := <-events
+	assertEquals(t, e.Security, GoneSecure)
+	assertEquals(t, e.Dropped, 2)
+
+	c.securityEvent(StillSecure)
+	assertEquals(t, (<-events).Dropped, 0)
+}
+
+func Test_Events_canDropTheOldestEventsInstead(t *testing.T) {
+	c := &Conversation{}
+	c.SetEventBuffer(2, DropOldestEvent)
+	events := c.Events()
+
+	c.securityEvent(GoneSecure)
+	c.securityEvent(StillSecure)
+	c.securityEvent(GoneInsecure)
+
+	e := <-events
+	assertEquals(t, e.Security, StillSecure)
+	assertEquals(t, e.Dropped, 0)
+
+	e = <-events
+	assertEquals(t, e.Security, GoneInsecure)
+	assertEquals(t, e.Dropped, 1)
+}
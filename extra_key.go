@@ -9,11 +9,16 @@ func (c *Conversation) processExtraSymmetricKeyTLV(t tlv, x dataMessageExtra) (t
 }
 
 // UseExtraSymmetricKey takes a usage parameter and optional usageData and returns the current symmetric key
-// and a set of messages to send in order to ask the peer to use the same symmetric key for the usage defined
+// and a set of messages to send in order to ask the peer to use the same symmetric key for the usage defined.
+// It fails while the key of the peer is held, since the key would be shared with a peer not yet approved
 func (c *Conversation) UseExtraSymmetricKey(usage uint32, usageData []byte) ([]byte, []ValidMessage, error) {
 	if c.msgState != encrypted ||
 		c.keys.theirKeyID == 0 {
-		return nil, nil, newOtrError("cannot send message in current state")
+		return nil, nil, newOtrErrorOfKind(ErrWrongState, "cannot send message in current state").inState(c.msgState)
+	}
+
+	if c.IsTheirKeyHeld() {
+		return nil, nil, errTheirKeyHeld
 	}
 
 	t := tlv{
@@ -41,6 +46,11 @@ func (d dynamicReceivedKeyHandler) ReceivedSymmetricKey(usage uint32, usageData
 }
 
 func (c *Conversation) receivedSymKey(usage uint32, usageData []byte, symkey []byte) {
+	c.withReceiveInfo(func(info *ReceivedMessageInfo) {
+		info.SymmetricKeys = append(info.SymmetricKeys, ReceivedSymmetricKey{usage, makeCopy(usageData), makeCopy(symkey)})
+	})
+	c.publishEvent(Event{Kind: EventKindReceivedKey, SymmetricKey: ReceivedSymmetricKey{usage, makeCopy(usageData), makeCopy(symkey)}})
+
 	if c.receivedKeyHandler != nil {
 		c.receivedKeyHandler.ReceivedSymmetricKey(usage, usageData, symkey)
 	}
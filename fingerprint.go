@@ -0,0 +1,52 @@
+package otr3
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// fingerprintLength is the length in bytes of a fingerprint of a public key
+const fingerprintLength = 20
+
+const fingerprintGroupLength = 8
+
+// FormatFingerprint returns the human readable representation of a fingerprint, the same as libotr uses:
+// upper case hexadecimal digits in groups of eight, separated by spaces
+func FormatFingerprint(fpr []byte) string {
+	h := fmt.Sprintf("%X", fpr)
+	groups := make([]string, 0, len(h)/fingerprintGroupLength+1)
+	for len(h) > fingerprintGroupLength {
+		groups = append(groups, h[:fingerprintGroupLength])
+		h = h[fingerprintGroupLength:]
+	}
+	return strings.Join(append(groups, h), " ")
+}
+
+// ParseFingerprint parses a fingerprint typed or pasted by a user. It ignores case, whitespace and colons,
+// so both the libotr format and colon separated hex bytes are accepted.
+func ParseFingerprint(s string) ([]byte, error) {
+	h := strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) || r == ':' {
+			return -1
+		}
+		return r
+	}, s)
+
+	fpr, err := hex.DecodeString(h)
+	if err != nil {
+		return nil, newOtrErrorf("invalid fingerprint %q", s)
+	}
+	if len(fpr) != fingerprintLength {
+		return nil, newOtrErrorf("invalid fingerprint length %d, expected %d bytes", len(fpr), fingerprintLength)
+	}
+	return fpr, nil
+}
+
+// MatchesFingerprint returns true if the given fingerprint is the fingerprint of this key
+func (pub *DSAPublicKey) MatchesFingerprint(fpr []byte) bool {
+	own := pub.Fingerprint()
+	return own != nil && bytes.Equal(own, fpr)
+}
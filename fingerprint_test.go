@@ -0,0 +1,53 @@
+package otr3
+
+import "testing"
+
+var fingerprintFixture = bytesFromHex("0BB01C360424522E94EE9C346CE877A1A4288B2F")
+
+func Test_FormatFingerprint_returnsFiveGroupsOfEightUppercaseHexDigits(t *testing.T) {
+	assertEquals(t, FormatFingerprint(fingerprintFixture), "0BB01C36 0424522E 94EE9C34 6CE877A1 A4288B2F")
+}
+
+func Test_FormatFingerprint_handlesFingerprintsThatAreNotMultiplesOfTheGroupLength(t *testing.T) {
+	assertEquals(t, FormatFingerprint([]byte{0x01, 0x02, 0x03, 0x04, 0xAB}), "01020304 AB")
+	assertEquals(t, FormatFingerprint(nil), "")
+}
+
+func Test_ParseFingerprint_parsesTheLibOTRFormat(t *testing.T) {
+	fpr, err := ParseFingerprint("0BB01C36 0424522E 94EE9C34 6CE877A1 A4288B2F")
+	assertNil(t, err)
+	assertDeepEquals(t, fpr, fingerprintFixture)
+}
+
+func Test_ParseFingerprint_ignoresCaseColonsAndWhitespace(t *testing.T) {
+	fpr, err := ParseFingerprint(" 0b:b0:1c:36:04:24:52:2e:94:ee:9c:34:6c:e8:77:a1:a4:28:8b:2f\n")
+	assertNil(t, err)
+	assertDeepEquals(t, fpr, fingerprintFixture)
+
+	fpr, err = ParseFingerprint("0bb01c360424522e\t94EE9C346CE877A1A4288B2F")
+	assertNil(t, err)
+	assertDeepEquals(t, fpr, fingerprintFixture)
+}
+
+func Test_ParseFingerprint_returnsAnErrorForInvalidCharacters(t *testing.T) {
+	_, err := ParseFingerprint("0BB01C36 0424522E 94EE9C34 6CE877A1 A4288B2X")
+	assertDeepEquals(t, err, newOtrError("invalid fingerprint \"0BB01C36 0424522E 94EE9C34 6CE877A1 A4288B2X\""))
+}
+
+func Test_ParseFingerprint_returnsAnErrorForTheWrongLength(t *testing.T) {
+	_, err := ParseFingerprint("0BB01C36 0424522E 94EE9C34 6CE877A1")
+	assertDeepEquals(t, err, newOtrError("invalid fingerprint length 16, expected 20 bytes"))
+}
+
+func Test_DSAPublicKey_MatchesFingerprint_comparesWithTheKeyFingerprint(t *testing.T) {
+	pub := alicePrivateKey.PublicKey()
+	fpr, _ := ParseFingerprint(FormatFingerprint(pub.Fingerprint()))
+
+	assertTrue(t, pub.MatchesFingerprint(fpr))
+	assertFalse(t, pub.MatchesFingerprint(bobPrivateKey.PublicKey().Fingerprint()))
+	assertFalse(t, pub.MatchesFingerprint(nil))
+}
+
+func Test_DSAPublicKey_MatchesFingerprint_doesNotMatchForAnEmptyKey(t *testing.T) {
+	assertFalse(t, (&DSAPublicKey{}).MatchesFingerprint(nil))
+}
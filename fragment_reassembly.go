@@ -0,0 +1,228 @@
+package otr3
+
+import (
+	"bytes"
+	"time"
+)
+
+// defaultMaxFragmentedMessageSize is the largest message reassembled from fragments if no other limit has been set
+const defaultMaxFragmentedMessageSize = 1024 * 1024
+
+// defaultMaxFragmentBuffers is the number of messages reassembled at the same time, one per instance of the peer,
+// if no other limit has been set
+const defaultMaxFragmentBuffers = 16
+
+// defaultMaxFragmentBytes is the total size of the messages reassembled at the same time if no other limit has been set
+const defaultMaxFragmentBytes = 4 * defaultMaxFragmentedMessageSize
+
+// fragmentBuffer is a message being reassembled from the fragments sent by one instance of the peer
+type fragmentBuffer struct {
+	ctx     fragmentationContext
+	started time.Time
+}
+
+// fragmentReassembly keeps one fragmentBuffer for each instance tag of the peer, so fragmented messages from different
+// instances don't destroy each other
+type fragmentReassembly struct {
+	buffers    map[uint32]fragmentBuffer
+	maxSize    int
+	timeout    time.Duration
+	maxBuffers int
+	maxBytes   int
+}
+
+func (r *fragmentReassembly) limit() int {
+	if r.maxSize == 0 {
+		return defaultMaxFragmentedMessageSize
+	}
+	return r.maxSize
+}
+
+func (r *fragmentReassembly) buffersLimit() int {
+	if r.maxBuffers == 0 {
+		return defaultMaxFragmentBuffers
+	}
+	return r.maxBuffers
+}
+
+func (r *fragmentReassembly) bytesLimit() int {
+	if r.maxBytes == 0 {
+		return defaultMaxFragmentBytes
+	}
+	return r.maxBytes
+}
+
+func (r *fragmentReassembly) totalBytes() int {
+	total := 0
+	for _, b := range r.buffers {
+		total += len(b.ctx.frag)
+	}
+	return total
+}
+
+// oldest returns the tag of the buffer that was started first, other than the one given
+func (r *fragmentReassembly) oldest(except uint32) (uint32, bool) {
+	var tag uint32
+	var started time.Time
+	found := false
+	for t, b := range r.buffers {
+		if t != except && (!found || b.started.Before(started)) {
+			tag, started, found = t, b.started, true
+		}
+	}
+	return tag, found
+}
+
+func (r *fragmentReassembly) expired(b fragmentBuffer, now time.Time) bool {
+	return r.timeout > 0 && fragmentInProgress(b.ctx) && now.Sub(b.started) > r.timeout
+}
+
+// SetFragmentReassemblyLimits sets the largest message that will be reassembled from fragments, and how long the
+// fragments of a message can take to arrive. A maximum size of zero keeps the default of 1MB, and a timeout
+// of zero waits forever. Partial messages that are discarded are reported with MessageEventReceivedMessageFragmentDiscarded
+func (c *Conversation) SetFragmentReassemblyLimits(maxSize int, timeout time.Duration) {
+	c.fragments.maxSize = maxSize
+	c.fragments.timeout = timeout
+}
+
+// SetFragmentBufferLimits sets how many fragmented messages, one for each instance of the peer, are reassembled at the
+// same time, and how many bytes they can take together. When either limit is reached, the oldest partial message is
+// discarded. Zero keeps the defaults of 16 messages and 4MB
+func (c *Conversation) SetFragmentBufferLimits(maxBuffers, maxBytes int) {
+	c.fragments.maxBuffers = maxBuffers
+	c.fragments.maxBytes = maxBytes
+}
+
+func fragmentInProgress(ctx fragmentationContext) bool {
+	return ctx.currentIndex > 0 && !fragmentsFinished(ctx)
+}
+
+func sameFragmentationContext(l, r fragmentationContext) bool {
+	return l.currentIndex == r.currentIndex && l.currentLen == r.currentLen && bytes.Equal(l.frag, r.frag)
+}
+
+// fragmentWasDiscarded returns true if a partial message was thrown away when going from one context to the next
+func fragmentWasDiscarded(before, after fragmentationContext) bool {
+	return fragmentInProgress(before) &&
+		!sameFragmentationContext(before, after) &&
+		!fragmentIsNextMessage(before, after.currentIndex, after.currentLen)
+}
+
+// fragmentSenderInstanceTag returns the sender instance tag of a version 3 fragment, or zero for other fragments
+func fragmentSenderInstanceTag(data []byte) uint32 {
+	if !bytes.HasPrefix(data, otrv3FragmentationPrefix) {
+		return 0
+	}
+
+	header := bytes.SplitN(data, fragmentSeparator, 2)[0]
+	itagParts := bytes.Split(header, fragmentItagsSeparator)
+	if len(itagParts) < 3 {
+		return 0
+	}
+
+	tag, err := parseItag(itagParts[1])
+	if err != nil {
+		return 0
+	}
+	return tag
+}
+
+func (c *Conversation) fragmentDiscarded(reason error) {
+	c.messageEventWithError(MessageEventReceivedMessageFragmentDiscarded, reason)
+}
+
+// receiveFragmentFrom adds a fragment to the message being reassembled for the instance that sent it.
+// It returns the reassembled message when the last fragment has arrived
+func (c *Conversation) receiveFragmentFrom(data ValidMessage) ([]byte, error) {
+	tag := fragmentSenderInstanceTag(data)
+	now := time.Now()
+
+	c.expireFragments(now)
+	buf := c.fragments.buffers[tag]
+
+	ctx, err := c.receiveFragment(buf.ctx, data)
+	if err != nil {
+		return nil, err
+	}
+
+	if fragmentWasDiscarded(buf.ctx, ctx) {
+		c.fragmentDiscarded(errFragmentedMessageInterrupted)
+	}
+
+	if !sameFragmentationContext(buf.ctx, ctx) && fragmentIsFirstMessage(ctx.currentIndex, ctx.currentLen) {
+		buf.started = now
+	}
+	buf.ctx = ctx
+
+	switch {
+	case len(ctx.frag) > c.fragments.limit():
+		c.fragmentDiscarded(errFragmentedMessageTooLarge)
+		delete(c.fragments.buffers, tag)
+	case fragmentsFinished(ctx):
+		delete(c.fragments.buffers, tag)
+		return ctx.frag, nil
+	case ctx.currentIndex == 0:
+		delete(c.fragments.buffers, tag)
+	default:
+		if c.fragments.buffers == nil {
+			c.fragments.buffers = make(map[uint32]fragmentBuffer)
+		}
+		c.fragments.buffers[tag] = buf
+		c.enforceFragmentBufferLimits(tag)
+	}
+
+	return nil, nil
+}
+
+// expireFragments throws away the partial messages of all instances that have taken too long to arrive
+func (c *Conversation) expireFragments(now time.Time) {
+	for tag, b := range c.fragments.buffers {
+		if c.fragments.expired(b, now) {
+			c.fragmentDiscarded(errFragmentedMessageTimedOut)
+			delete(c.fragments.buffers, tag)
+		}
+	}
+}
+
+// enforceFragmentBufferLimits throws away the oldest partial messages of other instances until the buffers are within their limits
+func (c *Conversation) enforceFragmentBufferLimits(current uint32) {
+	for len(c.fragments.buffers) > c.fragments.buffersLimit() || c.fragments.totalBytes() > c.fragments.bytesLimit() {
+		tag, ok := c.fragments.oldest(current)
+		if !ok {
+			return
+		}
+		c.fragmentDiscarded(errTooManyFragmentedMessages)
+		delete(c.fragments.buffers, tag)
+	}
+}
+
+// forgetFragments throws away the partial message of the instance with the tag, as the protocol requires when an
+// unfragmented message arrives from it. Partial messages from other instances are kept
+func (c *Conversation) forgetFragments(tag uint32) {
+	if b, ok := c.fragments.buffers[tag]; ok {
+		if fragmentInProgress(b.ctx) {
+			c.fragmentDiscarded(errFragmentedMessageInterrupted)
+		}
+		delete(c.fragments.buffers, tag)
+	}
+}
+
+// encodedSenderInstanceTag returns the sender instance tag of an encoded version 3 message, or zero for other messages
+func encodedSenderInstanceTag(message []byte) uint32 {
+	if !bytes.HasPrefix(message, msgMarker) {
+		return 0
+	}
+
+	encoded := message[len(msgMarker):]
+	// 12 base64 characters decode to the 9 bytes that hold the protocol version, message type and sender instance tag
+	if len(encoded) < 12 {
+		return 0
+	}
+
+	header, err := b64decode(encoded[:12])
+	if err != nil || len(header) < 7 || header[0] != 0 || header[1] != 3 {
+		return 0
+	}
+	_, tag, _ := extractWord(header[messageHeaderPrefix:])
+	return tag
+}
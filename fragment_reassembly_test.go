@@ -0,0 +1,163 @@
+package otr3
+
+import (
+	"crypto/rand"
+	"testing"
+	"time"
+)
+
+func Test_fragmentSenderInstanceTag_returnsTheSenderTagOfAV3Fragment(t *testing.T) {
+	assertEquals(t, fragmentSenderInstanceTag([]byte("?OTR|00000100|00000102,00001,00004,one ,")), uint32(0x100))
+}
+
+func Test_fragmentSenderInstanceTag_returnsZeroForAV2Fragment(t *testing.T) {
+	assertEquals(t, fragmentSenderInstanceTag([]byte("?OTR,00001,00004,one ,")), uint32(0))
+}
+
+func Test_receiveFragmentFrom_keepsTheFragmentsOfEachInstanceApart(t *testing.T) {
+	c := newConversation(otrV3{}, rand.Reader)
+	c.ourInstanceTag = 0x102
+	c.theirInstanceTag = 0x100
+
+	c.receiveFragmentFrom(ValidMessage("?OTR|00000100|00000102,00001,00002,one ,"))
+	c.receiveFragmentFrom(ValidMessage("?OTR|00000101|00000102,00001,00002,other ,"))
+
+	assertEquals(t, len(c.fragments.buffers), 1)
+	assertDeepEquals(t, c.fragments.buffers[0x100].ctx.frag, []byte("one "))
+}
+
+func Test_receiveFragmentFrom_returnsTheMessageWhenTheLastFragmentArrives(t *testing.T) {
+	c := newConversation(otrV2{}, rand.Reader)
+
+	complete, _ := c.receiveFragmentFrom(ValidMessage("?OTR,00001,00002,one ,"))
+	assertNil(t, complete)
+
+	complete, err := c.receiveFragmentFrom(ValidMessage("?OTR,00002,00002,two,"))
+	assertNil(t, err)
+	assertDeepEquals(t, complete, []byte("one two"))
+	assertEquals(t, len(c.fragments.buffers), 0)
+}
+
+func Test_receiveFragmentFrom_discardsMessagesThatAreTooLarge(t *testing.T) {
+	c := newConversation(otrV2{}, rand.Reader)
+	c.SetFragmentReassemblyLimits(5, 0)
+	c.receiveFragmentFrom(ValidMessage("?OTR,00001,00003,one ,"))
+
+	c.expectMessageEvent(t, func() {
+		c.receiveFragmentFrom(ValidMessage("?OTR,00002,00003,two ,"))
+	}, MessageEventReceivedMessageFragmentDiscarded, nil, errFragmentedMessageTooLarge)
+
+	assertEquals(t, len(c.fragments.buffers), 0)
+}
+
+func Test_receiveFragmentFrom_discardsMessagesThatTakeTooLong(t *testing.T) {
+	c := newConversation(otrV2{}, rand.Reader)
+	c.SetFragmentReassemblyLimits(0, time.Minute)
+	c.receiveFragmentFrom(ValidMessage("?OTR,00001,00002,one ,"))
+
+	buf := c.fragments.buffers[0]
+	buf.started = time.Now().Add(-2 * time.Minute)
+	c.fragments.buffers[0] = buf
+
+	var complete []byte
+	c.expectMessageEvent(t, func() {
+		complete, _ = c.receiveFragmentFrom(ValidMessage("?OTR,00002,00002,two,"))
+	}, MessageEventReceivedMessageFragmentDiscarded, nil, errFragmentedMessageTimedOut)
+
+	assertNil(t, complete)
+}
+
+func Test_receiveFragmentFrom_signalsWhenANewMessageInterruptsAPartialOne(t *testing.T) {
+	c := newConversation(otrV2{}, rand.Reader)
+	c.receiveFragmentFrom(ValidMessage("?OTR,00001,00003,one ,"))
+
+	c.expectMessageEvent(t, func() {
+		c.receiveFragmentFrom(ValidMessage("?OTR,00001,00002,new ,"))
+	}, MessageEventReceivedMessageFragmentDiscarded, nil, errFragmentedMessageInterrupted)
+
+	assertDeepEquals(t, c.fragments.buffers[0].ctx.frag, []byte("new "))
+}
+
+func Test_forgetFragments_signalsForThePartialMessage(t *testing.T) {
+	c := newConversation(otrV2{}, rand.Reader)
+	c.receiveFragmentFrom(ValidMessage("?OTR,00001,00003,one ,"))
+
+	c.expectMessageEvent(t, func() {
+		c.forgetFragments(0)
+	}, MessageEventReceivedMessageFragmentDiscarded, nil, errFragmentedMessageInterrupted)
+
+	assertEquals(t, len(c.fragments.buffers), 0)
+}
+
+func Test_forgetFragments_keepsThePartialMessagesOfOtherInstances(t *testing.T) {
+	c := newConversation(otrV3{}, rand.Reader)
+	c.ourInstanceTag = 0x102
+	c.fragments.buffers = map[uint32]fragmentBuffer{
+		0x100: {ctx: fragmentationContext{[]byte("one "), 1, 2}},
+		0x101: {ctx: fragmentationContext{[]byte("other "), 1, 2}},
+	}
+
+	c.forgetFragments(0x101)
+
+	assertEquals(t, len(c.fragments.buffers), 1)
+	assertDeepEquals(t, c.fragments.buffers[0x100].ctx.frag, []byte("one "))
+}
+
+func Test_encodedSenderInstanceTag_returnsTheSenderTagOfAV3Message(t *testing.T) {
+	c := bobContextAfterAKE()
+	c.ourInstanceTag = 0x1234
+	c.theirInstanceTag = 0x5678
+	m, _ := c.wrapMessageHeader(msgTypeData, []byte{0x01, 0x02})
+
+	assertEquals(t, encodedSenderInstanceTag(c.encode(m)), uint32(0x1234))
+}
+
+func Test_encodedSenderInstanceTag_returnsZeroForOtherMessages(t *testing.T) {
+	assertEquals(t, encodedSenderInstanceTag([]byte("hello")), uint32(0))
+	assertEquals(t, encodedSenderInstanceTag([]byte("?OTR:AAIC")), uint32(0))
+	assertEquals(t, encodedSenderInstanceTag([]byte("?OTR:AAICAAAAAQAAAAE.")), uint32(0))
+}
+
+func Test_receiveFragmentFrom_expiresTheStaleMessagesOfAllInstances(t *testing.T) {
+	c := newConversation(otrV2{}, rand.Reader)
+	c.SetFragmentReassemblyLimits(0, time.Minute)
+	c.fragments.buffers = map[uint32]fragmentBuffer{
+		0x100: {ctx: fragmentationContext{[]byte("one "), 1, 2}, started: time.Now().Add(-2 * time.Minute)},
+	}
+
+	c.expectMessageEvent(t, func() {
+		c.receiveFragmentFrom(ValidMessage("?OTR,00001,00002,new ,"))
+	}, MessageEventReceivedMessageFragmentDiscarded, nil, errFragmentedMessageTimedOut)
+
+	assertEquals(t, len(c.fragments.buffers), 1)
+	assertDeepEquals(t, c.fragments.buffers[0].ctx.frag, []byte("new "))
+}
+
+func Test_receiveFragmentFrom_discardsTheOldestMessageWhenThereAreTooMany(t *testing.T) {
+	c := newConversation(otrV2{}, rand.Reader)
+	c.SetFragmentBufferLimits(1, 0)
+	c.fragments.buffers = map[uint32]fragmentBuffer{
+		0x100: {ctx: fragmentationContext{[]byte("one "), 1, 2}, started: time.Now()},
+	}
+
+	c.expectMessageEvent(t, func() {
+		c.receiveFragmentFrom(ValidMessage("?OTR,00001,00002,new ,"))
+	}, MessageEventReceivedMessageFragmentDiscarded, nil, errTooManyFragmentedMessages)
+
+	assertEquals(t, len(c.fragments.buffers), 1)
+	assertDeepEquals(t, c.fragments.buffers[0].ctx.frag, []byte("new "))
+}
+
+func Test_receiveFragmentFrom_discardsTheOldestMessagesWhenTheyTakeTooManyBytes(t *testing.T) {
+	c := newConversation(otrV2{}, rand.Reader)
+	c.SetFragmentBufferLimits(0, 10)
+	c.fragments.buffers = map[uint32]fragmentBuffer{
+		0x100: {ctx: fragmentationContext{[]byte("one two "), 1, 3}, started: time.Now()},
+	}
+
+	c.expectMessageEvent(t, func() {
+		c.receiveFragmentFrom(ValidMessage("?OTR,00001,00002,new ,"))
+	}, MessageEventReceivedMessageFragmentDiscarded, nil, errTooManyFragmentedMessages)
+
+	assertEquals(t, len(c.fragments.buffers), 1)
+}
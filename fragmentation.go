@@ -1,6 +1,10 @@
 package otr3
 
-import "bytes"
+import (
+	"bytes"
+	"sort"
+	"unicode/utf8"
+)
 
 var (
 	fragmentSeparator      = []byte{','}
@@ -14,55 +18,188 @@ type fragmentationContext struct {
 	currentIndex, currentLen uint16
 }
 
-func min(l, r uint16) uint16 {
-	if l < r {
-		return l
+// SetFragmentSize sets the maximum size for a message fragment.
+// If specified, all messages produced by Receive and Send
+// will be fragmented into messages of, at most, this number of bytes.
+func (c *Conversation) SetFragmentSize(size uint16) {
+	c.fragmentSize = size
+}
+
+// FragmentSizeMeasurer returns the size of a message the way the transport counts it. The fragment size is given
+// in the same unit, so fragments are never larger than what the transport can carry
+type FragmentSizeMeasurer func(m []byte) int
+
+// MeasureBytes measures a message in bytes. It is the default
+func MeasureBytes(m []byte) int {
+	return len(m)
+}
+
+// MeasureUTF16 measures a message in UTF-16 code units, for transports such as SMS gateways that limit the number
+// of characters in that encoding
+func MeasureUTF16(m []byte) int {
+	units := 0
+	for len(m) > 0 {
+		r, size := utf8.DecodeRune(m)
+		if r >= 0x10000 {
+			units += 2
+		} else {
+			units++
+		}
+		m = m[size:]
 	}
-	return r
+	return units
 }
 
-func fragmentStart(i, fraglen uint16) uint16 {
-	return uint16(i * fraglen)
+// MaxIRCLineLength is the largest line an IRC server accepts, including the line ending
+const MaxIRCLineLength = 512
+
+// MeasureIRCPrivmsg returns a measurer that counts the bytes of the whole IRC line a message is delivered in, as the
+// server relays it to the peer - the source prefix, the PRIVMSG command with its target and the line ending. It should be
+// used with a fragment size of MaxIRCLineLength. The source is the nick!user@host of the sender, and its longest possible
+// value should be used if it isn't known exactly
+func MeasureIRCPrivmsg(source, target string) FragmentSizeMeasurer {
+	overhead := len(":" + source + " PRIVMSG " + target + " :\r\n")
+	return func(m []byte) int {
+		return overhead + len(m)
+	}
 }
 
-func fragmentEnd(i, fraglen, l uint16) uint16 {
-	return uint16(min((i+1)*fraglen, l))
+// SetFragmentSizeMeasurer sets how the size of fragments is measured against the fragment size. A nil measurer
+// measures in bytes
+func (c *Conversation) SetFragmentSizeMeasurer(m FragmentSizeMeasurer) {
+	c.fragmentSizeMeasurer = m
 }
 
-func fragmentData(data []byte, i int, fraglen, l uint16) []byte {
-	return data[fragmentStart(uint16(i), fraglen):fragmentEnd(uint16(i), fraglen, l)]
+func (c *Conversation) measurer() FragmentSizeMeasurer {
+	if c.fragmentSizeMeasurer == nil {
+		return MeasureBytes
+	}
+	return c.fragmentSizeMeasurer
 }
 
-// SetFragmentSize sets the maximum size for a message fragment.
-// If specified, all messages produced by Receive and Send
-// will be fragmented into messages of, at most, this number of bytes.
-func (c *Conversation) SetFragmentSize(size uint16) {
-	c.fragmentSize = size
+// DefaultFragmentSizes are the maximum message sizes of the protocols that need fragmentation, keyed by the protocol
+// names libpurple uses. The sizes are the same that pidgin-otr gives libotr
+var DefaultFragmentSizes = map[string]uint16{
+	"prpl-msn":   1409,
+	"prpl-icq":   2346,
+	"prpl-aim":   2343,
+	"prpl-yahoo": 799,
+	"prpl-gg":    1999,
+	"prpl-irc":   417,
+	"prpl-oscar": 2343,
+}
+
+// SetFragmentSizeForProtocol sets the fragment size to the one DefaultFragmentSizes has for the protocol. It returns false,
+// and turns off fragmentation, if the protocol isn't known - protocols not in the table don't need fragmentation
+func (c *Conversation) SetFragmentSizeForProtocol(protocol string) bool {
+	size, ok := DefaultFragmentSizes[protocol]
+	c.SetFragmentSize(size)
+	return ok
+}
+
+// FragmentSendMode decides which fragments of a fragmented message are returned to the caller, and which are given to the
+// message injector. It allows the normal send path of the application to carry one of the fragments
+type FragmentSendMode int
+
+const (
+	// FragmentSendAll returns all fragments to the caller. It is the default
+	FragmentSendAll FragmentSendMode = iota
+	// FragmentSendAllButFirst returns the first fragment to the caller and injects the others
+	FragmentSendAllButFirst
+	// FragmentSendAllButLast returns the last fragment to the caller and injects the others
+	FragmentSendAllButLast
+)
+
+// MessageInjector is an interface that will be invoked to send messages directly to the peer, outside the normal
+// send path of the application
+type MessageInjector interface {
+	// InjectMessage should send the message to the peer right away
+	InjectMessage(m ValidMessage)
+}
+
+type dynamicMessageInjector struct {
+	eh func(m ValidMessage)
+}
+
+func (d dynamicMessageInjector) InjectMessage(m ValidMessage) {
+	d.eh(m)
+}
+
+// SetFragmentSendMode sets which fragments are returned to the caller, and the injector used to send the other fragments.
+// Without an injector, all fragments are returned
+func (c *Conversation) SetFragmentSendMode(mode FragmentSendMode, injector MessageInjector) {
+	c.fragmentSendMode = mode
+	c.messageInjector = injector
+}
+
+// sendFragments injects the fragments the send mode says shouldn't be returned, and returns the rest
+func (c *Conversation) sendFragments(fragments []ValidMessage) []ValidMessage {
+	if len(fragments) < 2 || c.messageInjector == nil {
+		return fragments
+	}
+
+	var toReturn, toInject []ValidMessage
+	switch c.fragmentSendMode {
+	case FragmentSendAllButFirst:
+		toReturn, toInject = fragments[:1], fragments[1:]
+	case FragmentSendAllButLast:
+		toReturn, toInject = fragments[len(fragments)-1:], fragments[:len(fragments)-1]
+	default:
+		return fragments
+	}
+
+	for _, f := range toInject {
+		c.messageInjector.InjectMessage(f)
+	}
+
+	return toReturn
 }
 
 func (c *Conversation) fragment(data encodedMessage, fraglen uint16) []ValidMessage {
-	l := len(data)
+	measure := c.measurer()
 
-	if l <= int(fraglen) || fraglen == 0 {
+	if fraglen == 0 || measure(data) <= int(fraglen) {
 		return []ValidMessage{ValidMessage(data)}
 	}
 
 	fakeHeader := c.version.fragmentPrefix(1, 1, c.ourInstanceTag, c.theirInstanceTag)
-	realFraglen := (fraglen - uint16(len(fakeHeader))) - 1
+	chunks := fragmentChunks(data, fakeHeader, int(fraglen), measure)
 
-	if realFraglen <= 0 {
+	if chunks == nil {
 		return []ValidMessage{ValidMessage(data)}
 	}
 
-	numFragments := (l / int(realFraglen)) + 1
-	ret := make([]ValidMessage, numFragments)
-	for i := 0; i < numFragments; i++ {
-		prefix := c.version.fragmentPrefix(i, numFragments, c.ourInstanceTag, c.theirInstanceTag)
-		ret[i] = append(append(prefix, fragmentData(data, i, realFraglen, uint16(l))...), fragmentSeparator[0])
+	ret := make([]ValidMessage, len(chunks))
+	for i, chunk := range chunks {
+		prefix := c.version.fragmentPrefix(i, len(chunks), c.ourInstanceTag, c.theirInstanceTag)
+		ret[i] = append(append(prefix, chunk...), fragmentSeparator[0])
 	}
 	return ret
 }
 
+// fragmentChunks splits the data into the largest pieces that, with the header and separator around them, measure
+// no more than fraglen. It returns nil if not even one byte of data fits in a fragment
+func fragmentChunks(data, header []byte, fraglen int, measure FragmentSizeMeasurer) [][]byte {
+	fits := func(chunk []byte) bool {
+		frag := append(append(append(make([]byte, 0, len(header)+len(chunk)+1), header...), chunk...), fragmentSeparator[0])
+		return measure(frag) <= fraglen
+	}
+
+	var chunks [][]byte
+	for start := 0; start < len(data); {
+		rest := data[start:]
+		end := sort.Search(len(rest), func(i int) bool {
+			return !fits(rest[:i+1])
+		})
+		if end == 0 {
+			return nil
+		}
+		chunks = append(chunks, rest[:end])
+		start += end
+	}
+	return chunks
+}
+
 func fragmentsFinished(fctx fragmentationContext) bool {
 	return fctx.currentIndex > 0 && fctx.currentIndex == fctx.currentLen
 }
@@ -118,7 +255,7 @@ func (c *Conversation) receiveFragment(beforeCtx fragmentationContext, data Vali
 	}
 
 	if !ok1 || !ok2 {
-		return beforeCtx, newOtrError("invalid OTR fragment")
+		return beforeCtx, newOtrErrorOfKind(ErrFragment, "invalid OTR fragment")
 	}
 
 	switch {
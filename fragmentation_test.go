@@ -261,7 +261,7 @@ func Test_parseFragment_returnsOKIfThereAreExactlyTheRightAmountOfParts(t *testi
 func Test_receiveFragment_returnsErrorIfTheFragmentIsNotCorrect(t *testing.T) {
 	c := newConversation(otrV2{}, rand.Reader)
 	_, e := c.receiveFragment(fragmentationContext{}, []byte{0x00, 0x00, 0x00, 0x00, 0x00, 0x30, 0x30, 0x30, 0x30, 0x29, 0x2C, 0x30, 0x30, 0x30, 0x30, 0x31, 0x2C, 0x01, 0x2C})
-	assertDeepEquals(t, e, newOtrError("invalid OTR fragment"))
+	assertDeepEquals(t, e, newOtrErrorOfKind(ErrFragment, "invalid OTR fragment"))
 }
 
 func Test_parseFragmentPrefix_resolveVersion2IfNotDefined(t *testing.T) {
@@ -305,3 +305,155 @@ func Test_parseFragmentPrefix_rejectsVersion3IfNotAllowedByThePolicy(t *testing.
 	assertEquals(t, ignore, true)
 	assertEquals(t, c.version, nil)
 }
+
+func Test_SetFragmentSizeForProtocol_usesTheSizeOfAKnownProtocol(t *testing.T) {
+	c := &Conversation{}
+
+	assertEquals(t, c.SetFragmentSizeForProtocol("prpl-irc"), true)
+	assertEquals(t, c.fragmentSize, uint16(417))
+}
+
+func Test_SetFragmentSizeForProtocol_turnsOffFragmentationForAnUnknownProtocol(t *testing.T) {
+	c := &Conversation{}
+	c.SetFragmentSize(100)
+
+	assertEquals(t, c.SetFragmentSizeForProtocol("prpl-jabber"), false)
+	assertEquals(t, c.fragmentSize, uint16(0))
+}
+
+func fragmentingConversation(mode FragmentSendMode) (*Conversation, *[]ValidMessage) {
+	var injected []ValidMessage
+	c := &Conversation{version: otrV3{}}
+	c.SetFragmentSendMode(mode, dynamicMessageInjector{func(m ValidMessage) {
+		injected = append(injected, m)
+	}})
+	return c, &injected
+}
+
+func Test_sendFragments_returnsAllFragmentsByDefault(t *testing.T) {
+	c, injected := fragmentingConversation(FragmentSendAll)
+
+	all := c.fragment([]byte("one one one two two two three three three"), 40)
+
+	frags := c.sendFragments(all)
+
+	assertEquals(t, len(frags), 11)
+	assertEquals(t, len(*injected), 0)
+}
+
+func Test_sendFragments_returnsTheFirstFragmentAndInjectsTheOthers(t *testing.T) {
+	c, injected := fragmentingConversation(FragmentSendAllButFirst)
+	all := c.fragment([]byte("one one one two two two three three three"), 40)
+
+	frags := c.sendFragments(all)
+
+	assertDeepEquals(t, frags, all[:1])
+	assertDeepEquals(t, *injected, all[1:])
+}
+
+func Test_sendFragments_returnsTheLastFragmentAndInjectsTheOthers(t *testing.T) {
+	c, injected := fragmentingConversation(FragmentSendAllButLast)
+	all := c.fragment([]byte("one one one two two two three three three"), 40)
+
+	frags := c.sendFragments(all)
+
+	assertDeepEquals(t, frags, all[10:])
+	assertDeepEquals(t, *injected, all[:10])
+}
+
+func Test_sendFragments_returnsAllFragmentsWithoutAnInjector(t *testing.T) {
+	c := &Conversation{version: otrV3{}}
+	c.SetFragmentSendMode(FragmentSendAllButFirst, nil)
+
+	frags := c.sendFragments(c.fragment([]byte("one one one two two two three three three"), 40))
+
+	assertEquals(t, len(frags), 11)
+}
+
+func Test_sendFragments_returnsAnUnfragmentedMessage(t *testing.T) {
+	c, injected := fragmentingConversation(FragmentSendAllButLast)
+
+	frags := c.sendFragments(c.fragment([]byte("one two three"), 0))
+
+	assertEquals(t, len(frags), 1)
+	assertEquals(t, len(*injected), 0)
+}
+
+func Test_Send_returnsOneFragmentAndInjectsTheRest(t *testing.T) {
+	alice, bob := smpConversationsAfterAKE(t)
+
+	var injected []ValidMessage
+	alice.SetFragmentSize(100)
+	alice.SetFragmentSendMode(FragmentSendAllButLast, dynamicMessageInjector{func(m ValidMessage) {
+		injected = append(injected, m)
+	}})
+
+	toSend, err := alice.Send(ValidMessage("hello"))
+	assertNil(t, err)
+	assertEquals(t, len(toSend), 1)
+
+	for _, m := range injected {
+		bob.Receive(m)
+	}
+	plain, _, err := bob.Receive(toSend[0])
+	assertNil(t, err)
+	assertDeepEquals(t, plain, MessagePlaintext("hello"))
+}
+
+func Test_fragment_usesTheMeasurerToSizeTheFragments(t *testing.T) {
+	ctx := newConversation(otrV2{}, rand.Reader)
+	ctx.SetFragmentSizeMeasurer(func(m []byte) int { return 2 * len(m) })
+
+	data := []byte("one one one two two two three three three")
+
+	res := ctx.fragment(data, 44)
+	assertEquals(t, len(res), 11)
+	assertDeepEquals(t, res[0], ValidMessage("?OTR,00001,00011,one ,"))
+}
+
+func Test_fragment_doesntAddAnEmptyFragmentWhenTheDataFitsExactly(t *testing.T) {
+	ctx := newConversation(otrV2{}, rand.Reader)
+
+	res := ctx.fragment([]byte("one one one two two two "), 22)
+
+	assertEquals(t, len(res), 6)
+	assertDeepEquals(t, res[5], ValidMessage("?OTR,00006,00006,two ,"))
+}
+
+func Test_fragment_returnsTheMessageUnchangedIfTheHeaderDoesntFit(t *testing.T) {
+	ctx := newConversation(otrV2{}, rand.Reader)
+
+	data := []byte("one two three")
+
+	assertDeepEquals(t, ctx.fragment(data, 10), []ValidMessage{data})
+}
+
+func Test_fragment_neverProducesFragmentsLargerThanTheSize(t *testing.T) {
+	ctx := newConversation(otrV3{}, rand.Reader)
+	measure := MeasureIRCPrivmsg("alice!alice@example.com", "bob")
+	ctx.SetFragmentSizeMeasurer(measure)
+
+	data := make([]byte, 2000)
+	for i := range data {
+		data[i] = 'a'
+	}
+
+	for _, f := range ctx.fragment(data, MaxIRCLineLength) {
+		assertTrue(t, measure(f) <= MaxIRCLineLength)
+	}
+}
+
+func Test_MeasureBytes_countsBytes(t *testing.T) {
+	assertEquals(t, MeasureBytes([]byte("héllo")), 6)
+}
+
+func Test_MeasureUTF16_countsCodeUnits(t *testing.T) {
+	assertEquals(t, MeasureUTF16([]byte("héllo")), 5)
+	assertEquals(t, MeasureUTF16([]byte("a😀")), 3)
+}
+
+func Test_MeasureIRCPrivmsg_countsTheWholeLine(t *testing.T) {
+	measure := MeasureIRCPrivmsg("alice!a@host", "#otr")
+
+	assertEquals(t, measure([]byte("hello")), len(":alice!a@host PRIVMSG #otr :hello\r\n"))
+}
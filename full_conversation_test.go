@@ -4,11 +4,13 @@ import (
 	"crypto/rand"
 	"testing"
 	"time"
+
+	"github.com/twstrike/otr3/smp"
 )
 
 func Test_conversation_SMPStateMachineStartsAtSmpExpect1(t *testing.T) {
 	c := newConversation(otrV3{}, fixtureRand())
-	assertEquals(t, c.smp.state, smpStateExpect1{})
+	assertEquals(t, c.ensureSMP().State(), smp.StateExpect1)
 }
 
 func Test_receive_generatesErrorIfDoesNotHaveASecureChannel(t *testing.T) {
@@ -17,8 +19,8 @@ func Test_receive_generatesErrorIfDoesNotHaveASecureChannel(t *testing.T) {
 	}
 	c := bobContextAfterAKE()
 	c.msgState = encrypted
-	smpMsg := fixtureMessage1()
-	dataMsg, _, _ := c.genDataMsg(nil, smpMsg.tlv())
+	smpMsg := fixtureSMPMessage1()
+	dataMsg, _, _ := c.genDataMsg(nil, smpMessageTLV(smpMsg))
 	m := dataMsg.serialize(c.version)
 	m, _ = c.wrapMessageHeader(msgTypeData, m)
 	for _, s := range states {
@@ -36,8 +38,8 @@ func Test_receive_doesntGenerateErrorIfThereIsNoSecureChannelButTheMessageIsIGNO
 	}
 	c := bobContextAfterAKE()
 	c.msgState = encrypted
-	smpMsg := fixtureMessage1()
-	dataMsg, _, _ := c.genDataMsgWithFlag(nil, messageFlagIgnoreUnreadable, smpMsg.tlv())
+	smpMsg := fixtureSMPMessage1()
+	dataMsg, _, _ := c.genDataMsgWithFlag(nil, messageFlagIgnoreUnreadable, smpMessageTLV(smpMsg))
 	m, _ := c.wrapMessageHeader(msgTypeData, dataMsg.serialize(c.version))
 
 	for _, s := range states {
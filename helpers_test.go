@@ -6,6 +6,8 @@ import (
 	"math/big"
 	"reflect"
 	"testing"
+
+	"github.com/twstrike/otr3/smp"
 )
 
 func assertEquals(t *testing.T, actual, expected interface{}) {
@@ -111,11 +113,9 @@ func newConversation(v otrVersion, rand io.Reader) *Conversation {
 	akeNotStarted.state = authStateNone{}
 
 	return &Conversation{
-		version: v,
-		Rand:    rand,
-		smp: smp{
-			state: smpStateExpect1{},
-		},
+		version:          v,
+		Rand:             rand,
+		smp:              &smp.Protocol{},
 		ake:              akeNotStarted,
 		Policies:         policies(p),
 		fragmentSize:     65535, //we are not testing fragmentation by default
@@ -182,3 +182,36 @@ func (c *Conversation) doesntExpectSecurityEvent(t *testing.T, f func()) {
 
 	f()
 }
+
+func fixtureSMPSecret() *big.Int {
+	return bnFromHex("D9B2E56321F9A9F8E364607C8C82DECD8E8E6209E2CB952C7E649620F5286FE3")
+}
+
+// fixtureSMPMessage1 returns the first SMP message of a run started by the other party
+func fixtureSMPMessage1() smp.Message {
+	peer := &smp.Protocol{Rand: fixtureRand()}
+	msgs, _ := peer.Start("", fixtureSMPSecret())
+	return msgs[0]
+}
+
+// fixtureSMPMessage2 returns the second SMP message of a run between two other parties
+func fixtureSMPMessage2() smp.Message {
+	peer := &smp.Protocol{Rand: fixtureRand()}
+	peer.Receive(fixtureSMPMessage1())
+	m, _ := peer.ProvideSecret(fixtureSMPSecret())
+	return m
+}
+
+// startSMP starts an SMP run on the conversation without sending anything, and returns the reply of the other party
+func (c *Conversation) startSMP() smp.Message {
+	msgs, _ := c.ensureSMP().Start("", fixtureSMPSecret())
+	peer := &smp.Protocol{Rand: fixtureRand()}
+	peer.Receive(msgs[0])
+	m, _ := peer.ProvideSecret(fixtureSMPSecret())
+	return m
+}
+
+// waitForSMPSecret puts the conversation in the SMP state where it waits for the user to provide a secret
+func (c *Conversation) waitForSMPSecret() {
+	c.receiveSMP(fixtureSMPMessage1())
+}
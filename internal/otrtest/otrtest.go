@@ -0,0 +1,43 @@
+// Package otrtest has the fixtures shared by the tests of the packages that use otr3.
+package otrtest
+
+import (
+	"crypto/rand"
+	"sync"
+	"testing"
+
+	"github.com/twstrike/otr3"
+)
+
+var (
+	generateKeys sync.Once
+	keys         [2]*otr3.DSAPrivateKey
+	keysErr      error
+)
+
+// key returns one of the keys, which are generated once for all tests since that is slow
+func key(i int) (*otr3.DSAPrivateKey, error) {
+	generateKeys.Do(func() {
+		for i := range keys {
+			keys[i] = &otr3.DSAPrivateKey{}
+			if keysErr = keys[i].Generate(rand.Reader); keysErr != nil {
+				return
+			}
+		}
+	})
+	return keys[i], keysErr
+}
+
+// Conversation returns a new conversation that allows version 3, with the first or the second (i is 0 or 1) of
+// two keys shared by all tests. It fails the test if the keys couldn't be generated
+func Conversation(t testing.TB, i int) *otr3.Conversation {
+	k, err := key(i)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := &otr3.Conversation{Rand: rand.Reader}
+	c.SetOurKeys([]otr3.PrivateKey{k})
+	c.Policies.AllowV3()
+	return c
+}
@@ -0,0 +1,197 @@
+package irc
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/twstrike/otr3"
+)
+
+// Binding keeps the OTR conversations of one IRC connection, one for every nick it chats with.
+// All methods can be called from different goroutines
+type Binding struct {
+	client          Client
+	newConversation func(nick string) *otr3.Conversation
+
+	mu            sync.Mutex
+	source        string
+	conversations map[string]*otr3.Conversation
+}
+
+// NewBinding returns a Binding that sends its lines to client, and creates a conversation with newConversation
+// the first time a nick is seen. newConversation should set the keys and policies. The source is our
+// nick!user@host as the server shows it to others. If it isn't known exactly, the longest it can be should be given
+func NewBinding(client Client, source string, newConversation func(nick string) *otr3.Conversation) *Binding {
+	return &Binding{
+		client:          client,
+		source:          source,
+		newConversation: newConversation,
+		conversations:   make(map[string]*otr3.Conversation),
+	}
+}
+
+// SetSource changes our nick!user@host, for example after a nick change, so fragments keep fitting on a line
+func (b *Binding) SetSource(source string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.source = source
+	for peer, c := range b.conversations {
+		b.setFragmentation(c, peer)
+	}
+}
+
+// Conversation returns the conversation with the nick, creating it if needed.
+// It must not be used at the same time as the other methods of the Binding - use Do for that
+func (b *Binding) Conversation(nick string) *otr3.Conversation {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.conversation(nick)
+}
+
+// Do calls f with the conversation with the nick, and sends the messages it returns to the peer - also when it
+// returns an error, since the conversation can still have messages for the peer then.
+// It can be used for the operations the Binding doesn't have methods for, such as SMP
+func (b *Binding) Do(nick string, f func(c *otr3.Conversation) ([]otr3.ValidMessage, error)) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	toSend, err := f(b.conversation(nick))
+	if serr := b.send(nick, toSend); err == nil {
+		err = serr
+	}
+	return err
+}
+
+// Start asks the peer to start a private conversation
+func (b *Binding) Start(nick string) error {
+	return b.Do(nick, func(c *otr3.Conversation) ([]otr3.ValidMessage, error) {
+		return []otr3.ValidMessage{c.QueryMessage()}, nil
+	})
+}
+
+// Send sends the text to the peer, encrypted if the conversation is private
+func (b *Binding) Send(nick, text string) error {
+	if !validText(text) {
+		return ErrInvalidText
+	}
+
+	return b.Do(nick, func(c *otr3.Conversation) ([]otr3.ValidMessage, error) {
+		return c.Send(otr3.ValidMessage(text))
+	})
+}
+
+// SendAction sends a CTCP ACTION to the peer. In a private conversation it is sent encrypted, as text starting with /me.
+// Otherwise it goes through the conversation like any other message, so it is queued if the policies require encryption
+func (b *Binding) SendAction(nick, action string) error {
+	if !validText(action) {
+		return ErrInvalidText
+	}
+
+	return b.Do(nick, func(c *otr3.Conversation) ([]otr3.ValidMessage, error) {
+		if c.IsEncrypted() {
+			return c.Send(otr3.ValidMessage(actionPrefix + action))
+		}
+		return c.Send(otr3.ValidMessage(ctcpDelimiter + actionCommand + " " + action + ctcpDelimiter))
+	})
+}
+
+// End ends the private conversation with the peer
+func (b *Binding) End(nick string) error {
+	return b.Do(nick, func(c *otr3.Conversation) ([]otr3.ValidMessage, error) {
+		return c.End()
+	})
+}
+
+// Handle handles a line received from the server. It returns the message to show to the user, or nil if the line
+// wasn't a message, or was an OTR message with nothing to show
+func (b *Binding) Handle(raw string) (*Message, error) {
+	l := parseLine(raw)
+	if (l.command != "PRIVMSG" && l.command != "NOTICE") || len(l.params) < 2 {
+		return nil, nil
+	}
+
+	m := &Message{
+		From:   nick(l.prefix),
+		Target: l.params[0],
+		Text:   l.params[1],
+		Notice: l.command == "NOTICE",
+	}
+
+	if command, params, ok := ctcp(m.Text); ok {
+		m.Text = params
+		if command == actionCommand {
+			m.Action = true
+		} else {
+			m.CTCP = command
+		}
+		return m, nil
+	}
+
+	if m.Notice || isChannel(m.Target) {
+		return m, nil
+	}
+
+	return b.receive(m)
+}
+
+func (b *Binding) receive(m *Message) (*Message, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	info, toSend, err := b.conversation(m.From).ReceiveWithInfo(otr3.ValidMessage(m.Text))
+	if serr := b.send(m.From, toSend); err == nil {
+		err = serr
+	}
+
+	if len(info.Plaintext) == 0 {
+		return nil, err
+	}
+
+	m.Text = string(info.Plaintext)
+	m.Encrypted = info.Encrypted
+	if m.Encrypted && strings.HasPrefix(m.Text, actionPrefix) {
+		m.Text = strings.TrimPrefix(m.Text, actionPrefix)
+		m.Action = true
+	}
+	// Actions queued before the conversation was private arrive encrypted, but as CTCP
+	if command, params, ok := ctcp(m.Text); m.Encrypted && ok && command == actionCommand {
+		m.Text = params
+		m.Action = true
+	}
+	return m, err
+}
+
+func (b *Binding) conversation(nick string) *otr3.Conversation {
+	key := foldNick(nick)
+	c, ok := b.conversations[key]
+	if !ok {
+		c = b.newConversation(nick)
+		b.setFragmentation(c, nick)
+		b.conversations[key] = c
+	}
+	return c
+}
+
+// setFragmentation makes the conversation fragment its messages so they fit in the lines the server relays to the peer
+func (b *Binding) setFragmentation(c *otr3.Conversation, nick string) {
+	c.SetFragmentSize(otr3.MaxIRCLineLength)
+	c.SetFragmentSizeMeasurer(otr3.MeasureIRCPrivmsg(b.source, nick))
+}
+
+func (b *Binding) send(nick string, toSend []otr3.ValidMessage) error {
+	for _, m := range toSend {
+		if !validText(string(m)) {
+			return ErrInvalidText
+		}
+		if err := b.client.SendLine("PRIVMSG " + nick + " :" + string(m)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validText(text string) bool {
+	return !strings.ContainsAny(text, "\r\n\x00")
+}
@@ -0,0 +1,216 @@
+package irc
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/twstrike/otr3"
+	"github.com/twstrike/otr3/internal/otrtest"
+)
+
+// server is an in-process stand-in for an IRC server. It relays PRIVMSG and NOTICE lines between its clients,
+// truncating them to 512 bytes the way real servers do, and delivers them with flush
+type server struct {
+	t         *testing.T
+	queue     []string
+	clients   map[string]*Binding
+	truncated bool
+	received  map[string][]*Message
+}
+
+type serverClient struct {
+	s    *server
+	nick string
+}
+
+func (c serverClient) SendLine(l string) error {
+	parsed := parseLine(l)
+	relayed := ":" + c.nick + "!" + c.nick + "@irc.example.com " + l + "\r\n"
+	if len(relayed) > 512 {
+		relayed = relayed[:510] + "\r\n"
+		c.s.truncated = true
+	}
+	c.s.queue = append(c.s.queue, parsed.params[0]+" "+relayed)
+	return nil
+}
+
+func newServer(t *testing.T) *server {
+	return &server{t: t, clients: make(map[string]*Binding), received: make(map[string][]*Message)}
+}
+
+func (s *server) connect(nick string, key int) *Binding {
+	b := NewBinding(serverClient{s, nick}, nick+"!"+nick+"@irc.example.com", func(string) *otr3.Conversation {
+		return otrtest.Conversation(s.t, key)
+	})
+	s.clients[nick] = b
+	return b
+}
+
+func (s *server) flush() {
+	for len(s.queue) > 0 {
+		target, l := beforeSpace(s.queue[0]), afterSpace(s.queue[0])
+		s.queue = s.queue[1:]
+
+		m, err := s.clients[target].Handle(l)
+		if err != nil {
+			s.t.Errorf("Handling %s failed: %v", l, err)
+		}
+		if m != nil {
+			s.received[target] = append(s.received[target], m)
+		}
+	}
+}
+
+func privateConversation(t *testing.T) (s *server, alice, bob *Binding) {
+	s = newServer(t)
+	alice = s.connect("alice", 0)
+	bob = s.connect("bob", 1)
+
+	alice.Start("bob")
+	s.flush()
+	return
+}
+
+func Test_Binding_runsTheAKEAndSendsEncryptedMessages(t *testing.T) {
+	s, alice, bob := privateConversation(t)
+
+	assertEquals(t, alice.Conversation("bob").IsEncrypted(), true)
+	assertEquals(t, bob.Conversation("alice").IsEncrypted(), true)
+
+	alice.Send("bob", "hello")
+	s.flush()
+
+	assertDeepEquals(t, s.received["bob"], []*Message{{From: "alice", Target: "bob", Text: "hello", Encrypted: true}})
+}
+
+func Test_Binding_fragmentsLongMessagesSoTheyArentTruncated(t *testing.T) {
+	s, alice, _ := privateConversation(t)
+
+	long := strings.Repeat("all work and no play makes jack a dull boy ", 50)
+	alice.Send("bob", long)
+	s.flush()
+
+	assertEquals(t, len(s.received["bob"]), 1)
+	assertEquals(t, s.received["bob"][0].Text, long)
+	assertEquals(t, s.truncated, false)
+}
+
+func Test_Binding_keepsFragmentsWithinTheLimitAfterANickChange(t *testing.T) {
+	s, alice, _ := privateConversation(t)
+	alice.client = serverClient{s, "alice_with_a_much_longer_nick"}
+	alice.SetSource("alice_with_a_much_longer_nick!alice_with_a_much_longer_nick@irc.example.com")
+
+	alice.Send("bob", strings.Repeat("all work and no play makes jack a dull boy ", 50))
+
+	assertEquals(t, s.truncated, false)
+}
+
+func Test_Binding_sendsActionsEncryptedInAPrivateConversation(t *testing.T) {
+	s, alice, _ := privateConversation(t)
+
+	alice.SendAction("bob", "waves")
+	s.flush()
+
+	assertDeepEquals(t, s.received["bob"], []*Message{{From: "alice", Target: "bob", Text: "waves", Encrypted: true, Action: true}})
+}
+
+func Test_Binding_sendsActionsAsCTCPOutsideAPrivateConversation(t *testing.T) {
+	s := newServer(t)
+	alice := s.connect("alice", 0)
+	s.connect("bob", 1)
+
+	alice.SendAction("bob", "waves")
+	s.flush()
+
+	assertDeepEquals(t, s.received["bob"], []*Message{{From: "alice", Target: "bob", Text: "waves", Action: true}})
+}
+
+func Test_Binding_doesntSendActionsInPlaintextWhenEncryptionIsRequired(t *testing.T) {
+	s := newServer(t)
+	alice := NewBinding(serverClient{s, "alice"}, "alice!alice@irc.example.com", func(string) *otr3.Conversation {
+		c := otrtest.Conversation(t, 0)
+		c.Policies.RequireEncryption()
+		return c
+	})
+	s.clients["alice"] = alice
+	s.connect("bob", 1)
+
+	alice.SendAction("bob", "waves")
+	for _, l := range s.queue {
+		assertEquals(t, strings.Contains(l, "waves"), false)
+	}
+	s.flush()
+
+	assertDeepEquals(t, s.received["bob"], []*Message{{From: "alice", Target: "bob", Text: "waves", Encrypted: true, Action: true}})
+}
+
+func Test_Binding_doesntFeedNoticesToTheConversation(t *testing.T) {
+	s := newServer(t)
+	bob := s.connect("bob", 1)
+
+	m, err := bob.Handle(":alice!a@host NOTICE bob :?OTRv3?")
+
+	assertNil(t, err)
+	assertDeepEquals(t, m, &Message{From: "alice", Target: "bob", Text: "?OTRv3?", Notice: true})
+	assertEquals(t, len(s.queue), 0)
+}
+
+func Test_Binding_returnsCTCPRequests(t *testing.T) {
+	s := newServer(t)
+	bob := s.connect("bob", 1)
+
+	m, _ := bob.Handle(":alice!a@host PRIVMSG bob :\x01VERSION\x01")
+
+	assertDeepEquals(t, m, &Message{From: "alice", Target: "bob", CTCP: "VERSION"})
+	assertEquals(t, len(s.queue), 0)
+}
+
+func Test_Binding_returnsChannelMessagesUnchanged(t *testing.T) {
+	s := newServer(t)
+	bob := s.connect("bob", 1)
+
+	m, _ := bob.Handle(":alice!a@host PRIVMSG #otr :?OTRv3?")
+
+	assertDeepEquals(t, m, &Message{From: "alice", Target: "#otr", Text: "?OTRv3?"})
+	assertEquals(t, len(s.queue), 0)
+}
+
+func Test_Binding_ignoresOtherCommands(t *testing.T) {
+	s := newServer(t)
+	bob := s.connect("bob", 1)
+
+	m, err := bob.Handle(":irc.example.com 001 bob :Welcome")
+
+	assertNil(t, m)
+	assertNil(t, err)
+}
+
+func Test_Binding_Do_sendsTheMessagesEvenIfThereIsAnError(t *testing.T) {
+	s := newServer(t)
+	alice := s.connect("alice", 0)
+	failure := errors.New("failure")
+
+	err := alice.Do("bob", func(c *otr3.Conversation) ([]otr3.ValidMessage, error) {
+		return []otr3.ValidMessage{otr3.ValidMessage("?OTR Error: failure")}, failure
+	})
+
+	assertEquals(t, err, failure)
+	assertEquals(t, len(s.queue), 1)
+	assertEquals(t, s.queue[0], "bob :alice!alice@irc.example.com PRIVMSG bob :?OTR Error: failure\r\n")
+}
+
+func Test_Binding_usesTheSameConversationRegardlessOfNickCase(t *testing.T) {
+	s := newServer(t)
+	bob := s.connect("bob", 1)
+
+	assertEquals(t, bob.Conversation("Alice[away]"), bob.Conversation("alice{away}"))
+}
+
+func Test_Binding_refusesToSendLineBreaks(t *testing.T) {
+	s := newServer(t)
+	alice := s.connect("alice", 0)
+
+	assertEquals(t, alice.Send("bob", "hello\r\nQUIT"), ErrInvalidText)
+	assertEquals(t, len(s.queue), 0)
+}
@@ -0,0 +1,46 @@
+// Package irc carries OTR conversations over IRC private messages.
+//
+// A Binding keeps one Conversation for every nick the application chats with. The application gives it the lines
+// received from the server with Handle, and the Binding sends its PRIVMSG lines through a Client. OTR messages are
+// fragmented so that the line the server relays to the peer, with our nick!user@host prefix in front, stays
+// within the 512 bytes IRC allows.
+//
+// Only private messages are fed to the conversations. NOTICEs are never answered automatically, as RFC 1459
+// requires, so they are returned as they are, together with CTCP requests and channel messages. ACTIONs
+// (/me) in a private conversation are sent encrypted, as a message starting with "/me ".
+package irc
+
+import "errors"
+
+// Client sends lines to the IRC server
+type Client interface {
+	// SendLine sends a line, without the line ending. It must not call back into the Binding
+	SendLine(line string) error
+}
+
+// Message is a message received from a peer
+type Message struct {
+	// From is the nick of the sender, and Target the nick or channel the message was sent to
+	From   string
+	Target string
+	Text   string
+
+	// Encrypted is true if the message was received in a private conversation
+	Encrypted bool
+	// Action is true for a CTCP ACTION, sent with /me. Text is the action without the /me
+	Action bool
+	// Notice is true if the message was sent as a NOTICE. Notices are never decrypted
+	Notice bool
+	// CTCP is the command of a CTCP request other than ACTION, such as VERSION. Text has its parameters
+	CTCP string
+}
+
+// ErrInvalidText is returned when asked to send text that can't be sent on one IRC line
+var ErrInvalidText = errors.New("irc: text can't contain line breaks or NUL characters")
+
+const (
+	ctcpDelimiter = "\x01"
+	actionCommand = "ACTION"
+	// actionPrefix marks an ACTION sent in a private conversation
+	actionPrefix = "/me "
+)
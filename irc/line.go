@@ -0,0 +1,94 @@
+package irc
+
+import "strings"
+
+// line is a parsed IRC protocol line
+type line struct {
+	prefix  string
+	command string
+	params  []string
+}
+
+// parseLine parses a line received from the server. Message tags are skipped
+func parseLine(s string) line {
+	var l line
+	s = strings.TrimRight(s, "\r\n")
+
+	if strings.HasPrefix(s, "@") {
+		s = afterSpace(s)
+	}
+
+	if strings.HasPrefix(s, ":") {
+		l.prefix, s = beforeSpace(s[1:]), afterSpace(s)
+	}
+
+	l.command, s = strings.ToUpper(beforeSpace(s)), afterSpace(s)
+
+	for s != "" {
+		if strings.HasPrefix(s, ":") {
+			l.params = append(l.params, s[1:])
+			break
+		}
+		l.params = append(l.params, beforeSpace(s))
+		s = afterSpace(s)
+	}
+
+	return l
+}
+
+func beforeSpace(s string) string {
+	if i := strings.IndexByte(s, ' '); i != -1 {
+		return s[:i]
+	}
+	return s
+}
+
+func afterSpace(s string) string {
+	if i := strings.IndexByte(s, ' '); i != -1 {
+		return strings.TrimLeft(s[i+1:], " ")
+	}
+	return ""
+}
+
+// nick returns the nick from a nick!user@host prefix
+func nick(prefix string) string {
+	if i := strings.IndexAny(prefix, "!@"); i != -1 {
+		return prefix[:i]
+	}
+	return prefix
+}
+
+// isChannel returns true if the target is a channel rather than a nick
+func isChannel(target string) bool {
+	return target != "" && strings.ContainsRune("#&+!", rune(target[0]))
+}
+
+// foldNick returns the nick in lower case, using the RFC 1459 case mapping where []\~ are the upper case of {}|^
+func foldNick(n string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '[':
+			return '{'
+		case ']':
+			return '}'
+		case '\\':
+			return '|'
+		case '~':
+			return '^'
+		}
+		if r >= 'A' && r <= 'Z' {
+			return r + 'a' - 'A'
+		}
+		return r
+	}, n)
+}
+
+// ctcp splits a CTCP message into its command and parameters
+func ctcp(text string) (command, params string, ok bool) {
+	if len(text) < 2 || !strings.HasPrefix(text, ctcpDelimiter) {
+		return "", "", false
+	}
+
+	text = strings.TrimSuffix(text[1:], ctcpDelimiter)
+	return strings.ToUpper(beforeSpace(text)), afterSpace(text), true
+}
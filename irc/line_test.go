@@ -0,0 +1,40 @@
+package irc
+
+import "testing"
+
+func Test_parseLine_parsesAPrivmsg(t *testing.T) {
+	l := parseLine(":alice!a@host PRIVMSG bob :hello there\r\n")
+
+	assertDeepEquals(t, l, line{prefix: "alice!a@host", command: "PRIVMSG", params: []string{"bob", "hello there"}})
+}
+
+func Test_parseLine_skipsMessageTags(t *testing.T) {
+	l := parseLine("@time=2016-01-01T00:00:00Z :alice PRIVMSG bob :hi")
+
+	assertDeepEquals(t, l, line{prefix: "alice", command: "PRIVMSG", params: []string{"bob", "hi"}})
+}
+
+func Test_parseLine_parsesALineWithoutPrefixOrTrailingParameter(t *testing.T) {
+	l := parseLine("ping server")
+
+	assertDeepEquals(t, l, line{command: "PING", params: []string{"server"}})
+}
+
+func Test_nick_returnsTheNickOfAPrefix(t *testing.T) {
+	assertEquals(t, nick("alice!a@host"), "alice")
+	assertEquals(t, nick("irc.example.com"), "irc.example.com")
+}
+
+func Test_ctcp_splitsTheCommandAndParameters(t *testing.T) {
+	command, params, ok := ctcp("\x01ACTION waves hello\x01")
+
+	assertEquals(t, ok, true)
+	assertEquals(t, command, "ACTION")
+	assertEquals(t, params, "waves hello")
+}
+
+func Test_ctcp_isFalseForNormalText(t *testing.T) {
+	_, _, ok := ctcp("hello")
+
+	assertEquals(t, ok, false)
+}
@@ -0,0 +1,74 @@
+package otr3
+
+// KeyTrustHandler decides if the key of a peer is trusted, for the RequireTrustedKeys and HoldUntrustedKeys policies
+type KeyTrustHandler interface {
+	// IsKeyTrusted should return true if the user has verified the key with the fingerprint
+	IsKeyTrusted(key PublicKey, fingerprint []byte) bool
+}
+
+type dynamicKeyTrustHandler struct {
+	eh func(key PublicKey, fingerprint []byte) bool
+}
+
+func (d dynamicKeyTrustHandler) IsKeyTrusted(key PublicKey, fingerprint []byte) bool {
+	return d.eh(key, fingerprint)
+}
+
+// SetKeyTrustHandler sets the handler asked if the key of the peer is trusted when the AKE finishes. Without one,
+// no key is trusted when the RequireTrustedKeys or HoldUntrustedKeys policies are set
+func (c *Conversation) SetKeyTrustHandler(handler KeyTrustHandler) {
+	c.keyTrustHandler = handler
+}
+
+func (c *Conversation) isTheirKeyTrusted() bool {
+	if c.keyTrustHandler == nil || c.theirKey == nil {
+		return false
+	}
+	return c.keyTrustHandler.IsKeyTrusted(c.theirKey, c.theirKey.Fingerprint())
+}
+
+// checkTheirKey is called when the AKE has finished. It returns errUntrustedKey if the conversation mustn't become
+// encrypted, and otherwise decides if sending has to wait for the key to be approved
+func (c *Conversation) checkTheirKey() error {
+	c.theirKeyHeld = false
+
+	pinning := c.Policies.has(requireTrustedKeys) || c.Policies.has(holdUntrustedKeys)
+	if !pinning || c.isTheirKeyTrusted() {
+		return nil
+	}
+
+	if c.Policies.has(requireTrustedKeys) {
+		return errUntrustedKey
+	}
+
+	c.theirKeyHeld = true
+	return nil
+}
+
+// IsTheirKeyHeld returns true if the conversation is encrypted, but messages are held until ApproveTheirKey is called,
+// because the HoldUntrustedKeys policy is set and the key of the peer isn't trusted
+func (c *Conversation) IsTheirKeyHeld() bool {
+	return c.msgState == encrypted && c.theirKeyHeld
+}
+
+// ApproveTheirKey approves the key of the peer, held because of the HoldUntrustedKeys policy, and returns the messages sent while it was held
+func (c *Conversation) ApproveTheirKey() ([]ValidMessage, error) {
+	if !c.IsTheirKeyHeld() {
+		return nil, nil
+	}
+
+	c.theirKeyHeld = false
+	return c.FlushQueue()
+}
+
+// RejectTheirKey rejects the key of the peer, held because of the HoldUntrustedKeys policy. It discards the messages
+// sent while it was held, and ends the conversation
+func (c *Conversation) RejectTheirKey() ([]ValidMessage, error) {
+	if !c.IsTheirKeyHeld() {
+		return nil, nil
+	}
+
+	c.theirKeyHeld = false
+	c.DiscardQueue()
+	return c.End()
+}
@@ -0,0 +1,219 @@
+package otr3
+
+import (
+	"crypto/rand"
+	"errors"
+	"testing"
+)
+
+func pinningConversations(p policy) (alice, bob *Conversation) {
+	alice = &Conversation{Rand: rand.Reader}
+	alice.SetOurKeys([]PrivateKey{alicePrivateKey})
+	alice.Policies = policies(allowV3 | p)
+
+	bob = &Conversation{Rand: rand.Reader}
+	bob.SetOurKeys([]PrivateKey{bobPrivateKey})
+	bob.Policies = policies(allowV3)
+
+	return alice, bob
+}
+
+// runAKE lets alice start the AKE and passes the messages between the conversations until there are no more.
+// It returns the first error from alice
+func runAKE(alice, bob *Conversation) error {
+	toBob := []ValidMessage{alice.QueryMessage()}
+	var toAlice []ValidMessage
+	for len(toBob) > 0 || len(toAlice) > 0 {
+		var next []ValidMessage
+		for _, m := range toBob {
+			_, ts, _ := bob.Receive(m)
+			next = append(next, ts...)
+		}
+		toBob, toAlice = nil, next
+
+		for _, m := range toAlice {
+			_, ts, err := alice.Receive(m)
+			if err != nil {
+				return err
+			}
+			toBob = append(toBob, ts...)
+		}
+		toAlice = nil
+	}
+	return nil
+}
+
+func trusting(trusted bool, asked *[]byte) KeyTrustHandler {
+	return dynamicKeyTrustHandler{func(key PublicKey, fingerprint []byte) bool {
+		*asked = fingerprint
+		return trusted
+	}}
+}
+
+func Test_akeHasFinished_ignoresTrustWithoutAPinningPolicy(t *testing.T) {
+	alice, bob := pinningConversations(0)
+	var asked []byte
+	alice.SetKeyTrustHandler(trusting(false, &asked))
+
+	assertNil(t, runAKE(alice, bob))
+
+	assertEquals(t, alice.msgState, encrypted)
+	assertNil(t, asked)
+}
+
+func Test_RequireTrustedKeys_letsTheConversationBecomeEncryptedWithATrustedKey(t *testing.T) {
+	alice, bob := pinningConversations(requireTrustedKeys)
+	var asked []byte
+	alice.SetKeyTrustHandler(trusting(true, &asked))
+
+	assertNil(t, runAKE(alice, bob))
+
+	assertEquals(t, alice.msgState, encrypted)
+	assertDeepEquals(t, asked, bobPrivateKey.PublicKey().Fingerprint())
+}
+
+func Test_RequireTrustedKeys_abortsTheAKEWithAnUntrustedKey(t *testing.T) {
+	alice, bob := pinningConversations(requireTrustedKeys)
+	var asked []byte
+	alice.SetKeyTrustHandler(trusting(false, &asked))
+	var setupError error
+	alice.SetMessageEventHandler(dynamicMessageEventHandler{func(event MessageEvent, message []byte, err error, trace ...interface{}) {
+		if event == MessageEventSetupError {
+			setupError = err
+		}
+	}})
+
+	err := runAKE(alice, bob)
+
+	assertEquals(t, errors.Is(err, ErrUntrustedKey), true)
+	assertEquals(t, setupError, err)
+	assertEquals(t, alice.msgState, plainText)
+	assertDeepEquals(t, alice.GetTheirKey().Fingerprint(), bobPrivateKey.PublicKey().Fingerprint())
+}
+
+func Test_RequireTrustedKeys_trustsNoKeyWithoutAHandler(t *testing.T) {
+	alice, bob := pinningConversations(requireTrustedKeys)
+
+	err := runAKE(alice, bob)
+
+	assertEquals(t, err, errUntrustedKey)
+	assertEquals(t, alice.msgState, plainText)
+}
+
+func Test_RequireTrustedKeys_winsOverHoldUntrustedKeys(t *testing.T) {
+	alice, bob := pinningConversations(requireTrustedKeys | holdUntrustedKeys)
+
+	err := runAKE(alice, bob)
+
+	assertEquals(t, err, errUntrustedKey)
+}
+
+func Test_HoldUntrustedKeys_holdsMessagesUntilTheKeyIsApproved(t *testing.T) {
+	alice, bob := pinningConversations(holdUntrustedKeys)
+	var asked []byte
+	alice.SetKeyTrustHandler(trusting(false, &asked))
+	var held bool
+	alice.SetMessageEventHandler(dynamicMessageEventHandler{func(event MessageEvent, message []byte, err error, trace ...interface{}) {
+		held = held || event == MessageEventTheirKeyHeld
+	}})
+
+	assertNil(t, runAKE(alice, bob))
+	assertEquals(t, alice.msgState, encrypted)
+	assertEquals(t, alice.IsTheirKeyHeld(), true)
+	assertEquals(t, held, true)
+
+	toSend, err := alice.Send(ValidMessage("hello"))
+	assertNil(t, err)
+	assertNil(t, toSend)
+	assertEquals(t, len(alice.QueuedMessages()), 1)
+
+	toSend, err = alice.ApproveTheirKey()
+	assertNil(t, err)
+	assertEquals(t, alice.IsTheirKeyHeld(), false)
+	assertEquals(t, len(toSend), 1)
+
+	plain, _, err := bob.Receive(toSend[0])
+	assertNil(t, err)
+	assertDeepEquals(t, plain, MessagePlaintext("hello"))
+}
+
+func Test_HoldUntrustedKeys_doesntHoldTrustedKeys(t *testing.T) {
+	alice, bob := pinningConversations(holdUntrustedKeys)
+	var asked []byte
+	alice.SetKeyTrustHandler(trusting(true, &asked))
+
+	assertNil(t, runAKE(alice, bob))
+
+	assertEquals(t, alice.IsTheirKeyHeld(), false)
+	toSend, _ := alice.Send(ValidMessage("hello"))
+	assertEquals(t, len(toSend), 1)
+}
+
+func Test_RejectTheirKey_discardsTheHeldMessagesAndEndsTheConversation(t *testing.T) {
+	alice, bob := pinningConversations(holdUntrustedKeys)
+	assertNil(t, runAKE(alice, bob))
+	alice.Send(ValidMessage("hello"))
+
+	toSend, err := alice.RejectTheirKey()
+
+	assertNil(t, err)
+	assertEquals(t, len(toSend), 1)
+	assertEquals(t, alice.msgState, plainText)
+	assertNil(t, alice.QueuedMessages())
+	assertEquals(t, alice.IsTheirKeyHeld(), false)
+}
+
+func Test_ApproveTheirKey_doesNothingIfNoKeyIsHeld(t *testing.T) {
+	c := bobContextAfterAKE()
+	c.msgState = encrypted
+
+	toSend, err := c.ApproveTheirKey()
+
+	assertNil(t, toSend)
+	assertNil(t, err)
+}
+
+func heldConversations(t *testing.T) (alice, bob *Conversation) {
+	alice, bob = pinningConversations(holdUntrustedKeys)
+	assertNil(t, runAKE(alice, bob))
+	assertEquals(t, alice.IsTheirKeyHeld(), true)
+	return alice, bob
+}
+
+func Test_HoldUntrustedKeys_holdsTLVsUntilTheKeyIsApproved(t *testing.T) {
+	alice, bob := heldConversations(t)
+	var received []TLV
+	bob.RegisterTLVHandler(0x100, dynamicTLVHandler{func(t TLV) (*TLV, error) {
+		received = append(received, t)
+		return nil, nil
+	}})
+
+	toSend, err := alice.SendTLVs(ValidMessage("hello"), TLV{Type: 0x100, Value: []byte("data")})
+	assertNil(t, err)
+	assertNil(t, toSend)
+	assertEquals(t, len(alice.QueuedMessages()), 1)
+
+	toSend, _ = alice.ApproveTheirKey()
+	assertEquals(t, len(toSend), 1)
+
+	plain, _, err := bob.Receive(toSend[0])
+	assertNil(t, err)
+	assertDeepEquals(t, plain, MessagePlaintext("hello"))
+	assertDeepEquals(t, received, []TLV{{Type: 0x100, Value: []byte("data")}})
+}
+
+func Test_HoldUntrustedKeys_refusesToUseTheExtraSymmetricKey(t *testing.T) {
+	alice, _ := heldConversations(t)
+
+	key, toSend, err := alice.UseExtraSymmetricKey(1, nil)
+
+	assertNil(t, key)
+	assertNil(t, toSend)
+	assertEquals(t, err, errTheirKeyHeld)
+
+	alice.ApproveTheirKey()
+	key, toSend, err = alice.UseExtraSymmetricKey(1, nil)
+	assertNil(t, err)
+	assertEquals(t, len(key), 32)
+	assertEquals(t, len(toSend), 1)
+}
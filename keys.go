@@ -20,6 +20,7 @@ import (
 type PublicKey interface {
 	Parse([]byte) ([]byte, bool)
 	Fingerprint() []byte
+	MatchesFingerprint([]byte) bool
 	Verify([]byte, []byte) ([]byte, bool)
 
 	serialize() []byte
@@ -79,52 +80,113 @@ type Account struct {
 	Key      PrivateKey
 }
 
-func readSymbolAndExpect(r *bufio.Reader, s string) bool {
-	res, ok := readPotentialSymbol(r)
-	return ok && res == s
+func importError(d *sexp.Decoder, format string, a ...interface{}) error {
+	return importErrorAt(d.TokenOffset(), format, a...)
 }
 
-func readPotentialBigNum(r *bufio.Reader) (*big.Int, bool) {
-	res, _ := sexp.ReadValue(r)
-	if res != nil {
-		if tres, ok := res.(sexp.BigNum); ok {
-			return tres.Value().(*big.Int), true
-		}
+func importErrorAt(offset int64, format string, a ...interface{}) error {
+	return newOtrErrorf("couldn't import data into private key: %s at offset %d", fmt.Sprintf(format, a...), offset)
+}
+
+func wrapImportError(d *sexp.Decoder, err error) error {
+	if err == io.EOF {
+		return importErrorAt(d.InputOffset(), "unexpected end of input")
 	}
-	return nil, false
+	return OtrError{msg: fmt.Sprintf("couldn't import data into private key: %v", err)}.causedBy(err)
 }
 
-func readPotentialSymbol(r *bufio.Reader) (string, bool) {
-	res, _ := sexp.ReadValue(r)
-	if res != nil {
-		if tres, ok := res.(sexp.Symbol); ok {
-			return tres.Value().(string), true
-		}
+func readToken(d *sexp.Decoder) (sexp.Token, error) {
+	t, err := d.Token()
+	if err != nil {
+		return nil, wrapImportError(d, err)
 	}
-	return "", false
+	return t, nil
 }
 
-func readPotentialString(r *bufio.Reader) (string, bool) {
-	res, _ := sexp.ReadValue(r)
-	if res != nil {
-		if tres, ok := res.(sexp.Sstring); ok {
-			return tres.Value().(string), true
-		}
+func readListStart(d *sexp.Decoder) error {
+	t, err := readToken(d)
+	if err != nil {
+		return err
+	}
+	if _, ok := t.(sexp.ListStart); !ok {
+		return importError(d, "expected list start")
 	}
-	return "", false
+	return nil
 }
 
-func readPotentialStringOrSymbol(r *bufio.Reader) (string, bool) {
-	res, _ := sexp.ReadValue(r)
-	if res != nil {
-		if tres, ok := res.(sexp.Sstring); ok {
-			return tres.Value().(string), true
-		}
-		if tres, ok := res.(sexp.Symbol); ok {
-			return tres.Value().(string), true
-		}
+func readListEnd(d *sexp.Decoder) error {
+	t, err := readToken(d)
+	if err != nil {
+		return err
+	}
+	if _, ok := t.(sexp.ListEnd); !ok {
+		return importError(d, "expected list end")
+	}
+	return nil
+}
+
+// atListEnd consumes the next token if it is a list end and reports whether it was
+func atListEnd(d *sexp.Decoder) (bool, error) {
+	t, err := d.PeekToken()
+	if err != nil {
+		return false, wrapImportError(d, err)
+	}
+	if _, ok := t.(sexp.ListEnd); ok {
+		d.Token()
+		return true, nil
+	}
+	return false, nil
+}
+
+func readSymbol(d *sexp.Decoder) (string, error) {
+	t, err := readToken(d)
+	if err != nil {
+		return "", err
+	}
+	if s, ok := t.(sexp.Symbol); ok {
+		return string(s), nil
+	}
+	return "", importError(d, "expected symbol")
+}
+
+func readStringOrSymbol(d *sexp.Decoder) (string, error) {
+	t, err := readToken(d)
+	if err != nil {
+		return "", err
+	}
+	switch s := t.(type) {
+	case sexp.Sstring:
+		return string(s), nil
+	case sexp.Symbol:
+		return string(s), nil
+	}
+	return "", importError(d, "expected string or symbol")
+}
+
+func readBigNum(d *sexp.Decoder) (*big.Int, error) {
+	t, err := readToken(d)
+	if err != nil {
+		return nil, err
+	}
+	if b, ok := t.(sexp.BigNum); ok {
+		return b.Value().(*big.Int), nil
+	}
+	return nil, importError(d, "expected bignum")
+}
+
+// readTaggedListStart reads the start of a list whose first element is the given symbol
+func readTaggedListStart(d *sexp.Decoder, tag string) error {
+	if err := readListStart(d); err != nil {
+		return err
+	}
+	s, err := readSymbol(d)
+	if err != nil {
+		return err
+	}
+	if s != tag {
+		return importError(d, "expected %s but found %s", tag, s)
 	}
-	return "", false
+	return nil
 }
 
 // ImportKeysFromFile will read the libotr formatted file given and return all accounts defined in it
@@ -144,17 +206,19 @@ func ExportKeysToFile(acs []*Account, fname string) error {
 		return err
 	}
 	defer f.Close()
-	exportAccounts(acs, f)
-	return nil
+	return ExportKeys(acs, f)
+}
+
+// ExportKeys will write all the accounts to the given writer in libotr format.
+func ExportKeys(acs []*Account, w io.Writer) error {
+	return exportAccounts(acs, w)
 }
 
-// ImportKeys will read the libotr formatted data given and return all accounts defined in it
+// ImportKeys will read the libotr formatted data given and return all accounts defined in it.
+// If the data is not valid, the error returned describes the problem and where it was found. Syntax errors can be
+// retrieved with errors.As as a *sexp.SyntaxError
 func ImportKeys(r io.Reader) ([]*Account, error) {
-	res, ok := readAccounts(bufio.NewReader(r))
-	if !ok {
-		return nil, newOtrError("couldn't import data into private key")
-	}
-	return res, nil
+	return readAccounts(sexp.NewDecoder(r))
 }
 
 func assignParameter(k *dsa.PrivateKey, s string, v *big.Int) bool {
@@ -175,98 +239,115 @@ func assignParameter(k *dsa.PrivateKey, s string, v *big.Int) bool {
 	return true
 }
 
-func readAccounts(r *bufio.Reader) ([]*Account, bool) {
-	sexp.ReadListStart(r)
-	ok1 := readSymbolAndExpect(r, "privkeys")
-	ok2 := true
+func readAccounts(d *sexp.Decoder) ([]*Account, error) {
+	if err := readTaggedListStart(d, "privkeys"); err != nil {
+		return nil, err
+	}
 	var as []*Account
 	for {
-		a, ok, atEnd := readAccount(r)
-		ok2 = ok2 && ok
-		if atEnd {
-			break
+		end, err := atListEnd(d)
+		if err != nil {
+			return nil, err
+		}
+		if end {
+			return as, nil
+		}
+		a, err := readAccount(d)
+		if err != nil {
+			return nil, err
 		}
 		as = append(as, a)
 	}
-	ok3 := sexp.ReadListEnd(r)
-	return as, ok1 && ok2 && ok3
 }
 
-func readAccountName(r *bufio.Reader) (string, bool) {
-	sexp.ReadListStart(r)
-	ok1 := readSymbolAndExpect(r, "name")
-	nm, ok2 := readPotentialStringOrSymbol(r)
-	ok3 := sexp.ReadListEnd(r)
-	return nm, ok1 && ok2 && ok3
+func readAccountName(d *sexp.Decoder) (string, error) {
+	if err := readTaggedListStart(d, "name"); err != nil {
+		return "", err
+	}
+	nm, err := readStringOrSymbol(d)
+	if err != nil {
+		return "", err
+	}
+	return nm, readListEnd(d)
 }
 
-func readAccountProtocol(r *bufio.Reader) (string, bool) {
-	sexp.ReadListStart(r)
-	ok1 := readSymbolAndExpect(r, "protocol")
-	nm, ok2 := readPotentialSymbol(r)
-	ok3 := sexp.ReadListEnd(r)
-	return nm, ok1 && ok2 && ok3
+func readAccountProtocol(d *sexp.Decoder) (string, error) {
+	if err := readTaggedListStart(d, "protocol"); err != nil {
+		return "", err
+	}
+	nm, err := readSymbol(d)
+	if err != nil {
+		return "", err
+	}
+	return nm, readListEnd(d)
 }
 
-func readAccount(r *bufio.Reader) (a *Account, ok bool, atEnd bool) {
-	if !sexp.ReadListStart(r) {
-		return nil, true, true
+func readAccount(d *sexp.Decoder) (a *Account, err error) {
+	if err = readTaggedListStart(d, "account"); err != nil {
+		return nil, err
 	}
-	ok1 := readSymbolAndExpect(r, "account")
 	a = new(Account)
-	var ok2, ok3, ok4 bool
-	a.Name, ok2 = readAccountName(r)
-	a.Protocol, ok3 = readAccountProtocol(r)
-	a.Key, ok4 = readPrivateKey(r)
-	ok5 := sexp.ReadListEnd(r)
-	return a, ok1 && ok2 && ok3 && ok4 && ok5, false
+	if a.Name, err = readAccountName(d); err != nil {
+		return nil, err
+	}
+	if a.Protocol, err = readAccountProtocol(d); err != nil {
+		return nil, err
+	}
+	if a.Key, err = readPrivateKey(d); err != nil {
+		return nil, err
+	}
+	return a, readListEnd(d)
 }
 
-func readPrivateKey(r *bufio.Reader) (PrivateKey, bool) {
-	sexp.ReadListStart(r)
-	ok1 := readSymbolAndExpect(r, "private-key")
-	k := new(DSAPrivateKey)
-	res, ok2 := readDSAPrivateKey(r)
-	if ok2 {
-		k.PrivateKey = *res
-		k.DSAPublicKey.PublicKey = k.PrivateKey.PublicKey
+func readPrivateKey(d *sexp.Decoder) (PrivateKey, error) {
+	if err := readTaggedListStart(d, "private-key"); err != nil {
+		return nil, err
+	}
+	res, err := readDSAPrivateKey(d)
+	if err != nil {
+		return nil, err
 	}
-	ok3 := sexp.ReadListEnd(r)
-	return k, ok1 && ok2 && ok3
+	k := new(DSAPrivateKey)
+	k.PrivateKey = *res
+	k.DSAPublicKey.PublicKey = k.PrivateKey.PublicKey
+	return k, readListEnd(d)
 }
 
-func readDSAPrivateKey(r *bufio.Reader) (*dsa.PrivateKey, bool) {
-	sexp.ReadListStart(r)
-	ok1 := readSymbolAndExpect(r, "dsa")
+func readDSAPrivateKey(d *sexp.Decoder) (*dsa.PrivateKey, error) {
+	if err := readTaggedListStart(d, "dsa"); err != nil {
+		return nil, err
+	}
 	k := new(dsa.PrivateKey)
 	for {
-		tag, value, end, ok := readParameter(r)
-		if !ok {
-			return nil, false
+		end, err := atListEnd(d)
+		if err != nil {
+			return nil, err
 		}
 		if end {
-			break
+			return k, nil
+		}
+		at := d.TokenOffset()
+		tag, value, err := readParameter(d)
+		if err != nil {
+			return nil, err
 		}
 		if !assignParameter(k, tag, value) {
-			return nil, false
+			return nil, importErrorAt(at, "unknown DSA parameter %s", tag)
 		}
 	}
-	ok2 := sexp.ReadListEnd(r)
-	return k, ok1 && ok2
 }
 
-func readParameter(r *bufio.Reader) (tag string, value *big.Int, end bool, ok bool) {
-	if !sexp.ReadListStart(r) {
-		return "", nil, true, true
+func readParameter(d *sexp.Decoder) (tag string, value *big.Int, err error) {
+	if err = readListStart(d); err != nil {
+		return "", nil, err
 	}
-	tag, ok1 := readPotentialSymbol(r)
-	value, ok2 := readPotentialBigNum(r)
-	ok = ok1 && ok2
-	end = false
-	if !sexp.ReadListEnd(r) {
-		return "", nil, true, true
+	if tag, err = readSymbol(d); err != nil {
+		return "", nil, err
 	}
-	return
+	if value, err = readBigNum(d); err != nil {
+		return "", nil, err
+	}
+	return tag, value, readListEnd(d)
 }
 
 // IsAvailableForVersion returns true if this key is possible to use with the given version
@@ -509,9 +590,9 @@ func notHex(r rune) bool {
 func exportName(n string, w *bufio.Writer) {
 	indent := "    "
 	w.WriteString(indent)
-	w.WriteString("(name \"")
-	w.WriteString(n)
-	w.WriteString("\")\n")
+	w.WriteString("(name ")
+	w.WriteString(sexp.Sstring(n).String())
+	w.WriteString(")\n")
 }
 
 func exportProtocol(n string, w *bufio.Writer) {
@@ -561,12 +642,12 @@ func exportAccount(a *Account, w *bufio.Writer) {
 	w.WriteString(")\n")
 }
 
-func exportAccounts(as []*Account, w io.Writer) {
+func exportAccounts(as []*Account, w io.Writer) error {
 	bw := bufio.NewWriter(w)
 	bw.WriteString("(privkeys\n")
 	for _, a := range as {
 		exportAccount(a, bw)
 	}
 	bw.WriteString(")\n")
-	bw.Flush()
+	return bw.Flush()
 }
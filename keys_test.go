@@ -1,12 +1,14 @@
 package otr3
 
 import (
-	"bufio"
 	"bytes"
 	"crypto/rand"
+	"errors"
 	"os"
 	"syscall"
 	"testing"
+
+	"github.com/twstrike/otr3/sexp"
 )
 
 var (
@@ -65,36 +67,36 @@ var (
 	}
 )
 
-func inp(s string) *bufio.Reader {
-	return bufio.NewReader(bytes.NewBuffer([]byte(s)))
+func inp(s string) *sexp.Decoder {
+	return sexp.NewDecoder(bytes.NewBuffer([]byte(s)))
 }
 
 func Test_readParameter_willReturnTheParameterRead(t *testing.T) {
-	tag, value, _, _ := readParameter(inp(`(p #00FC07ABCF0DC916AFF6E9A0D450A9B7A857#)`))
+	tag, value, _ := readParameter(inp(`(p #00FC07ABCF0DC916AFF6E9A0D450A9B7A857#)`))
 	assertDeepEquals(t, tag, "p")
 	assertDeepEquals(t, value, bnFromHex("00FC07ABCF0DC916AFF6E9A0D450A9B7A857"))
 }
 
 func Test_readParameter_willReturnAnotherParameterRead(t *testing.T) {
-	tag, value, _, _ := readParameter(inp(`(quux #00FC07ABCF0DC916AFF6E9A0D450A9B7A858#)`))
+	tag, value, _ := readParameter(inp(`(quux #00FC07ABCF0DC916AFF6E9A0D450A9B7A858#)`))
 	assertDeepEquals(t, tag, "quux")
 	assertDeepEquals(t, value, bnFromHex("00FC07ABCF0DC916AFF6E9A0D450A9B7A858"))
 }
 
 func Test_readParameter_willReturnNotOKIfAskedToParseATooShortList(t *testing.T) {
-	_, _, _, ok := readParameter(inp(`()`))
-	assertDeepEquals(t, ok, false)
+	_, _, err := readParameter(inp(`()`))
+	assertNotNil(t, err)
 
-	_, _, _, ok = readParameter(inp(`(quux)`))
-	assertDeepEquals(t, ok, false)
+	_, _, err = readParameter(inp(`(quux)`))
+	assertNotNil(t, err)
 }
 
 func Test_readParameter_willReturnNotOKIfAskedToParseSomethingOfTheWrongType(t *testing.T) {
-	_, _, _, ok := readParameter(inp(`("quux" #00FC07ABCF0DC916AFF6E9A0D450A9B7A858#)`))
-	assertDeepEquals(t, ok, false)
+	_, _, err := readParameter(inp(`("quux" #00FC07ABCF0DC916AFF6E9A0D450A9B7A858#)`))
+	assertNotNil(t, err)
 
-	_, _, _, ok = readParameter(inp(`(quux "00FC07ABCF0DC916AFF6E9A0D450A9B7A858")`))
-	assertDeepEquals(t, ok, false)
+	_, _, err = readParameter(inp(`(quux "00FC07ABCF0DC916AFF6E9A0D450A9B7A858")`))
+	assertNotNil(t, err)
 }
 
 func Test_readDSAPrivateKey_willReturnADSAPrivateKey(t *testing.T) {
@@ -105,25 +107,25 @@ func Test_readDSAPrivateKey_willReturnADSAPrivateKey(t *testing.T) {
   (y #0AC8670AD767D7A8D9D14CC1AC6744CD7D76F993B77FFD9E39DF01E5A6536EF65E775FCEF2A983E2A19BD6415500F6979715D9FD1257E1FE2B6F5E1E74B333079E7C880D39868462A93454B41877BE62E5EF0A041C2EE9C9E76BD1E12AE25D9628DECB097025DD625EF49C3258A1A3C0FF501E3DC673B76D7BABF349009B6ECF#)
   (x #14D0345A3562C480A039E3C72764F72D79043216#)
   )`)
-	k, ok := readDSAPrivateKey(from)
+	k, err := readDSAPrivateKey(from)
 	assertDeepEquals(t, k.P, bnFromHex("00FC07ABCF0DC916AFF6E9AE47BEF60C7AB9B4D6B2469E436630E36F8A489BE812486A09F30B71224508654940A835301ACC525A4FF133FC152CC53DCC59D65C30A54F1993FE13FE63E5823D4C746DB21B90F9B9C00B49EC7404AB1D929BA7FBA12F2E45C6E0A651689750E8528AB8C031D3561FECEE72EBB4A090D450A9B7A857"))
 	assertDeepEquals(t, k.Q, bnFromHex("00997BD266EF7B1F60A5C23F3A741F2AEFD07A2081"))
 	assertDeepEquals(t, k.G, bnFromHex("535E360E8A95EBA46A4F7DE50AD6E9B2A6DB785A66B64EB9F20338D2A3E8FB0E94725848F1AA6CC567CB83A1CC517EC806F2E92EAE71457E80B2210A189B91250779434B41FC8A8873F6DB94BEA7D177F5D59E7E114EE10A49CFD9CEF88AE43387023B672927BA74B04EB6BBB5E57597766A2F9CE3857D7ACE3E1E3BC1FC6F26"))
 	assertDeepEquals(t, k.X, bnFromHex("14D0345A3562C480A039E3C72764F72D79043216"))
 	assertDeepEquals(t, k.Y, bnFromHex("0AC8670AD767D7A8D9D14CC1AC6744CD7D76F993B77FFD9E39DF01E5A6536EF65E775FCEF2A983E2A19BD6415500F6979715D9FD1257E1FE2B6F5E1E74B333079E7C880D39868462A93454B41877BE62E5EF0A041C2EE9C9E76BD1E12AE25D9628DECB097025DD625EF49C3258A1A3C0FF501E3DC673B76D7BABF349009B6ECF"))
-	assertDeepEquals(t, ok, true)
+	assertNil(t, err)
 }
 
 func Test_readDSAPrivateKey_willReturnNotOKForNoList(t *testing.T) {
 	from := inp(`dsa`)
-	_, ok := readDSAPrivateKey(from)
-	assertDeepEquals(t, ok, false)
+	_, err := readDSAPrivateKey(from)
+	assertNotNil(t, err)
 }
 
 func Test_readDSAPrivateKey_willReturnNotOKForListWithNoEntries(t *testing.T) {
 	from := inp(`()`)
-	_, ok := readDSAPrivateKey(from)
-	assertDeepEquals(t, ok, false)
+	_, err := readDSAPrivateKey(from)
+	assertNotNil(t, err)
 }
 
 func Test_readDSAPrivateKey_willReturnNotOKForListWithNoEnding(t *testing.T) {
@@ -134,8 +136,8 @@ func Test_readDSAPrivateKey_willReturnNotOKForListWithNoEnding(t *testing.T) {
   (y #0AC8670AD767D7A8D9D14CC1AC6744CD7D76F993B77FFD9E39DF01E5A6536EF65E775FCEF2A983E2A19BD6415500F6979715D9FD1257E1FE2B6F5E1E74B333079E7C880D39868462A93454B41877BE62E5EF0A041C2EE9C9E76BD1E12AE25D9628DECB097025DD625EF49C3258A1A3C0FF501E3DC673B76D7BABF349009B6ECF#)
   (x #14D0345A3562C480A039E3C72764F72D79043216#)
   `)
-	_, ok := readDSAPrivateKey(from)
-	assertDeepEquals(t, ok, false)
+	_, err := readDSAPrivateKey(from)
+	assertNotNil(t, err)
 }
 
 func Test_readDSAPrivateKey_willReturnNotOKForListWithTheWrongTag(t *testing.T) {
@@ -146,8 +148,8 @@ func Test_readDSAPrivateKey_willReturnNotOKForListWithTheWrongTag(t *testing.T)
   (y #0AC8670AD767D7A8D9D14CC1AC6744CD7D76F993B77FFD9E39DF01E5A6536EF65E775FCEF2A983E2A19BD6415500F6979715D9FD1257E1FE2B6F5E1E74B333079E7C880D39868462A93454B41877BE62E5EF0A041C2EE9C9E76BD1E12AE25D9628DECB097025DD625EF49C3258A1A3C0FF501E3DC673B76D7BABF349009B6ECF#)
   (x #14D0345A3562C480A039E3C72764F72D79043216#)
   `)
-	_, ok := readDSAPrivateKey(from)
-	assertDeepEquals(t, ok, false)
+	_, err := readDSAPrivateKey(from)
+	assertNotNil(t, err)
 }
 
 func Test_readDSAPrivateKey_willReturnNotOKForListWithInvalidTypeOfTag(t *testing.T) {
@@ -158,8 +160,8 @@ func Test_readDSAPrivateKey_willReturnNotOKForListWithInvalidTypeOfTag(t *testin
   (y #0AC8670AD767D7A8D9D14CC1AC6744CD7D76F993B77FFD9E39DF01E5A6536EF65E775FCEF2A983E2A19BD6415500F6979715D9FD1257E1FE2B6F5E1E74B333079E7C880D39868462A93454B41877BE62E5EF0A041C2EE9C9E76BD1E12AE25D9628DECB097025DD625EF49C3258A1A3C0FF501E3DC673B76D7BABF349009B6ECF#)
   (x #14D0345A3562C480A039E3C72764F72D79043216#)
   `)
-	_, ok := readDSAPrivateKey(from)
-	assertDeepEquals(t, ok, false)
+	_, err := readDSAPrivateKey(from)
+	assertNotNil(t, err)
 }
 
 func Test_readDSAPrivateKey_willReturnNotOKWhenPParameterIsInvalid(t *testing.T) {
@@ -170,8 +172,8 @@ func Test_readDSAPrivateKey_willReturnNotOKWhenPParameterIsInvalid(t *testing.T)
   (y #0AC8670AD767D7A8D9D14CC1AC6744CD7D76F993B77FFD9E39DF01E5A6536EF65E775FCEF2A983E2A19BD6415500F6979715D9FD1257E1FE2B6F5E1E74B333079E7C880D39868462A93454B41877BE62E5EF0A041C2EE9C9E76BD1E12AE25D9628DECB097025DD625EF49C3258A1A3C0FF501E3DC673B76D7BABF349009B6ECF#)
   (x #14D0345A3562C480A039E3C72764F72D79043216#))
   `)
-	_, ok := readDSAPrivateKey(from)
-	assertDeepEquals(t, ok, false)
+	_, err := readDSAPrivateKey(from)
+	assertNotNil(t, err)
 }
 
 func Test_readDSAPrivateKey_willReturnNotOKWhenQParameterIsInvalid(t *testing.T) {
@@ -182,8 +184,8 @@ func Test_readDSAPrivateKey_willReturnNotOKWhenQParameterIsInvalid(t *testing.T)
   (y #0AC8670AD767D7A8D9D14CC1AC6744CD7D76F993B77FFD9E39DF01E5A6536EF65E775FCEF2A983E2A19BD6415500F6979715D9FD1257E1FE2B6F5E1E74B333079E7C880D39868462A93454B41877BE62E5EF0A041C2EE9C9E76BD1E12AE25D9628DECB097025DD625EF49C3258A1A3C0FF501E3DC673B76D7BABF349009B6ECF#)
   (x #14D0345A3562C480A039E3C72764F72D79043216#))
   `)
-	_, ok := readDSAPrivateKey(from)
-	assertDeepEquals(t, ok, false)
+	_, err := readDSAPrivateKey(from)
+	assertNotNil(t, err)
 }
 
 func Test_readDSAPrivateKey_willReturnNotOKWhenGParameterIsInvalid(t *testing.T) {
@@ -194,8 +196,8 @@ func Test_readDSAPrivateKey_willReturnNotOKWhenGParameterIsInvalid(t *testing.T)
   (y #0AC8670AD767D7A8D9D14CC1AC6744CD7D76F993B77FFD9E39DF01E5A6536EF65E775FCEF2A983E2A19BD6415500F6979715D9FD1257E1FE2B6F5E1E74B333079E7C880D39868462A93454B41877BE62E5EF0A041C2EE9C9E76BD1E12AE25D9628DECB097025DD625EF49C3258A1A3C0FF501E3DC673B76D7BABF349009B6ECF#)
   (x #14D0345A3562C480A039E3C72764F72D79043216#))
   `)
-	_, ok := readDSAPrivateKey(from)
-	assertDeepEquals(t, ok, false)
+	_, err := readDSAPrivateKey(from)
+	assertNotNil(t, err)
 }
 
 func Test_readDSAPrivateKey_willReturnNotOKWhenYParameterIsInvalid(t *testing.T) {
@@ -206,8 +208,8 @@ func Test_readDSAPrivateKey_willReturnNotOKWhenYParameterIsInvalid(t *testing.T)
   (yx #0AC8670AD767D7A8D9D14CC1AC6744CD7D76F993B77FFD9E39DF01E5A6536EF65E775FCEF2A983E2A19BD6415500F6979715D9FD1257E1FE2B6F5E1E74B333079E7C880D39868462A93454B41877BE62E5EF0A041C2EE9C9E76BD1E12AE25D9628DECB097025DD625EF49C3258A1A3C0FF501E3DC673B76D7BABF349009B6ECF#)
   (x #14D0345A3562C480A039E3C72764F72D79043216#))
   `)
-	_, ok := readDSAPrivateKey(from)
-	assertDeepEquals(t, ok, false)
+	_, err := readDSAPrivateKey(from)
+	assertNotNil(t, err)
 }
 
 func Test_readDSAPrivateKey_willReturnNotOKWhenXParameterIsInvalid(t *testing.T) {
@@ -218,8 +220,8 @@ func Test_readDSAPrivateKey_willReturnNotOKWhenXParameterIsInvalid(t *testing.T)
   (y #0AC8670AD767D7A8D9D14CC1AC6744CD7D76F993B77FFD9E39DF01E5A6536EF65E775FCEF2A983E2A19BD6415500F6979715D9FD1257E1FE2B6F5E1E74B333079E7C880D39868462A93454B41877BE62E5EF0A041C2EE9C9E76BD1E12AE25D9628DECB097025DD625EF49C3258A1A3C0FF501E3DC673B76D7BABF349009B6ECF#)
   (xx #14D0345A3562C480A039E3C72764F72D79043216#))
   `)
-	_, ok := readDSAPrivateKey(from)
-	assertDeepEquals(t, ok, false)
+	_, err := readDSAPrivateKey(from)
+	assertNotNil(t, err)
 }
 
 func Test_readPrivateKey_willReturnAPrivateKey(t *testing.T) {
@@ -230,19 +232,19 @@ func Test_readPrivateKey_willReturnAPrivateKey(t *testing.T) {
   (y #0AC8670AD767D7A8D9D14CC1AC6744CD7D76F993B77FFD9E39DF01E5A6536EF65E775FCEF2A983E2A19BD6415500F6979715D9FD1257E1FE2B6F5E1E74B333079E7C880D39868462A93454B41877BE62E5EF0A041C2EE9C9E76BD1E12AE25D9628DECB097025DD625EF49C3258A1A3C0FF501E3DC673B76D7BABF349009B6ECF#)
   (x #14D0345A3562C480A039E3C72764F72D79043217#)
   ))`)
-	k, ok := readPrivateKey(from)
+	k, err := readPrivateKey(from)
 	assertDeepEquals(t, k.(*DSAPrivateKey).PrivateKey.P, bnFromHex("00FC07ABCF0DC916AFF6E9AE47BEF60C7AB9B4D6B2469E436630E36F8A489BE812486A09F30B71224508654940A835301ACC525A4FF133FC152CC53DCC59D65C30A54F1993FE13FE63E5823D4C746DB21B90F9B9C00B49EC7404AB1D929BA7FBA12F2E45C6E0A651689750E8528AB8C031D3561FECEE72EBB4A090D450A9B7A857"))
 	assertDeepEquals(t, k.(*DSAPrivateKey).PrivateKey.Q, bnFromHex("00997BD266EF7B1F60A5C23F3A741F2AEFD07A2081"))
 	assertDeepEquals(t, k.(*DSAPrivateKey).PrivateKey.G, bnFromHex("535E360E8A95EBA46A4F7DE50AD6E9B2A6DB785A66B64EB9F20338D2A3E8FB0E94725848F1AA6CC567CB83A1CC517EC806F2E92EAE71457E80B2210A189B91250779434B41FC8A8873F6DB94BEA7D177F5D59E7E114EE10A49CFD9CEF88AE43387023B672927BA74B04EB6BBB5E57597766A2F9CE3857D7ACE3E1E3BC1FC6F26"))
 	assertDeepEquals(t, k.(*DSAPrivateKey).PrivateKey.X, bnFromHex("14D0345A3562C480A039E3C72764F72D79043217"))
 	assertDeepEquals(t, k.(*DSAPrivateKey).PrivateKey.Y, bnFromHex("0AC8670AD767D7A8D9D14CC1AC6744CD7D76F993B77FFD9E39DF01E5A6536EF65E775FCEF2A983E2A19BD6415500F6979715D9FD1257E1FE2B6F5E1E74B333079E7C880D39868462A93454B41877BE62E5EF0A041C2EE9C9E76BD1E12AE25D9628DECB097025DD625EF49C3258A1A3C0FF501E3DC673B76D7BABF349009B6ECF"))
-	assertDeepEquals(t, ok, true)
+	assertNil(t, err)
 }
 
 func Test_readPrivateKey_willReturnNotOKForSomethingNotAList(t *testing.T) {
 	from := inp(`one`)
-	_, ok := readPrivateKey(from)
-	assertDeepEquals(t, ok, false)
+	_, err := readPrivateKey(from)
+	assertNotNil(t, err)
 }
 
 func Test_readPrivateKey_willReturnNotOKForAListThatIsNotEnded(t *testing.T) {
@@ -253,8 +255,8 @@ func Test_readPrivateKey_willReturnNotOKForAListThatIsNotEnded(t *testing.T) {
   (y #0AC8670AD767D7A8D9D14CC1AC6744CD7D76F993B77FFD9E39DF01E5A6536EF65E775FCEF2A983E2A19BD6415500F6979715D9FD1257E1FE2B6F5E1E74B333079E7C880D39868462A93454B41877BE62E5EF0A041C2EE9C9E76BD1E12AE25D9628DECB097025DD625EF49C3258A1A3C0FF501E3DC673B76D7BABF349009B6ECF#)
   (x #14D0345A3562C480A039E3C72764F72D79043217#)
   )`)
-	_, ok := readPrivateKey(from)
-	assertDeepEquals(t, ok, false)
+	_, err := readPrivateKey(from)
+	assertNotNil(t, err)
 }
 
 func Test_readPrivateKey_willReturnNotOKForAnInvalidDSAKey(t *testing.T) {
@@ -265,8 +267,8 @@ func Test_readPrivateKey_willReturnNotOKForAnInvalidDSAKey(t *testing.T) {
   (y #0AC8670AD767D7A8D9D14CC1AC6744CD7D76F993B77FFD9E39DF01E5A6536EF65E775FCEF2A983E2A19BD6415500F6979715D9FD1257E1FE2B6F5E1E74B333079E7C880D39868462A93454B41877BE62E5EF0A041C2EE9C9E76BD1E12AE25D9628DECB097025DD625EF49C3258A1A3C0FF501E3DC673B76D7BABF349009B6ECF#)
   (x #14D0345A3562C480A039E3C72764F72D79043217#)
   ))`)
-	_, ok := readPrivateKey(from)
-	assertDeepEquals(t, ok, false)
+	_, err := readPrivateKey(from)
+	assertNotNil(t, err)
 }
 
 func Test_readPrivateKey_willReturnNotOKForAnInvalidTag(t *testing.T) {
@@ -277,8 +279,8 @@ func Test_readPrivateKey_willReturnNotOKForAnInvalidTag(t *testing.T) {
   (y #0AC8670AD767D7A8D9D14CC1AC6744CD7D76F993B77FFD9E39DF01E5A6536EF65E775FCEF2A983E2A19BD6415500F6979715D9FD1257E1FE2B6F5E1E74B333079E7C880D39868462A93454B41877BE62E5EF0A041C2EE9C9E76BD1E12AE25D9628DECB097025DD625EF49C3258A1A3C0FF501E3DC673B76D7BABF349009B6ECF#)
   (x #14D0345A3562C480A039E3C72764F72D79043217#)
   ))`)
-	_, ok := readPrivateKey(from)
-	assertDeepEquals(t, ok, false)
+	_, err := readPrivateKey(from)
+	assertNotNil(t, err)
 }
 
 func Test_readPrivateKey_willReturnNotOKForATagOfWrongType(t *testing.T) {
@@ -289,8 +291,8 @@ func Test_readPrivateKey_willReturnNotOKForATagOfWrongType(t *testing.T) {
   (y #0AC8670AD767D7A8D9D14CC1AC6744CD7D76F993B77FFD9E39DF01E5A6536EF65E775FCEF2A983E2A19BD6415500F6979715D9FD1257E1FE2B6F5E1E74B333079E7C880D39868462A93454B41877BE62E5EF0A041C2EE9C9E76BD1E12AE25D9628DECB097025DD625EF49C3258A1A3C0FF501E3DC673B76D7BABF349009B6ECF#)
   (x #14D0345A3562C480A039E3C72764F72D79043217#)
   ))`)
-	_, ok := readPrivateKey(from)
-	assertDeepEquals(t, ok, false)
+	_, err := readPrivateKey(from)
+	assertNotNil(t, err)
 }
 
 func Test_readPrivateKey_willReturnNotOKForNoTag(t *testing.T) {
@@ -301,8 +303,8 @@ func Test_readPrivateKey_willReturnNotOKForNoTag(t *testing.T) {
   (y #0AC8670AD767D7A8D9D14CC1AC6744CD7D76F993B77FFD9E39DF01E5A6536EF65E775FCEF2A983E2A19BD6415500F6979715D9FD1257E1FE2B6F5E1E74B333079E7C880D39868462A93454B41877BE62E5EF0A041C2EE9C9E76BD1E12AE25D9628DECB097025DD625EF49C3258A1A3C0FF501E3DC673B76D7BABF349009B6ECF#)
   (x #14D0345A3562C480A039E3C72764F72D79043217#)
   ))`)
-	_, ok := readPrivateKey(from)
-	assertDeepEquals(t, ok, false)
+	_, err := readPrivateKey(from)
+	assertNotNil(t, err)
 }
 
 func Test_readAccount_willReturnAnAccount(t *testing.T) {
@@ -312,18 +314,17 @@ func Test_readAccount_willReturnAnAccount(t *testing.T) {
 (private-key (dsa
   (p #00FC07ABCF0DC916AFF6E9AE47BEF60C7AB9B4D6B2469E436630E36F8A489BE812486A09F30B71224508654940A835301ACC525A4FF133FC152CC53DCC59D65C30A54F1993FE13FE63E5823D4C746DB21B90F9B9C00B49EC7404AB1D929BA7FBA12F2E45C6E0A651689750E8528AB8C031D3561FECEE72EBB4A090D450A9B7A857#)
   )))`)
-	k, ok, _ := readAccount(from)
+	k, err := readAccount(from)
 	assertDeepEquals(t, k.Name, "foo")
 	assertDeepEquals(t, k.Protocol, "libpurple-Jabber")
 	assertDeepEquals(t, k.Key.(*DSAPrivateKey).PrivateKey.P, bnFromHex("00FC07ABCF0DC916AFF6E9AE47BEF60C7AB9B4D6B2469E436630E36F8A489BE812486A09F30B71224508654940A835301ACC525A4FF133FC152CC53DCC59D65C30A54F1993FE13FE63E5823D4C746DB21B90F9B9C00B49EC7404AB1D929BA7FBA12F2E45C6E0A651689750E8528AB8C031D3561FECEE72EBB4A090D450A9B7A857"))
-	assertDeepEquals(t, ok, true)
+	assertNil(t, err)
 }
 
 func Test_readAccount_willReturnNotOKForSomethingNotAList(t *testing.T) {
 	from := inp(`account`)
-	_, ok, atEnd := readAccount(from)
-	assertDeepEquals(t, ok, true)
-	assertDeepEquals(t, atEnd, true)
+	_, err := readAccount(from)
+	assertNotNil(t, err)
 }
 
 func Test_readAccount_willReturnNotOKForAListThatIsNotEnded(t *testing.T) {
@@ -337,8 +338,8 @@ func Test_readAccount_willReturnNotOKForAListThatIsNotEnded(t *testing.T) {
   (y #0AC8670AD767D7A8D9D14CC1AC6744CD7D76F993B77FFD9E39DF01E5A6536EF65E775FCEF2A983E2A19BD6415500F6979715D9FD1257E1FE2B6F5E1E74B333079E7C880D39868462A93454B41877BE62E5EF0A041C2EE9C9E76BD1E12AE25D9628DECB097025DD625EF49C3258A1A3C0FF501E3DC673B76D7BABF349009B6ECF#)
   (x #14D0345A3562C480A039E3C72764F72D79043217#)
   ))`)
-	_, ok, _ := readAccount(from)
-	assertDeepEquals(t, ok, false)
+	_, err := readAccount(from)
+	assertNotNil(t, err)
 }
 
 func Test_readAccount_willReturnNotOKForAMissingName(t *testing.T) {
@@ -351,8 +352,8 @@ func Test_readAccount_willReturnNotOKForAMissingName(t *testing.T) {
   (y #0AC8670AD767D7A8D9D14CC1AC6744CD7D76F993B77FFD9E39DF01E5A6536EF65E775FCEF2A983E2A19BD6415500F6979715D9FD1257E1FE2B6F5E1E74B333079E7C880D39868462A93454B41877BE62E5EF0A041C2EE9C9E76BD1E12AE25D9628DECB097025DD625EF49C3258A1A3C0FF501E3DC673B76D7BABF349009B6ECF#)
   (x #14D0345A3562C480A039E3C72764F72D79043217#)
   )))`)
-	_, ok, _ := readAccount(from)
-	assertDeepEquals(t, ok, false)
+	_, err := readAccount(from)
+	assertNotNil(t, err)
 }
 
 func Test_readAccount_willReturnNotOKForAMissingProtocol(t *testing.T) {
@@ -365,8 +366,8 @@ func Test_readAccount_willReturnNotOKForAMissingProtocol(t *testing.T) {
   (y #0AC8670AD767D7A8D9D14CC1AC6744CD7D76F993B77FFD9E39DF01E5A6536EF65E775FCEF2A983E2A19BD6415500F6979715D9FD1257E1FE2B6F5E1E74B333079E7C880D39868462A93454B41877BE62E5EF0A041C2EE9C9E76BD1E12AE25D9628DECB097025DD625EF49C3258A1A3C0FF501E3DC673B76D7BABF349009B6ECF#)
   (x #14D0345A3562C480A039E3C72764F72D79043217#)
   )))`)
-	_, ok, _ := readAccount(from)
-	assertDeepEquals(t, ok, false)
+	_, err := readAccount(from)
+	assertNotNil(t, err)
 }
 
 func Test_readAccount_willReturnNotOKForAMissingPrivateKey(t *testing.T) {
@@ -374,8 +375,8 @@ func Test_readAccount_willReturnNotOKForAMissingPrivateKey(t *testing.T) {
 (name "foo")
 (protocol libpurple-Jabber)
 )`)
-	_, ok, _ := readAccount(from)
-	assertDeepEquals(t, ok, false)
+	_, err := readAccount(from)
+	assertNotNil(t, err)
 }
 
 func Test_readAccount_willReturnNotOKForAnIncorrectName(t *testing.T) {
@@ -389,8 +390,8 @@ func Test_readAccount_willReturnNotOKForAnIncorrectName(t *testing.T) {
   (y #0AC8670AD767D7A8D9D14CC1AC6744CD7D76F993B77FFD9E39DF01E5A6536EF65E775FCEF2A983E2A19BD6415500F6979715D9FD1257E1FE2B6F5E1E74B333079E7C880D39868462A93454B41877BE62E5EF0A041C2EE9C9E76BD1E12AE25D9628DECB097025DD625EF49C3258A1A3C0FF501E3DC673B76D7BABF349009B6ECF#)
   (x #14D0345A3562C480A039E3C72764F72D79043217#)
   )))`)
-	_, ok, _ := readAccount(from)
-	assertDeepEquals(t, ok, false)
+	_, err := readAccount(from)
+	assertNotNil(t, err)
 }
 
 func Test_readAccount_willReturnNotOKForAnIncorrectProtocol(t *testing.T) {
@@ -404,8 +405,8 @@ func Test_readAccount_willReturnNotOKForAnIncorrectProtocol(t *testing.T) {
   (y #0AC8670AD767D7A8D9D14CC1AC6744CD7D76F993B77FFD9E39DF01E5A6536EF65E775FCEF2A983E2A19BD6415500F6979715D9FD1257E1FE2B6F5E1E74B333079E7C880D39868462A93454B41877BE62E5EF0A041C2EE9C9E76BD1E12AE25D9628DECB097025DD625EF49C3258A1A3C0FF501E3DC673B76D7BABF349009B6ECF#)
   (x #14D0345A3562C480A039E3C72764F72D79043217#)
   )))`)
-	_, ok, _ := readAccount(from)
-	assertDeepEquals(t, ok, false)
+	_, err := readAccount(from)
+	assertNotNil(t, err)
 }
 
 func Test_readAccount_willReturnNotOKForAnIncorrectPrivateKey(t *testing.T) {
@@ -419,8 +420,8 @@ func Test_readAccount_willReturnNotOKForAnIncorrectPrivateKey(t *testing.T) {
   (y #0AC8670AD767D7A8D9D14CC1AC6744CD7D76F993B77FFD9E39DF01E5A6536EF65E775FCEF2A983E2A19BD6415500F6979715D9FD1257E1FE2B6F5E1E74B333079E7C880D39868462A93454B41877BE62E5EF0A041C2EE9C9E76BD1E12AE25D9628DECB097025DD625EF49C3258A1A3C0FF501E3DC673B76D7BABF349009B6ECF#)
   (x #14D0345A3562C480A039E3C72764F72D79043217#)
   )))`)
-	_, ok, _ := readAccount(from)
-	assertDeepEquals(t, ok, false)
+	_, err := readAccount(from)
+	assertNotNil(t, err)
 }
 
 func Test_readAccounts_willReturnTheAccountRead(t *testing.T) {
@@ -430,42 +431,42 @@ func Test_readAccounts_willReturnTheAccountRead(t *testing.T) {
 (private-key (dsa
   (p #00FC07ABCF0DC916AFF6E9AE47BEF60C7AB9B4D6B2469E436630E36F8A489BE812486A09F30B71224508654940A835301ACC525A4FF133FC152CC53DCC59D65C30A54F1993FE13FE63E5823D4C746DB21B90F9B9C00B49EC7404AB1D929BA7FBA12F2E45C6E0A651689750E8528AB8C031D3561FECEE72EBB4A090D450A9B7A858#)
   ))))`)
-	k, ok := readAccounts(from)
+	k, err := readAccounts(from)
 	assertDeepEquals(t, k[0].Name, "foo2")
 	assertDeepEquals(t, k[0].Protocol, "libpurple-Jabberx")
 	assertDeepEquals(t, k[0].Key.(*DSAPrivateKey).PrivateKey.P, bnFromHex("00FC07ABCF0DC916AFF6E9AE47BEF60C7AB9B4D6B2469E436630E36F8A489BE812486A09F30B71224508654940A835301ACC525A4FF133FC152CC53DCC59D65C30A54F1993FE13FE63E5823D4C746DB21B90F9B9C00B49EC7404AB1D929BA7FBA12F2E45C6E0A651689750E8528AB8C031D3561FECEE72EBB4A090D450A9B7A858"))
-	assertDeepEquals(t, ok, true)
+	assertNil(t, err)
 }
 
 func Test_readAccounts_willReturnZeroAccountsIfNoAccountsThere(t *testing.T) {
 	from := inp(`(privkeys)`)
-	k, ok := readAccounts(from)
+	k, err := readAccounts(from)
 	assertDeepEquals(t, len(k), 0)
-	assertDeepEquals(t, ok, true)
+	assertNil(t, err)
 }
 
 func Test_readAccounts_willReturnNotOKForNoList(t *testing.T) {
 	from := inp(`privkeys`)
-	_, ok := readAccounts(from)
-	assertDeepEquals(t, ok, false)
+	_, err := readAccounts(from)
+	assertNotNil(t, err)
 }
 
 func Test_readAccounts_willReturnNotOKForNonFinishedList(t *testing.T) {
 	from := inp(`(privkeys`)
-	_, ok := readAccounts(from)
-	assertDeepEquals(t, ok, false)
+	_, err := readAccounts(from)
+	assertNotNil(t, err)
 }
 
 func Test_readAccounts_willReturnNotOKForIncorrectTag(t *testing.T) {
 	from := inp(`(privkeysx)`)
-	_, ok := readAccounts(from)
-	assertDeepEquals(t, ok, false)
+	_, err := readAccounts(from)
+	assertNotNil(t, err)
 }
 
 func Test_readAccounts_willReturnNotOKForTagWithWrongType(t *testing.T) {
 	from := inp(`("privkeys")`)
-	_, ok := readAccounts(from)
-	assertDeepEquals(t, ok, false)
+	_, err := readAccounts(from)
+	assertNotNil(t, err)
 }
 
 func Test_readAccounts_willReturnNotOKForAccountThatIsNotOK(t *testing.T) {
@@ -483,8 +484,8 @@ func Test_readAccounts_willReturnNotOKForAccountThatIsNotOK(t *testing.T) {
 	  )
 	 )
 	 ))`)
-	_, ok := readAccounts(from)
-	assertDeepEquals(t, ok, false)
+	_, err := readAccounts(from)
+	assertNotNil(t, err)
 }
 
 func Test_readAccounts_willReturnMoreThanOneAccount(t *testing.T) {
@@ -508,14 +509,14 @@ func Test_readAccounts_willReturnMoreThanOneAccount(t *testing.T) {
 	 )
 	 )
 	)`)
-	k, ok := readAccounts(from)
+	k, err := readAccounts(from)
 	assertDeepEquals(t, k[0].Name, "foo2")
 	assertDeepEquals(t, k[0].Protocol, "libpurple-Jabberx")
 	assertDeepEquals(t, k[0].Key.(*DSAPrivateKey).PrivateKey.P, bnFromHex("00FC07ABCF0DC916AFF6E9AE47BEF60C7AB9B4D6B2469E436630E36F8A489BE812486A09F30B71224508654940A835301ACC525A4FF133FC152CC53DCC59D65C30A54F1993FE13FE63E5823D4C746DB21B90F9B9C00B49EC7404AB1D929BA7FBA12F2E45C6E0A651689750E8528AB8C031D3561FECEE72EBB4A090D450A9B7A858"))
 	assertDeepEquals(t, k[1].Name, "2")
 	assertDeepEquals(t, k[1].Protocol, "libpurple-jabber-gtalk")
 	assertDeepEquals(t, k[1].Key.(*DSAPrivateKey).PrivateKey.Q, bnFromHex("00D16B2607FCBC0EDC639F763A54F34475B1CC8473"))
-	assertDeepEquals(t, ok, true)
+	assertNil(t, err)
 }
 
 func Test_PublicKey_parse_ParsePofAPublicKeyCorrectly(t *testing.T) {
@@ -847,104 +848,104 @@ func Test_PublicKey_Verify_willReturnNotOK(t *testing.T) {
 
 func Test_readAccountName_willSignalNotOKIfNoListIsGiven(t *testing.T) {
 	from := inp(`name`)
-	_, ok := readAccountName(from)
-	assertDeepEquals(t, ok, false)
+	_, err := readAccountName(from)
+	assertNotNil(t, err)
 }
 
 func Test_readAccountName_willSignalNotOKIfNoCompleteListIsGiven(t *testing.T) {
 	from := inp(`(name "foo"`)
-	_, ok := readAccountName(from)
-	assertDeepEquals(t, ok, false)
+	_, err := readAccountName(from)
+	assertNotNil(t, err)
 }
 
 func Test_readAccountName_willSignalNotOKIfNoNameValueIsGiven(t *testing.T) {
 	from := inp(`(name)`)
-	_, ok := readAccountName(from)
-	assertDeepEquals(t, ok, false)
+	_, err := readAccountName(from)
+	assertNotNil(t, err)
 }
 
 func Test_readAccountName_willSignalNotOKIfNoTagIsGiven(t *testing.T) {
 	from := inp(`()`)
-	_, ok := readAccountName(from)
-	assertDeepEquals(t, ok, false)
+	_, err := readAccountName(from)
+	assertNotNil(t, err)
 }
 
 func Test_readAccountName_willSignalNotOKIfTagIsTheWrongType(t *testing.T) {
 	from := inp(`("blarg" "foo")`)
-	_, ok := readAccountName(from)
-	assertDeepEquals(t, ok, false)
+	_, err := readAccountName(from)
+	assertNotNil(t, err)
 }
 
 func Test_readAccountName_willSignalNotOKIfTagIsNotTheSymbolName(t *testing.T) {
 	from := inp(`(namex "foo")`)
-	_, ok := readAccountName(from)
-	assertDeepEquals(t, ok, false)
+	_, err := readAccountName(from)
+	assertNotNil(t, err)
 }
 
 func Test_readAccountName_willSignalNotOKIfValueIsTheWrongType(t *testing.T) {
 	from := inp(`(name #42)`)
-	_, ok := readAccountName(from)
-	assertDeepEquals(t, ok, false)
+	_, err := readAccountName(from)
+	assertNotNil(t, err)
 }
 
 func Test_readAccountName_willSignalOKIfTagAndValueIsCorrect(t *testing.T) {
 	from := inp(`(name "foo")`)
-	_, ok := readAccountName(from)
-	assertDeepEquals(t, ok, true)
+	_, err := readAccountName(from)
+	assertNil(t, err)
 }
 
 func Test_readAccountName_willSignalOKIfTagAndValueAsSymbolIsCorrect(t *testing.T) {
 	from := inp(`(name foo)`)
-	_, ok := readAccountName(from)
-	assertDeepEquals(t, ok, true)
+	_, err := readAccountName(from)
+	assertNil(t, err)
 }
 
 func Test_readAccountProtocol_willSignalNotOKIfNoListIsGiven(t *testing.T) {
 	from := inp(`protocol`)
-	_, ok := readAccountProtocol(from)
-	assertDeepEquals(t, ok, false)
+	_, err := readAccountProtocol(from)
+	assertNotNil(t, err)
 }
 
 func Test_readAccountProtocol_willSignalNotOKIfNoCompleteListIsGiven(t *testing.T) {
 	from := inp(`(protocol libpurple`)
-	_, ok := readAccountProtocol(from)
-	assertDeepEquals(t, ok, false)
+	_, err := readAccountProtocol(from)
+	assertNotNil(t, err)
 }
 
 func Test_readAccountProtocol_willSignalNotOKIfNoProtocolValueIsGiven(t *testing.T) {
 	from := inp(`(protocol)`)
-	_, ok := readAccountProtocol(from)
-	assertDeepEquals(t, ok, false)
+	_, err := readAccountProtocol(from)
+	assertNotNil(t, err)
 }
 
 func Test_readAccountProtocol_willSignalNotOKIfNoTagIsGiven(t *testing.T) {
 	from := inp(`()`)
-	_, ok := readAccountProtocol(from)
-	assertDeepEquals(t, ok, false)
+	_, err := readAccountProtocol(from)
+	assertNotNil(t, err)
 }
 
 func Test_readAccountProtocol_willSignalNotOKIfTagIsTheWrongType(t *testing.T) {
 	from := inp(`("protocol" libpurple)`)
-	_, ok := readAccountProtocol(from)
-	assertDeepEquals(t, ok, false)
+	_, err := readAccountProtocol(from)
+	assertNotNil(t, err)
 }
 
 func Test_readAccountProtocol_willSignalNotOKIfTagIsNotTheSymbolProtocol(t *testing.T) {
 	from := inp(`(protocolx libpurple)`)
-	_, ok := readAccountProtocol(from)
-	assertDeepEquals(t, ok, false)
+	_, err := readAccountProtocol(from)
+	assertNotNil(t, err)
 }
 
 func Test_readAccountProtocol_willSignalNotOKIfValueIsTheWrongType(t *testing.T) {
 	from := inp(`(protocol "libpurple")`)
-	_, ok := readAccountProtocol(from)
-	assertDeepEquals(t, ok, false)
+	_, err := readAccountProtocol(from)
+	assertNotNil(t, err)
 }
 
 func Test_readAccountProtocol_willSignalOKIfTagAndValueIsCorrect(t *testing.T) {
 	from := inp(`(protocol libpurple)`)
-	_, ok := readAccountProtocol(from)
-	assertDeepEquals(t, ok, true)
+	_, err := readAccountProtocol(from)
+	assertNil(t, err)
 }
 
 func Test_ImportKeys_willReturnARelevantErrorForIncorrectData(t *testing.T) {
@@ -955,7 +956,36 @@ func Test_ImportKeys_willReturnARelevantErrorForIncorrectData(t *testing.T) {
   (px #00FC07ABCF0DC916AFF6E9AE47BEF60C7AB9B4D6B2469E436630E36F8A489BE812486A09F30B71224508654940A835301ACC525A4FF133FC152CC53DCC59D65C30A54F1993FE13FE63E5823D4C746DB21B90F9B9C00B49EC7404AB1D929BA7FBA12F2E45C6E0A651689750E8528AB8C031D3561FECEE72EBB4A090D450A9B7A858#)
   ))))`))
 	_, err := ImportKeys(from)
-	assertDeepEquals(t, err, newOtrError("couldn't import data into private key"))
+	assertDeepEquals(t, err, newOtrError("couldn't import data into private key: unknown DSA parameter px at offset 82"))
+}
+
+func Test_ImportKeys_willReturnTheSyntaxErrorForMalformedData(t *testing.T) {
+	from := bytes.NewBuffer([]byte(`(privkeys (account
+(name "foo2)`))
+	_, err := ImportKeys(from)
+	assertEquals(t, err.Error(), "otr: couldn't import data into private key: sexp: unexpected end of input at offset 31")
+
+	var syntaxError *sexp.SyntaxError
+	assertEquals(t, errors.As(err, &syntaxError), true)
+	assertEquals(t, syntaxError.Offset, int64(31))
+}
+
+func Test_ImportKeys_willReturnAnErrorForDataThatEndsTooEarly(t *testing.T) {
+	from := bytes.NewBuffer([]byte(`(privkeys`))
+	_, err := ImportKeys(from)
+	assertEquals(t, err.Error(), "otr: couldn't import data into private key: sexp: unexpected end of input at offset 9")
+}
+
+func Test_ImportKeys_willReadEscapedCharactersInTheAccountName(t *testing.T) {
+	from := bytes.NewBuffer([]byte(`(privkeys (account
+(name "foo\"bar\\")
+(protocol prpl-jabber)
+(private-key (dsa
+  (p #00FC07ABCF0DC916AFF6E9AE47BEF60C7AB9B4D6B2469E436630E36F8A489BE812486A09F30B71224508654940A835301ACC525A4FF133FC152CC53DCC59D65C30A54F1993FE13FE63E5823D4C746DB21B90F9B9C00B49EC7404AB1D929BA7FBA12F2E45C6E0A651689750E8528AB8C031D3561FECEE72EBB4A090D450A9B7A858#)
+  ))))`))
+	res, err := ImportKeys(from)
+	assertNil(t, err)
+	assertDeepEquals(t, res[0].Name, "foo\"bar\\")
 }
 
 func Test_ImportKeys_willReturnTheParsedAccountInformation(t *testing.T) {
@@ -986,7 +1016,7 @@ func Test_ImportKeysFromFile_willReturnAValidAccountReadFromAFile(t *testing.T)
 
 func Test_ImportKeysFromFile_willReturnAnErrorIfTheFileIsinvalid(t *testing.T) {
 	_, err := ImportKeysFromFile("test_resources/invalid_key.asc")
-	assertDeepEquals(t, err, newOtrError("couldn't import data into private key"))
+	assertDeepEquals(t, err, newOtrError("couldn't import data into private key: unknown DSA parameter px at offset 82"))
 }
 
 func Test_PrivateKey_ImportWithoutError(t *testing.T) {
@@ -0,0 +1,96 @@
+package otr3
+
+import "strings"
+
+// MessageCatalog has the human readable texts sent to the peer in one language
+type MessageCatalog struct {
+	// ErrorMessages has the text of the error message sent for every ErrorCode
+	ErrorMessages map[ErrorCode]string
+	// QueryMessage explains a query message to people whose clients don't have OTR. The %s in it is replaced with the
+	// name of the account asking for the conversation. It is not a format string: the rest of it is used as it is
+	QueryMessage string
+}
+
+// EnglishMessageCatalog has the texts libotr based clients send in English. The error messages are the ones recognized when received
+var EnglishMessageCatalog = MessageCatalog{
+	ErrorMessages: map[ErrorCode]string{
+		ErrorCodeEncryptionError:     "Error occurred encrypting message.",
+		ErrorCodeMessageUnreadable:   "You transmitted an unreadable encrypted message.",
+		ErrorCodeMessageMalformed:    "You transmitted a malformed data message.",
+		ErrorCodeMessageNotInPrivate: "You sent encrypted data to a peer who wasn't expecting it.",
+	},
+	QueryMessage: "%s has requested an Off-the-Record private conversation. However, you do not have a plugin to support that. See https://otr.cypherpunks.ca/ for more information.",
+}
+
+// queryMessageAccount is the placeholder for the account in the QueryMessage of a catalog
+const queryMessageAccount = "%s"
+
+// MessageCatalogs are the catalogs MessageCatalogFor chooses from, keyed by locale names like "en" or "pt_BR".
+// Applications can add their own translations
+var MessageCatalogs = map[string]*MessageCatalog{
+	"en": &EnglishMessageCatalog,
+}
+
+// MessageCatalogFor returns the catalog for the locale. If there is none for a locale like "pt_BR" or "pt-BR",
+// the one for "pt" is used, and the English catalog if there is none for that either
+func MessageCatalogFor(locale string) *MessageCatalog {
+	if c, ok := MessageCatalogs[locale]; ok {
+		return c
+	}
+
+	if i := strings.IndexAny(locale, "_-."); i > 0 {
+		if c, ok := MessageCatalogs[locale[:i]]; ok {
+			return c
+		}
+	}
+
+	return &EnglishMessageCatalog
+}
+
+// ErrorMessage returns the text of the error message for the error code, or nil if the catalog has none
+func (mc *MessageCatalog) ErrorMessage(ec ErrorCode) []byte {
+	if msg, ok := mc.ErrorMessages[ec]; ok {
+		return []byte(msg)
+	}
+	return nil
+}
+
+// FriendlyQueryMessage returns the query message text for the account, as given to SetFriendlyQueryMessage
+func (mc *MessageCatalog) FriendlyQueryMessage(account string) string {
+	return strings.Replace(mc.QueryMessage, queryMessageAccount, account, -1)
+}
+
+// CatalogErrorMessageHandler is an ErrorMessageHandler that returns the error messages of a MessageCatalog.
+// Conversations without another ErrorMessageHandler use one with their own catalog
+type CatalogErrorMessageHandler struct {
+	Catalog *MessageCatalog
+}
+
+// HandleErrorMessage returns the error message the catalog has for the error code
+func (h CatalogErrorMessageHandler) HandleErrorMessage(ec ErrorCode) []byte {
+	return h.Catalog.ErrorMessage(ec)
+}
+
+// SetMessageCatalog sets the catalog the texts sent to the peer come from. The default is EnglishMessageCatalog
+func (c *Conversation) SetMessageCatalog(mc *MessageCatalog) {
+	c.messageCatalog = mc
+}
+
+func (c *Conversation) catalog() *MessageCatalog {
+	if c.messageCatalog == nil {
+		return &EnglishMessageCatalog
+	}
+	return c.messageCatalog
+}
+
+func (c *Conversation) getErrorMessageHandler() ErrorMessageHandler {
+	if c.errorMessageHandler == nil {
+		return CatalogErrorMessageHandler{c.catalog()}
+	}
+	return c.errorMessageHandler
+}
+
+// SetDefaultQueryMessage makes query messages explain themselves with the text of the message catalog, saying the account asked for the conversation
+func (c *Conversation) SetDefaultQueryMessage(account string) {
+	c.SetFriendlyQueryMessage(c.catalog().FriendlyQueryMessage(account))
+}
@@ -0,0 +1,62 @@
+package otr3
+
+import "testing"
+
+func Test_EnglishMessageCatalog_hasAMessageForEveryErrorCode(t *testing.T) {
+	for _, ec := range []ErrorCode{ErrorCodeEncryptionError, ErrorCodeMessageUnreadable, ErrorCodeMessageMalformed, ErrorCodeMessageNotInPrivate} {
+		assertEquals(t, len(EnglishMessageCatalog.ErrorMessage(ec)) > 0, true)
+	}
+}
+
+func Test_EnglishMessageCatalog_errorMessagesAreRecognizedWhenReceived(t *testing.T) {
+	for ec, msg := range EnglishMessageCatalog.ErrorMessages {
+		assertEquals(t, parseErrorMessage([]byte(msg)).Code, ec)
+	}
+}
+
+func Test_MessageCatalog_ErrorMessage_returnsNilForUnknownCodes(t *testing.T) {
+	assertNil(t, EnglishMessageCatalog.ErrorMessage(ErrorCode(20000)))
+}
+
+func Test_MessageCatalogFor_fallsBackToTheLanguageAndThenToEnglish(t *testing.T) {
+	pt := &MessageCatalog{QueryMessage: "%s pediu uma conversa privada Off-the-Record."}
+	MessageCatalogs["pt"] = pt
+	defer delete(MessageCatalogs, "pt")
+
+	assertEquals(t, MessageCatalogFor("pt"), pt)
+	assertEquals(t, MessageCatalogFor("pt_BR"), pt)
+	assertEquals(t, MessageCatalogFor("pt-BR"), pt)
+	assertEquals(t, MessageCatalogFor("de_DE"), &EnglishMessageCatalog)
+	assertEquals(t, MessageCatalogFor(""), &EnglishMessageCatalog)
+}
+
+func Test_SetMessageCatalog_changesTheErrorMessagesSent(t *testing.T) {
+	c := &Conversation{}
+	c.SetMessageCatalog(&MessageCatalog{ErrorMessages: map[ErrorCode]string{
+		ErrorCodeMessageMalformed: "Você transmitiu uma mensagem de dados malformada.",
+	}})
+
+	c.generatePotentialErrorMessage(ErrorCodeMessageMalformed)
+
+	assertDeepEquals(t, c.injections.messages, []ValidMessage{ValidMessage("?OTR Error: Você transmitiu uma mensagem de dados malformada.")})
+}
+
+func Test_SetDefaultQueryMessage_usesTheTextOfTheCatalog(t *testing.T) {
+	c := &Conversation{}
+	c.Policies = policies(allowV3)
+
+	c.SetDefaultQueryMessage("alice@example.com")
+
+	assertEquals(t, string(c.QueryMessage()), "?OTRv3? alice@example.com has requested an Off-the-Record private conversation. However, you do not have a plugin to support that. See https://otr.cypherpunks.ca/ for more information.")
+}
+
+func Test_CatalogErrorMessageHandler_returnsTheMessagesOfItsCatalog(t *testing.T) {
+	h := CatalogErrorMessageHandler{&EnglishMessageCatalog}
+	assertEquals(t, string(h.HandleErrorMessage(ErrorCodeEncryptionError)), "Error occurred encrypting message.")
+}
+
+func Test_MessageCatalog_FriendlyQueryMessage_onlyReplacesTheAccountPlaceholder(t *testing.T) {
+	mc := &MessageCatalog{QueryMessage: "%s wants 100% privacy with %d %v %"}
+
+	assertEquals(t, mc.FriendlyQueryMessage("alice@example.com"), "alice@example.com wants 100% privacy with %d %v %")
+}
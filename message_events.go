@@ -22,10 +22,12 @@ const (
 	// MessageEventMessageReflected will be signaled if we received our own OTR messages.
 	MessageEventMessageReflected
 
-	// MessageEventMessageSent is signaled when a message is sent after having been queued
+	// MessageEventMessageSent is signaled when a message is sent after having been queued.
+	// If the message was sent with an ID, the trace contains the MessageID
 	MessageEventMessageSent
 
-	// MessageEventMessageResent is signaled when a message is resent
+	// MessageEventMessageResent is signaled when a message is resent.
+	// If the message was sent with an ID, the trace contains the MessageID
 	MessageEventMessageResent
 
 	// MessageEventReceivedMessageNotInPrivate will be signaled when we receive an encrypted message that we cannot read, because we don't have an established private connection
@@ -44,7 +46,8 @@ const (
 	MessageEventLogHeartbeatSent
 
 	// MessageEventReceivedMessageGeneralError will be signaled when we receive an OTR error from the peer.
-	// The message parameter will be passed, containing the error message
+	// The message parameter will be passed, containing the error message. If the error could be recognized,
+	// the attached error is a ReceivedErrorMessage with its ErrorCode
 	MessageEventReceivedMessageGeneralError
 
 	// MessageEventReceivedMessageUnencrypted is triggered when we receive a message that was sent in the clear when it should have been encrypted.
@@ -56,6 +59,14 @@ const (
 
 	// MessageEventReceivedMessageForOtherInstance is triggered when we receive and discard a message for another instance
 	MessageEventReceivedMessageForOtherInstance
+
+	// MessageEventReceivedMessageFragmentDiscarded is triggered when a partially received fragmented message is thrown away.
+	// The attached error tells if it was too large, took too long to arrive or was interrupted by another message
+	MessageEventReceivedMessageFragmentDiscarded
+
+	// MessageEventTheirKeyHeld is triggered when the conversation becomes encrypted with a key that isn't trusted, under the
+	// HoldUntrustedKeys policy. Messages sent are held until ApproveTheirKey or RejectTheirKey is called
+	MessageEventTheirKeyHeld
 )
 
 // MessageEventHandler handles MessageEvents
@@ -73,23 +84,37 @@ func (d dynamicMessageEventHandler) HandleMessageEvent(event MessageEvent, messa
 }
 
 func (c *Conversation) messageEvent(e MessageEvent, trace ...interface{}) {
+	c.publishEvent(Event{Kind: EventKindMessage, Message: e, Trace: trace})
+
 	if c.messageEventHandler != nil {
 		c.messageEventHandler.HandleMessageEvent(e, nil, nil, trace...)
 	}
 }
 
 func (c *Conversation) messageEventWithError(e MessageEvent, err error) {
+	c.publishEvent(Event{Kind: EventKindMessage, Message: e, Error: err})
+
 	if c.messageEventHandler != nil {
 		c.messageEventHandler.HandleMessageEvent(e, nil, err)
 	}
 }
 
 func (c *Conversation) messageEventWithMessage(e MessageEvent, msg []byte) {
+	c.publishEvent(Event{Kind: EventKindMessage, Message: e, MessageData: makeCopy(msg)})
+
 	if c.messageEventHandler != nil {
 		c.messageEventHandler.HandleMessageEvent(e, msg, nil)
 	}
 }
 
+func (c *Conversation) messageEventWithMessageAndError(e MessageEvent, msg []byte, err error) {
+	c.publishEvent(Event{Kind: EventKindMessage, Message: e, MessageData: makeCopy(msg), Error: err})
+
+	if c.messageEventHandler != nil {
+		c.messageEventHandler.HandleMessageEvent(e, msg, err)
+	}
+}
+
 // String returns the string representation of the MessageEvent
 func (s MessageEvent) String() string {
 	switch s {
@@ -125,6 +150,10 @@ func (s MessageEvent) String() string {
 		return "MessageEventReceivedMessageUnrecognized"
 	case MessageEventReceivedMessageForOtherInstance:
 		return "MessageEventReceivedMessageForOtherInstance"
+	case MessageEventReceivedMessageFragmentDiscarded:
+		return "MessageEventReceivedMessageFragmentDiscarded"
+	case MessageEventTheirKeyHeld:
+		return "MessageEventTheirKeyHeld"
 	default:
 		return "MESSAGE EVENT: (THIS SHOULD NEVER HAPPEN)"
 	}
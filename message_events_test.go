@@ -18,6 +18,8 @@ func Test_MessageEvent_hasValidStringImplementation(t *testing.T) {
 	assertEquals(t, MessageEventReceivedMessageUnencrypted.String(), "MessageEventReceivedMessageUnencrypted")
 	assertEquals(t, MessageEventReceivedMessageUnrecognized.String(), "MessageEventReceivedMessageUnrecognized")
 	assertEquals(t, MessageEventReceivedMessageForOtherInstance.String(), "MessageEventReceivedMessageForOtherInstance")
+	assertEquals(t, MessageEventReceivedMessageFragmentDiscarded.String(), "MessageEventReceivedMessageFragmentDiscarded")
+	assertEquals(t, MessageEventTheirKeyHeld.String(), "MessageEventTheirKeyHeld")
 	assertEquals(t, MessageEvent(20000).String(), "MESSAGE EVENT: (THIS SHOULD NEVER HAPPEN)")
 }
 
@@ -5,6 +5,7 @@ import (
 	"crypto/hmac"
 	"crypto/subtle"
 	"encoding/binary"
+	"io"
 	"math/big"
 )
 
@@ -44,7 +45,7 @@ func (c *dhCommit) deserialize(msg []byte) error {
 	msg, c.encryptedGx, ok1 = extractData(msg)
 	_, h, ok2 := extractData(msg)
 	if !ok1 || !ok2 {
-		return newOtrError("corrupt DH commit message")
+		return newOtrErrorOfKind(ErrMalformedMessage, "corrupt DH commit message").aboutMessage(msgTypeDHCommit)
 	}
 	c.yhashedGx = h
 	return nil
@@ -62,7 +63,7 @@ func (c *dhKey) deserialize(msg []byte) error {
 	_, gy, ok := extractMPI(msg)
 
 	if !ok {
-		return newOtrError("corrupt DH key message")
+		return newOtrErrorOfKind(ErrMalformedMessage, "corrupt DH key message").aboutMessage(msgTypeDHKey)
 	}
 
 	c.gy = gy
@@ -87,7 +88,7 @@ func (c *revealSig) deserialize(msg []byte, v otrVersion) error {
 	in, r, ok1 := extractData(msg)
 	macSig, encryptedSig, ok2 := extractData(in)
 	if !ok1 || !ok2 || len(macSig) != v.truncateLength() {
-		return newOtrError("corrupt reveal signature message")
+		return newOtrErrorOfKind(ErrMalformedMessage, "corrupt reveal signature message").aboutMessage(msgTypeRevealSig)
 	}
 
 	copy(c.r[:], r)
@@ -111,7 +112,7 @@ func (c *sig) deserialize(msg []byte) error {
 	macSig, encryptedSig, ok := extractData(msg)
 
 	if !ok || len(macSig) != 20 {
-		return newOtrError("corrupt signature message")
+		return newOtrErrorOfKind(ErrMalformedMessage, "corrupt signature message").aboutMessage(msgTypeSig)
 	}
 	c.encryptedSig = encryptedSig
 	c.macSig = macSig
@@ -147,7 +148,7 @@ func (c dataMsg) checkSign(key []byte, header []byte, v otrVersion) error {
 	authenticatorCalculated := mac.Sum(nil)
 
 	if subtle.ConstantTimeCompare(c.authenticator, authenticatorCalculated) == 0 {
-		return newOtrConflictError("bad signature MAC in encrypted signature")
+		return newOtrErrorOfKind(ErrMACFailure, "bad signature MAC in encrypted signature").asConflict().aboutMessage(msgTypeData)
 	}
 	return nil
 }
@@ -166,7 +167,7 @@ func (c dataMsg) serializeUnsigned() []byte {
 
 func (c *dataMsg) deserializeUnsigned(msg []byte) error {
 	if len(msg) == 0 {
-		return newOtrError("dataMsg.deserialize empty message")
+		return newOtrErrorOfKind(ErrMalformedMessage, "dataMsg.deserialize empty message").aboutMessage(msgTypeData)
 	}
 	in := msg
 	c.flag = in[0]
@@ -176,33 +177,33 @@ func (c *dataMsg) deserializeUnsigned(msg []byte) error {
 
 	in, c.senderKeyID, ok = extractWord(in)
 	if !ok {
-		return newOtrError("dataMsg.deserialize corrupted senderKeyID")
+		return newOtrErrorOfKind(ErrMalformedMessage, "dataMsg.deserialize corrupted senderKeyID").aboutMessage(msgTypeData)
 	}
 
 	in, c.recipientKeyID, ok = extractWord(in)
 	if !ok {
-		return newOtrError("dataMsg.deserialize corrupted recipientKeyID")
+		return newOtrErrorOfKind(ErrMalformedMessage, "dataMsg.deserialize corrupted recipientKeyID").aboutMessage(msgTypeData)
 	}
 
 	in, c.y, ok = extractMPI(in)
 	if !ok {
-		return newOtrError("dataMsg.deserialize corrupted y")
+		return newOtrErrorOfKind(ErrMalformedMessage, "dataMsg.deserialize corrupted y").aboutMessage(msgTypeData)
 	}
 
 	if len(in) < len(c.topHalfCtr) {
-		return newOtrError("dataMsg.deserialize corrupted topHalfCtr")
+		return newOtrErrorOfKind(ErrMalformedMessage, "dataMsg.deserialize corrupted topHalfCtr").aboutMessage(msgTypeData)
 	}
 
 	copy(c.topHalfCtr[:], in)
 	if binary.BigEndian.Uint64(c.topHalfCtr[:]) == 0 {
-		return newOtrError("dataMsg.deserialize invalid topHalfCtr")
+		return newOtrErrorOfKind(ErrMalformedMessage, "dataMsg.deserialize invalid topHalfCtr").aboutMessage(msgTypeData)
 	}
 
 	copy(c.topHalfCtr[:], in)
 	in = in[len(c.topHalfCtr):]
 	in, c.encryptedMsg, ok = extractData(in)
 	if !ok {
-		return newOtrError("dataMsg.deserialize corrupted encryptedMsg")
+		return newOtrErrorOfKind(ErrMalformedMessage, "dataMsg.deserialize corrupted encryptedMsg").aboutMessage(msgTypeData)
 	}
 
 	c.serializeUnsignedCache = msg[:len(msg)-len(in)]
@@ -239,11 +240,11 @@ func (c *dataMsg) deserialize(msg []byte, v otrVersion) error {
 	var revKeysBytes []byte
 	msg, revKeysBytes, ok := extractData(msg)
 	if !ok {
-		return newOtrError("dataMsg.deserialize corrupted revealMACKeys")
+		return newOtrErrorOfKind(ErrMalformedMessage, "dataMsg.deserialize corrupted revealMACKeys").aboutMessage(msgTypeData)
 	}
 	for len(revKeysBytes) > 0 {
 		if len(revKeysBytes) < v.hashLength() {
-			return newOtrError("dataMsg.deserialize corrupted revealMACKeys")
+			return newOtrErrorOfKind(ErrMalformedMessage, "dataMsg.deserialize corrupted revealMACKeys").aboutMessage(msgTypeData)
 		}
 		revKey := make([]byte, v.hashLength())
 		copy(revKey, revKeysBytes)
@@ -299,13 +300,29 @@ func (c plainDataMsg) serialize() []byte {
 }
 
 const (
-	paddingGranularity = 256
-	tlvHeaderLen       = 4
-	nulByteLen         = 1
+	tlvHeaderLen = 4
+	nulByteLen   = 1
 )
 
-func (c plainDataMsg) pad() plainDataMsg {
-	padding := paddingGranularity - ((len(c.message) + tlvHeaderLen + nulByteLen) % paddingGranularity)
+// lengthWithPaddingHeader returns the length the message will have when serialized together with an empty padding TLV
+func (c plainDataMsg) lengthWithPaddingHeader() int {
+	l := len(c.message) + nulByteLen + tlvHeaderLen
+	for _, t := range c.tlvs {
+		l += tlvHeaderLen + len(t.tlvValue)
+	}
+	return l
+}
+
+// pad adds a padding TLV with as many bytes as the policy asks for. No TLV is added if the policy asks for no padding
+func (c plainDataMsg) pad(policy PaddingPolicy, r io.Reader) (plainDataMsg, error) {
+	padding, err := policy.Padding(c.lengthWithPaddingHeader(), r)
+	if err != nil || padding <= 0 {
+		return c, err
+	}
+
+	if padding > maxTLVValueLength {
+		padding = maxTLVValueLength
+	}
 
 	paddingTlv := tlv{
 		tlvType:   uint16(tlvTypePadding),
@@ -313,16 +330,16 @@ func (c plainDataMsg) pad() plainDataMsg {
 		tlvValue:  make([]byte, padding),
 	}
 
-	c.tlvs = append(c.tlvs, paddingTlv)
+	c.tlvs = append(append([]tlv{}, c.tlvs...), paddingTlv)
 
-	return c
+	return c, nil
 }
 
 func (c plainDataMsg) encrypt(key []byte, topHalfCtr [8]byte) []byte {
 	var iv [aes.BlockSize]byte
 	copy(iv[:], topHalfCtr[:])
 
-	data := c.pad().serialize()
+	data := c.serialize()
 	dst := make([]byte, len(data))
 	counterEncipher(key[:], iv[:], data, dst)
 	return dst
@@ -7,7 +7,8 @@ import (
 	"crypto/sha256"
 	"fmt"
 	"hash"
-	"math/big"
+
+	"github.com/twstrike/otr3/smp"
 )
 
 var otrv2FragmentationPrefix = []byte("?OTR,")
@@ -16,12 +17,8 @@ const otrv2HeaderLen = 3
 
 type otrV2 struct{}
 
-func (v otrV2) parameterLength() int {
-	return 16
-}
-
-func (v otrV2) isGroupElement(n *big.Int) bool {
-	return true
+func (v otrV2) smpVersion() smp.Version {
+	return smp.Version2
 }
 
 func (v otrV2) isFragmented(data []byte) bool {
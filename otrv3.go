@@ -8,8 +8,9 @@ import (
 	"encoding/binary"
 	"fmt"
 	"hash"
-	"math/big"
 	"strconv"
+
+	"github.com/twstrike/otr3/smp"
 )
 
 var otrv3FragmentationPrefix = []byte("?OTR|")
@@ -21,12 +22,8 @@ const (
 
 type otrV3 struct{}
 
-func (v otrV3) parameterLength() int {
-	return 192
-}
-
-func (v otrV3) isGroupElement(n *big.Int) bool {
-	return isGroupElement(n)
+func (v otrV3) smpVersion() smp.Version {
+	return smp.Version3
 }
 
 func (v otrV3) isFragmented(data []byte) bool {
@@ -1,5 +1,95 @@
 package otr3
 
+import (
+	"encoding/binary"
+	"io"
+)
+
+// PaddingPolicy decides how much padding is added to an encrypted message, to hide the exact length of the message.
+// The padding is sent as a padding TLV, which all OTR implementations ignore
+type PaddingPolicy interface {
+	// Padding returns the number of padding bytes to add to a message. The length given is the length of the
+	// serialized message, including the header of the padding TLV. Returning zero means no padding TLV is added at all
+	Padding(length int, r io.Reader) (int, error)
+}
+
+// BlockPadding pads messages to the next multiple of Size bytes. A message that already is a multiple of Size is padded
+// with a full block, so a padding TLV is always added
+type BlockPadding struct {
+	Size int
+}
+
+// Padding implements PaddingPolicy
+func (p BlockPadding) Padding(length int, _ io.Reader) (int, error) {
+	if p.Size <= 0 {
+		return 0, nil
+	}
+	return p.Size - length%p.Size, nil
+}
+
+// PowerOfTwoPadding pads messages to the next power of two, but never to less than Min bytes
+type PowerOfTwoPadding struct {
+	Min int
+}
+
+// Padding implements PaddingPolicy
+func (p PowerOfTwoPadding) Padding(length int, _ io.Reader) (int, error) {
+	target := 1
+	for target < length || target < p.Min {
+		target <<= 1
+	}
+	return target - length, nil
+}
+
+// RandomPadding pads messages with a random number of bytes, between zero and Max
+type RandomPadding struct {
+	Max int
+}
+
+// Padding implements PaddingPolicy
+func (p RandomPadding) Padding(_ int, r io.Reader) (int, error) {
+	if p.Max <= 0 {
+		return 0, nil
+	}
+
+	var b [4]byte
+	if err := randomInto(r, b[:]); err != nil {
+		return 0, err
+	}
+
+	return int(binary.BigEndian.Uint32(b[:]) % uint32(p.Max+1)), nil
+}
+
+// NoPadding doesn't pad messages at all, so the length of the message can be seen on the wire
+type NoPadding struct{}
+
+// Padding implements PaddingPolicy
+func (NoPadding) Padding(int, io.Reader) (int, error) {
+	return 0, nil
+}
+
+// DefaultPadding is the padding used if no other policy has been set
+var DefaultPadding PaddingPolicy = BlockPadding{Size: 256}
+
+// SetPaddingPolicy sets how encrypted messages are padded. Setting nil returns to DefaultPadding
+func (c *Conversation) SetPaddingPolicy(policy PaddingPolicy) {
+	c.paddingPolicy = policy
+}
+
+func (c *Conversation) padding() PaddingPolicy {
+	if c.paddingPolicy == nil {
+		return DefaultPadding
+	}
+	return c.paddingPolicy
+}
+
+func (o SendOptions) padding(c *Conversation) PaddingPolicy {
+	if o.Padding != nil {
+		return o.Padding
+	}
+	return c.padding()
+}
+
 func (c *Conversation) processPaddingTLV(tlv, dataMessageExtra) (toSend *tlv, err error) {
 	return nil, nil
 }
@@ -0,0 +1,108 @@
+package otr3
+
+import (
+	"bytes"
+	"testing"
+)
+
+func Test_BlockPadding_padsToTheNextMultipleOfTheSize(t *testing.T) {
+	p, _ := BlockPadding{Size: 256}.Padding(5, nil)
+	assertEquals(t, p, 251)
+
+	p, _ = BlockPadding{Size: 256}.Padding(300, nil)
+	assertEquals(t, p, 212)
+}
+
+func Test_BlockPadding_padsWithAFullBlockIfTheLengthIsAMultipleOfTheSize(t *testing.T) {
+	p, _ := BlockPadding{Size: 16}.Padding(32, nil)
+	assertEquals(t, p, 16)
+}
+
+func Test_BlockPadding_doesntPadWithoutASize(t *testing.T) {
+	p, _ := BlockPadding{}.Padding(32, nil)
+	assertEquals(t, p, 0)
+}
+
+func Test_PowerOfTwoPadding_padsToTheNextPowerOfTwo(t *testing.T) {
+	p, _ := PowerOfTwoPadding{}.Padding(100, nil)
+	assertEquals(t, p, 28)
+
+	p, _ = PowerOfTwoPadding{}.Padding(128, nil)
+	assertEquals(t, p, 0)
+}
+
+func Test_PowerOfTwoPadding_padsToAtLeastTheMinimum(t *testing.T) {
+	p, _ := PowerOfTwoPadding{Min: 512}.Padding(100, nil)
+	assertEquals(t, p, 412)
+}
+
+func Test_RandomPadding_padsWithARandomNumberOfBytesUpToTheMaximum(t *testing.T) {
+	p, err := RandomPadding{Max: 10}.Padding(100, fixedRand([]string{"0000000f"}))
+	assertNil(t, err)
+	assertEquals(t, p, 4)
+}
+
+func Test_RandomPadding_returnsAnErrorIfThereIsNotEnoughRandomness(t *testing.T) {
+	_, err := RandomPadding{Max: 10}.Padding(100, fixedRand([]string{"00"}))
+	assertEquals(t, err, errShortRandomRead)
+}
+
+func Test_pad_doesntAddAPaddingTLVIfThePolicyDoesntPad(t *testing.T) {
+	plain := plainDataMsg{message: []byte("hello")}
+
+	padded, err := plain.pad(NoPadding{}, nil)
+
+	assertNil(t, err)
+	assertNil(t, padded.tlvs)
+}
+
+func Test_pad_neverAddsMorePaddingThanFitsInATLV(t *testing.T) {
+	plain := plainDataMsg{message: []byte("hello")}
+
+	padded, _ := plain.pad(BlockPadding{Size: 100000}, nil)
+
+	assertEquals(t, padded.tlvs[0].tlvLength, uint16(maxTLVValueLength))
+}
+
+func Test_pad_doesntChangeTheTLVsOfTheOriginalMessage(t *testing.T) {
+	tlvs := make([]tlv, 1, 2)
+	tlvs[0] = tlv{tlvType: tlvTypeDisconnected}
+	plain := plainDataMsg{message: []byte("hello"), tlvs: tlvs}
+
+	_, _ = plain.pad(DefaultPadding, nil)
+
+	assertEquals(t, len(plain.tlvs), 1)
+	assertEquals(t, tlvs[:2][1].tlvType, uint16(0))
+}
+
+func Test_Send_hidesTheLengthOfMessagesInTheSameBlock(t *testing.T) {
+	alice, _ := smpConversationsAfterAKE(t)
+	alice.SetPaddingPolicy(BlockPadding{Size: 64})
+
+	short, _ := alice.Send(ValidMessage("hi"))
+	long, _ := alice.Send(ValidMessage("hello there, how are you?"))
+
+	assertEquals(t, len(short[0]), len(long[0]))
+}
+
+func Test_Send_showsTheLengthOfMessagesWithoutPadding(t *testing.T) {
+	alice, _ := smpConversationsAfterAKE(t)
+	alice.SetPaddingPolicy(NoPadding{})
+
+	short, _ := alice.Send(ValidMessage("hi"))
+	long, _ := alice.Send(ValidMessage("hello there, how are you?"))
+
+	assertTrue(t, len(short[0]) < len(long[0]))
+}
+
+func Test_Send_paddedMessagesCanBeReceived(t *testing.T) {
+	alice, bob := smpConversationsAfterAKE(t)
+	alice.SetPaddingPolicy(RandomPadding{Max: 1000})
+
+	toSend, err := alice.Send(ValidMessage("hello"))
+	assertNil(t, err)
+
+	info, _, err := bob.ReceiveWithInfo(toSend[0])
+	assertNil(t, err)
+	assertTrue(t, bytes.Equal(info.Plaintext, []byte("hello")))
+}
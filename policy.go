@@ -11,6 +11,9 @@ const (
 	sendWhitespaceTag
 	whitespaceStartAKE
 	errorStartAKE
+	sendErrorCodes
+	requireTrustedKeys
+	holdUntrustedKeys
 )
 
 func (p *policies) isOTREnabled() bool {
@@ -48,3 +51,20 @@ func (p *policies) WhitespaceStartAKE() {
 func (p *policies) ErrorStartAKE() {
 	p.add(errorStartAKE)
 }
+
+// RequireTrustedKeys makes the AKE fail with MessageEventSetupError if the KeyTrustHandler doesn't trust the key of the peer.
+// It wins over HoldUntrustedKeys
+func (p *policies) RequireTrustedKeys() {
+	p.add(requireTrustedKeys)
+}
+
+// HoldUntrustedKeys makes the conversation hold the messages sent after the AKE, if the KeyTrustHandler doesn't trust
+// the key of the peer, until ApproveTheirKey is called
+func (p *policies) HoldUntrustedKeys() {
+	p.add(holdUntrustedKeys)
+}
+
+// SendErrorCodes makes the error messages sent to the peer start with a machine readable code, like ERROR_1
+func (p *policies) SendErrorCodes() {
+	p.add(sendErrorCodes)
+}
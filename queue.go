@@ -0,0 +1,103 @@
+package otr3
+
+import "time"
+
+// QueuedMessageStatus tells what happened to a message that was queued because it couldn't be sent before the
+// conversation was encrypted
+type QueuedMessageStatus int
+
+const (
+	// QueuedMessageQueued is signaled when a message is queued, waiting for the conversation to be encrypted
+	QueuedMessageQueued QueuedMessageStatus = iota
+	// QueuedMessageSentEncrypted is signaled when a queued message has been sent encrypted
+	QueuedMessageSentEncrypted
+	// QueuedMessageExpired is signaled when a queued message was removed from the queue because it was too old
+	QueuedMessageExpired
+	// QueuedMessageDropped is signaled when a queued message was removed from the queue because the queue was full,
+	// or because the queue was discarded
+	QueuedMessageDropped
+)
+
+// QueuedMessage is a message waiting in the queue of a conversation
+type QueuedMessage struct {
+	ID      MessageID
+	Message MessagePlaintext
+	Queued  time.Time
+}
+
+// QueuedMessageHandler is an interface that will be invoked when the status of a queued message changes
+type QueuedMessageHandler interface {
+	// HandleQueuedMessage is called with the ID the message was sent with, if any, the message and its new status
+	HandleQueuedMessage(id MessageID, message MessagePlaintext, status QueuedMessageStatus)
+}
+
+type dynamicQueuedMessageHandler struct {
+	eh func(id MessageID, message MessagePlaintext, status QueuedMessageStatus)
+}
+
+func (d dynamicQueuedMessageHandler) HandleQueuedMessage(id MessageID, message MessagePlaintext, status QueuedMessageStatus) {
+	d.eh(id, message, status)
+}
+
+func (c *Conversation) queuedMessageStatus(status QueuedMessageStatus, msgs ...queuedMessage) {
+	if c.queuedMessageHandler == nil {
+		return
+	}
+
+	for _, m := range msgs {
+		if m.waiting {
+			c.queuedMessageHandler.HandleQueuedMessage(m.opts.ID, makeCopy(m.m), status)
+		}
+	}
+}
+
+// SetQueuedMessageHandler sets the handler invoked when the status of a queued message changes
+func (c *Conversation) SetQueuedMessageHandler(handler QueuedMessageHandler) {
+	c.queuedMessageHandler = handler
+}
+
+// SetQueueLimits sets how many messages are kept in the queue, and for how long. When the queue is full, the oldest
+// message is dropped. A maximum of zero messages keeps the default of 100, and a maximum age of zero keeps messages
+// until they are sent. Messages that are too old are removed when the conversation is next used
+func (c *Conversation) SetQueueLimits(maxMessages int, maxAge time.Duration) {
+	c.resend.maxMessages = maxMessages
+	c.resend.maxAge = maxAge
+}
+
+// QueuedMessages returns the messages waiting for the conversation to be encrypted, oldest first
+func (c *Conversation) QueuedMessages() []QueuedMessage {
+	c.expireQueuedMessages()
+
+	var ret []QueuedMessage
+	for _, m := range c.resend.waiting() {
+		ret = append(ret, QueuedMessage{ID: m.opts.ID, Message: makeCopy(m.m), Queued: m.queued})
+	}
+	return ret
+}
+
+// FlushQueue sends the queued messages now. Queued messages are normally sent as soon as the conversation becomes encrypted,
+// so this is only needed if that didn't happen, for example because they were queued while the conversation was being refreshed
+func (c *Conversation) FlushQueue() ([]ValidMessage, error) {
+	if c.msgState != encrypted {
+		return nil, newOtrErrorOfKind(ErrWrongState, "cannot send queued messages in unencrypted state").asConflict().inState(c.msgState)
+	}
+
+	c.expireQueuedMessages()
+
+	queued := c.resend.takeWaiting()
+	if len(queued) == 0 {
+		return nil, nil
+	}
+
+	msgs, err := c.sendQueued(queued, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.encodeAndCombine(msgs), nil
+}
+
+// DiscardQueue removes all queued messages without sending them
+func (c *Conversation) DiscardQueue() {
+	c.queuedMessageStatus(QueuedMessageDropped, c.resend.takeWaiting()...)
+}
@@ -0,0 +1,232 @@
+package otr3
+
+import (
+	"testing"
+	"time"
+)
+
+type queuedMessageStatusChange struct {
+	id      MessageID
+	message string
+	status  QueuedMessageStatus
+}
+
+func (c *Conversation) recordQueuedMessageStatus() *[]queuedMessageStatusChange {
+	var changes []queuedMessageStatusChange
+	c.SetQueuedMessageHandler(dynamicQueuedMessageHandler{func(id MessageID, message MessagePlaintext, status QueuedMessageStatus) {
+		changes = append(changes, queuedMessageStatusChange{id, string(message), status})
+	}})
+	return &changes
+}
+
+func conversationRequiringEncryption() *Conversation {
+	c := bobContextAfterAKE()
+	c.msgState = plainText
+	c.Policies = policies(allowV3 | requireEncryption)
+	return c
+}
+
+func Test_SendWithOptions_queuesMessagesWhenEncryptionIsRequired(t *testing.T) {
+	c := conversationRequiringEncryption()
+	changes := c.recordQueuedMessageStatus()
+
+	_, err := c.SendWithOptions(ValidMessage("hello"), SendOptions{ID: "1"})
+	assertNil(t, err)
+
+	assertDeepEquals(t, *changes, []queuedMessageStatusChange{{"1", "hello", QueuedMessageQueued}})
+
+	queued := c.QueuedMessages()
+	assertEquals(t, len(queued), 1)
+	assertEquals(t, queued[0].ID, MessageID("1"))
+	assertDeepEquals(t, queued[0].Message, MessagePlaintext("hello"))
+}
+
+func Test_QueuedMessages_doesntReturnMessagesAlreadySent(t *testing.T) {
+	c := &Conversation{}
+	c.lastMessage(MessagePlaintext("hello"), SendOptions{})
+
+	assertNil(t, c.QueuedMessages())
+}
+
+func Test_queue_dropsTheOldestMessageWhenFull(t *testing.T) {
+	c := conversationRequiringEncryption()
+	c.SetQueueLimits(2, 0)
+	changes := c.recordQueuedMessageStatus()
+
+	c.SendWithOptions(ValidMessage("one"), SendOptions{ID: "1"})
+	c.SendWithOptions(ValidMessage("two"), SendOptions{ID: "2"})
+	c.SendWithOptions(ValidMessage("three"), SendOptions{ID: "3"})
+
+	assertDeepEquals(t, (*changes)[2], queuedMessageStatusChange{"1", "one", QueuedMessageDropped})
+	assertDeepEquals(t, (*changes)[3], queuedMessageStatusChange{"3", "three", QueuedMessageQueued})
+	assertEquals(t, len(c.QueuedMessages()), 2)
+	assertEquals(t, c.QueuedMessages()[0].ID, MessageID("2"))
+}
+
+func Test_queue_keepsAHundredMessagesByDefault(t *testing.T) {
+	c := &Conversation{}
+	for i := 0; i < 150; i++ {
+		c.lastMessage(MessagePlaintext("hello"), SendOptions{})
+	}
+
+	assertEquals(t, len(c.resend.pending()), 100)
+}
+
+func Test_queue_expiresMessagesThatAreTooOld(t *testing.T) {
+	c := conversationRequiringEncryption()
+	c.SetQueueLimits(0, time.Minute)
+	changes := c.recordQueuedMessageStatus()
+
+	c.SendWithOptions(ValidMessage("one"), SendOptions{ID: "1"})
+	c.SendWithOptions(ValidMessage("two"), SendOptions{ID: "2"})
+	c.resend.queued.m[0].queued = time.Now().Add(-2 * time.Minute)
+
+	queued := c.QueuedMessages()
+
+	assertEquals(t, len(queued), 1)
+	assertEquals(t, queued[0].ID, MessageID("2"))
+	assertDeepEquals(t, (*changes)[2], queuedMessageStatusChange{"1", "one", QueuedMessageExpired})
+}
+
+func Test_DiscardQueue_dropsTheQueuedMessages(t *testing.T) {
+	c := conversationRequiringEncryption()
+	changes := c.recordQueuedMessageStatus()
+
+	c.SendWithOptions(ValidMessage("one"), SendOptions{ID: "1"})
+	c.DiscardQueue()
+
+	assertNil(t, c.QueuedMessages())
+	assertDeepEquals(t, (*changes)[1], queuedMessageStatusChange{"1", "one", QueuedMessageDropped})
+}
+
+func Test_FlushQueue_failsIfNotEncrypted(t *testing.T) {
+	c := conversationRequiringEncryption()
+	c.SendWithOptions(ValidMessage("one"), SendOptions{ID: "1"})
+
+	_, err := c.FlushQueue()
+
+	assertDeepEquals(t, err, newOtrErrorOfKind(ErrWrongState, "cannot send queued messages in unencrypted state").asConflict().inState(plainText))
+	assertEquals(t, len(c.QueuedMessages()), 1)
+}
+
+func Test_FlushQueue_sendsOnlyTheMessagesNotSentYet(t *testing.T) {
+	alice, bob := smpConversationsAfterAKE(t)
+	changes := alice.recordQueuedMessageStatus()
+
+	alice.Send(ValidMessage("already sent"))
+	alice.queueMessage(MessagePlaintext("waiting"), SendOptions{ID: "1"})
+
+	toSend, err := alice.FlushQueue()
+	assertNil(t, err)
+	assertEquals(t, len(toSend), 1)
+
+	plain, _, err := bob.Receive(toSend[0])
+	assertNil(t, err)
+	assertDeepEquals(t, plain, MessagePlaintext("waiting"))
+	assertDeepEquals(t, (*changes)[1], queuedMessageStatusChange{"1", "waiting", QueuedMessageSentEncrypted})
+	assertNil(t, alice.QueuedMessages())
+}
+
+func Test_FlushQueue_doesNothingWithAnEmptyQueue(t *testing.T) {
+	alice, _ := smpConversationsAfterAKE(t)
+
+	toSend, err := alice.FlushQueue()
+
+	assertNil(t, err)
+	assertNil(t, toSend)
+}
+
+func Test_retransmit_signalsThatQueuedMessagesWereSentEncrypted(t *testing.T) {
+	alice, bob := smpConversationsAfterAKE(t)
+	changes := alice.recordQueuedMessageStatus()
+
+	alice.queueMessage(MessagePlaintext("waiting"), SendOptions{ID: "1"})
+	alice.updateMayRetransmitTo(retransmitExact)
+	alice.updateLastSent()
+
+	toSend, err := alice.maybeRetransmit()
+	assertNil(t, err)
+	assertEquals(t, len(toSend), 1)
+
+	plain, _, _ := bob.Receive(ValidMessage(alice.encode(toSend[0])))
+	assertDeepEquals(t, plain, MessagePlaintext("waiting"))
+	assertDeepEquals(t, (*changes)[1], queuedMessageStatusChange{"1", "waiting", QueuedMessageSentEncrypted})
+}
+
+func Test_queue_doesntDropWaitingMessagesToKeepSentOnes(t *testing.T) {
+	c := conversationRequiringEncryption()
+	c.SetQueueLimits(2, 0)
+	changes := c.recordQueuedMessageStatus()
+
+	c.SendWithOptions(ValidMessage("one"), SendOptions{ID: "1"})
+	for i := 0; i < 5; i++ {
+		c.lastMessage(MessagePlaintext("sent"), SendOptions{})
+	}
+
+	assertDeepEquals(t, *changes, []queuedMessageStatusChange{{"1", "one", QueuedMessageQueued}})
+	assertEquals(t, len(c.QueuedMessages()), 1)
+	assertEquals(t, len(c.resend.pending()), 2)
+}
+
+func Test_maybeRetransmit_sendsWaitingMessagesWithoutThePrefixForResentMessages(t *testing.T) {
+	alice, bob := smpConversationsAfterAKE(t)
+	changes := alice.recordQueuedMessageStatus()
+
+	alice.resend.clear()
+	alice.lastMessage(MessagePlaintext("sent"), SendOptions{})
+	alice.queueMessage(MessagePlaintext("waiting"), SendOptions{ID: "1"})
+	alice.updateMayRetransmitTo(retransmitWithPrefix)
+	alice.updateLastSent()
+
+	var events []MessageEvent
+	alice.SetMessageEventHandler(dynamicMessageEventHandler{func(event MessageEvent, message []byte, err error, trace ...interface{}) {
+		events = append(events, event)
+	}})
+
+	toSend, err := alice.maybeRetransmit()
+	assertNil(t, err)
+	assertEquals(t, len(toSend), 2)
+
+	plain, _, _ := bob.Receive(ValidMessage(alice.encode(toSend[0])))
+	assertDeepEquals(t, plain, MessagePlaintext("[resent] sent"))
+	plain, _, _ = bob.Receive(ValidMessage(alice.encode(toSend[1])))
+	assertDeepEquals(t, plain, MessagePlaintext("waiting"))
+
+	assertDeepEquals(t, events, []MessageEvent{MessageEventMessageResent, MessageEventMessageSent})
+	assertDeepEquals(t, (*changes)[1], queuedMessageStatusChange{"1", "waiting", QueuedMessageSentEncrypted})
+}
+
+func Test_maybeRetransmit_sendsWaitingMessagesQueuedLongBeforeTheConversationIsEncrypted(t *testing.T) {
+	alice, bob := smpConversationsAfterAKE(t)
+
+	alice.queueMessage(MessagePlaintext("waiting"), SendOptions{ID: "1"})
+	alice.updateMayRetransmitTo(retransmitExact)
+	alice.heartbeat.lastSent = time.Now().Add(-10 * resendInterval)
+
+	toSend, err := alice.maybeRetransmit()
+	assertNil(t, err)
+	assertEquals(t, len(toSend), 1)
+
+	plain, _, _ := bob.Receive(ValidMessage(alice.encode(toSend[0])))
+	assertDeepEquals(t, plain, MessagePlaintext("waiting"))
+	assertNil(t, alice.QueuedMessages())
+}
+
+func Test_FlushQueue_keepsTheMessagesQueuedIfOneOfThemCantBeSent(t *testing.T) {
+	alice, _ := smpConversationsAfterAKE(t)
+	changes := alice.recordQueuedMessageStatus()
+
+	alice.queueMessage(MessagePlaintext("one"), SendOptions{ID: "1"})
+	alice.queueMessage(MessagePlaintext("two"), SendOptions{ID: "2", TLVs: []TLV{{Type: tlvTypeDisconnected}}})
+
+	toSend, err := alice.FlushQueue()
+
+	assertEquals(t, err != nil, true)
+	assertNil(t, toSend)
+	assertEquals(t, len(*changes), 2)
+
+	queued := alice.QueuedMessages()
+	assertEquals(t, len(queued), 2)
+	assertEquals(t, queued[0].ID, MessageID("1"))
+	assertEquals(t, queued[1].ID, MessageID("2"))
+}
@@ -2,7 +2,8 @@ package otr3
 
 // Receive handles a message from a peer. It returns a human readable message and zero or more messages to send back to the peer.
 func (c *Conversation) Receive(m ValidMessage) (plain MessagePlaintext, toSend []ValidMessage, err error) {
-	return c.receiveUnit(m, true)
+	info, toSend, err := c.ReceiveWithInfo(m)
+	return info.Plaintext, toSend, err
 }
 
 // Receive handles a message from a peer. It returns a human readable message and zero or more messages to send back to the peer.
@@ -30,9 +31,9 @@ func (c *Conversation) receiveUnit(m ValidMessage, forgetFragments bool) (plain
 		return nil, nil, errUnsupportedOTRVersion
 	case msgGuessFragment:
 		shouldForgetFragment = false
-		c.fragmentationContext, err = c.receiveFragment(c.fragmentationContext, message)
-		if fragmentsFinished(c.fragmentationContext) {
-			return c.withInjectionsPlain(c.receiveUnit(c.fragmentationContext.frag, false))
+		var complete []byte
+		if complete, err = c.receiveFragmentFrom(message); complete != nil {
+			return c.withInjectionsPlain(c.receiveUnit(complete, false))
 		}
 	case msgGuessUnknown:
 		c.messageEvent(MessageEventReceivedMessageUnrecognized)
@@ -41,7 +42,7 @@ func (c *Conversation) receiveUnit(m ValidMessage, forgetFragments bool) (plain
 	}
 
 	if shouldForgetFragment && forgetFragments {
-		c.fragmentationContext = forgetFragment()
+		c.forgetFragments(encodedSenderInstanceTag(message))
 	}
 
 	return c.withInjectionsPlain(c.toSendEncoded(plain, messagesToSend, err))
@@ -69,6 +70,11 @@ func (c *Conversation) receiveErrorMessage(message ValidMessage) (plain MessageP
 		c.updateMayRetransmitTo(retransmitWithPrefix)
 	}
 
+	if parsed := parseErrorMessage(msg); parsed != nil {
+		c.messageEventWithMessageAndError(MessageEventReceivedMessageGeneralError, withoutPotentialSpaceStart(msg), *parsed)
+		return
+	}
+
 	c.messageEventWithMessage(MessageEventReceivedMessageGeneralError, withoutPotentialSpaceStart(msg))
 	return
 }
@@ -118,6 +124,9 @@ func (c *Conversation) receivePlaintext(message ValidMessage) (plain MessagePlai
 
 func (c *Conversation) receiveTaggedPlaintext(message ValidMessage) (plain MessagePlaintext, toSend []messageWithHeader, err error) {
 	plain, toSend, err = c.processWhitespaceTag(message)
+	c.withReceiveInfo(func(info *ReceivedMessageInfo) {
+		info.WhitespaceTagged = true
+	})
 	c.checkPlaintextPolicies(plain)
 	return
 }
@@ -165,6 +174,10 @@ func (c *Conversation) receiveAKEMessage(msgType byte, messageBody []byte) (plai
 }
 
 func (c *Conversation) receiveDataMessage(messageHeader, messageBody []byte) (plain MessagePlaintext, toSend []messageWithHeader, err error) {
+	c.withReceiveInfo(func(info *ReceivedMessageInfo) {
+		info.SenderInstanceTag = senderInstanceTagFrom(messageHeader)
+	})
+
 	plain, toSend, err = c.maybeHeartbeat(c.processDataMessage(messageHeader, messageBody))
 	if err != nil {
 		c.notifyDataMessageError(err)
@@ -0,0 +1,88 @@
+package otr3
+
+// ReceivedMessageInfo describes a message handled by ReceiveWithInfo - the human readable message, how it arrived,
+// and the side effects processing it had on the conversation
+type ReceivedMessageInfo struct {
+	// Plaintext is the human readable message, the same that Receive returns
+	Plaintext MessagePlaintext
+
+	// Encrypted is true if the message was a data message that was successfully decrypted
+	Encrypted bool
+	// WhitespaceTagged is true if the message was a plaintext message carrying a whitespace tag
+	WhitespaceTagged bool
+	// Heartbeat is true if the message was an encrypted message without any content or TLVs except padding
+	Heartbeat bool
+
+	// SenderInstanceTag is the instance tag of the peer that sent the message. It is zero for OTR version 2 messages,
+	// and for messages that don't carry an instance tag
+	SenderInstanceTag uint32
+	// SenderKeyID and RecipientKeyID are the key IDs used to encrypt the message. They are only set if Encrypted is true
+	SenderKeyID    uint32
+	RecipientKeyID uint32
+
+	// TLVTypes are the types of the TLVs that were processed, in the order they appeared in the message
+	TLVTypes []uint16
+	// UnknownTLVs are the TLVs that neither the protocol nor a handler registered by the application knows about
+	UnknownTLVs []TLV
+	// SMPEvents are the SMP events that happened while processing the message
+	SMPEvents []ReceivedSMPEvent
+	// SymmetricKeys are the extra symmetric keys the peer asked us to use
+	SymmetricKeys []ReceivedSymmetricKey
+}
+
+// ReceivedSMPEvent is an SMP event that happened while a message was received.
+// The same event is also given to the SMP event handler
+type ReceivedSMPEvent struct {
+	Event           SMPEvent
+	ProgressPercent int
+	Question        string
+}
+
+// ReceivedSymmetricKey is a request to use the extra symmetric key, received in a message.
+// The same request is also given to the received key handler
+type ReceivedSymmetricKey struct {
+	Usage     uint32
+	UsageData []byte
+	Key       []byte
+}
+
+// ReceiveWithInfo handles a message from a peer. It works like Receive, but returns information about the message
+// together with the human readable message
+func (c *Conversation) ReceiveWithInfo(m ValidMessage) (info ReceivedMessageInfo, toSend []ValidMessage, err error) {
+	c.receiveInfo = &info
+	defer func() {
+		c.receiveInfo = nil
+	}()
+
+	c.expireQueuedMessages()
+
+	info.Plaintext, toSend, err = c.receiveUnit(m, true)
+	return
+}
+
+// withReceiveInfo calls f with the information about the message currently being received, if there is one
+func (c *Conversation) withReceiveInfo(f func(*ReceivedMessageInfo)) {
+	if c.receiveInfo != nil {
+		f(c.receiveInfo)
+	}
+}
+
+func senderInstanceTagFrom(header []byte) uint32 {
+	if len(header) < otrv3HeaderLen {
+		return 0
+	}
+	_, tag, _ := extractWord(header[messageHeaderPrefix:])
+	return tag
+}
+
+func isHeartbeat(plain MessagePlaintext, tlvs []tlv) bool {
+	if len(plain) > 0 {
+		return false
+	}
+	for _, t := range tlvs {
+		if t.tlvType != tlvTypePadding {
+			return false
+		}
+	}
+	return true
+}
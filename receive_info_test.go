@@ -0,0 +1,102 @@
+package otr3
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_ReceiveWithInfo_returnsThePlaintextOfAnUnencryptedMessage(t *testing.T) {
+	c := &Conversation{}
+	c.Policies = policies(allowV3)
+
+	info, toSend, err := c.ReceiveWithInfo(ValidMessage("hello"))
+
+	assertNil(t, err)
+	assertNil(t, toSend)
+	assertDeepEquals(t, info.Plaintext, MessagePlaintext("hello"))
+	assertFalse(t, info.Encrypted)
+	assertFalse(t, info.WhitespaceTagged)
+	assertFalse(t, info.Heartbeat)
+}
+
+func Test_ReceiveWithInfo_reportsAWhitespaceTaggedMessage(t *testing.T) {
+	c := &Conversation{}
+	c.Policies = policies(allowV3)
+
+	msg := append([]byte("hello"), genWhitespaceTag(policies(allowV3))...)
+	info, _, err := c.ReceiveWithInfo(msg)
+
+	assertNil(t, err)
+	assertDeepEquals(t, info.Plaintext, MessagePlaintext("hello"))
+	assertTrue(t, info.WhitespaceTagged)
+	assertFalse(t, info.Encrypted)
+}
+
+func Test_ReceiveWithInfo_reportsAnEncryptedMessage(t *testing.T) {
+	alice, bob := smpConversationsAfterAKE(t)
+
+	msg, err := alice.Send(ValidMessage("hello"))
+	assertNil(t, err)
+
+	info, _, err := bob.ReceiveWithInfo(msg[0])
+
+	assertNil(t, err)
+	assertDeepEquals(t, info.Plaintext, MessagePlaintext("hello"))
+	assertTrue(t, info.Encrypted)
+	assertFalse(t, info.Heartbeat)
+	assertEquals(t, info.SenderInstanceTag, alice.ourInstanceTag)
+	assertEquals(t, info.SenderKeyID, alice.keys.ourKeyID-1)
+	assertEquals(t, info.RecipientKeyID, alice.keys.theirKeyID)
+}
+
+func Test_ReceiveWithInfo_reportsAHeartbeat(t *testing.T) {
+	alice, bob := smpConversationsAfterAKE(t)
+	alice.heartbeat.lastSent = time.Now().Add(-2 * heartbeatInterval)
+
+	heartbeat, err := alice.potentialHeartbeat(MessagePlaintext("hello"))
+	assertNil(t, err)
+
+	info, _, err := bob.ReceiveWithInfo(ValidMessage(alice.encode(heartbeat)))
+
+	assertNil(t, err)
+	assertNil(t, info.Plaintext)
+	assertTrue(t, info.Encrypted)
+	assertTrue(t, info.Heartbeat)
+}
+
+func Test_ReceiveWithInfo_reportsTheTLVsAndSMPEvents(t *testing.T) {
+	alice, bob := smpConversationsAfterAKE(t)
+
+	msg, err := bob.StartAuthenticate("what's the secret?", []byte("secret"))
+	assertNil(t, err)
+
+	info, _, err := alice.ReceiveWithInfo(msg[0])
+
+	assertNil(t, err)
+	assertTrue(t, info.Encrypted)
+	assertFalse(t, info.Heartbeat)
+	assertDeepEquals(t, info.TLVTypes, []uint16{tlvTypeSMP1WithQuestion, tlvTypePadding})
+	assertDeepEquals(t, info.SMPEvents, []ReceivedSMPEvent{{SMPEventAskForAnswer, 25, "what's the secret?"}})
+}
+
+func Test_ReceiveWithInfo_reportsTheExtraSymmetricKeysReceived(t *testing.T) {
+	alice, bob := smpConversationsAfterAKE(t)
+
+	key, msg, err := alice.UseExtraSymmetricKey(42, []byte("usage data"))
+	assertNil(t, err)
+
+	info, _, err := bob.ReceiveWithInfo(msg[0])
+
+	assertNil(t, err)
+	assertDeepEquals(t, info.TLVTypes, []uint16{tlvTypeExtraSymmetricKey, tlvTypePadding})
+	assertDeepEquals(t, info.SymmetricKeys, []ReceivedSymmetricKey{{42, []byte("usage data"), key}})
+}
+
+func Test_ReceiveWithInfo_doesntLeaveInformationBehindForTheNextMessage(t *testing.T) {
+	c := &Conversation{}
+	c.Policies = policies(allowV3)
+
+	_, _, _ = c.ReceiveWithInfo(ValidMessage("hello"))
+
+	assertNil(t, c.receiveInfo)
+}
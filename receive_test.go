@@ -52,7 +52,7 @@ func Test_receiveDecoded_returnsErrorIfTheMessageIsCorrupt(t *testing.T) {
 	assertEquals(t, err, errWrongProtocolVersion)
 
 	_, _, err = cV3.receiveDecoded([]byte{0x00, 0x03, 0x56, 0x00, 0x00, 0x01, 0x02, 0x00, 0x00, 0x01, 0x01})
-	assertDeepEquals(t, err, newOtrError("unknown message type 0x56"))
+	assertDeepEquals(t, err, newOtrErrorOfKind(ErrMalformedMessage, "unknown message type 0x56").aboutMessage(0x56))
 }
 
 func Test_receivePlaintext_signalsAMessageEventThatItWasUnencryptedIfNotInPlaintextMessageMode(t *testing.T) {
@@ -236,10 +236,18 @@ func Test_Receive_returnsAnErrorIfWeReceiveARequestToStartAVersion1KeyExchange(t
 
 func Test_Receive_willResetFragmentationContextIfWeReceiveAnUnfragmentedMessage(t *testing.T) {
 	c := aliceContextAfterAKE()
-	c.fragmentationContext = fragmentationContext{[]byte("hello"), 2, 5}
+	c.fragments.buffers = map[uint32]fragmentBuffer{0: {ctx: fragmentationContext{[]byte("hello"), 2, 5}}}
 	c.Receive(ValidMessage("Hello World"))
 
-	assertNil(t, c.fragmentationContext.frag)
-	assertEquals(t, c.fragmentationContext.currentIndex, uint16(0))
-	assertEquals(t, c.fragmentationContext.currentLen, uint16(0))
+	assertEquals(t, len(c.fragments.buffers), 0)
+}
+
+func Test_receiveErrorMessage_willSignalTheRecognizedErrorCode(t *testing.T) {
+	c := aliceContextAfterAKE()
+	c.msgState = encrypted
+	m := []byte("?OTR Error: ERROR_2: Not in private state message")
+
+	c.expectMessageEvent(t, func() {
+		c.receiveErrorMessage(m)
+	}, MessageEventReceivedMessageGeneralError, []byte("ERROR_2: Not in private state message"), ReceivedErrorMessage{Code: ErrorCodeMessageNotInPrivate, Coded: true, Text: []byte("Not in private state message")})
 }
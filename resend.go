@@ -18,8 +18,25 @@ const (
 )
 
 type messageToResend struct {
-	m      MessagePlaintext
-	opaque []interface{}
+	m    MessagePlaintext
+	opts SendOptions
+}
+
+// queuedMessage is a message kept for sending or resending later. A waiting message is one the user asked to send before
+// the conversation was encrypted, the others have already been sent and are only kept in case they have to be resent
+type queuedMessage struct {
+	messageToResend
+	queued  time.Time
+	waiting bool
+}
+
+// defaultMaxQueuedMessages is the number of messages kept if no other limit has been set
+const defaultMaxQueuedMessages = 100
+
+// queuedMessages is a list of messages, oldest first
+type queuedMessages struct {
+	m []queuedMessage
+	sync.RWMutex
 }
 
 type resendContext struct {
@@ -27,41 +44,156 @@ type resendContext struct {
 	messageTransform func([]byte) []byte
 	retransmitting   bool
 
-	messages struct {
-		m []messageToResend
-		sync.RWMutex
-	}
+	maxMessages int
+	maxAge      time.Duration
+
+	// messages have already been sent, and are kept in case they have to be resent
+	messages queuedMessages
+	// queued are waiting to be sent once the conversation is encrypted
+	queued queuedMessages
 }
 
-func (r *resendContext) later(msg MessagePlaintext, opaque ...interface{}) {
+// later keeps a message that has already been sent, in case it has to be resent
+func (r *resendContext) later(msg MessagePlaintext, opts SendOptions) {
 	if r.retransmitting {
 		return
 	}
 
-	r.messages.Lock()
-	defer r.messages.Unlock()
+	r.remember(messageToResend{makeCopy(msg), opts})
+}
+
+// remember keeps a message that has been sent, even while retransmitting
+func (r *resendContext) remember(m messageToResend) {
+	r.messages.add(queuedMessage{m, time.Now(), false}, r.limit())
+}
+
+// queue keeps a message that hasn't been sent yet. It returns the messages dropped to make room for it
+func (r *resendContext) queue(msg MessagePlaintext, opts SendOptions) []queuedMessage {
+	return r.queued.add(queuedMessage{messageToResend{makeCopy(msg), opts}, time.Now(), true}, r.limit())
+}
+
+// requeue puts back messages that were taken but couldn't be sent, in front of the ones kept since
+func (r *resendContext) requeue(msgs []queuedMessage) {
+	var sent, waiting []queuedMessage
+	for _, m := range msgs {
+		if m.waiting {
+			waiting = append(waiting, m)
+		} else {
+			sent = append(sent, m)
+		}
+	}
+
+	r.messages.prepend(sent)
+	r.queued.prepend(waiting)
+}
+
+func (r *resendContext) limit() int {
+	if r.maxMessages == 0 {
+		return defaultMaxQueuedMessages
+	}
+	return r.maxMessages
+}
+
+// expire removes the waiting messages older than the maximum age, and returns them
+func (r *resendContext) expire(now time.Time) (expired []queuedMessage) {
+	if r.maxAge == 0 {
+		return nil
+	}
+
+	r.queued.Lock()
+	defer r.queued.Unlock()
 
-	if r.messages.m == nil {
-		r.messages.m = make([]messageToResend, 0, 5)
+	var kept []queuedMessage
+	for _, m := range r.queued.m {
+		if now.Sub(m.queued) > r.maxAge {
+			expired = append(expired, m)
+		} else {
+			kept = append(kept, m)
+		}
 	}
-	r.messages.m = append(r.messages.m, messageToResend{makeCopy(msg), opaque})
+	r.queued.m = kept
+
+	return expired
 }
 
 func (r *resendContext) pending() []messageToResend {
-	r.messages.RLock()
-	defer r.messages.RUnlock()
+	return r.messages.pending()
+}
 
-	ret := make([]messageToResend, len(r.messages.m))
-	copy(ret, r.messages.m)
+func (r *resendContext) waiting() []queuedMessage {
+	return r.queued.list()
+}
 
-	return ret
+// take removes the messages already sent and returns them
+func (r *resendContext) take() []queuedMessage {
+	return r.messages.take()
+}
+
+// takeWaiting removes the messages that haven't been sent yet, and returns them
+func (r *resendContext) takeWaiting() []queuedMessage {
+	return r.queued.take()
 }
 
 func (r *resendContext) clear() {
-	r.messages.Lock()
-	defer r.messages.Unlock()
+	r.take()
+}
+
+// add appends a message, and returns the oldest messages dropped to keep at most max of them
+func (q *queuedMessages) add(m queuedMessage, max int) (dropped []queuedMessage) {
+	q.Lock()
+	defer q.Unlock()
+
+	if q.m == nil {
+		q.m = make([]queuedMessage, 0, 5)
+	}
+	q.m = append(q.m, m)
+
+	if len(q.m) > max {
+		dropped = q.m[:len(q.m)-max]
+		q.m = append([]queuedMessage{}, q.m[len(q.m)-max:]...)
+	}
+
+	return dropped
+}
+
+func (q *queuedMessages) prepend(msgs []queuedMessage) {
+	if len(msgs) == 0 {
+		return
+	}
+
+	q.Lock()
+	defer q.Unlock()
+
+	q.m = append(append([]queuedMessage{}, msgs...), q.m...)
+}
+
+func (q *queuedMessages) list() []queuedMessage {
+	q.RLock()
+	defer q.RUnlock()
+
+	return append([]queuedMessage(nil), q.m...)
+}
+
+func (q *queuedMessages) pending() []messageToResend {
+	q.RLock()
+	defer q.RUnlock()
+
+	ret := make([]messageToResend, len(q.m))
+	for i, m := range q.m {
+		ret[i] = m.messageToResend
+	}
 
-	r.messages.m = nil
+	return ret
+}
+
+func (q *queuedMessages) take() []queuedMessage {
+	q.Lock()
+	defer q.Unlock()
+
+	ret := q.m
+	q.m = nil
+
+	return ret
 }
 
 func (r *resendContext) shouldRetransmit() bool {
@@ -87,8 +219,19 @@ func (c *Conversation) resendMessageTransformer() func([]byte) []byte {
 	return c.resend.messageTransform
 }
 
-func (c *Conversation) lastMessage(msg MessagePlaintext, opaque ...interface{}) {
-	c.resend.later(msg, opaque...)
+func (c *Conversation) lastMessage(msg MessagePlaintext, opts SendOptions) {
+	c.resend.later(msg, opts)
+}
+
+// queueMessage keeps a message to be sent once the conversation is encrypted
+func (c *Conversation) queueMessage(msg MessagePlaintext, opts SendOptions) {
+	dropped := c.resend.queue(msg, opts)
+	c.queuedMessageStatus(QueuedMessageDropped, dropped...)
+	c.queuedMessageStatus(QueuedMessageQueued, queuedMessage{messageToResend: messageToResend{msg, opts}, waiting: true})
+}
+
+func (c *Conversation) expireQueuedMessages() {
+	c.queuedMessageStatus(QueuedMessageExpired, c.resend.expire(time.Now())...)
 }
 
 func (c *Conversation) updateMayRetransmitTo(f retransmitFlag) {
@@ -100,20 +243,44 @@ func (c *Conversation) shouldRetransmit() bool {
 		c.heartbeat.lastSent.After(time.Now().Add(-resendInterval))
 }
 
+// maybeRetransmit is called when the conversation becomes encrypted. It resends the messages that were recently sent, if
+// the peer couldn't read them, and sends the messages waiting for the conversation to be encrypted
 func (c *Conversation) maybeRetransmit() ([]messageWithHeader, error) {
-	if !c.shouldRetransmit() {
+	c.expireQueuedMessages()
+
+	if c.theirKeyHeld {
 		return nil, nil
 	}
 
-	return c.retransmit()
+	var ret []messageWithHeader
+	if c.shouldRetransmit() {
+		resent, err := c.retransmit()
+		if err != nil {
+			return nil, err
+		}
+		ret = resent
+	}
+
+	waiting, err := c.sendQueued(c.resend.takeWaiting(), false)
+	if err != nil {
+		return ret, err
+	}
+
+	return append(ret, waiting...), nil
 }
 
 func (c *Conversation) retransmit() ([]messageWithHeader, error) {
-	msgs := c.resend.pending()
-	c.resend.clear()
-	ret := make([]messageWithHeader, 0, len(msgs))
+	return c.sendQueued(c.resend.take(), c.resend.mayRetransmit == retransmitWithPrefix)
+}
 
-	resending := c.resend.mayRetransmit == retransmitWithPrefix
+// sendQueued generates the data messages for the given messages. If that fails, none of them is sent and they are put back
+// where they were taken from
+func (c *Conversation) sendQueued(msgs []queuedMessage, resending bool) ([]messageWithHeader, error) {
+	if len(msgs) == 0 {
+		return nil, nil
+	}
+
+	ret := make([]messageWithHeader, 0, len(msgs))
 
 	c.resend.startRetransmitting()
 	defer c.resend.endRetransmitting()
@@ -123,8 +290,16 @@ func (c *Conversation) retransmit() ([]messageWithHeader, error) {
 		if resending {
 			msg = c.resendMessageTransformer()(msg)
 		}
-		dataMsg, _, err := c.genDataMsg(msg)
+
+		tlvs, err := tlvsFrom(msgx.opts.TLVs)
+		if err != nil {
+			c.resend.requeue(msgs)
+			return nil, err
+		}
+
+		dataMsg, _, err := c.genDataMsgWithOptions(msg, msgx.opts.flag(), tlvs, msgx.opts)
 		if err != nil {
+			c.resend.requeue(msgs)
 			return nil, err
 		}
 
@@ -140,7 +315,11 @@ func (c *Conversation) retransmit() ([]messageWithHeader, error) {
 		ev = MessageEventMessageResent
 	}
 	for _, msgx := range msgs {
-		c.messageEvent(ev, msgx.opaque...)
+		c.messageEvent(ev, msgx.opts.eventTrace()...)
+		c.queuedMessageStatus(QueuedMessageSentEncrypted, msgx)
+		if msgx.waiting {
+			c.resend.remember(msgx.messageToResend)
+		}
 	}
 
 	c.updateLastSent()
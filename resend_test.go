@@ -7,7 +7,7 @@ import (
 )
 
 func fixtureCorrectResend(c *Conversation) {
-	c.resend.later(MessagePlaintext("hello"))
+	c.resend.later(MessagePlaintext("hello"), SendOptions{})
 	c.resend.mayRetransmit = retransmitExact
 	c.updateLastSent()
 }
@@ -66,7 +66,6 @@ func Test_maybeRetransmit_createsADataMessageWithTheExactMessageWhenAskedToRetra
 	c := newConversation(otrV3{}, rand.Reader)
 	c.Policies.add(allowV3)
 	c.ourCurrentKey = bobPrivateKey
-	c.smp.secret = bnFromHex("ABCDE56321F9A9F8E364607C8C82DECD8E8E6209E2CB952C7E649620F5286FE3")
 
 	plain := plainDataMsg{
 		message: []byte(""),
@@ -78,7 +77,7 @@ func Test_maybeRetransmit_createsADataMessageWithTheExactMessageWhenAskedToRetra
 
 	fixtureCorrectResend(c)
 	c.resend.clear()
-	c.resend.later(MessagePlaintext("Something else to think about"))
+	c.resend.later(MessagePlaintext("Something else to think about"), SendOptions{})
 
 	res, err := c.maybeRetransmit()
 	assertNil(t, err)
@@ -93,7 +92,6 @@ func Test_maybeRetransmit_createsADataMessageWithTheResendPrefixAndMessageWhenAs
 	c := newConversation(otrV3{}, rand.Reader)
 	c.Policies.add(allowV3)
 	c.ourCurrentKey = bobPrivateKey
-	c.smp.secret = bnFromHex("ABCDE56321F9A9F8E364607C8C82DECD8E8E6209E2CB952C7E649620F5286FE3")
 
 	plain := plainDataMsg{
 		message: []byte(""),
@@ -106,7 +104,7 @@ func Test_maybeRetransmit_createsADataMessageWithTheResendPrefixAndMessageWhenAs
 	fixtureCorrectResend(c)
 	c.resend.clear()
 	c.resend.mayRetransmit = retransmitWithPrefix
-	c.resend.later(MessagePlaintext("Something else to think about"))
+	c.resend.later(MessagePlaintext("Something else to think about"), SendOptions{})
 
 	res, err := c.maybeRetransmit()
 	dec := fixtureDecryptDataMsg(res[0])
@@ -121,7 +119,6 @@ func Test_maybeRetransmit_createsADataMessageWithTheCustomResendPrefixAndMessage
 	c := newConversation(otrV3{}, rand.Reader)
 	c.Policies.add(allowV3)
 	c.ourCurrentKey = bobPrivateKey
-	c.smp.secret = bnFromHex("ABCDE56321F9A9F8E364607C8C82DECD8E8E6209E2CB952C7E649620F5286FE3")
 
 	plain := plainDataMsg{
 		message: []byte(""),
@@ -134,7 +131,7 @@ func Test_maybeRetransmit_createsADataMessageWithTheCustomResendPrefixAndMessage
 	fixtureCorrectResend(c)
 	c.resend.clear()
 	c.resend.mayRetransmit = retransmitWithPrefix
-	c.resend.later(MessagePlaintext("Something much more to think about"))
+	c.resend.later(MessagePlaintext("Something much more to think about"), SendOptions{})
 	c.resend.messageTransform = func(msg []byte) []byte {
 		return append(append([]byte("<resend>"), msg...), []byte("</resend>")...)
 	}
@@ -152,7 +149,6 @@ func Test_maybeRetransmit_updatesLastSentWhenSendingAMessage(t *testing.T) {
 	c := newConversation(otrV3{}, rand.Reader)
 	c.Policies.add(allowV3)
 	c.ourCurrentKey = bobPrivateKey
-	c.smp.secret = bnFromHex("ABCDE56321F9A9F8E364607C8C82DECD8E8E6209E2CB952C7E649620F5286FE3")
 
 	plain := plainDataMsg{
 		message: []byte(""),
@@ -176,7 +172,6 @@ func Test_maybeRetransmit_returnsErrorIfWeFailAtGeneratingDataMsg(t *testing.T)
 	c := newConversation(otrV3{}, rand.Reader)
 	c.Policies.add(allowV3)
 	c.ourCurrentKey = bobPrivateKey
-	c.smp.secret = bnFromHex("ABCDE56321F9A9F8E364607C8C82DECD8E8E6209E2CB952C7E649620F5286FE3")
 
 	plain := plainDataMsg{
 		message: []byte(""),
@@ -188,14 +183,13 @@ func Test_maybeRetransmit_returnsErrorIfWeFailAtGeneratingDataMsg(t *testing.T)
 	c.keys.ourKeyID = 0
 	_, err := c.maybeRetransmit()
 
-	assertEquals(t, err, newOtrConflictError("invalid key id for local peer"))
+	assertEquals(t, err, errInvalidOurKeyID)
 }
 
 func Test_maybeRetransmit_signalsMessageEventWhenResendingMessage(t *testing.T) {
 	c := newConversation(otrV3{}, rand.Reader)
 	c.Policies.add(allowV3)
 	c.ourCurrentKey = bobPrivateKey
-	c.smp.secret = bnFromHex("ABCDE56321F9A9F8E364607C8C82DECD8E8E6209E2CB952C7E649620F5286FE3")
 
 	plain := plainDataMsg{
 		message: []byte(""),
@@ -217,7 +211,6 @@ func Test_maybeRetransmit_signalMessageEventWhenSendingMessageExact(t *testing.T
 	c := newConversation(otrV3{}, rand.Reader)
 	c.Policies.add(allowV3)
 	c.ourCurrentKey = bobPrivateKey
-	c.smp.secret = bnFromHex("ABCDE56321F9A9F8E364607C8C82DECD8E8E6209E2CB952C7E649620F5286FE3")
 
 	plain := plainDataMsg{
 		message: []byte(""),
@@ -29,6 +29,8 @@ func (d dynamicSecurityEventHandler) HandleSecurityEvent(event SecurityEvent) {
 }
 
 func (c *Conversation) securityEvent(e SecurityEvent) {
+	c.publishEvent(Event{Kind: EventKindSecurity, Security: e})
+
 	if c.securityEventHandler != nil {
 		c.securityEventHandler.HandleSecurityEvent(e)
 	}
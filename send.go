@@ -5,9 +5,56 @@ import (
 	"bytes"
 )
 
+// MessageID identifies a message sent with SendWithOptions in the message events about it
+type MessageID string
+
+// SendOptions control how SendWithOptions sends a message
+type SendOptions struct {
+	// ID identifies the message. If set, it is given as the trace of the message events about the message,
+	// such as MessageEventMessageSent and MessageEventMessageResent. MessageIDFromTrace retrieves it
+	ID MessageID
+	// IgnoreUnreadable asks the peer not to report an error if it can't read the message. It is meant for messages
+	// without human readable content
+	IgnoreUnreadable bool
+	// TLVs are sent together with the message. Only types not used by the OTR protocol can be sent, and only encrypted
+	TLVs []TLV
+	// Padding is used for this message instead of the padding policy of the conversation
+	Padding PaddingPolicy
+
+	trace []interface{}
+}
+
+func (o SendOptions) flag() byte {
+	if o.IgnoreUnreadable {
+		return messageFlagIgnoreUnreadable
+	}
+	return messageFlagNormal
+}
+
+func (o SendOptions) eventTrace() []interface{} {
+	if o.ID != "" {
+		return []interface{}{o.ID}
+	}
+	return o.trace
+}
+
+// MessageIDFromTrace returns the ID of the message a message event is about, if the message was sent with an ID
+func MessageIDFromTrace(trace ...interface{}) (MessageID, bool) {
+	if len(trace) != 1 {
+		return "", false
+	}
+	id, ok := trace[0].(MessageID)
+	return id, ok
+}
+
 // Send takes a human readable message from the local user, possibly encrypts
 // it and returns zero or more messages to send to the peer.
 func (c *Conversation) Send(m ValidMessage, trace ...interface{}) ([]ValidMessage, error) {
+	return c.SendWithOptions(m, SendOptions{trace: trace})
+}
+
+// SendWithOptions works like Send, but lets the message be sent with flags, TLVs, padding or an ID
+func (c *Conversation) SendWithOptions(m ValidMessage, opts SendOptions) ([]ValidMessage, error) {
 	message := makeCopy(m)
 	defer wipeBytes(message)
 
@@ -20,33 +67,52 @@ func (c *Conversation) Send(m ValidMessage, trace ...interface{}) ([]ValidMessag
 		return nil, nil
 	}
 
+	if _, err := tlvsFrom(opts.TLVs); err != nil {
+		return nil, err
+	}
+
+	c.expireQueuedMessages()
+
 	switch c.msgState {
 	case plainText:
-		return c.withInjections(c.sendMessageOnPlaintext(message, trace...))
+		return c.withInjections(c.sendMessageOnPlaintext(message, opts))
 	case encrypted:
-		return c.withInjections(c.sendMessageOnEncrypted(message))
+		if c.theirKeyHeld {
+			c.queueMessage(MessagePlaintext(makeCopy(message)), opts)
+			return c.withInjections(nil, nil)
+		}
+		return c.withInjections(c.sendMessageOnEncrypted(message, opts))
 	case finished:
 		c.messageEvent(MessageEventConnectionEnded)
-		return c.withInjections(nil, newOtrError("cannot send message because secure conversation has finished"))
+		return c.withInjections(nil, newOtrErrorOfKind(ErrWrongState, "cannot send message because secure conversation has finished").inState(c.msgState))
 	}
 
-	return c.withInjections(nil, newOtrError("cannot send message in current state"))
+	return c.withInjections(nil, newOtrErrorOfKind(ErrWrongState, "cannot send message in current state").inState(c.msgState))
 }
 
-func (c *Conversation) sendMessageOnPlaintext(message ValidMessage, trace ...interface{}) ([]ValidMessage, error) {
+func (c *Conversation) sendMessageOnPlaintext(message ValidMessage, opts SendOptions) ([]ValidMessage, error) {
 	if c.Policies.has(requireEncryption) {
-		c.messageEvent(MessageEventEncryptionRequired, trace...)
+		c.messageEvent(MessageEventEncryptionRequired, opts.eventTrace()...)
 		c.updateLastSent()
 		c.updateMayRetransmitTo(retransmitExact)
-		c.lastMessage(MessagePlaintext(makeCopy(message)), trace...)
+		c.queueMessage(MessagePlaintext(makeCopy(message)), opts)
 		return []ValidMessage{c.QueryMessage()}, nil
 	}
 
+	if len(opts.TLVs) > 0 {
+		return nil, newOtrErrorOfKind(ErrWrongState, "cannot send TLVs in an unencrypted conversation").inState(c.msgState)
+	}
+
 	return []ValidMessage{makeCopy(c.appendWhitespaceTag(message))}, nil
 }
 
-func (c *Conversation) sendMessageOnEncrypted(message ValidMessage) ([]ValidMessage, error) {
-	result, _, err := c.createSerializedDataMessage(message, messageFlagNormal, []tlv{})
+func (c *Conversation) sendMessageOnEncrypted(message ValidMessage, opts SendOptions) ([]ValidMessage, error) {
+	tlvs, err := tlvsFrom(opts.TLVs)
+	if err != nil {
+		return nil, err
+	}
+
+	result, _, err := c.createSerializedDataMessageWithOptions(message, opts.flag(), tlvs, opts)
 	if err != nil {
 		c.messageEvent(MessageEventEncryptionError)
 		c.generatePotentialErrorMessage(ErrorCodeEncryptionError)
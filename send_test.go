@@ -2,6 +2,7 @@ package otr3
 
 import (
 	"bytes"
+	"crypto/rand"
 	"testing"
 )
 
@@ -77,9 +78,9 @@ func Test_Send_saveLastMessageWhenMsgIsPlainTextAndEncryptedIsExpected(t *testin
 
 	c.Send(m)
 
-	assertDeepEquals(t, c.resend.pending(),
+	assertDeepEquals(t, c.resend.queued.pending(),
 		[]messageToResend{
-			messageToResend{MessagePlaintext(m), nil},
+			messageToResend{MessagePlaintext(m), SendOptions{}},
 		})
 }
 
@@ -93,10 +94,10 @@ func Test_Send_saveLastMessageWhenMsgIsPlainTextAndEncryptedIsExpected_AndAddsAn
 	c.Send(m, 42, "hello")
 	c.Send(m2, 15, "something")
 
-	assertDeepEquals(t, c.resend.pending(),
+	assertDeepEquals(t, c.resend.queued.pending(),
 		[]messageToResend{
-			messageToResend{MessagePlaintext(m), []interface{}{42, "hello"}},
-			messageToResend{MessagePlaintext(m2), []interface{}{15, "something"}},
+			messageToResend{MessagePlaintext(m), SendOptions{trace: []interface{}{42, "hello"}}},
+			messageToResend{MessagePlaintext(m2), SendOptions{trace: []interface{}{15, "something"}}},
 		})
 }
 
@@ -158,3 +159,108 @@ func Test_Send_printsDebugStatementToStderrIfGivenMagicString(t *testing.T) {
     Received_Q: 0
 `)
 }
+
+func Test_SendWithOptions_givesTheMessageIDToTheEventsOfAQueuedMessage(t *testing.T) {
+	alice := &Conversation{Rand: rand.Reader}
+	alice.ourKeys = []PrivateKey{alicePrivateKey}
+	alice.Policies = policies(allowV3 | requireEncryption)
+
+	bob := &Conversation{Rand: rand.Reader}
+	bob.ourKeys = []PrivateKey{bobPrivateKey}
+	bob.Policies = policies(allowV3)
+
+	var events []MessageEvent
+	var ids []MessageID
+	alice.messageEventHandler = dynamicMessageEventHandler{func(event MessageEvent, message []byte, err error, trace ...interface{}) {
+		if id, ok := MessageIDFromTrace(trace...); ok {
+			events = append(events, event)
+			ids = append(ids, id)
+		}
+	}}
+
+	aliceMessages, err := alice.SendWithOptions(ValidMessage("hello"), SendOptions{ID: "msg-1"})
+	assertNil(t, err)
+
+	var received []MessagePlaintext
+	var bobMessages []ValidMessage
+	for len(aliceMessages)+len(bobMessages) > 0 {
+		bobMessages = nil
+		for _, m := range aliceMessages {
+			plain, toSend, _ := bob.Receive(m)
+			if plain != nil {
+				received = append(received, plain)
+			}
+			bobMessages = append(bobMessages, toSend...)
+		}
+
+		aliceMessages = nil
+		for _, m := range bobMessages {
+			_, toSend, _ := alice.Receive(m)
+			aliceMessages = append(aliceMessages, toSend...)
+		}
+	}
+
+	assertDeepEquals(t, events, []MessageEvent{MessageEventEncryptionRequired, MessageEventMessageSent})
+	assertDeepEquals(t, ids, []MessageID{"msg-1", "msg-1"})
+	assertDeepEquals(t, received, []MessagePlaintext{MessagePlaintext("hello")})
+}
+
+func Test_SendWithOptions_setsTheIgnoreUnreadableFlag(t *testing.T) {
+	alice, _ := smpConversationsAfterAKE(t)
+
+	toSend, err := alice.SendWithOptions(ValidMessage("hello"), SendOptions{IgnoreUnreadable: true})
+	assertNil(t, err)
+
+	dec, _ := alice.decode(encodedMessage(toSend[0]))
+	assertEquals(t, extractDataMessageFlag(dec[otrv3HeaderLen:]), messageFlagIgnoreUnreadable)
+}
+
+func Test_SendWithOptions_sendsTLVsTogetherWithTheMessage(t *testing.T) {
+	alice, bob := smpConversationsAfterAKE(t)
+
+	toSend, err := alice.SendWithOptions(ValidMessage("hello"), SendOptions{TLVs: []TLV{{0x100, []byte("one")}}})
+	assertNil(t, err)
+
+	info, _, err := bob.ReceiveWithInfo(toSend[0])
+	assertNil(t, err)
+	assertDeepEquals(t, info.Plaintext, MessagePlaintext("hello"))
+	assertDeepEquals(t, info.UnknownTLVs, []TLV{{0x100, []byte("one")}})
+}
+
+func Test_SendWithOptions_rejectsTLVsUsedByTheProtocol(t *testing.T) {
+	alice, _ := smpConversationsAfterAKE(t)
+
+	_, err := alice.SendWithOptions(ValidMessage("hello"), SendOptions{TLVs: []TLV{{Type: tlvTypeSMPAbort}}})
+
+	assertDeepEquals(t, err, newOtrError("TLV type 6 is reserved by the protocol"))
+}
+
+func Test_SendWithOptions_cantSendTLVsInPlaintext(t *testing.T) {
+	c := &Conversation{}
+	c.Policies = policies(allowV3)
+
+	_, err := c.SendWithOptions(ValidMessage("hello"), SendOptions{TLVs: []TLV{{Type: 0x100}}})
+
+	assertDeepEquals(t, err, newOtrErrorOfKind(ErrWrongState, "cannot send TLVs in an unencrypted conversation").inState(plainText))
+}
+
+func Test_SendWithOptions_usesThePaddingGivenInsteadOfThePolicyOfTheConversation(t *testing.T) {
+	alice, _ := smpConversationsAfterAKE(t)
+
+	padded, _ := alice.SendWithOptions(ValidMessage("hello"), SendOptions{})
+	unpadded, _ := alice.SendWithOptions(ValidMessage("hello"), SendOptions{Padding: NoPadding{}})
+
+	assertTrue(t, len(unpadded[0]) < len(padded[0]))
+}
+
+func Test_MessageIDFromTrace_returnsTheIDOfAMessage(t *testing.T) {
+	id, ok := MessageIDFromTrace(MessageID("abc"))
+	assertEquals(t, ok, true)
+	assertEquals(t, id, MessageID("abc"))
+
+	_, ok = MessageIDFromTrace(42, "hello")
+	assertEquals(t, ok, false)
+
+	_, ok = MessageIDFromTrace()
+	assertEquals(t, ok, false)
+}
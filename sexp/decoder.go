@@ -0,0 +1,369 @@
+package sexp
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+)
+
+// DefaultMaxDepth is the deepest list nesting a Decoder accepts unless told otherwise
+const DefaultMaxDepth = 64
+
+var (
+	// ErrUnexpectedEOF is returned when the input ends in the middle of a value
+	ErrUnexpectedEOF = errors.New("unexpected end of input")
+	// ErrUnexpectedListEnd is returned when a list end is found without a matching list start
+	ErrUnexpectedListEnd = errors.New("unexpected list end")
+	// ErrMaxDepthExceeded is returned when lists are nested deeper than the decoder allows
+	ErrMaxDepthExceeded = errors.New("maximum nesting depth exceeded")
+	// ErrInvalidEscape is returned when a string contains an unknown or truncated escape sequence
+	ErrInvalidEscape = errors.New("invalid escape sequence in string")
+	// ErrInvalidBigNum is returned when a bignum contains something else than hexadecimal digits
+	ErrInvalidBigNum = errors.New("invalid bignum")
+)
+
+// SyntaxError describes a problem found at a specific byte offset of the input
+type SyntaxError struct {
+	Offset int64
+	Err    error
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("sexp: %s at offset %d", e.Err, e.Offset)
+}
+
+// Unwrap returns the underlying error, one of the Err* values of this package
+func (e *SyntaxError) Unwrap() error {
+	return e.Err
+}
+
+// Token is a single lexical element of an S-Expression.
+// It is one of ListStart, ListEnd, Symbol, Sstring or BigNum.
+type Token interface{}
+
+// ListStart is the token for an opening parenthesis
+type ListStart struct{}
+
+// ListEnd is the token for a closing parenthesis
+type ListEnd struct{}
+
+// Decoder reads S-Expressions from an input stream, either token by token or as complete values.
+// Contrary to ReadValue, every failure is reported as a *SyntaxError carrying the offset where it happened.
+type Decoder struct {
+	// MaxDepth limits how deeply lists can be nested. Zero means DefaultMaxDepth.
+	MaxDepth int
+
+	r      *bufio.Reader
+	offset int64
+	depth  int
+
+	peeked       Token
+	peekedOffset int64
+	peekedErr    error
+	hasPeeked    bool
+
+	tokenOffset int64
+}
+
+// NewDecoder returns a new decoder reading from r
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{r: bufio.NewReader(r)}
+}
+
+// InputOffset returns the offset of the first byte after the last token consumed
+func (d *Decoder) InputOffset() int64 {
+	return d.offset
+}
+
+// TokenOffset returns the offset where the last token returned, by either Token or PeekToken, started
+func (d *Decoder) TokenOffset() int64 {
+	return d.tokenOffset
+}
+
+// Depth returns the number of lists currently open
+func (d *Decoder) Depth() int {
+	return d.depth
+}
+
+func (d *Decoder) maxDepth() int {
+	if d.MaxDepth == 0 {
+		return DefaultMaxDepth
+	}
+	return d.MaxDepth
+}
+
+func (d *Decoder) syntaxError(at int64, err error) error {
+	return &SyntaxError{Offset: at, Err: err}
+}
+
+func (d *Decoder) readByte() (byte, error) {
+	c, err := d.r.ReadByte()
+	if err == nil {
+		d.offset++
+	}
+	return c, err
+}
+
+func (d *Decoder) peekByte() (byte, error) {
+	b, err := d.r.Peek(1)
+	if err != nil {
+		return 0, err
+	}
+	return b[0], nil
+}
+
+func (d *Decoder) skipWhitespace() error {
+	for {
+		c, err := d.peekByte()
+		if err != nil {
+			return err
+		}
+		if !isWhitespace(c) {
+			return nil
+		}
+		d.readByte()
+	}
+}
+
+// Token returns the next token in the input stream. At the end of the input it returns nil and io.EOF
+func (d *Decoder) Token() (Token, error) {
+	if d.hasPeeked {
+		d.hasPeeked = false
+		d.offset, d.peekedOffset = d.peekedOffset, d.offset
+		return d.peeked, d.peekedErr
+	}
+	return d.readToken()
+}
+
+// PeekToken returns the next token without consuming it
+func (d *Decoder) PeekToken() (Token, error) {
+	if !d.hasPeeked {
+		start := d.offset
+		d.peeked, d.peekedErr = d.readToken()
+		d.peekedOffset = d.offset
+		d.offset = start
+		d.hasPeeked = true
+	}
+	return d.peeked, d.peekedErr
+}
+
+func (d *Decoder) readToken() (Token, error) {
+	if err := d.skipWhitespace(); err != nil {
+		if err == io.EOF && d.depth > 0 {
+			return nil, d.syntaxError(d.offset, ErrUnexpectedEOF)
+		}
+		return nil, err
+	}
+
+	d.tokenOffset = d.offset
+	c, _ := d.peekByte()
+	switch c {
+	case '(':
+		d.readByte()
+		if d.depth >= d.maxDepth() {
+			return nil, d.syntaxError(d.tokenOffset, ErrMaxDepthExceeded)
+		}
+		d.depth++
+		return ListStart{}, nil
+	case ')':
+		d.readByte()
+		if d.depth == 0 {
+			return nil, d.syntaxError(d.tokenOffset, ErrUnexpectedListEnd)
+		}
+		d.depth--
+		return ListEnd{}, nil
+	case '"':
+		return d.readString()
+	case '#':
+		return d.readBigNum()
+	default:
+		return d.readSymbol()
+	}
+}
+
+func (d *Decoder) readSymbol() (Token, error) {
+	var result []byte
+	for {
+		c, err := d.peekByte()
+		if err != nil || isNotSymbolCharacter(c) {
+			return Symbol(result), nil
+		}
+		d.readByte()
+		result = append(result, c)
+	}
+}
+
+func (d *Decoder) readBigNum() (Token, error) {
+	d.readByte()
+	var digits []byte
+	for {
+		c, err := d.readByte()
+		if err != nil {
+			return nil, d.syntaxError(d.offset, ErrUnexpectedEOF)
+		}
+		if c == '#' {
+			break
+		}
+		if !isHexDigit(c) {
+			return nil, d.syntaxError(d.offset-1, ErrInvalidBigNum)
+		}
+		digits = append(digits, c)
+	}
+
+	val, ok := new(big.Int).SetString(string(digits), 16)
+	if !ok {
+		return nil, d.syntaxError(d.tokenOffset, ErrInvalidBigNum)
+	}
+	return BigNum{val}, nil
+}
+
+func (d *Decoder) readString() (Token, error) {
+	d.readByte()
+	var result []byte
+	for {
+		c, err := d.readByte()
+		if err != nil {
+			return nil, d.syntaxError(d.offset, ErrUnexpectedEOF)
+		}
+		switch c {
+		case '"':
+			return Sstring(result), nil
+		case '\\':
+			if result, err = d.readEscape(result); err != nil {
+				return nil, err
+			}
+		default:
+			result = append(result, c)
+		}
+	}
+}
+
+var simpleEscapes = map[byte]byte{
+	'b':  '\b',
+	't':  '\t',
+	'v':  '\v',
+	'n':  '\n',
+	'f':  '\f',
+	'r':  '\r',
+	'"':  '"',
+	'\'': '\'',
+	'\\': '\\',
+}
+
+// readEscape reads the rest of an escape sequence, after the backslash, and appends the result to the given data.
+// The escapes understood are the same as those libgcrypt uses when printing S-Expressions in advanced format.
+func (d *Decoder) readEscape(result []byte) ([]byte, error) {
+	start := d.offset - 1
+	c, err := d.readByte()
+	if err != nil {
+		return nil, d.syntaxError(d.offset, ErrUnexpectedEOF)
+	}
+
+	if e, ok := simpleEscapes[c]; ok {
+		return append(result, e), nil
+	}
+
+	switch {
+	case c == '\n':
+		// An escaped line break is a line continuation
+		if n, _ := d.peekByte(); n == '\r' {
+			d.readByte()
+		}
+		return result, nil
+	case c == '\r':
+		if n, _ := d.peekByte(); n == '\n' {
+			d.readByte()
+		}
+		return result, nil
+	case c == 'x':
+		v, ok := d.readEscapeDigits(2, 16)
+		if !ok {
+			return nil, d.syntaxError(start, ErrInvalidEscape)
+		}
+		return append(result, v), nil
+	case c >= '0' && c <= '7':
+		d.r.UnreadByte()
+		d.offset--
+		v, ok := d.readEscapeDigits(3, 8)
+		if !ok {
+			return nil, d.syntaxError(start, ErrInvalidEscape)
+		}
+		return append(result, v), nil
+	}
+
+	return nil, d.syntaxError(start, ErrInvalidEscape)
+}
+
+func (d *Decoder) readEscapeDigits(n int, base uint) (byte, bool) {
+	v := uint(0)
+	for i := 0; i < n; i++ {
+		c, err := d.readByte()
+		if err != nil {
+			return 0, false
+		}
+		digit, ok := digitValue(c)
+		if !ok || digit >= base {
+			return 0, false
+		}
+		v = v*base + digit
+	}
+	return byte(v), v <= 0xFF
+}
+
+func digitValue(c byte) (uint, bool) {
+	switch {
+	case c >= '0' && c <= '9':
+		return uint(c - '0'), true
+	case c >= 'a' && c <= 'f':
+		return uint(c-'a') + 10, true
+	case c >= 'A' && c <= 'F':
+		return uint(c-'A') + 10, true
+	}
+	return 0, false
+}
+
+func isHexDigit(c byte) bool {
+	_, ok := digitValue(c)
+	return ok
+}
+
+// Decode reads the next complete S-Expression value from the input.
+// At the end of the input it returns nil and io.EOF
+func (d *Decoder) Decode() (Value, error) {
+	t, err := d.Token()
+	if err != nil {
+		return nil, err
+	}
+	return d.decodeFrom(t)
+}
+
+func (d *Decoder) decodeFrom(t Token) (Value, error) {
+	switch tt := t.(type) {
+	case ListStart:
+		return d.decodeListItems()
+	case Value:
+		return tt, nil
+	}
+	return nil, d.syntaxError(d.tokenOffset, ErrUnexpectedListEnd)
+}
+
+func (d *Decoder) decodeListItems() (Value, error) {
+	var values []Value
+	for {
+		t, err := d.Token()
+		if err != nil {
+			return nil, err
+		}
+		if _, end := t.(ListEnd); end {
+			return List(values...), nil
+		}
+
+		v, err := d.decodeFrom(t)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+	}
+}
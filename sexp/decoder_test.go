@@ -0,0 +1,122 @@
+package sexp
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func dec(s string) *Decoder {
+	return NewDecoder(bytes.NewBufferString(s))
+}
+
+func Test_Decoder_Token_returnsTheTokensOfAList(t *testing.T) {
+	d := dec(`(dsa "hello" #0A#)`)
+	var res []Token
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		assertEquals(t, err, nil)
+		res = append(res, tok)
+	}
+	assertDeepEquals(t, res, []Token{ListStart{}, Symbol("dsa"), Sstring("hello"), NewBigNum("0A"), ListEnd{}})
+}
+
+func Test_Decoder_PeekToken_doesNotConsumeTheToken(t *testing.T) {
+	d := dec(` (one)`)
+	tok, _ := d.PeekToken()
+	assertDeepEquals(t, tok, ListStart{})
+	assertEquals(t, d.InputOffset(), int64(0))
+	assertEquals(t, d.TokenOffset(), int64(1))
+
+	tok, _ = d.Token()
+	assertDeepEquals(t, tok, ListStart{})
+	assertEquals(t, d.InputOffset(), int64(2))
+
+	tok, _ = d.Token()
+	assertDeepEquals(t, tok, Symbol("one"))
+}
+
+func Test_Decoder_Decode_returnsACompleteValue(t *testing.T) {
+	res, err := dec(`(an-atom ("a string") #FF#)`).Decode()
+	assertEquals(t, err, nil)
+	assertDeepEquals(t, res, List(Symbol("an-atom"), List(Sstring("a string")), NewBigNum("FF")))
+}
+
+func Test_Decoder_Decode_returnsNilForAnEmptyList(t *testing.T) {
+	res, err := dec(`()`).Decode()
+	assertEquals(t, err, nil)
+	assertDeepEquals(t, res, List())
+}
+
+func Test_Decoder_Decode_returnsEOFAtTheEndOfInput(t *testing.T) {
+	_, err := dec(`  `).Decode()
+	assertEquals(t, err, io.EOF)
+}
+
+func Test_Decoder_handlesEscapesInStrings(t *testing.T) {
+	res, err := dec(`"a\"b\\c\n\t\x41\101\
+d"`).Decode()
+	assertEquals(t, err, nil)
+	assertDeepEquals(t, res, Sstring("a\"b\\c\n\tAAd"))
+}
+
+func Test_Decoder_returnsAnErrorForAnInvalidEscape(t *testing.T) {
+	_, err := dec(`"abc\qdef"`).Decode()
+	assertDeepEquals(t, err, &SyntaxError{Offset: 4, Err: ErrInvalidEscape})
+}
+
+func Test_Decoder_returnsAnErrorForATruncatedHexEscape(t *testing.T) {
+	_, err := dec(`"\x4"`).Decode()
+	assertDeepEquals(t, err, &SyntaxError{Offset: 1, Err: ErrInvalidEscape})
+}
+
+func Test_Decoder_returnsAnErrorForAnUnfinishedString(t *testing.T) {
+	_, err := dec(`("abc`).Decode()
+	assertDeepEquals(t, err, &SyntaxError{Offset: 5, Err: ErrUnexpectedEOF})
+}
+
+func Test_Decoder_returnsAnErrorForAnUnfinishedList(t *testing.T) {
+	_, err := dec(`(abc (def)`).Decode()
+	assertDeepEquals(t, err, &SyntaxError{Offset: 10, Err: ErrUnexpectedEOF})
+}
+
+func Test_Decoder_returnsAnErrorForAnUnexpectedListEnd(t *testing.T) {
+	_, err := dec(` )`).Decode()
+	assertDeepEquals(t, err, &SyntaxError{Offset: 1, Err: ErrUnexpectedListEnd})
+}
+
+func Test_Decoder_returnsAnErrorForAnInvalidBigNum(t *testing.T) {
+	_, err := dec(`#12G4#`).Decode()
+	assertDeepEquals(t, err, &SyntaxError{Offset: 3, Err: ErrInvalidBigNum})
+}
+
+func Test_Decoder_returnsAnErrorWhenNestingTooDeep(t *testing.T) {
+	d := dec(`(((())))`)
+	d.MaxDepth = 3
+	_, err := d.Decode()
+	assertDeepEquals(t, err, &SyntaxError{Offset: 3, Err: ErrMaxDepthExceeded})
+}
+
+func Test_Decoder_usesADefaultMaximumDepth(t *testing.T) {
+	in := strings.Repeat("(", DefaultMaxDepth+1) + strings.Repeat(")", DefaultMaxDepth+1)
+	_, err := dec(in).Decode()
+	assertDeepEquals(t, err, &SyntaxError{Offset: DefaultMaxDepth, Err: ErrMaxDepthExceeded})
+}
+
+func Test_SyntaxError_canBeUnwrapped(t *testing.T) {
+	var err error = &SyntaxError{Offset: 42, Err: ErrInvalidEscape}
+	assertEquals(t, errors.Is(err, ErrInvalidEscape), true)
+	assertEquals(t, err.Error(), "sexp: invalid escape sequence in string at offset 42")
+}
+
+func Test_Sstring_String_canBeDecodedAgain(t *testing.T) {
+	orig := Sstring("a \"quoted\"\\ value\n\x01")
+	res, err := dec(orig.String()).Decode()
+	assertEquals(t, err, nil)
+	assertDeepEquals(t, res, orig)
+}
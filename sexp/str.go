@@ -1,6 +1,10 @@
 package sexp
 
-import "bufio"
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+)
 
 // Sstring represents an S-Expression symbol.
 type Sstring string
@@ -15,9 +19,32 @@ func (s Sstring) Second() Value {
 	panic("not valid to call Second on an SString")
 }
 
-// String returns the string quoted as a string in an S-Expression
+// String returns the string quoted as a string in an S-Expression.
+// Quotes, backslashes and control characters are escaped so the result can be read back by a Decoder
 func (s Sstring) String() string {
-	return "\"" + string(s) + "\""
+	var b bytes.Buffer
+	b.WriteByte('"')
+	for _, c := range []byte(s) {
+		switch c {
+		case '"', '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case '\n':
+			b.WriteString("\\n")
+		case '\r':
+			b.WriteString("\\r")
+		case '\t':
+			b.WriteString("\\t")
+		default:
+			if c < 0x20 || c == 0x7F {
+				fmt.Fprintf(&b, "\\x%02x", c)
+			} else {
+				b.WriteByte(c)
+			}
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
 }
 
 // Value returns the string as a string
@@ -1,109 +1,135 @@
 package otr3
 
-import "math/big"
-
-type smp struct {
-	state    smpState
-	question *string
-	secret   *big.Int
-	s1       *smp1State
-	s2       *smp2State
-	s3       *smp3State
-}
+import (
+	"math/big"
+
+	"github.com/twstrike/otr3/smp"
+)
 
-const smpVersion = 1
+// ensureSMP makes sure the conversation has an SMP state, and that it uses the current settings of the conversation
+func (c *Conversation) ensureSMP() *smp.Protocol {
+	if c.smp == nil {
+		c.smp = &smp.Protocol{}
+	}
 
-func (s *smp) wipe() {
-	s.state = nil
-	s.question = nil
-	wipeBigInt(s.secret)
-	s.secret = nil
-	s.s1 = nil
-	s.s2 = nil
-	s.s3 = nil
+	c.smp.Rand = c.rand()
+	c.smp.Version = c.smpVersion()
+	c.smp.EventHandler = smpEventAdapter{c}
+	c.smp.Timeout = c.smpTimeout
+
+	return c.smp
 }
 
-func (s *smp) ensureSMP() {
-	if s.state != nil {
-		return
+func (c *Conversation) smpVersion() smp.Version {
+	if c.version == nil {
+		return smp.Version3
 	}
+	return c.version.smpVersion()
+}
 
-	s.state = smpStateExpect1{}
+func (c *Conversation) wipeSMP() {
+	if c.smp != nil {
+		c.smp.Wipe()
+		c.smp = nil
+	}
+	c.theirSMPNormalization = smp.NormalizeNone
 }
 
 // SMPQuestion returns the current SMP question and ok if there is one, and not ok if there isn't one.
 func (c *Conversation) SMPQuestion() (string, bool) {
-	if c.smp.question == nil {
+	if c.smp == nil {
 		return "", false
 	}
-	return *c.smp.question, true
+	return c.smp.Question()
 }
 
-func generateSMPSecret(initiatorFingerprint, recipientFingerprint, ssid, secret []byte, v otrVersion) *big.Int {
-	h := v.hash2Instance()
-	h.Write([]byte{smpVersion})
-	h.Write(initiatorFingerprint)
-	h.Write(recipientFingerprint)
-	h.Write(ssid)
-	h.Write(secret)
-	return new(big.Int).SetBytes(h.Sum(nil))
+// SMPSecretNormalization returns how secrets are normalized before they are used for authentication. It can be shown
+// to the user, for example to explain that answers are compared without regard to case, or that both peers need the same setting.
+func (c *Conversation) SMPSecretNormalization() smp.Normalization {
+	return c.smpNormalization
 }
 
-func generateDZKP(r, a, c *big.Int) *big.Int {
-	return subMod(r, mul(a, c), q)
+// TheirSMPSecretNormalization returns how the peer normalizes the secrets in the current authentication. Peers that don't
+// announce a normalization, such as libotr, use the secrets exactly as given
+func (c *Conversation) TheirSMPSecretNormalization() smp.Normalization {
+	return c.theirSMPNormalization
 }
 
-func generateZKP(r, a *big.Int, ix byte, v otrVersion) (c, d *big.Int) {
-	c = hashMPIsBN(v.hash2Instance(), ix, modExp(g1, r))
-	d = generateDZKP(r, a, c)
-	return
+// announcesSMPSecretNormalization tells if the SMP messages of the type are sent by a peer that has already used its secret,
+// so they are sent along the normalization of that secret
+func announcesSMPSecretNormalization(tp uint16) bool {
+	return tp == smp.TypeMessage1 || tp == smp.TypeMessage1WithQuestion || tp == smp.TypeMessage2
 }
 
-func verifyZKP(d, gen, c *big.Int, ix byte, v otrVersion) bool {
-	r := modExp(g1, d)
-	s := modExp(gen, c)
-	t := hashMPIsBN(v.hash2Instance(), ix, mulMod(r, s, p))
-	return eq(c, t)
+func smpSecretNormalizationTLV(n smp.Normalization) tlv {
+	return tlv{
+		tlvType:   tlvTypeSMPSecretNormalization,
+		tlvLength: 4,
+		tlvValue:  appendWord(nil, uint32(n)),
+	}
 }
 
-func verifyZKP2(g2, g3, d5, d6, pb, qb, cp *big.Int, ix byte, v otrVersion) bool {
-	l := mulMod(
-		modExp(g3, d5),
-		modExp(pb, cp),
-		p)
-	r := mulMod(mul(modExp(g1, d5),
-		modExp(g2, d6)),
-		modExp(qb, cp),
-		p)
-	t := hashMPIsBN(v.hash2Instance(), ix, l, r)
-	return eq(cp, t)
+func (c *Conversation) processSMPSecretNormalizationTLV(t tlv, x dataMessageExtra) (*tlv, error) {
+	_, n, ok := extractWord(t.tlvValue)
+	if !ok {
+		return nil, newOtrErrorOfKind(ErrMalformedMessage, "corrupt data message").aboutMessage(msgTypeData)
+	}
+
+	announced := smp.Normalization(n)
+	c.announcedSMPNormalization = &announced
+	return nil, nil
 }
 
-func verifyZKP3(cp, g2, g3, d5, d6, pa, qa *big.Int, ix byte, v otrVersion) bool {
-	l := mulMod(modExp(g3, d5), modExp(pa, cp), p)
-	r := mulMod(mul(modExp(g1, d5), modExp(g2, d6)), modExp(qa, cp), p)
-	t := hashMPIsBN(v.hash2Instance(), ix, l, r)
-	return eq(cp, t)
+// checkSMPSecretNormalization is called when an SMP message that announces the normalization of the peer arrives.
+// It signals if the peer doesn't normalize the secret the same way we do, since the authentication would fail then
+func (c *Conversation) checkSMPSecretNormalization() {
+	c.theirSMPNormalization = smp.NormalizeNone
+	if c.announcedSMPNormalization != nil {
+		c.theirSMPNormalization = *c.announcedSMPNormalization
+	}
+	c.announcedSMPNormalization = nil
+
+	if c.theirSMPNormalization != c.smpNormalization {
+		c.smpEventWithQuestion(SMPEventNormalizationMismatch, 0, "")
+	}
 }
 
-func verifyZKP4(cr, g3a, d7, qaqb, ra *big.Int, ix byte, v otrVersion) bool {
-	l := mulMod(modExp(g1, d7), modExp(g3a, cr), p)
-	r := mulMod(modExp(qaqb, d7), modExp(ra, cr), p)
-	t := hashMPIsBN(v.hash2Instance(), ix, l, r)
-	return eq(cr, t)
+// generateSMPSecret binds the fingerprints and the ssid of the conversation to the secret the users share.
+// Using ssid here should always be safe - we can't be in an encrypted state without having gone through the AKE
+func (c *Conversation) generateSMPSecret(initiator bool, mutualSecret []byte) *big.Int {
+	ours := c.ourCurrentKey.PublicKey().Fingerprint()
+	theirs := c.theirKey.Fingerprint()
+	if initiator {
+		return c.smpNormalization.GenerateSecret(ours, theirs, c.ssid[:], mutualSecret)
+	}
+	return c.smpNormalization.GenerateSecret(theirs, ours, c.ssid[:], mutualSecret)
 }
 
-func genSMPTLV(tp uint16, mpis ...*big.Int) tlv {
-	data := make([]byte, 0, 1000)
+func (c *Conversation) receiveSMP(m smp.Message) (*tlv, error) {
+	toSend, err := c.ensureSMP().Receive(m)
+
+	if err != nil {
+		return nil, smpError(err)
+	}
 
-	data = appendWord(data, uint32(len(mpis)))
-	data = appendMPIs(data, mpis...)
-	length := uint16(len(data))
-	out := tlv{
-		tlvType:   tp,
-		tlvLength: length,
-		tlvValue:  data,
+	if toSend == nil {
+		return nil, nil
 	}
 
-	return out
+	result := smpMessageTLV(toSend)
+
+	return &result, nil
+}
+
+// smpError returns the error from the SMP package as an error of this package, with the kind that matches it
+func smpError(err error) error {
+	switch err {
+	case smp.ErrShortRandomRead:
+		return errShortRandomRead.causedBy(err)
+	case smp.ErrNotWaitingForSecret:
+		return errNotWaitingForSMPSecret.causedBy(err)
+	case smp.ErrInvalidMessage:
+		return errInvalidSMPMessage.causedBy(err)
+	}
+	return newOtrErrorOfKind(ErrMalformedMessage, err.Error()).causedBy(err)
 }
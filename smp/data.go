@@ -0,0 +1,108 @@
+package smp
+
+import (
+	"hash"
+	"io"
+	"math/big"
+)
+
+func appendWord(l []byte, r uint32) []byte {
+	return append(l, byte(r>>24), byte(r>>16), byte(r>>8), byte(r))
+}
+
+func appendShort(l []byte, r uint16) []byte {
+	return append(l, byte(r>>8), byte(r))
+}
+
+func appendMPI(l []byte, r *big.Int) []byte {
+	b := r.Bytes()
+	return append(appendWord(l, uint32(len(b))), b...)
+}
+
+func appendMPIs(l []byte, r ...*big.Int) []byte {
+	for _, mpi := range r {
+		l = appendMPI(l, mpi)
+	}
+	return l
+}
+
+func extractWord(d []byte) ([]byte, uint32, bool) {
+	if len(d) < 4 {
+		return nil, 0, false
+	}
+
+	return d[4:], uint32(d[0])<<24 |
+		uint32(d[1])<<16 |
+		uint32(d[2])<<8 |
+		uint32(d[3]), true
+}
+
+func extractShort(d []byte) ([]byte, uint16, bool) {
+	if len(d) < 2 {
+		return nil, 0, false
+	}
+
+	return d[2:], uint16(d[0])<<8 |
+		uint16(d[1]), true
+}
+
+func extractMPI(d []byte) (newPoint []byte, mpi *big.Int, ok bool) {
+	d, mpiLen, ok := extractWord(d)
+	if !ok || len(d) < int(mpiLen) {
+		return nil, nil, false
+	}
+
+	mpi = new(big.Int).SetBytes(d[:int(mpiLen)])
+	newPoint = d[int(mpiLen):]
+	ok = true
+	return
+}
+
+func extractMPIs(d []byte) ([]byte, []*big.Int, bool) {
+	current, mpiCount, ok := extractWord(d)
+	if !ok {
+		return nil, nil, false
+	}
+	// Every MPI takes at least the four bytes of its length, so a larger count can't be valid
+	if int64(mpiCount) > int64(len(current)/4) {
+		return nil, nil, false
+	}
+	result := make([]*big.Int, int(mpiCount))
+	for i := 0; i < int(mpiCount); i++ {
+		current, result[i], ok = extractMPI(current)
+		if !ok {
+			return nil, nil, false
+		}
+	}
+	return current, result, true
+}
+
+func hashMPIs(h hash.Hash, magic byte, mpis ...*big.Int) []byte {
+	h.Reset()
+	h.Write([]byte{magic})
+	for _, mpi := range mpis {
+		h.Write(appendMPI(nil, mpi))
+	}
+	return h.Sum(nil)
+}
+
+func hashMPIsBN(h hash.Hash, magic byte, mpis ...*big.Int) *big.Int {
+	return new(big.Int).SetBytes(hashMPIs(h, magic, mpis...))
+}
+
+func randMPI(r io.Reader, b []byte) (*big.Int, error) {
+	if _, err := io.ReadFull(r, b); err != nil {
+		return nil, ErrShortRandomRead
+	}
+
+	return new(big.Int).SetBytes(b), nil
+}
+
+func firstError(es ...error) error {
+	for _, e := range es {
+		if e != nil {
+			return e
+		}
+	}
+	return nil
+}
@@ -0,0 +1,12 @@
+package smp
+
+import "errors"
+
+var (
+	// ErrShortRandomRead is returned when the source of randomness can't provide enough data
+	ErrShortRandomRead = errors.New("short read from random source")
+	// ErrNotWaitingForSecret is returned when a secret is provided while the protocol isn't waiting for one
+	ErrNotWaitingForSecret = errors.New("not expected SMP secret to be provided now")
+	// ErrInvalidMessage is returned when a message can't be parsed
+	ErrInvalidMessage = errors.New("invalid SMP message")
+)
@@ -0,0 +1,64 @@
+package smp
+
+// Event describes the progress of the protocol to the user interface
+type Event int
+
+const (
+	// EventError means the protocol was aborted because a message arrived that wasn't expected in the current state
+	EventError Event = iota
+	// EventAbort means the other party aborted the protocol
+	EventAbort
+	// EventCheated means the protocol was aborted because a message from the other party failed verification
+	EventCheated
+	// EventAskForAnswer means the user should answer the question asked by the other party
+	EventAskForAnswer
+	// EventAskForSecret means the user should provide the shared secret
+	EventAskForSecret
+	// EventInProgress means the protocol is progressing
+	EventInProgress
+	// EventSuccess means both parties have the same secret
+	EventSuccess
+	// EventFailure means the parties don't have the same secret
+	EventFailure
+)
+
+// EventHandler is notified about the progress of the protocol
+type EventHandler interface {
+	// HandleEvent is called with the event, how far the protocol has come and the question from the other party, if any
+	HandleEvent(event Event, progressPercent int, question string)
+}
+
+func (pr *Protocol) event(e Event, percent int) {
+	if pr.EventHandler != nil {
+		pr.EventHandler.HandleEvent(e, percent, "")
+	}
+}
+
+func (pr *Protocol) eventWithQuestion(e Event, percent int, question string) {
+	if pr.EventHandler != nil {
+		pr.EventHandler.HandleEvent(e, percent, question)
+	}
+}
+
+func (e Event) String() string {
+	switch e {
+	case EventError:
+		return "EventError"
+	case EventAbort:
+		return "EventAbort"
+	case EventCheated:
+		return "EventCheated"
+	case EventAskForAnswer:
+		return "EventAskForAnswer"
+	case EventAskForSecret:
+		return "EventAskForSecret"
+	case EventInProgress:
+		return "EventInProgress"
+	case EventSuccess:
+		return "EventSuccess"
+	case EventFailure:
+		return "EventFailure"
+	default:
+		return "SMP EVENT: (THIS SHOULD NEVER HAPPEN)"
+	}
+}
@@ -0,0 +1,15 @@
+package smp
+
+import "testing"
+
+func Test_Event_String_returnsTheNameOfTheEvent(t *testing.T) {
+	assertEquals(t, EventError.String(), "EventError")
+	assertEquals(t, EventAbort.String(), "EventAbort")
+	assertEquals(t, EventCheated.String(), "EventCheated")
+	assertEquals(t, EventAskForAnswer.String(), "EventAskForAnswer")
+	assertEquals(t, EventAskForSecret.String(), "EventAskForSecret")
+	assertEquals(t, EventInProgress.String(), "EventInProgress")
+	assertEquals(t, EventSuccess.String(), "EventSuccess")
+	assertEquals(t, EventFailure.String(), "EventFailure")
+	assertEquals(t, Event(100).String(), "SMP EVENT: (THIS SHOULD NEVER HAPPEN)")
+}
@@ -1,4 +1,4 @@
-package otr3
+package smp
 
 import (
 	"io"
@@ -45,16 +45,16 @@ func fixtureSecret() *big.Int {
 	return bnFromHex("D9B2E56321F9A9F8E364607C8C82DECD8E8E6209E2CB952C7E649620F5286FE3")
 }
 
-func fixtureSmp1() *smp1State {
-	var s smp1State
+func fixtureSmp1() *state1 {
+	var s state1
 	s.a2 = fixtureShort1
 	s.a3 = fixtureShort2
 	s.msg = fixtureMessage1()
 	return &s
 }
 
-func fixtureSmp2() *smp2State {
-	var s smp2State
+func fixtureSmp2() *state2 {
+	var s state2
 	s.b2 = fixtureShort1
 	s.b3 = fixtureShort2
 	s.r2 = fixtureShort3
@@ -71,8 +71,8 @@ func fixtureSmp2() *smp2State {
 	return &s
 }
 
-func fixtureSmp3() *smp3State {
-	var s smp3State
+func fixtureSmp3() *state3 {
+	var s state3
 	s.x = fixtureShort1
 	s.r4 = fixtureShort2
 	s.r5 = fixtureShort3
@@ -84,8 +84,8 @@ func fixtureSmp3() *smp3State {
 	return &s
 }
 
-func fixtureMessage1() smp1Message {
-	return smp1Message{
+func fixtureMessage1() Message1 {
+	return Message1{
 		g2a: bnFromHex("8a88c345c63aa25dab9815f8c51f6b7b621a12d31c8220a0579381c1e2e85a2275e2407c79c8e6e1f72ae765804e6b4562ac1b2d634313c70d59752ac119c6da5cb95dde3eedd9c48595b37256f5b64c56fb938eb1131447c9af9054b42841c57d1f41fe5aa510e2bd2965434f46dd0473c60d6114da088c7047760b00bc10287a03afc4c4f30e1c7dd7c9dbd51bdbd049eb2b8921cbdc72b4f69309f61e559c2d6dec9c9ce6f38ccb4dfd07f4cf2cf6e76279b88b297848c473e13f091a0f77"),
 		g3a: bnFromHex("d275468351fd48246e406ee74a8dc3db6ee335067bfa63300ce6a23867a1b2beddbdae9a8a36555fd4837f3ef8bad4f7fd5d7b4f346d7c7b7cb64bd7707eeb515902c66aa0c9323931364471ab93dd315f65c6624c956d74680863a9388cd5d89f1b5033b1cf232b8b6dcffaaea195de4e17cc1ba4c99497be18c011b2ad7742b43fa9ee3f95f7b6da02c8e894d054eb178a7822273655dc286ad15874687fe6671908d83662e7a529744ce4ea8dad49290d19dbe6caba202a825a20a27ee98a"),
 		c2:  bnFromHex("d3b6ef5528fa97e983395bec165fa4ced7657bdabf3742d60880965c369c880c"),
@@ -95,8 +95,8 @@ func fixtureMessage1() smp1Message {
 	}
 }
 
-func fixtureMessage1Q() smp1Message {
-	return smp1Message{
+func fixtureMessage1Q() Message1 {
+	return Message1{
 		g2a:         bnFromHex("8a88c345c63aa25dab9815f8c51f6b7b621a12d31c8220a0579381c1e2e85a2275e2407c79c8e6e1f72ae765804e6b4562ac1b2d634313c70d59752ac119c6da5cb95dde3eedd9c48595b37256f5b64c56fb938eb1131447c9af9054b42841c57d1f41fe5aa510e2bd2965434f46dd0473c60d6114da088c7047760b00bc10287a03afc4c4f30e1c7dd7c9dbd51bdbd049eb2b8921cbdc72b4f69309f61e559c2d6dec9c9ce6f38ccb4dfd07f4cf2cf6e76279b88b297848c473e13f091a0f77"),
 		g3a:         bnFromHex("d275468351fd48246e406ee74a8dc3db6ee335067bfa63300ce6a23867a1b2beddbdae9a8a36555fd4837f3ef8bad4f7fd5d7b4f346d7c7b7cb64bd7707eeb515902c66aa0c9323931364471ab93dd315f65c6624c956d74680863a9388cd5d89f1b5033b1cf232b8b6dcffaaea195de4e17cc1ba4c99497be18c011b2ad7742b43fa9ee3f95f7b6da02c8e894d054eb178a7822273655dc286ad15874687fe6671908d83662e7a529744ce4ea8dad49290d19dbe6caba202a825a20a27ee98a"),
 		c2:          bnFromHex("d3b6ef5528fa97e983395bec165fa4ced7657bdabf3742d60880965c369c880c"),
@@ -108,15 +108,15 @@ func fixtureMessage1Q() smp1Message {
 	}
 }
 
-func fixtureMessage1v3() smp1Message {
-	return smp1Message{
+func fixtureMessage1v3() Message1 {
+	return Message1{
 		g2a: bnFromHex("ff4fb16e465739dff9297312090c2a0271d0579e5871746311b4b4b1cecb4404512f21936268f9903bf7b9ec21f9f68151ece99c892c3adbbccf4511e6d3ddba25f11cf15d140f5db7a2a8b1e4c17d4681ac9466e84c3e518e80c3c1a16c109951e9a4adf2818e7a6ccd6df9d1759065c6a43bb34c0692081619865dec358dba5a2e17cb7f69d998259f26965c794d013b15606e8503968836b284be3929438e46b845b19c0c724e8aee2aff162bbbd95a8195f83f4245f3281ce3a1d7872c92"),
 		g3a: bnFromHex("39eaa0273de38f9a16078890a51c37bfce0f113ba445ef54c0f1e72c667a3cbe8d2f4587c3eaad9630027f56543f58f0f0633250287ef6de17c7313e5b8516eace4bddb1d9cdfa7729a48db9255e073f6f82ab37684843a839d785d330295322d75208093566fbec4bd01e8f462ee71e393af34de8688a5244b8aaf5fae2019308b3abd790c5b1eb971bf4505d376af071413389d56332cfe5b98fb30b77f72ddf2a629275b68c364de8f76137aa953ce9b3746d2c919a9827459f08ead78c71"),
 	}
 }
 
-func fixtureMessage2() smp2Message {
-	return smp2Message{
+func fixtureMessage2() Message2 {
+	return Message2{
 		g2b: bnFromHex("8a88c345c63aa25dab9815f8c51f6b7b621a12d31c8220a0579381c1e2e85a2275e2407c79c8e6e1f72ae765804e6b4562ac1b2d634313c70d59752ac119c6da5cb95dde3eedd9c48595b37256f5b64c56fb938eb1131447c9af9054b42841c57d1f41fe5aa510e2bd2965434f46dd0473c60d6114da088c7047760b00bc10287a03afc4c4f30e1c7dd7c9dbd51bdbd049eb2b8921cbdc72b4f69309f61e559c2d6dec9c9ce6f38ccb4dfd07f4cf2cf6e76279b88b297848c473e13f091a0f77"),
 		g3b: bnFromHex("d275468351fd48246e406ee74a8dc3db6ee335067bfa63300ce6a23867a1b2beddbdae9a8a36555fd4837f3ef8bad4f7fd5d7b4f346d7c7b7cb64bd7707eeb515902c66aa0c9323931364471ab93dd315f65c6624c956d74680863a9388cd5d89f1b5033b1cf232b8b6dcffaaea195de4e17cc1ba4c99497be18c011b2ad7742b43fa9ee3f95f7b6da02c8e894d054eb178a7822273655dc286ad15874687fe6671908d83662e7a529744ce4ea8dad49290d19dbe6caba202a825a20a27ee98a"),
 		c2:  bnFromHex("5f78f76ed595e10b8ec22a10b848a2dbfc01d5b4bf4f3354fa7d9e7a7b89be3c"),
@@ -131,8 +131,8 @@ func fixtureMessage2() smp2Message {
 	}
 }
 
-func fixtureMessage3() smp3Message {
-	s := smp3Message{
+func fixtureMessage3() Message3 {
+	s := Message3{
 		pa: bnFromHex("8EE76C232535FA68E18C13817056B7415E8FE8224AD15FA317C8D6F1AF17A0E45F538930F10DB29943E54E8D39D145E51B53D6A58C9E499A6353BBF378FD9D32370105EA4DEF5C88B755EFA485EF70C9097DEEA76A853F32CE98AA7ECE96073C0ABEDC91D1C9C0E092E86D36F4F1319EC7E8E40D4156F04CF18D7A79B01D44EBBB685F272FA39AA8C90662E4D8FBFB3F0A9F06478366C6708741F26FFA5F492CDD07D1F73A93BC18B3ECBE9F4071EC9FE600BCB67A8BC76920ED2C61BB94D07C"),
 		qa: bnFromHex("5533DDDE3704615657E1A654293D110C1557E6913DD8B79A5F15B5AF1F276153DBB8DEC7E17D157CF20DD54BC9B9373D6D0F2B44B3E88AD6F926B0D18DD87940C6E969184F1B184E441D379234C52EBB67584863925D775A423A962DC88A1A2E58152C1E7458BDF6FE762C5EA580A46C9AF6AD34D47F26B12D514F637FFD1D15D1CFB3FF330B53F1213D759A8F528ED4C22A9003A186A65F509EC96DB02420EF24D43E08FF469A0B4558B3A39778668E463647858C241B81F61A6C97FD076D72"),
 		cp: bnFromHex("F1F0147F5E53C85F410DB88C0C04370E45C341B735DA7CAF363B2497A358FCE7"),
@@ -146,8 +146,8 @@ func fixtureMessage3() smp3Message {
 	return s
 }
 
-func fixtureMessage4() smp4Message {
-	s := smp4Message{
+func fixtureMessage4() Message4 {
+	s := Message4{
 		rb: bnFromHex("6ca88ee8cf412f4ed088d67c2e22f28569c83833669abf0393688929b4a4e85cbdbcdbd3e30a5291edf31f108e10f296413d686a3567a7859e889dad8cf4089e9f6dd1299aba36fa09742e404f80eaadbcecb0ac38c861f0c15a606bcc33987c3611cf72ebccf5fbe055b28f14ff6ea78fc793287b44f4e832e97234ef1f26147d4bf9ad510bf6f8a3319cafaf7bad6af55d9d3f3e0bdc3877538e2b5c0b01d0eb1b5e4945f469ed9fccfb8ed5f588e7e4badaed7f9f4a3a205a594adcf3eb1e"),
 		cr: bnFromHex("91c6b49e6cd0db5af988fd95ab2e65959607f693305440f2a3e32d6304f02714"),
 		d7: bnFromHex("7fffffffffffffffe487ed5110b4611a62633145c06e0e68948127044533e63a0105df531d89cd9128a5043cc71a026ef7ca8cd9e69d218d98158536f92f8a1ba7f09ab6b6a8e122f242dabb312f3f637a262174d31bf6b585ffae5b7a035bf6f71c35fdad44cfd2d74f9208be258ff324943328f6722d9ee1003e5c50b1df82cc6d241b0e2ae9cd348b1fd47e9267af56c16aaeb95ed529fe253547f6d3f246c32062e08372b03f89223f84da5de2791a6b8dca81fdd15a2d8c29c8a66004c8"),
@@ -156,6 +156,6 @@ func fixtureMessage4() smp4Message {
 	return s
 }
 
-func fixtureMessageAbort() smpMessageAbort {
-	return smpMessageAbort{}
+func fixtureMessageAbort() MessageAbort {
+	return MessageAbort{}
 }
@@ -0,0 +1,142 @@
+package smp
+
+import "bytes"
+
+// The types of the messages, the same as the OTR TLV types used to transport them
+const (
+	TypeMessage1             = uint16(0x02)
+	TypeMessage2             = uint16(0x03)
+	TypeMessage3             = uint16(0x04)
+	TypeMessage4             = uint16(0x05)
+	TypeAbort                = uint16(0x06)
+	TypeMessage1WithQuestion = uint16(0x07)
+)
+
+const headerLength = 4
+
+// Message is a message sent between the parties of the protocol
+type Message interface {
+	// Type returns the type of the message
+	Type() uint16
+	// Serialize returns the body of the message, without the type
+	Serialize() []byte
+
+	receivedMessage(*Protocol) (Message, error)
+}
+
+// ParseMessage parses the body of a message of the given type
+func ParseMessage(tp uint16, body []byte) (Message, error) {
+	var m Message
+	var ok bool
+
+	switch tp {
+	case TypeMessage1:
+		m, ok = parseMessage1(body)
+	case TypeMessage1WithQuestion:
+		m, ok = parseMessage1WithQuestion(body)
+	case TypeMessage2:
+		m, ok = parseMessage2(body)
+	case TypeMessage3:
+		m, ok = parseMessage3(body)
+	case TypeMessage4:
+		m, ok = parseMessage4(body)
+	case TypeAbort:
+		m, ok = MessageAbort{}, true
+	}
+
+	if !ok {
+		return nil, ErrInvalidMessage
+	}
+	return m, nil
+}
+
+// Marshal returns the message encoded the same way as an OTR TLV: the type and the length
+// of the body as 16 bit big endian numbers, followed by the body
+func Marshal(m Message) []byte {
+	body := m.Serialize()
+	out := appendShort(make([]byte, 0, headerLength+len(body)), m.Type())
+	out = appendShort(out, uint16(len(body)))
+	return append(out, body...)
+}
+
+// Unmarshal parses a message encoded by Marshal
+func Unmarshal(data []byte) (Message, error) {
+	data, tp, ok1 := extractShort(data)
+	data, length, ok2 := extractShort(data)
+	if !ok1 || !ok2 || len(data) != int(length) {
+		return nil, ErrInvalidMessage
+	}
+	return ParseMessage(tp, data)
+}
+
+func parseMessage1(body []byte) (msg Message1, ok bool) {
+	_, mpis, ok := extractMPIs(body)
+	if !ok || len(mpis) < 6 {
+		return msg, false
+	}
+	msg.g2a = mpis[0]
+	msg.c2 = mpis[1]
+	msg.d2 = mpis[2]
+	msg.g3a = mpis[3]
+	msg.c3 = mpis[4]
+	msg.d3 = mpis[5]
+	return msg, true
+}
+
+func parseMessage1WithQuestion(body []byte) (msg Message1, ok bool) {
+	nulPos := bytes.IndexByte(body, 0)
+	if nulPos == -1 {
+		return msg, false
+	}
+	question := string(body[:nulPos])
+	msg, ok = parseMessage1(body[(nulPos + 1):])
+	msg.hasQuestion = true
+	msg.question = question
+	return msg, ok
+}
+
+func parseMessage2(body []byte) (msg Message2, ok bool) {
+	_, mpis, ok := extractMPIs(body)
+	if !ok || len(mpis) < 11 {
+		return msg, false
+	}
+	msg.g2b = mpis[0]
+	msg.c2 = mpis[1]
+	msg.d2 = mpis[2]
+	msg.g3b = mpis[3]
+	msg.c3 = mpis[4]
+	msg.d3 = mpis[5]
+	msg.pb = mpis[6]
+	msg.qb = mpis[7]
+	msg.cp = mpis[8]
+	msg.d5 = mpis[9]
+	msg.d6 = mpis[10]
+	return msg, true
+}
+
+func parseMessage3(body []byte) (msg Message3, ok bool) {
+	_, mpis, ok := extractMPIs(body)
+	if !ok || len(mpis) < 8 {
+		return msg, false
+	}
+	msg.pa = mpis[0]
+	msg.qa = mpis[1]
+	msg.cp = mpis[2]
+	msg.d5 = mpis[3]
+	msg.d6 = mpis[4]
+	msg.ra = mpis[5]
+	msg.cr = mpis[6]
+	msg.d7 = mpis[7]
+	return msg, true
+}
+
+func parseMessage4(body []byte) (msg Message4, ok bool) {
+	_, mpis, ok := extractMPIs(body)
+	if !ok || len(mpis) < 3 {
+		return msg, false
+	}
+	msg.rb = mpis[0]
+	msg.cr = mpis[1]
+	msg.d7 = mpis[2]
+	return msg, true
+}
@@ -0,0 +1,251 @@
+package smp
+
+import "testing"
+
+const (
+	typeLen     = 2
+	sizeLen     = 2
+	mpiCountLen = 4
+	c2Len       = 32
+	c3Len       = 32
+	cpLen       = 32
+	crLen       = 32
+	d2Len       = 192
+	d3Len       = 192
+	d5Len       = 192
+	d6Len       = 192
+	d7Len       = 192
+	g2aLen      = 192
+	g2bLen      = 192
+	g3aLen      = 192
+	g3bLen      = 192
+	paLen       = 192
+	pbLen       = 192
+	qaLen       = 192
+	qbLen       = 192
+	raLen       = 192
+	rbLen       = 192
+)
+
+func Test_Marshal_Message1(t *testing.T) {
+	expectedLength := typeLen + sizeLen + mpiCountLen + (4 + g2aLen) + (4 + c2Len) + (4 + d2Len) +
+		(4 + g3aLen) + (4 + c3Len) + (4 + d3Len)
+
+	exp := []byte{
+		0x00, 0x02,
+		0x03, 0x5C,
+		0x00, 0x00, 0x00, 0x06,
+		0x00, 0x00, 0x00, 0xC0,
+		0x8A, 0x88, 0xC3, 0x45,
+	}
+
+	msg := fixtureMessage1()
+	data := Marshal(msg)
+	assertEquals(t, len(data), expectedLength)
+	assertDeepEquals(t, data[:len(exp)], exp)
+}
+
+func Test_Marshal_Message2(t *testing.T) {
+	expectedLength := typeLen + sizeLen + mpiCountLen +
+		(4 + g2bLen) + (4 + c2Len) + (4 + d2Len) + (4 + g3bLen) +
+		(4 + c3Len) + (4 + d3Len) + (4 + pbLen) + (4 + qbLen) +
+		(4 + cpLen) + (4 + d5Len) + (4 + d6Len)
+
+	exp := []byte{
+		0x00, 0x03,
+		0x06, 0x90,
+		0x00, 0x00, 0x00, 0x0b,
+		0x00, 0x00, 0x00, 0xC0,
+		0x8A, 0x88, 0xC3, 0x45,
+	}
+
+	msg := fixtureMessage2()
+	data := Marshal(msg)
+	assertEquals(t, len(data), expectedLength)
+	assertDeepEquals(t, data[:len(exp)], exp)
+}
+
+func Test_Marshal_Message3(t *testing.T) {
+	expectedLength := typeLen + sizeLen + mpiCountLen +
+		(4 + paLen) + (4 + qaLen) + (4 + cpLen) +
+		(4 + d5Len) + (4 + d6Len) + (4 + raLen) +
+		(4 + crLen) + (4 + d7Len)
+
+	exp := []byte{
+		0x00, 0x04,
+		0x04, 0xE4,
+		0x00, 0x00, 0x00, 0x08,
+		0x00, 0x00, 0x00, 0xC0,
+		0x8E, 0xE7, 0x6C, 0x23,
+	}
+
+	msg := fixtureMessage3()
+	data := Marshal(msg)
+	assertEquals(t, len(data), expectedLength)
+	assertDeepEquals(t, data[:len(exp)], exp)
+}
+
+func Test_Marshal_Message4(t *testing.T) {
+	expectedLength := typeLen + sizeLen + mpiCountLen + (4 + rbLen) + (4 + crLen) + (4 + d7Len)
+	exp := []byte{
+		0x00, 0x05,
+		0x01, 0xB0,
+		0x00, 0x00, 0x00, 0x03,
+		0x00, 0x00, 0x00, 0xC0,
+		0x6C, 0xA8, 0x8E, 0xE8,
+	}
+
+	msg := fixtureMessage4()
+	data := Marshal(msg)
+	assertEquals(t, len(data), expectedLength)
+	assertDeepEquals(t, data[:len(exp)], exp)
+}
+
+func Test_Unmarshal_Message1(t *testing.T) {
+	msg := fixtureMessage1()
+
+	parsed, err := Unmarshal(Marshal(msg))
+	assertNil(t, err)
+	assertDeepEquals(t, parsed, msg)
+}
+
+func Test_Unmarshal_Message1WithAQuestion(t *testing.T) {
+	msg := fixtureMessage1Q()
+
+	parsed, err := Unmarshal(Marshal(msg))
+	assertNil(t, err)
+	assertDeepEquals(t, parsed, msg)
+}
+
+func Test_Unmarshal_Message2(t *testing.T) {
+	msg := fixtureMessage2()
+
+	parsed, err := Unmarshal(Marshal(msg))
+	assertNil(t, err)
+	assertDeepEquals(t, parsed, msg)
+}
+
+func Test_Unmarshal_Message3(t *testing.T) {
+	msg := fixtureMessage3()
+
+	parsed, err := Unmarshal(Marshal(msg))
+	assertNil(t, err)
+	assertDeepEquals(t, parsed, msg)
+}
+
+func Test_Unmarshal_Message4(t *testing.T) {
+	msg := fixtureMessage4()
+
+	parsed, err := Unmarshal(Marshal(msg))
+	assertNil(t, err)
+	assertDeepEquals(t, parsed, msg)
+}
+
+func Test_Unmarshal_MessageAbort(t *testing.T) {
+	msg := fixtureMessageAbort()
+
+	parsed, err := Unmarshal(Marshal(msg))
+	assertNil(t, err)
+	assertDeepEquals(t, parsed, msg)
+}
+
+func Test_Unmarshal_failsIfTheLengthDoesNotMatchTheBody(t *testing.T) {
+	data := Marshal(fixtureMessage4())
+
+	_, err := Unmarshal(data[:len(data)-1])
+	assertEquals(t, err, ErrInvalidMessage)
+}
+
+func Test_Unmarshal_failsForATooShortHeader(t *testing.T) {
+	_, err := Unmarshal([]byte{0x00, 0x02, 0x00})
+	assertEquals(t, err, ErrInvalidMessage)
+}
+
+func Test_ParseMessage_Message1WithAQuestion_willFailIfThereIsNoNulByte(t *testing.T) {
+	_, err := ParseMessage(TypeMessage1WithQuestion, []byte{})
+	assertEquals(t, err, ErrInvalidMessage)
+}
+
+func Test_ParseMessage_Message1WithAQuestion_willHandleItCorrectlyIfTheQuestionEndsOnAByte(t *testing.T) {
+	_, err := ParseMessage(TypeMessage1WithQuestion, []byte{0x01, 0x00})
+	assertEquals(t, err, ErrInvalidMessage)
+}
+
+func Test_ParseMessage_Message1WithAQuestion_willHandleItCorrectlyIfANulByteIsTheOnlyContent(t *testing.T) {
+	_, err := ParseMessage(TypeMessage1WithQuestion, []byte{0x00})
+	assertEquals(t, err, ErrInvalidMessage)
+}
+
+func Test_ParseMessage_Message1_failsForAnInvalidMessage(t *testing.T) {
+	msg := fixtureMessage1()
+
+	_, err := ParseMessage(msg.Type(), msg.Serialize()[:24])
+	assertEquals(t, err, ErrInvalidMessage)
+}
+
+func Test_ParseMessage_Message1_failsIfTheNumberOfMPIsIsTooShort(t *testing.T) {
+	msg := fixtureMessage1()
+	body := msg.Serialize()
+	body[3] = 0x01
+
+	_, err := ParseMessage(msg.Type(), body)
+	assertEquals(t, err, ErrInvalidMessage)
+}
+
+func Test_ParseMessage_Message2_failsForAnInvalidMessage(t *testing.T) {
+	msg := fixtureMessage2()
+
+	_, err := ParseMessage(msg.Type(), msg.Serialize()[:24])
+	assertEquals(t, err, ErrInvalidMessage)
+}
+
+func Test_ParseMessage_Message2_failsIfTheNumberOfMPIsIsTooShort(t *testing.T) {
+	msg := fixtureMessage2()
+	body := msg.Serialize()
+	body[3] = 0x01
+
+	_, err := ParseMessage(msg.Type(), body)
+	assertEquals(t, err, ErrInvalidMessage)
+}
+
+func Test_ParseMessage_Message3_failsForAnInvalidMessage(t *testing.T) {
+	msg := fixtureMessage3()
+
+	_, err := ParseMessage(msg.Type(), msg.Serialize()[:24])
+	assertEquals(t, err, ErrInvalidMessage)
+}
+
+func Test_ParseMessage_Message3_failsIfTheNumberOfMPIsIsTooShort(t *testing.T) {
+	msg := fixtureMessage3()
+	body := msg.Serialize()
+	body[3] = 0x01
+
+	_, err := ParseMessage(msg.Type(), body)
+	assertEquals(t, err, ErrInvalidMessage)
+}
+
+func Test_ParseMessage_Message4_failsForAnInvalidMessage(t *testing.T) {
+	msg := fixtureMessage4()
+
+	_, err := ParseMessage(msg.Type(), msg.Serialize()[:24])
+	assertEquals(t, err, ErrInvalidMessage)
+}
+
+func Test_ParseMessage_Message4_failsIfTheNumberOfMPIsIsTooShort(t *testing.T) {
+	msg := fixtureMessage4()
+	body := msg.Serialize()
+	body[3] = 0x01
+
+	_, err := ParseMessage(msg.Type(), body)
+	assertEquals(t, err, ErrInvalidMessage)
+}
+
+func Test_ParseMessage_failsForAnUnknownType(t *testing.T) {
+	_, err := ParseMessage(0x0A, []byte{})
+	assertEquals(t, err, ErrInvalidMessage)
+}
+
+func Test_ParseMessage_rejectsAnMPICountLargerThanTheMessage(t *testing.T) {
+	_, err := ParseMessage(TypeMessage1, []byte{0xFF, 0xFF, 0xFF, 0xFF, 0x00, 0x00, 0x00, 0x01, 0x01})
+	assertEquals(t, err, ErrInvalidMessage)
+}
@@ -0,0 +1,84 @@
+package smp
+
+import (
+	"errors"
+	"math/big"
+)
+
+type state1 struct {
+	a2, a3 *big.Int
+	r2, r3 *big.Int
+	msg    Message1
+}
+
+// Message1 starts a run of the protocol, optionally with a question for the other party
+type Message1 struct {
+	g2a, g3a    *big.Int
+	c2, c3      *big.Int
+	d2, d3      *big.Int
+	hasQuestion bool
+	question    string
+}
+
+// Type returns TypeMessage1WithQuestion if the message has a question, and TypeMessage1 otherwise
+func (m Message1) Type() uint16 {
+	if m.hasQuestion {
+		return TypeMessage1WithQuestion
+	}
+	return TypeMessage1
+}
+
+// Serialize returns the body of the message
+func (m Message1) Serialize() []byte {
+	body := serializeMPIs(m.g2a, m.c2, m.d2, m.g3a, m.c3, m.d3)
+	if m.hasQuestion {
+		body = append(append([]byte(m.question), 0), body...)
+	}
+	return body
+}
+
+func (pr *Protocol) generateSMP1Parameters() (s state1, err error) {
+	b := make([]byte, pr.Version.parameterLength())
+	var err1, err2, err3, err4 error
+	s.a2, err1 = pr.randMPI(b)
+	s.a3, err2 = pr.randMPI(b)
+	s.r2, err3 = pr.randMPI(b)
+	s.r3, err4 = pr.randMPI(b)
+	return s, firstError(err1, err2, err3, err4)
+}
+
+func generateSMP1Message(s state1, v Version) (m Message1) {
+	m.g2a = modExp(g1, s.a2)
+	m.g3a = modExp(g1, s.a3)
+	m.c2, m.d2 = generateZKP(s.r2, s.a2, 1, v)
+	m.c3, m.d3 = generateZKP(s.r3, s.a3, 2, v)
+	return
+}
+
+func (pr *Protocol) generateSMP1() (s state1, err error) {
+	if s, err = pr.generateSMP1Parameters(); err != nil {
+		return s, err
+	}
+	s.msg = generateSMP1Message(s, pr.Version)
+	return
+}
+
+func (pr *Protocol) verifySMP1(msg Message1) error {
+	if !pr.Version.isGroupElement(msg.g2a) {
+		return errors.New("g2a is an invalid group element")
+	}
+
+	if !pr.Version.isGroupElement(msg.g3a) {
+		return errors.New("g3a is an invalid group element")
+	}
+
+	if !verifyZKP(msg.d2, msg.g2a, msg.c2, 1, pr.Version) {
+		return errors.New("c2 is not a valid zero knowledge proof")
+	}
+
+	if !verifyZKP(msg.d3, msg.g3a, msg.c3, 2, pr.Version) {
+		return errors.New("c3 is not a valid zero knowledge proof")
+	}
+
+	return nil
+}
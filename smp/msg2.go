@@ -0,0 +1,124 @@
+package smp
+
+import (
+	"errors"
+	"math/big"
+)
+
+type state2 struct {
+	y                  *big.Int
+	b2, b3             *big.Int
+	r2, r3, r4, r5, r6 *big.Int
+	g3a                *big.Int
+	g2, g3             *big.Int
+	pb, qb             *big.Int
+	msg                Message2
+}
+
+// Message2 is the reply to Message1, sent once the secret has been provided
+type Message2 struct {
+	g2b, g3b *big.Int
+	c2, c3   *big.Int
+	d2, d3   *big.Int
+	pb, qb   *big.Int
+	cp       *big.Int
+	d5, d6   *big.Int
+}
+
+// Type returns TypeMessage2
+func (m Message2) Type() uint16 {
+	return TypeMessage2
+}
+
+// Serialize returns the body of the message
+func (m Message2) Serialize() []byte {
+	return serializeMPIs(m.g2b, m.c2, m.d2, m.g3b, m.c3, m.d3, m.pb, m.qb, m.cp, m.d5, m.d6)
+}
+
+func (pr *Protocol) generateSMP2Parameters() (s state2, err error) {
+	b := make([]byte, pr.Version.parameterLength())
+	var err1, err2, err3, err4, err5, err6, err7 error
+	s.b2, err1 = pr.randMPI(b)
+	s.b3, err2 = pr.randMPI(b)
+	s.r2, err3 = pr.randMPI(b)
+	s.r3, err4 = pr.randMPI(b)
+	s.r4, err5 = pr.randMPI(b)
+	s.r5, err6 = pr.randMPI(b)
+	s.r6, err7 = pr.randMPI(b)
+
+	return s, firstError(err1, err2, err3, err4, err5, err6, err7)
+}
+
+func generateSMP2Message(s *state2, s1 Message1, v Version) Message2 {
+	var m Message2
+
+	m.g2b = modExp(g1, s.b2)
+	m.g3b = modExp(g1, s.b3)
+
+	m.c2, m.d2 = generateZKP(s.r2, s.b2, 3, v)
+	m.c3, m.d3 = generateZKP(s.r3, s.b3, 4, v)
+
+	s.g3a = s1.g3a
+	s.g2 = modExp(s1.g2a, s.b2)
+	s.g3 = modExp(s1.g3a, s.b3)
+
+	s.pb = modExp(s.g3, s.r4)
+	s.qb = mulMod(modExp(g1, s.r4), modExp(s.g2, s.y), p)
+
+	m.pb = s.pb
+	m.qb = s.qb
+
+	m.cp = hashMPIsBN(v.hashInstance(), 5,
+		modExp(s.g3, s.r5),
+		mulMod(modExp(g1, s.r5), modExp(s.g2, s.r6), p))
+
+	m.d5 = subMod(s.r5, mul(s.r4, m.cp), q)
+	m.d6 = subMod(s.r6, mul(s.y, m.cp), q)
+
+	return m
+}
+
+func (pr *Protocol) generateSMP2(secret *big.Int, s1 Message1) (s state2, err error) {
+	if s, err = pr.generateSMP2Parameters(); err != nil {
+		return s, err
+	}
+
+	s.y = secret
+	s.msg = generateSMP2Message(&s, s1, pr.Version)
+	return
+}
+
+func (pr *Protocol) verifySMP2(s1 *state1, msg Message2) error {
+	if !pr.Version.isGroupElement(msg.g2b) {
+		return errors.New("g2b is an invalid group element")
+	}
+
+	if !pr.Version.isGroupElement(msg.g3b) {
+		return errors.New("g3b is an invalid group element")
+	}
+
+	if !pr.Version.isGroupElement(msg.pb) {
+		return errors.New("Pb is an invalid group element")
+	}
+
+	if !pr.Version.isGroupElement(msg.qb) {
+		return errors.New("Qb is an invalid group element")
+	}
+
+	if !verifyZKP(msg.d2, msg.g2b, msg.c2, 3, pr.Version) {
+		return errors.New("c2 is not a valid zero knowledge proof")
+	}
+
+	if !verifyZKP(msg.d3, msg.g3b, msg.c3, 4, pr.Version) {
+		return errors.New("c3 is not a valid zero knowledge proof")
+	}
+
+	g2 := modExp(msg.g2b, s1.a2)
+	g3 := modExp(msg.g3b, s1.a3)
+
+	if !verifyZKP2(g2, g3, msg.d5, msg.d6, msg.pb, msg.qb, msg.cp, 5, pr.Version) {
+		return errors.New("cP is not a valid zero knowledge proof")
+	}
+
+	return nil
+}
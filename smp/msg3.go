@@ -0,0 +1,116 @@
+package smp
+
+import (
+	"errors"
+	"math/big"
+)
+
+type state3 struct {
+	x              *big.Int
+	g3b            *big.Int
+	r4, r5, r6, r7 *big.Int
+	qaqb, papb     *big.Int
+	msg            Message3
+}
+
+// Message3 is the reply to Message2
+type Message3 struct {
+	pa, qa     *big.Int
+	cp         *big.Int
+	d5, d6, d7 *big.Int
+	ra         *big.Int
+	cr         *big.Int
+}
+
+// Type returns TypeMessage3
+func (m Message3) Type() uint16 {
+	return TypeMessage3
+}
+
+// Serialize returns the body of the message
+func (m Message3) Serialize() []byte {
+	return serializeMPIs(m.pa, m.qa, m.cp, m.d5, m.d6, m.ra, m.cr, m.d7)
+}
+
+func (pr *Protocol) generateSMP3Parameters() (s state3, err error) {
+	b := make([]byte, pr.Version.parameterLength())
+	var err1, err2, err3, err4 error
+
+	s.r4, err1 = pr.randMPI(b)
+	s.r5, err2 = pr.randMPI(b)
+	s.r6, err3 = pr.randMPI(b)
+	s.r7, err4 = pr.randMPI(b)
+
+	return s, firstError(err1, err2, err3, err4)
+}
+
+func generateSMP3Message(s *state3, s1 state1, m2 Message2, v Version) Message3 {
+	var m Message3
+
+	g2 := modExp(m2.g2b, s1.a2)
+	g3 := modExp(m2.g3b, s1.a3)
+
+	m.pa = modExp(g3, s.r4)
+	m.qa = mulMod(modExp(g1, s.r4), modExp(g2, s.x), p)
+
+	s.g3b = m2.g3b
+	s.qaqb = divMod(m.qa, m2.qb, p)
+	s.papb = divMod(m.pa, m2.pb, p)
+
+	m.cp = hashMPIsBN(v.hashInstance(), 6, modExp(g3, s.r5), mulMod(modExp(g1, s.r5), modExp(g2, s.r6), p))
+	m.d5 = generateDZKP(s.r5, s.r4, m.cp)
+	m.d6 = generateDZKP(s.r6, s.x, m.cp)
+
+	m.ra = modExp(s.qaqb, s1.a3)
+
+	m.cr = hashMPIsBN(v.hashInstance(), 7, modExp(g1, s.r7), modExp(s.qaqb, s.r7))
+	m.d7 = subMod(s.r7, mul(s1.a3, m.cr), q)
+
+	return m
+}
+
+func (pr *Protocol) generateSMP3(secret *big.Int, s1 state1, m2 Message2) (s state3, err error) {
+	if s, err = pr.generateSMP3Parameters(); err != nil {
+		return s, err
+	}
+	s.x = secret
+	s.msg = generateSMP3Message(&s, s1, m2, pr.Version)
+	return
+}
+
+func (pr *Protocol) verifySMP3(s2 *state2, msg Message3) error {
+	if !pr.Version.isGroupElement(msg.pa) {
+		return errors.New("Pa is an invalid group element")
+	}
+
+	if !pr.Version.isGroupElement(msg.qa) {
+		return errors.New("Qa is an invalid group element")
+	}
+
+	if !pr.Version.isGroupElement(msg.ra) {
+		return errors.New("Ra is an invalid group element")
+	}
+
+	if !verifyZKP3(msg.cp, s2.g2, s2.g3, msg.d5, msg.d6, msg.pa, msg.qa, 6, pr.Version) {
+		return errors.New("cP is not a valid zero knowledge proof")
+	}
+
+	qaqb := divMod(msg.qa, s2.qb, p)
+
+	if !verifyZKP4(msg.cr, s2.g3a, msg.d7, qaqb, msg.ra, 7, pr.Version) {
+		return errors.New("cR is not a valid zero knowledge proof")
+	}
+
+	return nil
+}
+
+func (pr *Protocol) verifySMP3ProtocolSuccess(s2 *state2, msg Message3) error {
+	papb := divMod(msg.pa, s2.pb, p)
+
+	rab := modExp(msg.ra, s2.b3)
+	if !eq(rab, papb) {
+		return errors.New("protocol failed: x != y")
+	}
+
+	return nil
+}
@@ -0,0 +1,77 @@
+package smp
+
+import (
+	"errors"
+	"math/big"
+)
+
+type state4 struct {
+	y   *big.Int
+	r7  *big.Int
+	msg Message4
+}
+
+// Message4 is the reply to Message3, and the last message of a run of the protocol
+type Message4 struct {
+	cr *big.Int
+	d7 *big.Int
+	rb *big.Int
+}
+
+// Type returns TypeMessage4
+func (m Message4) Type() uint16 {
+	return TypeMessage4
+}
+
+// Serialize returns the body of the message
+func (m Message4) Serialize() []byte {
+	return serializeMPIs(m.rb, m.cr, m.d7)
+}
+
+func (pr *Protocol) generateSMP4(secret *big.Int, s2 state2, msg3 Message3) (s state4, err error) {
+	if s, err = pr.generateSMP4Parameters(); err != nil {
+		return s, err
+	}
+	s.y = secret
+	s.msg = generateSMP4Message(s, s2, msg3, pr.Version)
+	return
+}
+
+func (pr *Protocol) verifySMP4(s3 *state3, msg Message4) error {
+	if !pr.Version.isGroupElement(msg.rb) {
+		return errors.New("Rb is an invalid group element")
+	}
+
+	if !verifyZKP4(msg.cr, s3.g3b, msg.d7, s3.qaqb, msg.rb, 8, pr.Version) {
+		return errors.New("cR is not a valid zero knowledge proof")
+	}
+
+	return nil
+}
+
+func (pr *Protocol) generateSMP4Parameters() (s state4, err error) {
+	b := make([]byte, pr.Version.parameterLength())
+	s.r7, err = pr.randMPI(b)
+	return
+}
+
+func generateSMP4Message(s state4, s2 state2, msg3 Message3, v Version) Message4 {
+	var m Message4
+
+	qaqb := divMod(msg3.qa, s2.qb, p)
+
+	m.rb = modExp(qaqb, s2.b3)
+	m.cr = hashMPIsBN(v.hashInstance(), 8, modExp(g1, s.r7), modExp(qaqb, s.r7))
+	m.d7 = subMod(s.r7, mul(s2.b3, m.cr), q)
+
+	return m
+}
+
+func (pr *Protocol) verifySMP4ProtocolSuccess(s1 *state1, s3 *state3, msg Message4) error {
+	rab := modExp(msg.rb, s1.a3)
+	if !eq(rab, s3.papb) {
+		return errors.New("protocol failed: x != y")
+	}
+
+	return nil
+}
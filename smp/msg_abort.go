@@ -0,0 +1,14 @@
+package smp
+
+// MessageAbort tells the other party that the current run of the protocol has been aborted
+type MessageAbort struct{}
+
+// Type returns TypeAbort
+func (m MessageAbort) Type() uint16 {
+	return TypeAbort
+}
+
+// Serialize returns the body of the message
+func (m MessageAbort) Serialize() []byte {
+	return serializeMPIs()
+}
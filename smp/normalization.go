@@ -0,0 +1,72 @@
+package smp
+
+import (
+	"math/big"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Normalization selects how a secret typed by a user is normalized before it is hashed, so small
+// differences in how two users type the same answer don't make the protocol fail.
+// Both parties have to use the same normalization. The values can be combined.
+type Normalization int
+
+const (
+	// NormalizeUnicode applies Unicode NFKC normalization, so composed and decomposed characters, and compatibility
+	// characters such as full width letters, compare equal
+	NormalizeUnicode Normalization = 1 << iota
+	// NormalizeSpace removes leading and trailing white space
+	NormalizeSpace
+	// NormalizeCase applies Unicode case folding, so the case of the letters doesn't matter
+	NormalizeCase
+)
+
+// NormalizeNone uses the secret exactly as given. It is the default
+const NormalizeNone Normalization = 0
+
+// NormalizeDefault is the normalization that doesn't change the meaning of any answer
+const NormalizeDefault = NormalizeUnicode | NormalizeSpace
+
+// Apply returns the secret normalized. A secret that isn't valid UTF-8 is returned without any Unicode normalization or case folding.
+func (n Normalization) Apply(secret []byte) []byte {
+	s := string(secret)
+	if n&NormalizeSpace != 0 {
+		s = strings.TrimSpace(s)
+	}
+	if !utf8.ValidString(s) {
+		return []byte(s)
+	}
+	if n&NormalizeUnicode != 0 {
+		s = norm.NFKC.String(s)
+	}
+	if n&NormalizeCase != 0 {
+		s = cases.Fold().String(s)
+	}
+	return []byte(s)
+}
+
+// GenerateSecret works like the GenerateSecret function, but normalizes the secret first
+func (n Normalization) GenerateSecret(initiatorFingerprint, recipientFingerprint, ssid, secret []byte) *big.Int {
+	return GenerateSecret(initiatorFingerprint, recipientFingerprint, ssid, n.Apply(secret))
+}
+
+// String returns the names of the normalizations that are applied, separated by plus signs, or "none"
+func (n Normalization) String() string {
+	var names []string
+	if n&NormalizeUnicode != 0 {
+		names = append(names, "unicode")
+	}
+	if n&NormalizeSpace != 0 {
+		names = append(names, "space")
+	}
+	if n&NormalizeCase != 0 {
+		names = append(names, "case")
+	}
+	if len(names) == 0 {
+		return "none"
+	}
+	return strings.Join(names, "+")
+}
@@ -0,0 +1,48 @@
+package smp
+
+import "testing"
+
+func Test_Normalization_Apply_withNoneReturnsTheSecretAsIs(t *testing.T) {
+	assertDeepEquals(t, NormalizeNone.Apply([]byte(" Paris ")), []byte(" Paris "))
+}
+
+func Test_Normalization_Apply_trimsWhiteSpace(t *testing.T) {
+	assertDeepEquals(t, NormalizeSpace.Apply([]byte("\t Paris \n")), []byte("Paris"))
+}
+
+func Test_Normalization_Apply_makesComposedAndDecomposedCharactersEqual(t *testing.T) {
+	composed := NormalizeUnicode.Apply([]byte("caf\u00e9"))
+	decomposed := NormalizeUnicode.Apply([]byte("cafe\u0301"))
+	assertDeepEquals(t, composed, decomposed)
+}
+
+func Test_Normalization_Apply_normalizesCompatibilityCharacters(t *testing.T) {
+	assertDeepEquals(t, NormalizeUnicode.Apply([]byte("\uff30aris")), []byte("Paris"))
+}
+
+func Test_Normalization_Apply_foldsCase(t *testing.T) {
+	assertDeepEquals(t, NormalizeCase.Apply([]byte("PARIS")), NormalizeCase.Apply([]byte("paris")))
+	assertDeepEquals(t, NormalizeCase.Apply([]byte("Straße")), NormalizeCase.Apply([]byte("STRASSE")))
+}
+
+func Test_Normalization_Apply_combinesNormalizations(t *testing.T) {
+	n := NormalizeDefault | NormalizeCase
+	assertDeepEquals(t, n.Apply([]byte(" Café\n")), n.Apply([]byte("CAFÉ")))
+}
+
+func Test_Normalization_Apply_leavesInvalidUTF8Alone(t *testing.T) {
+	secret := []byte{0xFF, 'A', 0xFE}
+	assertDeepEquals(t, (NormalizeUnicode | NormalizeCase).Apply(secret), secret)
+}
+
+func Test_Normalization_GenerateSecret_normalizesTheSecretFirst(t *testing.T) {
+	fpr := bytesFromHex("0102030405060708090A0B0C0D0E0F1011121314")
+	ssid := bytesFromHex("FFF1D1E412345668")
+	assertDeepEquals(t, NormalizeSpace.GenerateSecret(fpr, fpr, ssid, []byte(" Paris ")), GenerateSecret(fpr, fpr, ssid, []byte("Paris")))
+}
+
+func Test_Normalization_String(t *testing.T) {
+	assertEquals(t, NormalizeNone.String(), "none")
+	assertEquals(t, NormalizeDefault.String(), "unicode+space")
+	assertEquals(t, (NormalizeSpace | NormalizeCase).String(), "space+case")
+}
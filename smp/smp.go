@@ -0,0 +1,223 @@
+// Package smp implements the Socialist Millionaires' Protocol as specified by OTR.
+//
+// The protocol lets two parties find out whether they know the same secret without revealing
+// anything else about it. A Protocol keeps one side of the exchange: Start begins a run, Receive
+// processes the messages from the other party and ProvideSecret continues a run started by the
+// other party. The messages returned have to be transported to the other party, which can be done
+// by Conversation in the otr3 package, or with Marshal and Unmarshal over any other channel.
+package smp
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"hash"
+	"io"
+	"math/big"
+	"time"
+)
+
+// Version selects the parameters that differ between the OTR protocol versions
+type Version int
+
+const (
+	// Version3 uses the parameters of OTR version 3. It is the default
+	Version3 Version = iota
+	// Version2 uses the parameters of OTR version 2: shorter random exponents, and received values are not checked to be group elements
+	Version2
+)
+
+func (v Version) parameterLength() int {
+	if v == Version2 {
+		return 16
+	}
+	return 192
+}
+
+func (v Version) isGroupElement(n *big.Int) bool {
+	if v == Version2 {
+		return true
+	}
+	return isGroupElement(n)
+}
+
+func (v Version) hashInstance() hash.Hash {
+	return sha256.New()
+}
+
+// Protocol contains the state of one side of the Socialist Millionaires' Protocol.
+// The zero value is ready to use, and can be used for several runs of the protocol after each other.
+type Protocol struct {
+	// Rand is the source of randomness. If it is nil, crypto/rand.Reader will be used
+	Rand io.Reader
+	// Version selects the parameters to use, which have to be the same for both parties
+	Version Version
+	// EventHandler is notified about the progress of the protocol. It can be nil
+	EventHandler EventHandler
+	// Timeout is how long to wait for the other party before CheckTimeout gives up on a run of the protocol.
+	// Zero means waiting forever
+	Timeout time.Duration
+
+	state        state
+	question     *string
+	secret       *big.Int
+	s1           *state1
+	s2           *state2
+	s3           *state3
+	lastActivity time.Time
+}
+
+const secretVersion = 1
+
+// GenerateSecret creates the secret to compare from the secret the users share, the way OTR does it.
+// Binding the fingerprints of the long term keys and the session id to the secret makes sure the
+// users are also talking to each other. The fingerprints are ordered by who started the protocol.
+func GenerateSecret(initiatorFingerprint, recipientFingerprint, ssid, secret []byte) *big.Int {
+	h := sha256.New()
+	h.Write([]byte{secretVersion})
+	h.Write(initiatorFingerprint)
+	h.Write(recipientFingerprint)
+	h.Write(ssid)
+	h.Write(secret)
+	return new(big.Int).SetBytes(h.Sum(nil))
+}
+
+func (pr *Protocol) rand() io.Reader {
+	if pr.Rand != nil {
+		return pr.Rand
+	}
+	return rand.Reader
+}
+
+func (pr *Protocol) randMPI(b []byte) (*big.Int, error) {
+	return randMPI(pr.rand(), b)
+}
+
+func (pr *Protocol) currentState() state {
+	if pr.state == nil {
+		return stateExpect1{}
+	}
+	return pr.state
+}
+
+// State returns the state the protocol is in
+func (pr *Protocol) State() State {
+	return pr.currentState().identity()
+}
+
+// Question returns the question asked by the other party and ok if there is one, and not ok if there isn't one.
+func (pr *Protocol) Question() (string, bool) {
+	if pr.question == nil {
+		return "", false
+	}
+	return *pr.question, true
+}
+
+// Start begins a new run of the protocol with an optional question for the other party. If a run is already
+// in progress it is aborted, and the returned messages will start with an abort message for the other party.
+func (pr *Protocol) Start(question string, secret *big.Int) ([]Message, error) {
+	pr.lastActivity = time.Now()
+	return pr.currentState().startAuthenticate(pr, question, secret)
+}
+
+// ProvideSecret continues a run of the protocol started by the other party. It is only valid to call it after the
+// event handler has been asked for a secret or an answer. It returns the message to send to the other party.
+func (pr *Protocol) ProvideSecret(secret *big.Int) (ret Message, err error) {
+	pr.lastActivity = time.Now()
+	pr.state, ret, err = pr.currentState().continueMessage1(pr, secret)
+	return
+}
+
+// Receive processes a message from the other party. It returns the message to reply with, if any.
+func (pr *Protocol) Receive(m Message) (Message, error) {
+	pr.lastActivity = time.Now()
+	return m.receivedMessage(pr)
+}
+
+// Abort stops the current run of the protocol, and returns the message that tells the other party about it
+func (pr *Protocol) Abort() Message {
+	var ret Message
+	pr.state, ret, _ = sendAbortAndRestartStateMachine()
+	return ret
+}
+
+// waitingForOtherParty returns true if a run of the protocol is in progress, and the next step is up to the other party
+func (pr *Protocol) waitingForOtherParty() bool {
+	switch pr.State() {
+	case StateExpect2, StateExpect3, StateExpect4:
+		return true
+	}
+	return false
+}
+
+// CheckTimeout aborts the current run of the protocol if the other party hasn't answered within Timeout.
+// The event handler is notified with EventAbort, and the message that tells the other party about it is returned.
+// If the protocol hasn't timed out, nil is returned. Waiting for the secret from our own side never times out.
+func (pr *Protocol) CheckTimeout() Message {
+	if pr.Timeout == 0 || !pr.waitingForOtherParty() || time.Since(pr.lastActivity) < pr.Timeout {
+		return nil
+	}
+
+	pr.event(EventAbort, 0)
+	return pr.Abort()
+}
+
+// Wipe forgets the state of the protocol, and overwrites the secret values it kept
+func (pr *Protocol) Wipe() {
+	pr.state = nil
+	pr.question = nil
+	wipeBigInt(pr.secret)
+	pr.secret = nil
+	pr.s1 = nil
+	pr.s2 = nil
+	pr.s3 = nil
+}
+
+func generateDZKP(r, a, c *big.Int) *big.Int {
+	return subMod(r, mul(a, c), q)
+}
+
+func generateZKP(r, a *big.Int, ix byte, v Version) (c, d *big.Int) {
+	c = hashMPIsBN(v.hashInstance(), ix, modExp(g1, r))
+	d = generateDZKP(r, a, c)
+	return
+}
+
+func verifyZKP(d, gen, c *big.Int, ix byte, v Version) bool {
+	r := modExp(g1, d)
+	s := modExp(gen, c)
+	t := hashMPIsBN(v.hashInstance(), ix, mulMod(r, s, p))
+	return eq(c, t)
+}
+
+func verifyZKP2(g2, g3, d5, d6, pb, qb, cp *big.Int, ix byte, v Version) bool {
+	l := mulMod(
+		modExp(g3, d5),
+		modExp(pb, cp),
+		p)
+	r := mulMod(mul(modExp(g1, d5),
+		modExp(g2, d6)),
+		modExp(qb, cp),
+		p)
+	t := hashMPIsBN(v.hashInstance(), ix, l, r)
+	return eq(cp, t)
+}
+
+func verifyZKP3(cp, g2, g3, d5, d6, pa, qa *big.Int, ix byte, v Version) bool {
+	l := mulMod(modExp(g3, d5), modExp(pa, cp), p)
+	r := mulMod(mul(modExp(g1, d5), modExp(g2, d6)), modExp(qa, cp), p)
+	t := hashMPIsBN(v.hashInstance(), ix, l, r)
+	return eq(cp, t)
+}
+
+func verifyZKP4(cr, g3a, d7, qaqb, ra *big.Int, ix byte, v Version) bool {
+	l := mulMod(modExp(g1, d7), modExp(g3a, cr), p)
+	r := mulMod(modExp(qaqb, d7), modExp(ra, cr), p)
+	t := hashMPIsBN(v.hashInstance(), ix, l, r)
+	return eq(cr, t)
+}
+
+func serializeMPIs(mpis ...*big.Int) []byte {
+	data := make([]byte, 0, 1000)
+	data = appendWord(data, uint32(len(mpis)))
+	return appendMPIs(data, mpis...)
+}
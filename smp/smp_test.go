@@ -0,0 +1,218 @@
+package smp
+
+import (
+	"crypto/rand"
+	"testing"
+	"time"
+)
+
+func Test_GenerateSecret_generatesASecret(t *testing.T) {
+	aliceFingerprint := bytesFromHex("0102030405060708090A0B0C0D0E0F1011121314")
+	bobFingerprint := bytesFromHex("3132333435363738393A3B3C3D3E3F4041424344")
+	ssid := bytesFromHex("FFF1D1E412345668")
+	secret := []byte("this is something secret")
+	result := GenerateSecret(aliceFingerprint, bobFingerprint, ssid, secret)
+	assertDeepEquals(t, result, bnFromHex("D9B2E56321F9A9F8E364607C8C82DECD8E8E6209E2CB952C7E649620F5286FE3"))
+}
+
+func Test_Question_returnsTheCurrentQuestion(t *testing.T) {
+	pr := newProtocol(Version3, fixtureRand())
+	q := "Are all greeks liars?"
+	pr.question = &q
+	res, ok := pr.Question()
+	assertEquals(t, ok, true)
+	assertDeepEquals(t, res, "Are all greeks liars?")
+}
+
+func Test_Question_returnsNotOKIfThereIsNoQuestion(t *testing.T) {
+	pr := newProtocol(Version3, fixtureRand())
+	_, ok := pr.Question()
+	assertEquals(t, ok, false)
+}
+
+func Test_State_ofTheZeroValueIsExpect1(t *testing.T) {
+	assertEquals(t, (&Protocol{}).State(), StateExpect1)
+}
+
+func Test_Wipe_forgetsTheStateOfTheProtocol(t *testing.T) {
+	pr := newProtocol(Version3, fixtureRand())
+	q := "Are all greeks liars?"
+	pr.question = &q
+	pr.state = stateExpect3{}
+	pr.secret = fixtureSecret()
+	pr.s1 = fixtureSmp1()
+	pr.s2 = fixtureSmp2()
+	pr.s3 = fixtureSmp3()
+
+	pr.Wipe()
+
+	assertEquals(t, pr.State(), StateExpect1)
+	assertNil(t, pr.question)
+	assertNil(t, pr.secret)
+	assertNil(t, pr.s1)
+	assertNil(t, pr.s2)
+	assertNil(t, pr.s3)
+}
+
+func Test_Abort_restartsTheProtocolAndReturnsAnAbortMessage(t *testing.T) {
+	pr := newProtocol(Version3, fixtureRand())
+	pr.state = stateExpect3{}
+
+	m := pr.Abort()
+
+	assertDeepEquals(t, m, MessageAbort{})
+	assertEquals(t, pr.State(), StateExpect1)
+}
+
+func Test_CheckTimeout_abortsIfTheOtherPartyHasNotAnsweredInTime(t *testing.T) {
+	pr := newProtocol(Version3, fixtureRand())
+	pr.Timeout = time.Minute
+	pr.state = stateExpect2{}
+	pr.lastActivity = time.Now().Add(-2 * time.Minute)
+
+	var m Message
+	pr.expectEvent(t, func() {
+		m = pr.CheckTimeout()
+	}, EventAbort, 0, "")
+
+	assertDeepEquals(t, m, MessageAbort{})
+	assertEquals(t, pr.State(), StateExpect1)
+}
+
+func Test_CheckTimeout_doesNothingBeforeTheTimeout(t *testing.T) {
+	pr := newProtocol(Version3, fixtureRand())
+	pr.Timeout = time.Minute
+	pr.state = stateExpect3{}
+	pr.lastActivity = time.Now().Add(-30 * time.Second)
+
+	assertNil(t, pr.CheckTimeout())
+	assertEquals(t, pr.State(), StateExpect3)
+}
+
+func Test_CheckTimeout_doesNothingWithoutATimeout(t *testing.T) {
+	pr := newProtocol(Version3, fixtureRand())
+	pr.state = stateExpect4{}
+
+	assertNil(t, pr.CheckTimeout())
+	assertEquals(t, pr.State(), StateExpect4)
+}
+
+func Test_CheckTimeout_doesNotTimeOutWhileWaitingForOurOwnSecret(t *testing.T) {
+	pr := newProtocol(Version3, fixtureRand())
+	pr.Timeout = time.Minute
+	pr.state = stateWaitingForSecret{msg: fixtureMessage1()}
+
+	assertNil(t, pr.CheckTimeout())
+	assertEquals(t, pr.State(), StateWaitingForSecret)
+}
+
+func Test_Receive_restartsTheTimeout(t *testing.T) {
+	pr := newProtocol(Version3, fixtureRand())
+	pr.Timeout = time.Minute
+	pr.state = stateExpect2{}
+	pr.lastActivity = time.Now().Add(-2 * time.Minute)
+
+	pr.Receive(fixtureMessage1())
+	pr.state = stateExpect2{}
+
+	assertNil(t, pr.CheckTimeout())
+}
+
+func TestFullSMPHandshake(t *testing.T) {
+	secret := bnFromHex("ABCDE56321F9A9F8E364607C8C82DECD8E8E6209E2CB952C7E649620F5286FE3")
+	alice := newProtocol(Version3, rand.Reader)
+	bob := newProtocol(Version3, rand.Reader)
+
+	// Alice -> Bob
+	// Stores: x, a2, and a3
+	// Sends: g2a, c2, D2, g3a, c3 and D3
+	s1, _ := alice.generateSMP1()
+
+	//Bob
+	err := bob.verifySMP1(s1.msg)
+	assertDeepEquals(t, err, nil)
+
+	// Bob -> Alice
+	// Stores: g3a, g2, g3, b3, Pb and Qb
+	// Sends: g2b, c2, D2, g3b, c3, D3, Pb, Qb, cP, D5 and D6
+	s2, _ := bob.generateSMP2(secret, s1.msg)
+
+	// Alice
+	err = alice.verifySMP2(&s1, s2.msg)
+	assertDeepEquals(t, err, nil)
+
+	// Alice -> Bob
+	// Stores: g3b, (Pa / Pb), (Qa / Qb) and Ra
+	// Sends: Pa, Qa, cP, D5, D6, Ra, cR and D7
+	s3, _ := alice.generateSMP3(secret, s1, s2.msg)
+
+	// Bob
+	err = bob.verifySMP3(&s2, s3.msg)
+	assertDeepEquals(t, err, nil)
+
+	err = bob.verifySMP3ProtocolSuccess(&s2, s3.msg)
+	assertDeepEquals(t, err, nil)
+
+	// Bob -> Alice
+	// Stores: ???
+	// Sends: Rb, cR and D7
+	s4, _ := bob.generateSMP4(secret, s2, s3.msg)
+
+	// Alice
+	err = alice.verifySMP4(&s3, s4.msg)
+	assertDeepEquals(t, err, nil)
+
+	err = alice.verifySMP4ProtocolSuccess(&s1, &s3, s4.msg)
+	assertDeepEquals(t, err, nil)
+}
+
+func runProtocol(t *testing.T, aliceSecret, bobSecret string) (aliceEvents, bobEvents []Event) {
+	alice := &Protocol{EventHandler: dynamicEventHandler{func(e Event, _ int, _ string) { aliceEvents = append(aliceEvents, e) }}}
+	bob := &Protocol{EventHandler: dynamicEventHandler{func(e Event, _ int, _ string) { bobEvents = append(bobEvents, e) }}}
+
+	toBob, err := alice.Start("What's the password?", GenerateSecret(nil, nil, nil, []byte(aliceSecret)))
+	assertNil(t, err)
+	assertEquals(t, len(toBob), 1)
+
+	// The messages travel in their serialized form, to make sure that works too
+	m, err := Unmarshal(Marshal(toBob[0]))
+	assertNil(t, err)
+	_, err = bob.Receive(m)
+	assertNil(t, err)
+	q, _ := bob.Question()
+	assertEquals(t, q, "What's the password?")
+
+	toAlice, err := bob.ProvideSecret(GenerateSecret(nil, nil, nil, []byte(bobSecret)))
+	assertNil(t, err)
+
+	for toAlice != nil {
+		m, err = Unmarshal(Marshal(toAlice))
+		assertNil(t, err)
+		reply, err := alice.Receive(m)
+		assertNil(t, err)
+		toAlice = nil
+		if reply != nil {
+			m, err = Unmarshal(Marshal(reply))
+			assertNil(t, err)
+			toAlice, err = bob.Receive(m)
+			assertNil(t, err)
+		}
+	}
+
+	assertEquals(t, alice.State(), StateExpect1)
+	assertEquals(t, bob.State(), StateExpect1)
+	return aliceEvents, bobEvents
+}
+
+func Test_Protocol_succeedsWhenBothPartiesHaveTheSameSecret(t *testing.T) {
+	aliceEvents, bobEvents := runProtocol(t, "swordfish", "swordfish")
+	assertDeepEquals(t, aliceEvents, []Event{EventInProgress, EventSuccess})
+	assertDeepEquals(t, bobEvents, []Event{EventAskForAnswer, EventSuccess})
+}
+
+func Test_Protocol_failsWhenThePartiesHaveDifferentSecrets(t *testing.T) {
+	aliceEvents, bobEvents := runProtocol(t, "swordfish", "marlin")
+	// Bob finds out first, and aborts the protocol instead of sending the last message
+	assertDeepEquals(t, aliceEvents, []Event{EventInProgress, EventAbort})
+	assertDeepEquals(t, bobEvents, []Event{EventAskForAnswer, EventFailure})
+}
@@ -0,0 +1,237 @@
+package smp
+
+import "math/big"
+
+// State identifies what the protocol is waiting for
+type State int
+
+const (
+	// StateExpect1 means no run of the protocol is in progress
+	StateExpect1 State = iota
+	// StateWaitingForSecret means a Message1 has been received, and the user has to provide the secret
+	StateWaitingForSecret
+	// StateExpect2 means a Message1 has been sent, and a Message2 is expected
+	StateExpect2
+	// StateExpect3 means a Message2 has been sent, and a Message3 is expected
+	StateExpect3
+	// StateExpect4 means a Message3 has been sent, and a Message4 is expected
+	StateExpect4
+)
+
+func (s State) String() string {
+	switch s {
+	case StateExpect1:
+		return "SMPSTATE_EXPECT1"
+	case StateWaitingForSecret:
+		return "SMPSTATE_WAITINGFORSECRET (internal)"
+	case StateExpect2:
+		return "SMPSTATE_EXPECT2"
+	case StateExpect3:
+		return "SMPSTATE_EXPECT3"
+	case StateExpect4:
+		return "SMPSTATE_EXPECT4"
+	default:
+		return "SMP STATE: (THIS SHOULD NEVER HAPPEN)"
+	}
+}
+
+type stateBase struct{}
+type stateExpect1 struct{ stateBase }
+type stateExpect2 struct{ stateBase }
+type stateExpect3 struct{ stateBase }
+type stateExpect4 struct{ stateBase }
+type stateWaitingForSecret struct {
+	stateBase
+	msg Message1
+}
+
+type state interface {
+	startAuthenticate(*Protocol, string, *big.Int) ([]Message, error)
+	receiveMessage1(*Protocol, Message1) (state, Message, error)
+	continueMessage1(*Protocol, *big.Int) (state, Message, error)
+	receiveMessage2(*Protocol, Message2) (state, Message, error)
+	receiveMessage3(*Protocol, Message3) (state, Message, error)
+	receiveMessage4(*Protocol, Message4) (state, Message, error)
+	identity() State
+}
+
+func abortState(e error) (state, Message, error) {
+	return stateExpect1{}, MessageAbort{}, e
+}
+
+func sendAbortAndRestartStateMachine() (state, Message, error) {
+	//must return nil error otherwise the abort message will be ignored
+	return abortState(nil)
+}
+
+func (pr *Protocol) abortStateMachineAndNotifyCheated() (state, Message, error) {
+	pr.event(EventCheated, 0)
+	return sendAbortAndRestartStateMachine()
+}
+
+func abortStateMachineAndNotifyError(pr *Protocol) (state, Message, error) {
+	pr.event(EventError, 0)
+	return sendAbortAndRestartStateMachine()
+}
+
+func (stateBase) receiveMessage1(pr *Protocol, m Message1) (state, Message, error) {
+	return abortStateMachineAndNotifyError(pr)
+}
+
+func (stateBase) continueMessage1(pr *Protocol, secret *big.Int) (state, Message, error) {
+	return abortState(ErrNotWaitingForSecret)
+}
+
+func (stateBase) receiveMessage2(pr *Protocol, m Message2) (state, Message, error) {
+	return abortStateMachineAndNotifyError(pr)
+}
+
+func (stateBase) receiveMessage3(pr *Protocol, m Message3) (state, Message, error) {
+	return abortStateMachineAndNotifyError(pr)
+}
+
+func (stateBase) receiveMessage4(pr *Protocol, m Message4) (state, Message, error) {
+	return abortStateMachineAndNotifyError(pr)
+}
+
+func (stateExpect1) receiveMessage1(pr *Protocol, m Message1) (state, Message, error) {
+	err := pr.verifySMP1(m)
+	if err != nil {
+		return pr.abortStateMachineAndNotifyCheated()
+	}
+
+	if m.hasQuestion {
+		pr.question = &m.question
+		pr.eventWithQuestion(EventAskForAnswer, 25, m.question)
+	} else {
+		pr.event(EventAskForSecret, 25)
+	}
+
+	return stateWaitingForSecret{msg: m}, nil, nil
+}
+
+func (s stateWaitingForSecret) continueMessage1(pr *Protocol, secret *big.Int) (state, Message, error) {
+	pr.secret = secret
+	s2, err := pr.generateSMP2(pr.secret, s.msg)
+	if err != nil {
+		return pr.abortStateMachineAndNotifyCheated()
+	}
+
+	pr.s2 = &s2
+
+	return stateExpect3{}, s2.msg, nil
+}
+
+func (stateExpect2) receiveMessage2(pr *Protocol, m Message2) (state, Message, error) {
+	err := pr.verifySMP2(pr.s1, m)
+	if err != nil {
+		return pr.abortStateMachineAndNotifyCheated()
+	}
+
+	s3, err := pr.generateSMP3(pr.secret, *pr.s1, m)
+	if err != nil {
+		return pr.abortStateMachineAndNotifyCheated()
+	}
+
+	pr.event(EventInProgress, 60)
+
+	pr.s3 = &s3
+
+	return stateExpect4{}, s3.msg, nil
+}
+
+func (stateExpect3) receiveMessage3(pr *Protocol, m Message3) (state, Message, error) {
+	err := pr.verifySMP3(pr.s2, m)
+	if err != nil {
+		return pr.abortStateMachineAndNotifyCheated()
+	}
+
+	err = pr.verifySMP3ProtocolSuccess(pr.s2, m)
+	if err != nil {
+		pr.event(EventFailure, 100)
+		return sendAbortAndRestartStateMachine()
+	}
+	pr.event(EventSuccess, 100)
+
+	ret, err := pr.generateSMP4(pr.secret, *pr.s2, m)
+	if err != nil {
+		return pr.abortStateMachineAndNotifyCheated()
+	}
+
+	pr.Wipe()
+	return stateExpect1{}, ret.msg, nil
+}
+
+func (stateExpect4) receiveMessage4(pr *Protocol, m Message4) (state, Message, error) {
+	err := pr.verifySMP4(pr.s3, m)
+	if err != nil {
+		return pr.abortStateMachineAndNotifyCheated()
+	}
+
+	err = pr.verifySMP4ProtocolSuccess(pr.s1, pr.s3, m)
+	if err != nil {
+		pr.event(EventFailure, 100)
+		return sendAbortAndRestartStateMachine()
+	}
+	pr.event(EventSuccess, 100)
+
+	pr.Wipe()
+	return stateExpect1{}, nil, nil
+}
+
+func (m Message1) receivedMessage(pr *Protocol) (ret Message, err error) {
+	pr.state, ret, err = pr.currentState().receiveMessage1(pr, m)
+	return
+}
+
+func (m Message2) receivedMessage(pr *Protocol) (ret Message, err error) {
+	pr.state, ret, err = pr.currentState().receiveMessage2(pr, m)
+	return
+}
+
+func (m Message3) receivedMessage(pr *Protocol) (ret Message, err error) {
+	pr.state, ret, err = pr.currentState().receiveMessage3(pr, m)
+	return
+}
+
+func (m Message4) receivedMessage(pr *Protocol) (ret Message, err error) {
+	pr.state, ret, err = pr.currentState().receiveMessage4(pr, m)
+	return
+}
+
+func (m MessageAbort) receivedMessage(pr *Protocol) (ret Message, err error) {
+	pr.state = stateExpect1{}
+	pr.event(EventAbort, 0)
+	return
+}
+
+func (stateExpect1) identity() State          { return StateExpect1 }
+func (stateExpect2) identity() State          { return StateExpect2 }
+func (stateExpect3) identity() State          { return StateExpect3 }
+func (stateExpect4) identity() State          { return StateExpect4 }
+func (stateWaitingForSecret) identity() State { return StateWaitingForSecret }
+
+func (stateBase) startAuthenticate(pr *Protocol, question string, secret *big.Int) (msgs []Message, err error) {
+	msgs, err = stateExpect1{}.startAuthenticate(pr, question, secret)
+	msgs = append([]Message{MessageAbort{}}, msgs...)
+	return
+}
+
+func (stateExpect1) startAuthenticate(pr *Protocol, question string, secret *big.Int) (msgs []Message, err error) {
+	pr.secret = secret
+
+	s1, err := pr.generateSMP1()
+	if err != nil {
+		return nil, ErrShortRandomRead
+	}
+
+	if question != "" {
+		s1.msg.hasQuestion = true
+		s1.msg.question = question
+	}
+
+	pr.s1 = &s1
+	pr.state = stateExpect2{}
+
+	return []Message{s1.msg}, nil
+}
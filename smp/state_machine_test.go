@@ -0,0 +1,568 @@
+package smp
+
+import (
+	"math/big"
+	"testing"
+)
+
+func Test_stateExpect1_goToWaitingForSecretWhenReceivesSmpMessage1(t *testing.T) {
+	pr := newProtocol(Version3, fixtureRand())
+	msg := fixtureMessage1()
+	nextState, _, _ := stateExpect1{}.receiveMessage1(pr, msg)
+
+	assertDeepEquals(t, nextState, stateWaitingForSecret{msg: msg})
+}
+
+func Test_stateExpect1_willSendANotificationThatASecretIsNeeded(t *testing.T) {
+	pr := newProtocol(Version3, fixtureRand())
+	pr.expectEvent(t, func() {
+		stateExpect1{}.receiveMessage1(pr, fixtureMessage1())
+	}, EventAskForSecret, 25, "")
+}
+
+func Test_stateExpect1_willSendANotificationThatAnAnswerIsNeededIfQuestionProvided(t *testing.T) {
+	pr := newProtocol(Version3, fixtureRand())
+	msg := fixtureMessage1()
+	msg.hasQuestion = true
+	msg.question = "What do you think?"
+
+	pr.expectEvent(t, func() {
+		stateExpect1{}.receiveMessage1(pr, msg)
+	}, EventAskForAnswer, 25, "What do you think?")
+}
+
+func Test_stateWaitingForSecret_goToExpectState3WhenReceivesContinueSmpMessage1(t *testing.T) {
+	pr := newProtocol(Version3, fixtureRand())
+	pr.state = stateWaitingForSecret{msg: fixtureMessage1()}
+
+	msg := fixtureMessage1()
+	nextState, _, err := stateWaitingForSecret{msg: msg}.continueMessage1(pr, fixtureSecret())
+
+	assertNil(t, err)
+	assertNotNil(t, pr.s2)
+	assertEquals(t, nextState, stateExpect3{})
+}
+
+func Test_stateExpect1_receiveMessage1_setsTheSMPQuestionIfThereWasOneInTheMessage(t *testing.T) {
+	pr := newProtocol(Version3, fixtureRand())
+	pr.secret = bnFromHex("ABCDE56321F9A9F8E364607C8C82DECD8E8E6209E2CB952C7E649620F5286FE3")
+	msg := fixtureMessage1Q()
+
+	stateExpect1{}.receiveMessage1(pr, msg)
+	v, ok := pr.Question()
+
+	assertDeepEquals(t, ok, true)
+	assertDeepEquals(t, v, "What's the clue?")
+}
+
+func Test_stateExpect1_returnsSmpMessageAbortIfReceivesUnexpectedMessage(t *testing.T) {
+	state := stateExpect1{}
+	pr := newProtocol(Version3, fixtureRand())
+	_, msg, err := state.receiveMessage2(pr, Message2{})
+	assertEquals(t, err, nil)
+	assertDeepEquals(t, msg, MessageAbort{})
+
+	_, msg, err = state.receiveMessage3(pr, Message3{})
+	assertEquals(t, err, nil)
+	assertDeepEquals(t, msg, MessageAbort{})
+
+	_, msg, err = state.receiveMessage4(pr, Message4{})
+	assertEquals(t, err, nil)
+	assertDeepEquals(t, msg, MessageAbort{})
+}
+
+func Test_stateExpect1_givesAnErrorNotificationIfTheWrongMessageIsSent(t *testing.T) {
+	state := stateExpect1{}
+	pr := newProtocol(Version3, fixtureRand())
+
+	pr.expectEvent(t, func() {
+		state.receiveMessage2(pr, Message2{})
+	}, EventError, 0, "")
+
+	pr.expectEvent(t, func() {
+		state.receiveMessage3(pr, Message3{})
+	}, EventError, 0, "")
+
+	pr.expectEvent(t, func() {
+		state.receiveMessage4(pr, Message4{})
+	}, EventError, 0, "")
+}
+
+func Test_stateExpect2_givesAnErrorNotificationIfTheWrongMessageIsSent(t *testing.T) {
+	state := stateExpect2{}
+	pr := newProtocol(Version3, fixtureRand())
+
+	pr.expectEvent(t, func() {
+		state.receiveMessage1(pr, Message1{})
+	}, EventError, 0, "")
+
+	pr.expectEvent(t, func() {
+		state.receiveMessage3(pr, Message3{})
+	}, EventError, 0, "")
+
+	pr.expectEvent(t, func() {
+		state.receiveMessage4(pr, Message4{})
+	}, EventError, 0, "")
+}
+
+func Test_stateExpect3_givesAnErrorNotificationIfTheWrongMessageIsSent(t *testing.T) {
+	state := stateExpect3{}
+	pr := newProtocol(Version3, fixtureRand())
+
+	pr.expectEvent(t, func() {
+		state.receiveMessage1(pr, Message1{})
+	}, EventError, 0, "")
+
+	pr.expectEvent(t, func() {
+		state.receiveMessage2(pr, Message2{})
+	}, EventError, 0, "")
+
+	pr.expectEvent(t, func() {
+		state.receiveMessage4(pr, Message4{})
+	}, EventError, 0, "")
+}
+
+func Test_stateExpect4_givesAnErrorNotificationIfTheWrongMessageIsSent(t *testing.T) {
+	state := stateExpect4{}
+	pr := newProtocol(Version3, fixtureRand())
+
+	pr.expectEvent(t, func() {
+		state.receiveMessage1(pr, Message1{})
+	}, EventError, 0, "")
+
+	pr.expectEvent(t, func() {
+		state.receiveMessage2(pr, Message2{})
+	}, EventError, 0, "")
+
+	pr.expectEvent(t, func() {
+		state.receiveMessage3(pr, Message3{})
+	}, EventError, 0, "")
+}
+
+func Test_stateExpect2_goToExpectState4WhenReceivesSmpMessage2(t *testing.T) {
+	pr := newProtocol(Version3, fixtureRand())
+	pr.secret = bnFromHex("ABCDE56321F9A9F8E364607C8C82DECD8E8E6209E2CB952C7E649620F5286FE3")
+	pr.s1 = fixtureSmp1()
+
+	msg := fixtureMessage2()
+	nextState, _, err := stateExpect2{}.receiveMessage2(pr, msg)
+
+	assertNil(t, err)
+	assertNotNil(t, pr.s3)
+	assertEquals(t, nextState, stateExpect4{})
+}
+
+func Test_stateExpect2_sendsAnEventAboutSMPProgressHere(t *testing.T) {
+	pr := newProtocol(Version3, fixtureRand())
+	pr.secret = bnFromHex("ABCDE56321F9A9F8E364607C8C82DECD8E8E6209E2CB952C7E649620F5286FE3")
+	pr.s1 = fixtureSmp1()
+
+	pr.expectEvent(t, func() {
+		stateExpect2{}.receiveMessage2(pr, fixtureMessage2())
+	}, EventInProgress, 60, "")
+}
+
+func Test_stateExpect2_returnsSmpMessageAbortIfReceivesUnexpectedMessage(t *testing.T) {
+	state := stateExpect2{}
+	pr := newProtocol(Version3, fixtureRand())
+	_, msg, err := state.receiveMessage1(pr, Message1{})
+	assertEquals(t, err, nil)
+	assertDeepEquals(t, msg, MessageAbort{})
+
+	_, msg, err = state.receiveMessage3(pr, Message3{})
+	assertEquals(t, err, nil)
+	assertDeepEquals(t, msg, MessageAbort{})
+
+	_, msg, err = state.receiveMessage4(pr, Message4{})
+	assertEquals(t, err, nil)
+	assertDeepEquals(t, msg, MessageAbort{})
+}
+
+func Test_stateExpect3_goToExpectState1WhenReceivesSmpMessage3(t *testing.T) {
+	pr := newProtocol(Version3, fixtureRand())
+	pr.secret = bnFromHex("ABCDE56321F9A9F8E364607C8C82DECD8E8E6209E2CB952C7E649620F5286FE3")
+	pr.s2 = fixtureSmp2()
+	msg := fixtureMessage3()
+
+	nextState, _, _ := stateExpect3{}.receiveMessage3(pr, msg)
+
+	assertEquals(t, nextState, stateExpect1{})
+}
+
+func Test_stateExpect3_wipesSMPWhenReceivesSmpMessage3(t *testing.T) {
+	pr := newProtocol(Version3, fixtureRand())
+	pr.s2 = fixtureSmp2()
+	msg := fixtureMessage3()
+
+	nextState, _, _ := stateExpect3{}.receiveMessage3(pr, msg)
+
+	assertEquals(t, nextState, stateExpect1{})
+	assertNil(t, pr.secret)
+	assertNil(t, pr.s1)
+	assertNil(t, pr.s2)
+	assertNil(t, pr.s3)
+}
+
+func Test_stateExpect3_willSendAnSMPNotificationOnProtocolSuccess(t *testing.T) {
+	pr := newProtocol(Version3, fixtureRand())
+	pr.secret = bnFromHex("ABCDE56321F9A9F8E364607C8C82DECD8E8E6209E2CB952C7E649620F5286FE3")
+	pr.s2 = fixtureSmp2()
+
+	pr.expectEvent(t, func() {
+		stateExpect3{}.receiveMessage3(pr, fixtureMessage3())
+	}, EventSuccess, 100, "")
+}
+
+func Test_stateExpect3_returnsSmpMessageAbortIfReceivesUnexpectedMessage(t *testing.T) {
+	state := stateExpect3{}
+	pr := newProtocol(Version3, fixtureRand())
+	_, msg, err := state.receiveMessage1(pr, Message1{})
+	assertEquals(t, err, nil)
+	assertDeepEquals(t, msg, MessageAbort{})
+
+	_, msg, err = state.receiveMessage2(pr, Message2{})
+	assertEquals(t, err, nil)
+	assertDeepEquals(t, msg, MessageAbort{})
+
+	_, msg, err = state.receiveMessage4(pr, Message4{})
+	assertEquals(t, err, nil)
+	assertDeepEquals(t, msg, MessageAbort{})
+}
+
+func Test_stateExpect4_goToExpectState1WhenReceivesSmpMessage4(t *testing.T) {
+	pr := newProtocol(Version3, fixtureRand())
+	pr.s1 = fixtureSmp1()
+	pr.s3 = fixtureSmp3()
+	msg := fixtureMessage4()
+
+	nextState, _, _ := stateExpect4{}.receiveMessage4(pr, msg)
+
+	assertEquals(t, nextState, stateExpect1{})
+}
+
+func Test_stateExpect4_willSendAnSMPNotificationOnProtocolSuccess(t *testing.T) {
+	pr := newProtocol(Version3, fixtureRand())
+	pr.s1 = fixtureSmp1()
+	pr.s3 = fixtureSmp3()
+
+	pr.expectEvent(t, func() {
+		stateExpect4{}.receiveMessage4(pr, fixtureMessage4())
+	}, EventSuccess, 100, "")
+}
+
+func Test_stateExpect4_wipesSMPWhenReceivesSmpMessage4(t *testing.T) {
+	pr := newProtocol(Version3, fixtureRand())
+	pr.s1 = fixtureSmp1()
+	pr.s3 = fixtureSmp3()
+	msg := fixtureMessage4()
+
+	nextState, _, _ := stateExpect4{}.receiveMessage4(pr, msg)
+
+	assertEquals(t, nextState, stateExpect1{})
+	assertNil(t, pr.secret)
+	assertNil(t, pr.s1)
+	assertNil(t, pr.s2)
+	assertNil(t, pr.s3)
+}
+
+func Test_stateExpect4_returnsSmpMessageAbortIfReceivesUnexpectedMessage(t *testing.T) {
+	state := stateExpect4{}
+	pr := newProtocol(Version3, fixtureRand())
+	_, msg, err := state.receiveMessage1(pr, Message1{})
+	assertEquals(t, err, nil)
+	assertDeepEquals(t, msg, MessageAbort{})
+
+	_, msg, err = state.receiveMessage2(pr, Message2{})
+	assertEquals(t, err, nil)
+	assertDeepEquals(t, msg, MessageAbort{})
+
+	_, msg, err = state.receiveMessage3(pr, Message3{})
+	assertEquals(t, err, nil)
+	assertDeepEquals(t, msg, MessageAbort{})
+}
+
+func Test_contextTransitionsFromSmpExpect1ToSmpWaitingForSecret(t *testing.T) {
+	m := fixtureMessage1()
+	pr := newProtocol(Version3, fixtureRand())
+	pr.secret = bnFromHex("ABCDE56321F9A9F8E364607C8C82DECD8E8E6209E2CB952C7E649620F5286FE3")
+
+	pr.Receive(m)
+	assertDeepEquals(t, pr.state, stateWaitingForSecret{msg: m})
+}
+
+func Test_contextTransitionsFromSmpExpect2ToSmpExpect4(t *testing.T) {
+	m := fixtureMessage2()
+	pr := newProtocol(Version3, fixtureRand())
+	pr.state = stateExpect2{}
+	pr.s1 = fixtureSmp1()
+	pr.secret = bnFromHex("ABCDE56321F9A9F8E364607C8C82DECD8E8E6209E2CB952C7E649620F5286FE3")
+
+	pr.Receive(m)
+	assertEquals(t, pr.state, stateExpect4{})
+}
+
+func Test_contextTransitionsFromSmpExpect3ToSmpExpect1(t *testing.T) {
+	m := fixtureMessage3()
+	pr := newProtocol(Version3, fixtureRand())
+	pr.state = stateExpect3{}
+	pr.s2 = fixtureSmp2()
+	pr.secret = bnFromHex("ABCDE56321F9A9F8E364607C8C82DECD8E8E6209E2CB952C7E649620F5286FE3")
+
+	pr.Receive(m)
+	assertEquals(t, pr.state, stateExpect1{})
+}
+
+func Test_contextTransitionsFromSmpExpect4ToSmpExpect1(t *testing.T) {
+	m := fixtureMessage4()
+	pr := newProtocol(Version3, fixtureRand())
+	pr.state = stateExpect4{}
+	pr.s1 = fixtureSmp1()
+	pr.s3 = fixtureSmp3()
+
+	pr.Receive(m)
+	assertEquals(t, pr.state, stateExpect1{})
+}
+
+func Test_contextUnexpectedMessageTransitionsToSmpExpected1(t *testing.T) {
+	m := fixtureMessage1()
+
+	pr := newProtocol(Version3, fixtureRand())
+	pr.state = stateExpect3{}
+	toSend, err := pr.Receive(m)
+
+	assertNil(t, err)
+	assertEquals(t, pr.state, stateExpect1{})
+	assertDeepEquals(t, toSend, MessageAbort{})
+}
+
+func Test_stateExpect1_receiveMessage1_abortsSMPIfVerifySMP1ReturnsError(t *testing.T) {
+	pr := newProtocol(Version3, fixtureRand())
+
+	s, m, err := stateExpect1{}.receiveMessage1(pr, Message1{g2a: big.NewInt(1)})
+
+	assertNil(t, err)
+	assertEquals(t, s, stateExpect1{})
+	assertDeepEquals(t, m, MessageAbort{})
+}
+
+func Test_stateExpect1_receiveMessage1_signalsCheatingIfVerifySMP1Fails(t *testing.T) {
+	pr := newProtocol(Version3, fixtureRand())
+
+	pr.expectEvent(t, func() {
+		stateExpect1{}.receiveMessage1(pr, Message1{g2a: big.NewInt(1)})
+	}, EventCheated, 0, "")
+}
+
+func Test_Message1_receivedMessage_abortsSMPIfFailsToVerifyMessage1(t *testing.T) {
+	pr := newProtocol(Version3, fixtureRand())
+	pr.state = stateExpect1{}
+	m := Message1{g2a: big.NewInt(1)}
+	ret, err := m.receivedMessage(pr)
+
+	assertNil(t, err)
+	assertDeepEquals(t, ret, MessageAbort{})
+}
+
+func Test_stateWaitingForSecret_continueMessage1_abortsSMPIfgenerateSMP2Fails(t *testing.T) {
+	pr := newProtocol(Version3, fixedRand([]string{"ABCD"}))
+	pr.state = stateWaitingForSecret{msg: fixtureMessage1()}
+
+	s, m, err := stateWaitingForSecret{msg: fixtureMessage1()}.continueMessage1(pr, fixtureSecret())
+
+	assertNil(t, err)
+	assertEquals(t, s, stateExpect1{})
+	assertEquals(t, m, MessageAbort{})
+}
+
+func Test_stateExpect2_receiveMessage2_abortsSMPIfVerifySMPReturnsError(t *testing.T) {
+	pr := newProtocol(Version3, fixtureRand())
+	pr.s1 = fixtureSmp1()
+	pr.secret = bnFromHex("ABCDE56321F9A9F8E364607C8C82DECD8E8E6209E2CB952C7E649620F5286FE3")
+	s, m, err := stateExpect2{}.receiveMessage2(pr, Message2{g2b: big.NewInt(1)})
+
+	assertNil(t, err)
+	assertEquals(t, s, stateExpect1{})
+	assertDeepEquals(t, m, MessageAbort{})
+}
+
+func Test_Message2_receivedMessage_abortsSMPIfUnderlyingPrimitiveHasErrors(t *testing.T) {
+	pr := newProtocol(Version3, fixtureRand())
+	pr.state = stateExpect2{}
+	pr.s1 = fixtureSmp1()
+	pr.secret = bnFromHex("ABCDE56321F9A9F8E364607C8C82DECD8E8E6209E2CB952C7E649620F5286FE3")
+	ret, err := Message2{g2b: big.NewInt(1)}.receivedMessage(pr)
+
+	assertNil(t, err)
+	assertDeepEquals(t, ret, MessageAbort{})
+}
+
+func Test_stateExpect2_receiveMessage2_abortsSMPIfgenerateSMPFails(t *testing.T) {
+	pr := newProtocol(Version3, fixedRand([]string{"ABCD"}))
+	pr.s1 = fixtureSmp1()
+	pr.secret = bnFromHex("ABCDE56321F9A9F8E364607C8C82DECD8E8E6209E2CB952C7E649620F5286FE3")
+	s, m, err := stateExpect2{}.receiveMessage2(pr, fixtureMessage2())
+
+	assertNil(t, err)
+	assertEquals(t, s, stateExpect1{})
+	assertDeepEquals(t, m, MessageAbort{})
+}
+
+func Test_stateExpect3_receiveMessage3_abortsSMPIfVerifySMPReturnsError(t *testing.T) {
+	pr := newProtocol(Version3, fixtureRand())
+	pr.secret = bnFromHex("ABCDE56321F9A9F8E364607C8C82DECD8E8E6209E2CB952C7E649620F5286FE3")
+	pr.s2 = fixtureSmp2()
+	s, m, err := stateExpect3{}.receiveMessage3(pr, Message3{pa: big.NewInt(1)})
+
+	assertNil(t, err)
+	assertEquals(t, s, stateExpect1{})
+	assertDeepEquals(t, m, MessageAbort{})
+}
+
+func Test_Message3_receivedMessage_abortsSMPIfUnderlyingPrimitiveDoes(t *testing.T) {
+	pr := newProtocol(Version3, fixtureRand())
+	pr.state = stateExpect3{}
+	pr.secret = bnFromHex("ABCDE56321F9A9F8E364607C8C82DECD8E8E6209E2CB952C7E649620F5286FE3")
+	pr.s2 = fixtureSmp2()
+	ret, err := Message3{pa: big.NewInt(1)}.receivedMessage(pr)
+
+	assertNil(t, err)
+	assertDeepEquals(t, ret, MessageAbort{})
+}
+
+func Test_stateExpect3_receiveMessage3_abortsSMPIfProtocolFails(t *testing.T) {
+	pr := newProtocol(Version3, fixtureRand())
+	pr.secret = bnFromHex("ABCDE56321F9A9F8E364607C8C82DECD8E8E6209E2CB952C7E649620F5286FE3")
+	pr.s2 = fixtureSmp2()
+	pr.s2.b3 = sub(pr.s2.b3, big.NewInt(1))
+	s, m, err := stateExpect3{}.receiveMessage3(pr, fixtureMessage3())
+
+	assertNil(t, err)
+	assertEquals(t, s, stateExpect1{})
+	assertEquals(t, m, MessageAbort{})
+}
+
+func Test_stateExpect3_receiveMessage3_willSendAnSMPNotificationOnProtocolFailure(t *testing.T) {
+	pr := newProtocol(Version3, fixtureRand())
+	pr.secret = bnFromHex("ABCDE56321F9A9F8E364607C8C82DECD8E8E6209E2CB952C7E649620F5286FE3")
+	pr.s2 = fixtureSmp2()
+	pr.s2.b3 = sub(pr.s2.b3, big.NewInt(1))
+
+	pr.expectEvent(t, func() {
+		stateExpect3{}.receiveMessage3(pr, fixtureMessage3())
+	}, EventFailure, 100, "")
+
+}
+
+func Test_stateExpect3_receiveMessage3_abortsSMPIfCantGenerateFinalParameters(t *testing.T) {
+	pr := newProtocol(Version3, fixedRand([]string{"ABCD"}))
+	pr.secret = bnFromHex("ABCDE56321F9A9F8E364607C8C82DECD8E8E6209E2CB952C7E649620F5286FE3")
+	pr.s2 = fixtureSmp2()
+	s, m, err := stateExpect3{}.receiveMessage3(pr, fixtureMessage3())
+
+	assertNil(t, err)
+	assertEquals(t, s, stateExpect1{})
+	assertDeepEquals(t, m, MessageAbort{})
+}
+
+func Test_stateExpect4_receiveMessage4_abortsSMPIfVerifySMPReturnsError(t *testing.T) {
+	pr := newProtocol(Version3, fixtureRand())
+	pr.s1 = fixtureSmp1()
+	pr.s3 = fixtureSmp3()
+	s, m, err := stateExpect4{}.receiveMessage4(pr, Message4{rb: big.NewInt(1)})
+
+	assertNil(t, err)
+	assertEquals(t, s, stateExpect1{})
+	assertDeepEquals(t, m, MessageAbort{})
+}
+
+func Test_stateExpect4_receiveMessage4_abortsSMPIfProtocolFails(t *testing.T) {
+	pr := newProtocol(Version3, fixtureRand())
+	pr.s1 = fixtureSmp1()
+	pr.s3 = fixtureSmp3()
+	pr.s3.papb = sub(pr.s3.papb, big.NewInt(1))
+	s, m, err := stateExpect4{}.receiveMessage4(pr, fixtureMessage4())
+
+	assertNil(t, err)
+	assertEquals(t, s, stateExpect1{})
+	assertEquals(t, m, MessageAbort{})
+}
+
+func Test_stateExpect4_receiveMessage4_willSendAnSMPNotificationOnProtocolFailure(t *testing.T) {
+	pr := newProtocol(Version3, fixtureRand())
+	pr.s1 = fixtureSmp1()
+	pr.s3 = fixtureSmp3()
+	pr.s3.papb = sub(pr.s3.papb, big.NewInt(1))
+
+	pr.expectEvent(t, func() {
+		stateExpect4{}.receiveMessage4(pr, fixtureMessage4())
+	}, EventFailure, 100, "")
+}
+
+func Test_Message4_receivedMessage_abortsSMPIfTheUnderlyingPrimitiveDoes(t *testing.T) {
+	pr := newProtocol(Version3, fixtureRand())
+	pr.state = stateExpect4{}
+	pr.s1 = fixtureSmp1()
+	pr.s3 = fixtureSmp3()
+
+	ret, err := Message4{rb: big.NewInt(1)}.receivedMessage(pr)
+	assertNil(t, err)
+	assertDeepEquals(t, ret, MessageAbort{})
+}
+
+func Test_receive_returnsAnyErrorThatOccurs(t *testing.T) {
+	m := fixtureMessage2()
+	pr := newProtocol(Version3, fixedRand([]string{"ABCD"}))
+	pr.s1 = fixtureSmp1()
+	pr.state = stateExpect2{}
+	pr.secret = bnFromHex("ABCDE56321F9A9F8E364607C8C82DECD8E8E6209E2CB952C7E649620F5286FE3")
+
+	ret, err := pr.Receive(m)
+	assertNil(t, err)
+	assertDeepEquals(t, ret, MessageAbort{})
+}
+
+func Test_StateExpect1_String_returnsTheCorrectString(t *testing.T) {
+	assertEquals(t, StateExpect1.String(), "SMPSTATE_EXPECT1")
+}
+
+func Test_StateExpect2_String_returnsTheCorrectString(t *testing.T) {
+	assertEquals(t, StateExpect2.String(), "SMPSTATE_EXPECT2")
+}
+
+func Test_StateExpect3_String_returnsTheCorrectString(t *testing.T) {
+	assertEquals(t, StateExpect3.String(), "SMPSTATE_EXPECT3")
+}
+
+func Test_StateExpect4_String_returnsTheCorrectString(t *testing.T) {
+	assertEquals(t, StateExpect4.String(), "SMPSTATE_EXPECT4")
+}
+
+func Test_MessageAbort_receivedMessage_setsTheNewState(t *testing.T) {
+	pr := newProtocol(Version3, fixtureRand())
+	pr.state = stateExpect2{}
+	ret, err := MessageAbort{}.receivedMessage(pr)
+	assertDeepEquals(t, ret, nil)
+	assertDeepEquals(t, err, nil)
+	assertDeepEquals(t, pr.state, stateExpect1{})
+}
+
+func Test_MessageAbort_receivedMessage_sendsAnEventAboutTheAbort(t *testing.T) {
+	pr := newProtocol(Version3, fixtureRand())
+	pr.state = stateExpect2{}
+
+	pr.expectEvent(t, func() {
+		MessageAbort{}.receivedMessage(pr)
+	}, EventAbort, 0, "")
+}
+
+func Test_StateWaitingForSecret_String_returnsTheCorrectString(t *testing.T) {
+	assertEquals(t, StateWaitingForSecret.String(), "SMPSTATE_WAITINGFORSECRET (internal)")
+}
+
+func Test_identity_returnsThePublicStateOfEachInternalState(t *testing.T) {
+	assertEquals(t, stateExpect1{}.identity(), StateExpect1)
+	assertEquals(t, stateWaitingForSecret{}.identity(), StateWaitingForSecret)
+	assertEquals(t, stateExpect2{}.identity(), StateExpect2)
+	assertEquals(t, stateExpect3{}.identity(), StateExpect3)
+	assertEquals(t, stateExpect4{}.identity(), StateExpect4)
+}
@@ -1,6 +1,10 @@
 package otr3
 
-import "fmt"
+import (
+	"fmt"
+
+	"github.com/twstrike/otr3/smp"
+)
 
 // SMPEvent define the events used to indicate status of SMP to the UI
 type SMPEvent int
@@ -22,6 +26,10 @@ const (
 	SMPEventSuccess
 	// SMPEventFailure means update the auth progress dialog with progress_percent
 	SMPEventFailure
+	// SMPEventNormalizationMismatch means the peer normalizes the secret differently, so the authentication fails even if
+	// both users give the same secret. It is signaled when the first SMP message of the peer arrives, before any request for
+	// the secret. TheirSMPSecretNormalization tells how the peer normalizes it
+	SMPEventNormalizationMismatch
 )
 
 // SMPEventHandler handles SMPEvents
@@ -38,18 +46,38 @@ func (d dynamicSMPEventHandler) HandleSMPEvent(event SMPEvent, pp int, question
 	d.eh(event, pp, question)
 }
 
-func (c *Conversation) smpEvent(e SMPEvent, percent int) {
-	if c.smpEventHandler != nil {
-		c.smpEventHandler.HandleSMPEvent(e, percent, "")
-	}
-}
-
 func (c *Conversation) smpEventWithQuestion(e SMPEvent, percent int, question string) {
+	c.withReceiveInfo(func(info *ReceivedMessageInfo) {
+		info.SMPEvents = append(info.SMPEvents, ReceivedSMPEvent{e, percent, question})
+	})
+	c.publishEvent(Event{Kind: EventKindSMP, SMP: e, ProgressPercent: percent, Question: question})
+
 	if c.smpEventHandler != nil {
 		c.smpEventHandler.HandleSMPEvent(e, percent, question)
 	}
 }
 
+// smpEvents maps the events of the smp package to the SMP events of a conversation
+var smpEvents = map[smp.Event]SMPEvent{
+	smp.EventError:        SMPEventError,
+	smp.EventAbort:        SMPEventAbort,
+	smp.EventCheated:      SMPEventCheated,
+	smp.EventAskForAnswer: SMPEventAskForAnswer,
+	smp.EventAskForSecret: SMPEventAskForSecret,
+	smp.EventInProgress:   SMPEventInProgress,
+	smp.EventSuccess:      SMPEventSuccess,
+	smp.EventFailure:      SMPEventFailure,
+}
+
+// smpEventAdapter passes the events of the SMP state of a conversation on to its SMP event handler
+type smpEventAdapter struct {
+	c *Conversation
+}
+
+func (a smpEventAdapter) HandleEvent(event smp.Event, progressPercent int, question string) {
+	a.c.smpEventWithQuestion(smpEvents[event], progressPercent, question)
+}
+
 func (s SMPEvent) String() string {
 	switch s {
 	case SMPEventError:
@@ -68,6 +96,8 @@ func (s SMPEvent) String() string {
 		return "SMPEventSuccess"
 	case SMPEventFailure:
 		return "SMPEventFailure"
+	case SMPEventNormalizationMismatch:
+		return "SMPEventNormalizationMismatch"
 	default:
 		return "SMP EVENT: (THIS SHOULD NEVER HAPPEN)"
 	}
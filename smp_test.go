@@ -1,21 +1,18 @@
 package otr3
 
-import "testing"
-
-func Test_generateSMPSecretGeneratesASecret(t *testing.T) {
-	aliceFingerprint := bytesFromHex("0102030405060708090A0B0C0D0E0F1011121314")
-	bobFingerprint := bytesFromHex("3132333435363738393A3B3C3D3E3F4041424344")
-	ssid := bytesFromHex("FFF1D1E412345668")
-	secret := []byte("this is something secret")
-	result := generateSMPSecret(aliceFingerprint, bobFingerprint, ssid, secret, otrV3{})
-	assertDeepEquals(t, result, bnFromHex("D9B2E56321F9A9F8E364607C8C82DECD8E8E6209E2CB952C7E649620F5286FE3"))
-}
+import (
+	"testing"
+
+	"github.com/twstrike/otr3/smp"
+)
 
 func Test_SMPQuestion_returnsTheCurrentSMPQuestion(t *testing.T) {
-	c := newConversation(otrV3{}, fixtureRand())
-	q := "Are all greeks liars?"
-	c.smp.question = &q
-	res, ok := c.SMPQuestion()
+	alice, bob := smpConversationsAfterAKE(t)
+
+	msgs, _ := alice.StartAuthenticate("Are all greeks liars?", []byte("secret"))
+	bob.Receive(msgs[0])
+
+	res, ok := bob.SMPQuestion()
 	assertEquals(t, ok, true)
 	assertDeepEquals(t, res, "Are all greeks liars?")
 }
@@ -25,3 +22,26 @@ func Test_SMPQuestion_returnsNotOKIfThereIsNoQuestion(t *testing.T) {
 	_, ok := c.SMPQuestion()
 	assertEquals(t, ok, false)
 }
+
+func Test_wipeSMP_forgetsTheSMPState(t *testing.T) {
+	alice, _ := smpConversationsAfterAKE(t)
+	alice.StartAuthenticate("", []byte("secret"))
+
+	alice.wipeSMP()
+
+	assertNil(t, alice.smp)
+}
+
+func Test_smpVersion_usesTheParametersOfTheVersionOfTheConversation(t *testing.T) {
+	assertEquals(t, newConversation(otrV2{}, fixtureRand()).smpVersion(), smp.Version2)
+	assertEquals(t, newConversation(otrV3{}, fixtureRand()).smpVersion(), smp.Version3)
+	assertEquals(t, (&Conversation{}).smpVersion(), smp.Version3)
+}
+
+func Test_smpEventAdapter_mapsEveryEventOfTheSMPPackage(t *testing.T) {
+	for e := smp.EventError; e <= smp.EventFailure; e++ {
+		mapped, ok := smpEvents[e]
+		assertEquals(t, ok, true)
+		assertEquals(t, int(mapped), int(e))
+	}
+}
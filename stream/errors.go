@@ -0,0 +1,12 @@
+package stream
+
+import "errors"
+
+var (
+	// ErrClosed is returned when a Conn is used after it was closed
+	ErrClosed = errors.New("stream: connection closed")
+	// ErrConversationEnded is returned when writing after the peer has ended the OTR conversation
+	ErrConversationEnded = errors.New("stream: the peer ended the conversation")
+	// ErrUnencryptedData is returned when the peer sends data that wasn't encrypted. The data is thrown away
+	ErrUnencryptedData = errors.New("stream: received unencrypted data")
+)
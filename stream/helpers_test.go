@@ -0,0 +1,90 @@
+package stream
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/twstrike/otr3/internal/otrtest"
+)
+
+func assertEquals(t *testing.T, actual, expected interface{}) {
+	if actual != expected {
+		t.Errorf("Expected:\n%#v \nto equal:\n%#v\n", actual, expected)
+	}
+}
+
+func assertDeepEquals(t *testing.T, actual, expected interface{}) {
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expected:\n%#v \nto equal:\n%#v\n", actual, expected)
+	}
+}
+
+func assertNil(t *testing.T, actual interface{}) {
+	if actual != nil {
+		t.Errorf("Expected:\n%#v \nto be nil\n", actual)
+	}
+}
+
+// bufferedPipe is one direction of an in-memory connection. Unlike net.Pipe, writes don't wait for the reader,
+// the same way writes to a socket don't
+type bufferedPipe struct {
+	mu     sync.Mutex
+	data   *sync.Cond
+	buf    bytes.Buffer
+	closed bool
+}
+
+func newBufferedPipe() *bufferedPipe {
+	p := &bufferedPipe{}
+	p.data = sync.NewCond(&p.mu)
+	return p
+}
+
+func (p *bufferedPipe) Read(b []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for p.buf.Len() == 0 {
+		if p.closed {
+			return 0, io.EOF
+		}
+		p.data.Wait()
+	}
+	return p.buf.Read(b)
+}
+
+func (p *bufferedPipe) Write(b []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return 0, io.ErrClosedPipe
+	}
+	defer p.data.Broadcast()
+	return p.buf.Write(b)
+}
+
+func (p *bufferedPipe) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.closed = true
+	p.data.Broadcast()
+	return nil
+}
+
+type pipeEnd struct {
+	in, out *bufferedPipe
+}
+
+func (e pipeEnd) Read(b []byte) (int, error)  { return e.in.Read(b) }
+func (e pipeEnd) Write(b []byte) (int, error) { return e.out.Write(b) }
+func (e pipeEnd) Close() error {
+	e.in.Close()
+	return e.out.Close()
+}
+
+func connectedConns(t *testing.T) (alice, bob *Conn) {
+	toAlice, toBob := newBufferedPipe(), newBufferedPipe()
+	return New(pipeEnd{toAlice, toBob}, otrtest.Conversation(t, 0)), New(pipeEnd{toBob, toAlice}, otrtest.Conversation(t, 1))
+}
@@ -0,0 +1,229 @@
+// Package stream runs OTR conversations over line based byte streams, such as a TCP connection or a pipe
+// between two processes.
+//
+// A Conn wraps the stream and a Conversation. Every OTR message is written to the stream on a line of its own,
+// and the plaintext sent and received is exposed as an io.ReadWriteCloser. The AKE is started by Handshake,
+// or by the first Write, and the messages it needs are handled by Read on the other side:
+//
+//	c := &otr3.Conversation{}
+//	c.SetOurKeys(keys)
+//	c.Policies.AllowV3()
+//
+//	conn := stream.New(tcpConn, c)
+//	conn.Write([]byte("hello"))
+//	conn.Close()
+package stream
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"sync"
+
+	"github.com/twstrike/otr3"
+)
+
+// Conn is an io.ReadWriteCloser of plaintext that is sent encrypted with OTR over a line based stream.
+// Read and Write can be called from different goroutines
+type Conn struct {
+	conv   *otr3.Conversation
+	lines  *bufio.Reader
+	w      io.Writer
+	closer io.Closer
+
+	mu      sync.Mutex
+	input   *sync.Cond
+	reading bool
+	readErr error
+
+	plain        bytes.Buffer
+	queried      bool
+	wasEncrypted bool
+	closed       bool
+}
+
+// New returns a Conn that runs the conversation over rw. If rw is also an io.Closer, it is closed by Close
+func New(rw io.ReadWriter, c *otr3.Conversation) *Conn {
+	s := &Conn{
+		conv:  c,
+		lines: bufio.NewReader(rw),
+		w:     rw,
+	}
+	s.closer, _ = rw.(io.Closer)
+	s.input = sync.NewCond(&s.mu)
+	return s
+}
+
+// Conversation returns the conversation of the Conn, for example to authenticate the peer with SMP.
+// It must not be used while a Read or Write is in progress
+func (s *Conn) Conversation() *otr3.Conversation {
+	return s.conv
+}
+
+// Handshake asks the peer to start the AKE and waits until the conversation is encrypted.
+// Write calls it when needed, so it only has to be called to find out early if the peer can't be reached
+func (s *Conn) Handshake() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.handshake()
+}
+
+// Read reads plaintext sent by the peer. It returns io.EOF when the peer ends the conversation or the stream is closed
+func (s *Conn) Read(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for s.plain.Len() == 0 {
+		if s.closed {
+			return 0, ErrClosed
+		}
+		if s.wasEncrypted && !s.conv.IsEncrypted() {
+			return 0, io.EOF
+		}
+		if err := s.waitForInput(); err != nil && s.plain.Len() == 0 {
+			return 0, err
+		}
+	}
+
+	return s.plain.Read(p)
+}
+
+// Write sends p to the peer as one encrypted message, running the AKE first if the conversation isn't encrypted yet
+func (s *Conn) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return 0, ErrClosed
+	}
+
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	if err := s.handshake(); err != nil {
+		return 0, err
+	}
+
+	toSend, err := s.conv.Send(otr3.ValidMessage(p))
+	if err != nil {
+		return 0, err
+	}
+
+	if err := s.writeMessages(toSend); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Close ends the conversation, and closes the underlying stream if it can be closed
+func (s *Conn) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	defer s.input.Broadcast()
+
+	toSend, err := s.conv.End()
+	if err == nil {
+		err = s.writeMessages(toSend)
+	}
+
+	if s.closer != nil {
+		if cerr := s.closer.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// handshake must be called with the lock held
+func (s *Conn) handshake() error {
+	for !s.conv.IsEncrypted() {
+		if s.closed {
+			return ErrClosed
+		}
+		if s.wasEncrypted {
+			return ErrConversationEnded
+		}
+
+		if !s.queried {
+			if err := s.writeMessages([]otr3.ValidMessage{s.conv.QueryMessage()}); err != nil {
+				return err
+			}
+			s.queried = true
+		}
+
+		if err := s.waitForInput(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// waitForInput must be called with the lock held. It reads and handles the next line from the stream,
+// or waits for the goroutine already doing that
+func (s *Conn) waitForInput() error {
+	if s.readErr != nil {
+		return s.readErr
+	}
+
+	if s.reading {
+		s.input.Wait()
+		return nil
+	}
+
+	s.reading = true
+	s.mu.Unlock()
+	line, err := s.lines.ReadBytes('\n')
+	s.mu.Lock()
+	s.reading = false
+	defer s.input.Broadcast()
+
+	if err != nil {
+		s.readErr = err
+	}
+
+	if line = bytes.TrimRight(line, "\r\n"); len(line) > 0 {
+		if rerr := s.receive(line); rerr != nil {
+			return rerr
+		}
+	}
+
+	return s.readErr
+}
+
+func (s *Conn) receive(line []byte) error {
+	info, toSend, err := s.conv.ReceiveWithInfo(otr3.ValidMessage(line))
+
+	if s.conv.IsEncrypted() {
+		s.wasEncrypted = true
+	}
+
+	if err == nil && len(info.Plaintext) > 0 {
+		if info.Encrypted {
+			s.plain.Write(info.Plaintext)
+		} else {
+			err = ErrUnencryptedData
+		}
+	}
+
+	if werr := s.writeMessages(toSend); err == nil {
+		err = werr
+	}
+	return err
+}
+
+func (s *Conn) writeMessages(msgs []otr3.ValidMessage) error {
+	for _, m := range msgs {
+		line := append(append(make([]byte, 0, len(m)+1), m...), '\n')
+		if _, err := s.w.Write(line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package stream
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/twstrike/otr3/internal/otrtest"
+)
+
+func readString(t *testing.T, r io.Reader) string {
+	buf := make([]byte, 100)
+	n, err := r.Read(buf)
+	assertNil(t, err)
+	return string(buf[:n])
+}
+
+func Test_Write_runsTheAKEAndSendsEncryptedData(t *testing.T) {
+	alice, bob := connectedConns(t)
+
+	done := make(chan error)
+	go func() {
+		_, err := alice.Write([]byte("hello"))
+		done <- err
+	}()
+
+	assertEquals(t, readString(t, bob), "hello")
+	assertNil(t, <-done)
+	assertEquals(t, alice.Conversation().IsEncrypted(), true)
+	assertEquals(t, bob.Conversation().IsEncrypted(), true)
+}
+
+func Test_Conn_carriesDataInBothDirections(t *testing.T) {
+	alice, bob := connectedConns(t)
+
+	go func() {
+		alice.Write([]byte("ping"))
+		alice.Write([]byte("line one\nline two"))
+	}()
+
+	assertEquals(t, readString(t, bob), "ping")
+	assertEquals(t, readString(t, bob), "line one\nline two")
+
+	go bob.Write([]byte("pong"))
+	assertEquals(t, readString(t, alice), "pong")
+}
+
+func Test_Read_returnsEOFWhenThePeerEndsTheConversation(t *testing.T) {
+	alice, bob := connectedConns(t)
+
+	go func() {
+		alice.Write([]byte("bye"))
+		alice.Close()
+	}()
+
+	assertEquals(t, readString(t, bob), "bye")
+	_, err := bob.Read(make([]byte, 10))
+	assertEquals(t, err, io.EOF)
+}
+
+func Test_Read_rejectsUnencryptedData(t *testing.T) {
+	c := New(&readWriter{bytes.NewBufferString("hello\n"), &bytes.Buffer{}}, otrtest.Conversation(t, 0))
+
+	_, err := c.Read(make([]byte, 10))
+
+	assertEquals(t, err, ErrUnencryptedData)
+}
+
+func Test_Write_failsAfterClose(t *testing.T) {
+	var out bytes.Buffer
+	c := New(&out, otrtest.Conversation(t, 0))
+	assertNil(t, c.Close())
+
+	_, err := c.Write([]byte("hello"))
+	assertEquals(t, err, ErrClosed)
+}
+
+func Test_Handshake_returnsTheErrorOfTheStream(t *testing.T) {
+	var out bytes.Buffer
+	c := New(&readWriter{&bytes.Buffer{}, &out}, otrtest.Conversation(t, 0))
+
+	err := c.Handshake()
+
+	assertEquals(t, err, io.EOF)
+	assertDeepEquals(t, out.String(), string(c.Conversation().QueryMessage())+"\n")
+}
+
+type readWriter struct {
+	io.Reader
+	io.Writer
+}
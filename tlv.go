@@ -1,6 +1,6 @@
 package otr3
 
-import "bytes"
+import "github.com/twstrike/otr3/smp"
 
 const tlvHeaderLength = 4
 
@@ -14,6 +14,10 @@ const (
 	tlvTypeSMPAbort          = uint16(0x06)
 	tlvTypeSMP1WithQuestion  = uint16(0x07)
 	tlvTypeExtraSymmetricKey = uint16(0x08)
+	// tlvTypeSMPSecretNormalization isn't part of the OTR protocol. It is sent along the SMP messages to tell how the
+	// sender normalizes the secret, and other clients ignore it. It is taken from the end of the range, away from the
+	// types applications use
+	tlvTypeSMPSecretNormalization = uint16(0xFF01)
 )
 
 type tlvHandler func(*Conversation, tlv, dataMessageExtra) (*tlv, error)
@@ -51,8 +55,11 @@ func initTLVHandlers() {
 }
 
 func messageHandlerForTLV(t tlv) (tlvHandler, error) {
+	if t.tlvType == tlvTypeSMPSecretNormalization {
+		return (*Conversation).processSMPSecretNormalizationTLV, nil
+	}
 	if t.tlvType >= uint16(len(tlvHandlers)) {
-		return nil, newOtrError("unexpected TLV type")
+		return nil, newOtrErrorOfKind(ErrMalformedMessage, "unexpected TLV type").aboutMessage(msgTypeData)
 	}
 	return tlvHandlers[t.tlvType], nil
 }
@@ -73,14 +80,14 @@ func (c *tlv) deserialize(tlvsBytes []byte) error {
 	var ok bool
 	tlvsBytes, c.tlvType, ok = extractShort(tlvsBytes)
 	if !ok {
-		return newOtrError("wrong tlv type")
+		return newOtrErrorOfKind(ErrMalformedMessage, "wrong tlv type").aboutMessage(msgTypeData)
 	}
 	tlvsBytes, c.tlvLength, ok = extractShort(tlvsBytes)
 	if !ok {
-		return newOtrError("wrong tlv length")
+		return newOtrErrorOfKind(ErrMalformedMessage, "wrong tlv length").aboutMessage(msgTypeData)
 	}
 	if len(tlvsBytes) < int(c.tlvLength) {
-		return newOtrError("wrong tlv value")
+		return newOtrErrorOfKind(ErrMalformedMessage, "wrong tlv value").aboutMessage(msgTypeData)
 	}
 	c.tlvValue = tlvsBytes[:int(c.tlvLength)]
 	return nil
@@ -90,98 +97,15 @@ func (c tlv) isSMPMessage() bool {
 	return c.tlvType >= tlvTypeSMP1 && c.tlvType <= tlvTypeSMP1WithQuestion
 }
 
-func (c tlv) smpMessage() (smpMessage, bool) {
-	switch c.tlvType {
-	case tlvTypeSMP1:
-		return toSmpMessage1(c)
-	case tlvTypeSMP1WithQuestion:
-		return toSmpMessage1Q(c)
-	case tlvTypeSMP2:
-		return toSmpMessage2(c)
-	case tlvTypeSMP3:
-		return toSmpMessage3(c)
-	case tlvTypeSMP4:
-		return toSmpMessage4(c)
-	case tlvTypeSMPAbort:
-		return toSmpMessageAbort(c)
-	}
-
-	return nil, false
-}
-
-func toSmpMessage1(t tlv) (msg smp1Message, ok bool) {
-	_, mpis, ok := extractMPIs(t.tlvValue)
-	if !ok || len(mpis) < 6 {
-		return msg, false
-	}
-	msg.g2a = mpis[0]
-	msg.c2 = mpis[1]
-	msg.d2 = mpis[2]
-	msg.g3a = mpis[3]
-	msg.c3 = mpis[4]
-	msg.d3 = mpis[5]
-	return msg, true
-}
-
-func toSmpMessage1Q(t tlv) (msg smp1Message, ok bool) {
-	nulPos := bytes.IndexByte(t.tlvValue, 0)
-	if nulPos == -1 {
-		return msg, false
-	}
-	question := string(t.tlvValue[:nulPos])
-	t.tlvValue = t.tlvValue[(nulPos + 1):]
-	msg, ok = toSmpMessage1(t)
-	msg.hasQuestion = true
-	msg.question = question
-	return msg, ok
-}
-
-func toSmpMessage2(t tlv) (msg smp2Message, ok bool) {
-	_, mpis, ok := extractMPIs(t.tlvValue)
-	if !ok || len(mpis) < 11 {
-		return msg, false
-	}
-	msg.g2b = mpis[0]
-	msg.c2 = mpis[1]
-	msg.d2 = mpis[2]
-	msg.g3b = mpis[3]
-	msg.c3 = mpis[4]
-	msg.d3 = mpis[5]
-	msg.pb = mpis[6]
-	msg.qb = mpis[7]
-	msg.cp = mpis[8]
-	msg.d5 = mpis[9]
-	msg.d6 = mpis[10]
-	return msg, true
-}
-
-func toSmpMessage3(t tlv) (msg smp3Message, ok bool) {
-	_, mpis, ok := extractMPIs(t.tlvValue)
-	if !ok || len(mpis) < 8 {
-		return msg, false
-	}
-	msg.pa = mpis[0]
-	msg.qa = mpis[1]
-	msg.cp = mpis[2]
-	msg.d5 = mpis[3]
-	msg.d6 = mpis[4]
-	msg.ra = mpis[5]
-	msg.cr = mpis[6]
-	msg.d7 = mpis[7]
-	return msg, true
-}
-
-func toSmpMessage4(t tlv) (msg smp4Message, ok bool) {
-	_, mpis, ok := extractMPIs(t.tlvValue)
-	if !ok || len(mpis) < 3 {
-		return msg, false
+func smpMessageTLV(m smp.Message) tlv {
+	body := m.Serialize()
+	return tlv{
+		tlvType:   m.Type(),
+		tlvLength: uint16(len(body)),
+		tlvValue:  body,
 	}
-	msg.rb = mpis[0]
-	msg.cr = mpis[1]
-	msg.d7 = mpis[2]
-	return msg, true
 }
 
-func toSmpMessageAbort(t tlv) (msg smpMessageAbort, ok bool) {
-	return msg, true
+func (c tlv) smpMessage() (smp.Message, error) {
+	return smp.ParseMessage(c.tlvType, c.tlvValue)
 }
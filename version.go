@@ -2,15 +2,14 @@ package otr3
 
 import (
 	"bytes"
-	"errors"
 	"hash"
-	"math/big"
+
+	"github.com/twstrike/otr3/smp"
 )
 
 type otrVersion interface {
 	protocolVersion() uint16
-	parameterLength() int
-	isGroupElement(n *big.Int) bool
+	smpVersion() smp.Version
 	isFragmented(data []byte) bool
 	parseFragmentPrefix(c *Conversation, data []byte) (rest []byte, ignore bool, ok bool)
 	fragmentPrefix(n, total int, itags uint32, itagr uint32) []byte
@@ -106,5 +105,5 @@ func (c *Conversation) setKeyMatchingVersion() error {
 		}
 	}
 
-	return errors.New("no possible key for current version")
+	return errNoKeyForVersion
 }
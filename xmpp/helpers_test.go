@@ -0,0 +1,37 @@
+package xmpp
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func assertEquals(t *testing.T, actual, expected interface{}) {
+	if actual != expected {
+		t.Errorf("Expected:\n%#v \nto equal:\n%#v\n", actual, expected)
+	}
+}
+
+func isNil(actual interface{}) bool {
+	val := reflect.ValueOf(actual)
+	switch val.Kind() {
+	case reflect.Invalid:
+		return true
+	case reflect.Chan, reflect.Func, reflect.Interface, reflect.Map, reflect.Ptr, reflect.Slice:
+		return val.IsNil()
+	default:
+		return actual == nil
+	}
+}
+
+func assertNil(t *testing.T, actual interface{}) {
+	if !isNil(actual) {
+		t.Errorf("Expected:\n%#v \nto be nil\n", actual)
+	}
+}
+
+func assertContains(t *testing.T, actual, expected string) {
+	if !strings.Contains(actual, expected) {
+		t.Errorf("Expected:\n%s \nto contain:\n%s\n", actual, expected)
+	}
+}
@@ -0,0 +1,39 @@
+package xmpp
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"strconv"
+	"strings"
+)
+
+const (
+	otrv3DataPrefix     = "?OTR:AAM"
+	otrv3FragmentPrefix = "?OTR|"
+	// otrv3HeaderLength is the version, the message type and the two instance tags
+	otrv3HeaderLength = 2 + 1 + 4 + 4
+)
+
+// senderInstanceTag returns the instance tag of the sender of an OTR version 3 message, or zero if the message
+// doesn't carry one
+func senderInstanceTag(body string) uint32 {
+	switch {
+	case strings.HasPrefix(body, otrv3FragmentPrefix):
+		tag, err := strconv.ParseUint(strings.SplitN(body[len(otrv3FragmentPrefix):], "|", 2)[0], 16, 32)
+		if err != nil {
+			return 0
+		}
+		return uint32(tag)
+	case strings.HasPrefix(body, otrv3DataPrefix):
+		encoded := body[len("?OTR:"):]
+		if len(encoded) < base64.StdEncoding.EncodedLen(otrv3HeaderLength) {
+			return 0
+		}
+		header, err := base64.StdEncoding.DecodeString(encoded[:base64.StdEncoding.EncodedLen(otrv3HeaderLength)])
+		if err != nil || len(header) < otrv3HeaderLength {
+			return 0
+		}
+		return binary.BigEndian.Uint32(header[3:7])
+	}
+	return 0
+}
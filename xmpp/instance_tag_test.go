@@ -0,0 +1,19 @@
+package xmpp
+
+import "testing"
+
+func Test_senderInstanceTag_readsTheTagOfAFragment(t *testing.T) {
+	assertEquals(t, senderInstanceTag("?OTR|00000100|00000102,00001,00004,one ,"), uint32(0x100))
+}
+
+func Test_senderInstanceTag_readsTheTagOfAMessage(t *testing.T) {
+	// version 3, data message, sender 0x12345678, receiver 0x100
+	assertEquals(t, senderInstanceTag("?OTR:AAMDEjRWeAAAAQABAg==."), uint32(0x12345678))
+}
+
+func Test_senderInstanceTag_isZeroForMessagesWithoutATag(t *testing.T) {
+	assertEquals(t, senderInstanceTag("?OTR:AAIDAAAAAQ=="), uint32(0))
+	assertEquals(t, senderInstanceTag("?OTRv3?"), uint32(0))
+	assertEquals(t, senderInstanceTag("hello"), uint32(0))
+	assertEquals(t, senderInstanceTag("?OTR:AAM"), uint32(0))
+}
@@ -0,0 +1,11 @@
+package xmpp
+
+import "strings"
+
+// bareJID returns the JID without its resource
+func bareJID(jid string) string {
+	if i := strings.Index(jid, "/"); i != -1 {
+		return jid[:i]
+	}
+	return jid
+}
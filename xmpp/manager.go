@@ -0,0 +1,193 @@
+package xmpp
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/twstrike/otr3"
+)
+
+// Manager keeps the OTR conversations of one XMPP account, one for every full JID it chats with.
+// All methods can be called from different goroutines
+type Manager struct {
+	stream          Stream
+	newConversation func(peer string) *otr3.Conversation
+
+	mu            sync.Mutex
+	conversations map[string]*otr3.Conversation
+	instanceTags  map[uint32]string
+}
+
+// NewManager returns a Manager that sends its messages to stream, and creates a conversation with
+// newConversation the first time a full JID is seen. newConversation should set the keys and policies
+func NewManager(stream Stream, newConversation func(peer string) *otr3.Conversation) *Manager {
+	return &Manager{
+		stream:          stream,
+		newConversation: newConversation,
+		conversations:   make(map[string]*otr3.Conversation),
+		instanceTags:    make(map[uint32]string),
+	}
+}
+
+// Conversation returns the conversation with the full JID, creating it if needed.
+// It must not be used at the same time as the other methods of the Manager - use Do for that
+func (m *Manager) Conversation(peer string) *otr3.Conversation {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.conversation(peer)
+}
+
+// Do calls f with the conversation with the full JID, and sends the messages it returns to the peer - also when it
+// returns an error, since the conversation can still have messages for the peer then.
+// It can be used for the operations the Manager doesn't have methods for, such as SMP
+func (m *Manager) Do(peer string, f func(c *otr3.Conversation) ([]otr3.ValidMessage, error)) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	toSend, err := f(m.conversation(peer))
+	if serr := m.send(peer, toSend); err == nil {
+		err = serr
+	}
+	return err
+}
+
+// PeerForInstanceTag returns the full JID of the peer client with the instance tag, once a message from it has been received
+func (m *Manager) PeerForInstanceTag(tag uint32) (string, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	peer, ok := m.instanceTags[tag]
+	return peer, ok
+}
+
+// InstanceTag returns the instance tag of the peer client with the full JID, once a message from it has been received
+func (m *Manager) InstanceTag(peer string) (uint32, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for tag, p := range m.instanceTags {
+		if p == peer {
+			return tag, true
+		}
+	}
+	return 0, false
+}
+
+// Start asks the peer to start a private conversation
+func (m *Manager) Start(peer string) error {
+	return m.Do(peer, func(c *otr3.Conversation) ([]otr3.ValidMessage, error) {
+		return []otr3.ValidMessage{c.QueryMessage()}, nil
+	})
+}
+
+// Send sends the message to the peer, encrypted if the conversation is private
+func (m *Manager) Send(peer, body string) error {
+	return m.Do(peer, func(c *otr3.Conversation) ([]otr3.ValidMessage, error) {
+		return c.Send(otr3.ValidMessage(body))
+	})
+}
+
+// End ends the private conversation with the peer
+func (m *Manager) End(peer string) error {
+	return m.Do(peer, func(c *otr3.Conversation) ([]otr3.ValidMessage, error) {
+		return c.End()
+	})
+}
+
+// Receive handles a message stanza from a peer, and returns the human readable message together with
+// information about how it was received. Error and groupchat messages, and messages without a body, are ignored
+func (m *Manager) Receive(msg *Message) (otr3.ReceivedMessageInfo, error) {
+	if msg.Body == "" || msg.Type == "error" || msg.Type == "groupchat" {
+		return otr3.ReceivedMessageInfo{}, nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	peer := msg.From
+	tag := senderInstanceTag(msg.Body)
+	from := m.previousJID(tag, peer)
+
+	info, toSend, err := m.conversation(from).ReceiveWithInfo(otr3.ValidMessage(msg.Body))
+	if err == nil && tag != 0 && m.mayUseInstanceTag(tag, peer) {
+		m.followInstance(from, peer)
+		m.instanceTags[tag] = peer
+	}
+
+	if serr := m.send(peer, toSend); err == nil {
+		err = serr
+	}
+	return info, err
+}
+
+// previousJID returns the full JID of the conversation with the client of the peer that has the instance tag, if the
+// client has reconnected with another resource, or the peer otherwise. The instance tag isn't authenticated, so the
+// message is received by that conversation and the conversation only follows the client if the message is accepted
+func (m *Manager) previousJID(tag uint32, peer string) string {
+	old, ok := m.instanceTags[tag]
+	if !ok || old == peer || !m.mayUseInstanceTag(tag, peer) {
+		return peer
+	}
+
+	if _, ok := m.conversations[old]; !ok {
+		return peer
+	}
+	if _, exists := m.conversations[peer]; exists {
+		return peer
+	}
+	return old
+}
+
+// followInstance moves the conversation with a client of the peer to its new full JID
+func (m *Manager) followInstance(old, peer string) {
+	if old == peer {
+		return
+	}
+
+	m.conversations[peer] = m.conversations[old]
+	delete(m.conversations, old)
+}
+
+// mayUseInstanceTag returns false if the instance tag belongs to a client of another account
+func (m *Manager) mayUseInstanceTag(tag uint32, peer string) bool {
+	old, ok := m.instanceTags[tag]
+	return !ok || bareJID(old) == bareJID(peer)
+}
+
+func (m *Manager) conversation(peer string) *otr3.Conversation {
+	c, ok := m.conversations[peer]
+	if !ok {
+		c = m.newConversation(peer)
+		m.conversations[peer] = c
+	}
+	return c
+}
+
+func (m *Manager) send(peer string, toSend []otr3.ValidMessage) error {
+	for _, body := range toSend {
+		if err := m.stream.SendMessage(outgoingMessage(peer, body)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// outgoingMessage returns the stanza for an OTR message. Encrypted messages are marked as such, and kept out of
+// archives and carbons
+func outgoingMessage(peer string, body otr3.ValidMessage) *Message {
+	msg := &Message{To: peer, Type: "chat", Body: string(body)}
+	if isEncryptedBody(msg.Body) {
+		msg.Encryption = &Encryption{Namespace: NamespaceOTR, Name: "OTR"}
+		msg.NoStore = &Hint{}
+		msg.NoCopy = &Hint{}
+		msg.Private = &Hint{}
+	}
+	return msg
+}
+
+// isEncryptedBody returns true for the OTR messages that can't be read without OTR - all but plaintext,
+// query and error messages
+func isEncryptedBody(body string) bool {
+	return strings.HasPrefix(body, "?OTR:") || strings.HasPrefix(body, "?OTR|") || strings.HasPrefix(body, "?OTR,")
+}
@@ -0,0 +1,216 @@
+package xmpp
+
+import (
+	"encoding/xml"
+	"errors"
+	"testing"
+
+	"github.com/twstrike/otr3"
+	"github.com/twstrike/otr3/internal/otrtest"
+)
+
+const (
+	aliceJID = "alice@example.com/laptop"
+	bobJID   = "bob@example.com/phone"
+)
+
+// router is an in-memory stand-in for an XMPP server. Stanzas are encoded and decoded on the way,
+// and delivered by flush
+type router struct {
+	t        *testing.T
+	queue    []*Message
+	clients  map[string]*Manager
+	stanzas  []string
+	received map[string][]otr3.ReceivedMessageInfo
+}
+
+type routedStream struct {
+	r    *router
+	from *string
+}
+
+func (s routedStream) SendMessage(m *Message) error {
+	c := *m
+	c.From = *s.from
+
+	stanza, err := xml.Marshal(&c)
+	if err != nil {
+		return err
+	}
+	s.r.stanzas = append(s.r.stanzas, string(stanza))
+
+	routed := &Message{}
+	if err := xml.Unmarshal(stanza, routed); err != nil {
+		return err
+	}
+	s.r.queue = append(s.r.queue, routed)
+	return nil
+}
+
+func newRouter(t *testing.T) *router {
+	return &router{
+		t:        t,
+		clients:  make(map[string]*Manager),
+		received: make(map[string][]otr3.ReceivedMessageInfo),
+	}
+}
+
+// connect returns the manager of a client, and a pointer to the JID it sends from
+func (r *router) connect(jid string, key int) (*Manager, *string) {
+	from := jid
+	m := NewManager(routedStream{r, &from}, func(peer string) *otr3.Conversation {
+		return otrtest.Conversation(r.t, key)
+	})
+	r.clients[jid] = m
+	return m, &from
+}
+
+func (r *router) flush() {
+	for len(r.queue) > 0 {
+		m := r.queue[0]
+		r.queue = r.queue[1:]
+
+		if client, ok := r.clients[m.To]; ok {
+			info, err := client.Receive(m)
+			if err != nil {
+				r.t.Errorf("Receiving %s failed: %v", m.Body, err)
+			}
+			if len(info.Plaintext) > 0 {
+				r.received[m.To] = append(r.received[m.To], info)
+			}
+		}
+	}
+}
+
+func privateConversation(t *testing.T) (r *router, alice, bob *Manager, aliceFrom *string) {
+	r = newRouter(t)
+	alice, aliceFrom = r.connect(aliceJID, 0)
+	bob, _ = r.connect(bobJID, 1)
+
+	alice.Start(bobJID)
+	r.flush()
+	return
+}
+
+func Test_Manager_runsTheAKEAndSendsEncryptedMessages(t *testing.T) {
+	r, alice, bob, _ := privateConversation(t)
+
+	assertEquals(t, alice.Conversation(bobJID).IsEncrypted(), true)
+	assertEquals(t, bob.Conversation(aliceJID).IsEncrypted(), true)
+
+	alice.Send(bobJID, "hello")
+	r.flush()
+
+	assertEquals(t, len(r.received[bobJID]), 1)
+	assertEquals(t, string(r.received[bobJID][0].Plaintext), "hello")
+	assertEquals(t, r.received[bobJID][0].Encrypted, true)
+}
+
+func Test_Manager_marksEncryptedMessagesAndKeepsThemOutOfArchivesAndCarbons(t *testing.T) {
+	r, alice, _, _ := privateConversation(t)
+	r.stanzas = nil
+
+	alice.Send(bobJID, "hello")
+
+	assertEquals(t, len(r.stanzas), 1)
+	assertContains(t, r.stanzas[0], `<encryption xmlns="urn:xmpp:eme:0" namespace="urn:xmpp:otr:0" name="OTR"></encryption>`)
+	assertContains(t, r.stanzas[0], `<no-store xmlns="urn:xmpp:hints"></no-store>`)
+	assertContains(t, r.stanzas[0], `<no-copy xmlns="urn:xmpp:hints"></no-copy>`)
+	assertContains(t, r.stanzas[0], `<private xmlns="urn:xmpp:carbons:2"></private>`)
+}
+
+func Test_Manager_doesntMarkQueryMessagesAsEncrypted(t *testing.T) {
+	r := newRouter(t)
+	alice, _ := r.connect(aliceJID, 0)
+
+	alice.Start(bobJID)
+
+	assertEquals(t, r.queue[0].Encrypted(), false)
+	assertNil(t, r.queue[0].Private)
+}
+
+func Test_Manager_Do_sendsTheMessagesEvenIfThereIsAnError(t *testing.T) {
+	r := newRouter(t)
+	alice, _ := r.connect(aliceJID, 0)
+	failure := errors.New("failure")
+
+	err := alice.Do(bobJID, func(c *otr3.Conversation) ([]otr3.ValidMessage, error) {
+		return []otr3.ValidMessage{otr3.ValidMessage("?OTR Error: failure")}, failure
+	})
+
+	assertEquals(t, err, failure)
+	assertEquals(t, len(r.queue), 1)
+	assertEquals(t, r.queue[0].Body, "?OTR Error: failure")
+}
+
+func Test_Manager_mapsInstanceTagsToFullJIDs(t *testing.T) {
+	_, _, bob, _ := privateConversation(t)
+
+	tag, ok := bob.InstanceTag(aliceJID)
+	assertEquals(t, ok, true)
+
+	peer, ok := bob.PeerForInstanceTag(tag)
+	assertEquals(t, ok, true)
+	assertEquals(t, peer, aliceJID)
+}
+
+func Test_Manager_followsAClientThatReconnectsWithAnotherResource(t *testing.T) {
+	r, alice, bob, aliceFrom := privateConversation(t)
+	tag, _ := bob.InstanceTag(aliceJID)
+
+	*aliceFrom = "alice@example.com/laptop-2"
+	alice.Send(bobJID, "still here")
+	r.flush()
+
+	assertEquals(t, string(r.received[bobJID][0].Plaintext), "still here")
+	assertEquals(t, r.received[bobJID][0].Encrypted, true)
+
+	peer, _ := bob.PeerForInstanceTag(tag)
+	assertEquals(t, peer, "alice@example.com/laptop-2")
+	assertEquals(t, bob.Conversation("alice@example.com/laptop-2").IsEncrypted(), true)
+}
+
+func Test_Manager_doesntFollowAnInstanceTagOfAMessageThatIsntAccepted(t *testing.T) {
+	r, alice, bob, _ := privateConversation(t)
+	tag, _ := bob.InstanceTag(aliceJID)
+
+	alice.Send(bobJID, "hello")
+	forged := *r.queue[0]
+	r.queue = nil
+	body := []byte(forged.Body)
+	body[len(body)-10] ^= 1
+	forged.From, forged.Body = "alice@example.com/evil", string(body)
+
+	_, err := bob.Receive(&forged)
+
+	assertEquals(t, err != nil, true)
+	peer, _ := bob.PeerForInstanceTag(tag)
+	assertEquals(t, peer, aliceJID)
+	assertEquals(t, bob.Conversation(aliceJID).IsEncrypted(), true)
+}
+
+func Test_Manager_doesntFollowAnInstanceTagToAnotherAccount(t *testing.T) {
+	r, alice, bob, aliceFrom := privateConversation(t)
+	tag, _ := bob.InstanceTag(aliceJID)
+
+	*aliceFrom = "mallory@example.com/laptop"
+	alice.Send(bobJID, "hello")
+	r.flush()
+
+	peer, _ := bob.PeerForInstanceTag(tag)
+	assertEquals(t, peer, aliceJID)
+	assertEquals(t, bob.Conversation("mallory@example.com/laptop").IsEncrypted(), false)
+}
+
+func Test_Manager_ignoresErrorAndGroupchatMessages(t *testing.T) {
+	r := newRouter(t)
+	bob, _ := r.connect(bobJID, 1)
+
+	for _, typ := range []string{"error", "groupchat"} {
+		info, err := bob.Receive(&Message{From: aliceJID, To: bobJID, Type: typ, Body: "?OTRv3?"})
+		assertNil(t, err)
+		assertNil(t, info.Plaintext)
+	}
+
+	assertEquals(t, len(r.queue), 0)
+}
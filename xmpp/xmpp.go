@@ -0,0 +1,72 @@
+// Package xmpp carries OTR conversations over XMPP.
+//
+// A Manager keeps one Conversation for every full JID the application chats with. Messages from the peer are given
+// to Receive, and the messages the conversations need to send are given to a Stream as message stanzas. OTR messages
+// are sent in the body of the stanza, marked as encrypted with XEP-0380 and with the XEP-0334 hints that keep the
+// server from storing them, and are kept out of message carbons (XEP-0280), since the other clients of the account
+// can't decrypt them anyway.
+//
+// The package doesn't implement XMPP itself. Message can be encoded with encoding/xml, or copied to the
+// message type of any XMPP library:
+//
+//	m := xmpp.NewManager(stream, func(peer string) *otr3.Conversation {
+//		c := &otr3.Conversation{}
+//		c.SetOurKeys(keys)
+//		c.Policies.AllowV3()
+//		return c
+//	})
+//
+//	m.Send("juliet@example.com/balcony", "hello")
+//	info, err := m.Receive(stanza)
+package xmpp
+
+import "encoding/xml"
+
+const (
+	// NamespaceEME is the namespace of XEP-0380, Explicit Message Encryption
+	NamespaceEME = "urn:xmpp:eme:0"
+	// NamespaceOTR is the namespace XEP-0380 uses for OTR
+	NamespaceOTR = "urn:xmpp:otr:0"
+	// NamespaceHints is the namespace of XEP-0334, Message Processing Hints
+	NamespaceHints = "urn:xmpp:hints"
+	// NamespaceCarbons is the namespace of XEP-0280, Message Carbons
+	NamespaceCarbons = "urn:xmpp:carbons:2"
+)
+
+// Message is a message stanza
+type Message struct {
+	XMLName xml.Name `xml:"jabber:client message"`
+	From    string   `xml:"from,attr,omitempty"`
+	To      string   `xml:"to,attr,omitempty"`
+	Type    string   `xml:"type,attr,omitempty"`
+	ID      string   `xml:"id,attr,omitempty"`
+	Body    string   `xml:"body,omitempty"`
+
+	// Encryption tells clients without OTR support that the body is encrypted, as specified by XEP-0380
+	Encryption *Encryption `xml:"urn:xmpp:eme:0 encryption,omitempty"`
+	// NoStore and NoCopy are the XEP-0334 hints that ask the server not to store or copy the message
+	NoStore *Hint `xml:"urn:xmpp:hints no-store,omitempty"`
+	NoCopy  *Hint `xml:"urn:xmpp:hints no-copy,omitempty"`
+	// Private keeps the message out of message carbons, as specified by XEP-0280
+	Private *Hint `xml:"urn:xmpp:carbons:2 private,omitempty"`
+}
+
+// Encryption is the XEP-0380 element naming the encryption used for a message
+type Encryption struct {
+	Namespace string `xml:"namespace,attr"`
+	Name      string `xml:"name,attr,omitempty"`
+}
+
+// Hint is an empty element used as a processing hint
+type Hint struct{}
+
+// Stream sends message stanzas to the XMPP server
+type Stream interface {
+	// SendMessage sends the message. It must not call back into the Manager
+	SendMessage(m *Message) error
+}
+
+// Encrypted returns true if the message is marked as encrypted with OTR
+func (m *Message) Encrypted() bool {
+	return m.Encryption != nil && m.Encryption.Namespace == NamespaceOTR
+}
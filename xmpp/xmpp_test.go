@@ -0,0 +1,33 @@
+package xmpp
+
+import (
+	"encoding/xml"
+	"testing"
+
+	"github.com/twstrike/otr3"
+)
+
+func Test_Message_isEncodedAsAMessageStanza(t *testing.T) {
+	m := outgoingMessage("bob@example.com", otr3.ValidMessage("?OTR:AAMD."))
+
+	stanza, err := xml.Marshal(m)
+
+	assertNil(t, err)
+	assertEquals(t, string(stanza), `<message xmlns="jabber:client" to="bob@example.com" type="chat"><body>?OTR:AAMD.</body>`+
+		`<encryption xmlns="urn:xmpp:eme:0" namespace="urn:xmpp:otr:0" name="OTR"></encryption>`+
+		`<no-store xmlns="urn:xmpp:hints"></no-store><no-copy xmlns="urn:xmpp:hints"></no-copy>`+
+		`<private xmlns="urn:xmpp:carbons:2"></private></message>`)
+}
+
+func Test_Message_Encrypted_isTrueForOTREncryption(t *testing.T) {
+	m := &Message{}
+	xml.Unmarshal([]byte(`<message xmlns="jabber:client"><body>x</body><encryption xmlns="urn:xmpp:eme:0" namespace="urn:xmpp:otr:0"/></message>`), m)
+
+	assertEquals(t, m.Encrypted(), true)
+	assertEquals(t, (&Message{Encryption: &Encryption{Namespace: "eu.siacs.conversations.axolotl"}}).Encrypted(), false)
+}
+
+func Test_bareJID_removesTheResource(t *testing.T) {
+	assertEquals(t, bareJID("alice@example.com/laptop/1"), "alice@example.com")
+	assertEquals(t, bareJID("alice@example.com"), "alice@example.com")
+}